@@ -0,0 +1,115 @@
+// Command ai-eval runs a JSONL dataset of prompts and expected outputs
+// through the gateway's AI service and reports quality and latency.
+//
+// Each dataset line is a case such as
+//
+//	{"id": "capital-fr", "prompt": "The capital of France is", "expected": "Paris"}
+//
+// with optional mode (complete, generate or chat with messages), max_tokens
+// and temperature. The run is written as a JSON and a Markdown report. With
+// -baseline, the run is compared to an earlier JSON report and the command
+// exits with status 1 when anything regressed.
+//
+// Usage:
+//
+//	ai-eval -dataset cases.jsonl [-backend default] [-out eval-report] [-baseline previous.json]
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Ammar0144/ai/models"
+	"github.com/Ammar0144/ai/services"
+)
+
+func main() {
+	dataset := flag.String("dataset", "", "JSONL file of evaluation cases (required)")
+	backend := flag.String("backend", services.DefaultCompareBackend, "Backend to evaluate: \"default\" or a name from AI_COMPARE_BACKENDS")
+	out := flag.String("out", "eval-report", "Report path without extension; writes .json and .md")
+	baselinePath := flag.String("baseline", "", "Earlier JSON report to check for regressions")
+	tolerance := flag.Float64("tolerance", 0.01, "Drop in a quality score or rise in failure rate allowed before it counts as a regression")
+	latencyTolerance := flag.Float64("latency-tolerance", 0.2, "Fractional p95 latency increase allowed before it counts as a regression")
+	parallel := flag.Int("parallel", 4, "Cases in flight at once")
+	timeout := flag.Duration("timeout", 60*time.Second, "Timeout per case")
+	maxTokens := flag.Int("max-tokens", 150, "max_tokens for cases that do not set it")
+	temperature := flag.Float64("temperature", 0.7, "temperature for cases that do not set it")
+	flag.Parse()
+
+	if *dataset == "" {
+		fmt.Fprintln(os.Stderr, "ai-eval: -dataset is required")
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	// The comparer knows the default upstream and every configured backend
+	comparer := services.NewComparer(services.NewAIService())
+	service, ok := comparer.Backend(*backend)
+	if !ok {
+		log.Fatalf("unknown backend %q; configured backends are %s", *backend, strings.Join(comparer.Backends(), ", "))
+	}
+
+	file, err := os.Open(*dataset)
+	if err != nil {
+		log.Fatalf("failed to open dataset: %v", err)
+	}
+	cases, err := services.LoadEvalDataset(file)
+	file.Close()
+	if err != nil {
+		log.Fatalf("failed to read dataset %s: %v", *dataset, err)
+	}
+
+	var baseline *models.EvalReport
+	if *baselinePath != "" {
+		data, err := os.ReadFile(*baselinePath)
+		if err != nil {
+			log.Fatalf("failed to read baseline: %v", err)
+		}
+		baseline = &models.EvalReport{}
+		if err := json.Unmarshal(data, baseline); err != nil {
+			log.Fatalf("failed to parse baseline %s: %v", *baselinePath, err)
+		}
+	}
+
+	log.Printf("Evaluating %d cases from %s on backend %s", len(cases), *dataset, *backend)
+	report := services.RunEval(context.Background(), service, cases, services.EvalOptions{
+		Parallel:    *parallel,
+		Timeout:     *timeout,
+		MaxTokens:   *maxTokens,
+		Temperature: *temperature,
+	})
+	report.Dataset = filepath.Base(*dataset)
+	report.Backend = *backend
+	if baseline != nil {
+		report.Baseline = *baselinePath
+		report.Regressions = services.CompareEvalReports(baseline, report, *tolerance, *latencyTolerance)
+	}
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		log.Fatalf("failed to encode report: %v", err)
+	}
+	if err := os.WriteFile(*out+".json", data, 0644); err != nil {
+		log.Fatalf("failed to write report: %v", err)
+	}
+	if err := os.WriteFile(*out+".md", []byte(services.RenderEvalMarkdown(report, baseline)), 0644); err != nil {
+		log.Fatalf("failed to write report: %v", err)
+	}
+
+	metrics := report.Metrics
+	log.Printf("Exact match %.3f, token F1 %.3f, ROUGE-L %.3f, BLEU %.3f, failure rate %.3f, p95 %dms",
+		metrics.ExactMatch, metrics.TokenF1, metrics.RougeL, metrics.BLEU, metrics.FailureRate, metrics.LatencyP95Ms)
+	log.Printf("Reports written to %s.json and %s.md", *out, *out)
+
+	if len(report.Regressions) > 0 {
+		log.Printf("%d regressions against %s", len(report.Regressions), *baselinePath)
+		os.Exit(1)
+	}
+}
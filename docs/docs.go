@@ -24,9 +24,9 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/ai/chat/completions": {
+        "/ai/agent": {
             "post": {
-                "description": "Generate a chat completion based on conversation history. Rate limited to 30 requests per minute per IP address.",
+                "description": "Run a bounded reason-act loop with built-in sandboxed tools (calculator, current_time, knowledge_lookup). Returns the final answer and a full step trace. Rate limited to 30 requests per minute per IP address.",
                 "consumes": [
                     "application/json"
                 ],
@@ -36,23 +36,23 @@ const docTemplate = `{
                 "tags": [
                     "AI Processing"
                 ],
-                "summary": "Chat completion",
+                "summary": "Agent",
                 "parameters": [
                     {
-                        "description": "Chat completion request",
+                        "description": "Agent request",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.ChatCompletionRequest"
+                            "$ref": "#/definitions/models.AgentRequest"
                         }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Successful chat completion",
+                        "description": "Agent run result",
                         "schema": {
-                            "$ref": "#/definitions/models.ChatCompletionResponse"
+                            "$ref": "#/definitions/models.AgentResponse"
                         }
                     },
                     "400": {
@@ -76,9 +76,137 @@ const docTemplate = `{
                 }
             }
         },
-        "/ai/complete": {
+        "/ai/agent/knowledge": {
+            "get": {
+                "description": "List (GET), add or replace (POST/PUT with {\"key\",\"value\"}) and delete (DELETE ?key=) static knowledge for the agent's knowledge_lookup tool. Changes require the X-Admin-Token header. Rate limited to 100 requests per minute per IP address.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Admin"
+                ],
+                "summary": "Agent knowledge base",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin token (required for changes)",
+                        "name": "X-Admin-Token",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Key to delete",
+                        "name": "key",
+                        "in": "query"
+                    },
+                    {
+                        "description": "Entry to store",
+                        "name": "entry",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/models.KnowledgeEntry"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Knowledge entries",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.KnowledgeEntry"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Entry not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "List (GET), add or replace (POST/PUT with {\"key\",\"value\"}) and delete (DELETE ?key=) static knowledge for the agent's knowledge_lookup tool. Changes require the X-Admin-Token header. Rate limited to 100 requests per minute per IP address.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Admin"
+                ],
+                "summary": "Agent knowledge base",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin token (required for changes)",
+                        "name": "X-Admin-Token",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Key to delete",
+                        "name": "key",
+                        "in": "query"
+                    },
+                    {
+                        "description": "Entry to store",
+                        "name": "entry",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/models.KnowledgeEntry"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Knowledge entries",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.KnowledgeEntry"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Entry not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
             "post": {
-                "description": "Complete text based on a given prompt. Rate limited to 30 requests per minute per IP address.",
+                "description": "List (GET), add or replace (POST/PUT with {\"key\",\"value\"}) and delete (DELETE ?key=) static knowledge for the agent's knowledge_lookup tool. Changes require the X-Admin-Token header. Rate limited to 100 requests per minute per IP address.",
                 "consumes": [
                     "application/json"
                 ],
@@ -86,25 +214,39 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "AI Processing"
+                    "Admin"
                 ],
-                "summary": "Text completion",
+                "summary": "Agent knowledge base",
                 "parameters": [
                     {
-                        "description": "Complete request",
-                        "name": "request",
+                        "type": "string",
+                        "description": "Admin token (required for changes)",
+                        "name": "X-Admin-Token",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Key to delete",
+                        "name": "key",
+                        "in": "query"
+                    },
+                    {
+                        "description": "Entry to store",
+                        "name": "entry",
                         "in": "body",
-                        "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.CompleteRequest"
+                            "$ref": "#/definitions/models.KnowledgeEntry"
                         }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Successful text completion",
+                        "description": "Knowledge entries",
                         "schema": {
-                            "$ref": "#/definitions/models.CompleteResponse"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.KnowledgeEntry"
+                            }
                         }
                     },
                     "400": {
@@ -113,14 +255,78 @@ const docTemplate = `{
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
                     },
-                    "429": {
-                        "description": "Rate limit exceeded",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
                     },
-                    "500": {
-                        "description": "Internal server error",
+                    "404": {
+                        "description": "Entry not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "List (GET), add or replace (POST/PUT with {\"key\",\"value\"}) and delete (DELETE ?key=) static knowledge for the agent's knowledge_lookup tool. Changes require the X-Admin-Token header. Rate limited to 100 requests per minute per IP address.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Admin"
+                ],
+                "summary": "Agent knowledge base",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin token (required for changes)",
+                        "name": "X-Admin-Token",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Key to delete",
+                        "name": "key",
+                        "in": "query"
+                    },
+                    {
+                        "description": "Entry to store",
+                        "name": "entry",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/models.KnowledgeEntry"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Knowledge entries",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.KnowledgeEntry"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Entry not found",
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
@@ -128,9 +334,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/ai/generate": {
+        "/ai/autocomplete": {
             "post": {
-                "description": "Generate text based on a given prompt. Rate limited to 30 requests per minute per IP address.",
+                "description": "Suggest a short continuation of the prefix for keystroke-by-keystroke typeahead. A newer request with the same session ID (X-Session-ID header or session_id) cancels the older one, which returns 409. Identical in-flight prefixes share one upstream call (source \"coalesced\"), and a prefix that extends an earlier prefix by the start of its suggestion is answered from the rest of that suggestion without calling the model (source \"cache\"). The suggestion stops at the end of the line. The prefix is limited to 4 KB; send the text nearest the cursor. max_tokens defaults to 8 (at most 16), temperature to 0.2, and the upstream call times out after 5 seconds. Rate limited to 300 requests per minute per IP address, separately from the other AI endpoints.",
                 "consumes": [
                     "application/json"
                 ],
@@ -140,23 +346,29 @@ const docTemplate = `{
                 "tags": [
                     "AI Processing"
                 ],
-                "summary": "Text generation",
+                "summary": "Typeahead autocomplete",
                 "parameters": [
                     {
-                        "description": "Generate request",
+                        "description": "Typed text",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.GenerateRequest"
+                            "$ref": "#/definitions/models.AutocompleteRequest"
                         }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Typing session; a newer request cancels the older one",
+                        "name": "X-Session-ID",
+                        "in": "header"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Successful text generation",
+                        "description": "Suggested continuation",
                         "schema": {
-                            "$ref": "#/definitions/models.GenerateResponse"
+                            "$ref": "#/definitions/models.AutocompleteResponse"
                         }
                     },
                     "400": {
@@ -165,6 +377,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
                     },
+                    "409": {
+                        "description": "Superseded by a newer request from the session",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
                     "429": {
                         "description": "Rate limit exceeded",
                         "schema": {
@@ -176,36 +394,61 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
+                    },
+                    "504": {
+                        "description": "Upstream timed out",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
                     }
                 }
             }
         },
-        "/ai/model-info": {
-            "get": {
-                "description": "Get detailed information about the current AI model being used. Rate limited to 100 requests per minute per IP address.",
+        "/ai/chains": {
+            "post": {
+                "description": "Run a DAG of complete, generate or chat steps in one request, either a stored chain by name or an inline chain. Step prompts are Go text/templates over {{.input}} and earlier outputs as {{.steps.\u003cid\u003e}}; steps run in parallel where the dependencies allow. Returns every intermediate output with per-step timings and errors. Rate limited to 30 requests per minute per IP address.",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "System"
+                    "Chains"
+                ],
+                "summary": "Run a prompt chain",
+                "parameters": [
+                    {
+                        "description": "Chain name or inline chain, with input",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.ChainRequest"
+                        }
+                    }
                 ],
-                "summary": "Get model information",
                 "responses": {
                     "200": {
-                        "description": "Model information",
+                        "description": "Chain result",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": true
+                            "$ref": "#/definitions/models.ChainResponse"
                         }
                     },
-                    "429": {
-                        "description": "Rate limit exceeded",
+                    "400": {
+                        "description": "Bad request",
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
                     },
-                    "500": {
-                        "description": "Internal server error",
+                    "404": {
+                        "description": "Chain not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit exceeded",
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
@@ -213,64 +456,6468 @@ const docTemplate = `{
                 }
             }
         },
-        "/health": {
+        "/ai/chains/definitions": {
             "get": {
-                "description": "Check the health status of the AI service. Rate limited to 200 requests per minute per IP address.",
+                "description": "GET lists stored chain definitions. POST validates and stores a named chain so it can be run with {\"name\": ...}. Creating requires the X-Admin-Token header. Rate limited to 100 requests per minute per IP address.",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "System"
+                    "Chains"
+                ],
+                "summary": "List or create stored chains",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin token (required for POST)",
+                        "name": "X-Admin-Token",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Chain to store (POST)",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/models.ChainDefinition"
+                        }
+                    }
                 ],
-                "summary": "Health check",
                 "responses": {
                     "200": {
-                        "description": "Service is healthy",
+                        "description": "Chains",
                         "schema": {
-                            "$ref": "#/definitions/models.HealthResponse"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.ChainDefinition"
+                            }
                         }
                     },
-                    "429": {
-                        "description": "Rate limit exceeded",
+                    "201": {
+                        "description": "Created chain",
+                        "schema": {
+                            "$ref": "#/definitions/models.ChainDefinition"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Chain already exists",
                         "schema": {
                             "$ref": "#/definitions/models.ErrorResponse"
                         }
                     }
                 }
-            }
-        }
-    },
-    "definitions": {
-        "models.ChatCompletionRequest": {
-            "type": "object",
-            "properties": {
-                "max_tokens": {
-                    "type": "integer"
-                },
-                "messages": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/models.ChatMessage"
+            },
+            "post": {
+                "description": "GET lists stored chain definitions. POST validates and stores a named chain so it can be run with {\"name\": ...}. Creating requires the X-Admin-Token header. Rate limited to 100 requests per minute per IP address.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Chains"
+                ],
+                "summary": "List or create stored chains",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin token (required for POST)",
+                        "name": "X-Admin-Token",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Chain to store (POST)",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/models.ChainDefinition"
+                        }
                     }
-                },
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Chains",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.ChainDefinition"
+                            }
+                        }
+                    },
+                    "201": {
+                        "description": "Created chain",
+                        "schema": {
+                            "$ref": "#/definitions/models.ChainDefinition"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Chain already exists",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/ai/chains/definitions/{name}": {
+            "get": {
+                "description": "GET returns the chain definition. PUT replaces it. DELETE removes it. Changes require the X-Admin-Token header. Rate limited to 100 requests per minute per IP address.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Chains"
+                ],
+                "summary": "Get, replace or delete a stored chain",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Chain name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Admin token (required for PUT and DELETE)",
+                        "name": "X-Admin-Token",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Replacement chain (PUT)",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/models.ChainDefinition"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Chain",
+                        "schema": {
+                            "$ref": "#/definitions/models.ChainDefinition"
+                        }
+                    },
+                    "204": {
+                        "description": "Chain deleted"
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Chain not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "GET returns the chain definition. PUT replaces it. DELETE removes it. Changes require the X-Admin-Token header. Rate limited to 100 requests per minute per IP address.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Chains"
+                ],
+                "summary": "Get, replace or delete a stored chain",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Chain name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Admin token (required for PUT and DELETE)",
+                        "name": "X-Admin-Token",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Replacement chain (PUT)",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/models.ChainDefinition"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Chain",
+                        "schema": {
+                            "$ref": "#/definitions/models.ChainDefinition"
+                        }
+                    },
+                    "204": {
+                        "description": "Chain deleted"
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Chain not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "GET returns the chain definition. PUT replaces it. DELETE removes it. Changes require the X-Admin-Token header. Rate limited to 100 requests per minute per IP address.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Chains"
+                ],
+                "summary": "Get, replace or delete a stored chain",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Chain name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Admin token (required for PUT and DELETE)",
+                        "name": "X-Admin-Token",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Replacement chain (PUT)",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/models.ChainDefinition"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Chain",
+                        "schema": {
+                            "$ref": "#/definitions/models.ChainDefinition"
+                        }
+                    },
+                    "204": {
+                        "description": "Chain deleted"
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Chain not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/ai/chat/completions": {
+            "post": {
+                "description": "Generate a chat completion based on conversation history. Supports OpenAI-style tools and tool_calls; tools are emulated through the prompt when the upstream has no native support. When tool_choice is \"required\" or names a function, a reply without a matching call is retried once and then fails. With conversation_id, only the new turn needs to be sent and both turns are appended to the stored conversation. With template_id, the rendered prompt template is appended as a user message, so the messages must not already end with one. With memory.mode \"summary\", older turns beyond a token threshold are replaced by a cached rolling summary. With dry_run or the X-AI-Dry-Run header, the rendered upstream request is returned instead of calling the model. Rate limited to 30 requests per minute per IP address.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "AI Processing"
+                ],
+                "summary": "Chat completion",
+                "parameters": [
+                    {
+                        "description": "Chat completion request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.ChatCompletionRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "User ID owning conversation_id",
+                        "name": "X-User-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Return the upstream request without calling the model",
+                        "name": "X-AI-Dry-Run",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successful chat completion",
+                        "schema": {
+                            "$ref": "#/definitions/models.ChatCompletionResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Conversation not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/ai/classify": {
+            "post": {
+                "description": "Return a probability for each candidate label, highest first. When AI_UPSTREAM_CAPABILITIES includes \"logprobs\", labels are scored from echoed token logprobs: single-label scores are a softmax over each label name's mean token logprob, and with multi_label each label is scored by a yes/no question. Otherwise the model is sampled votes times (default 5) with a prompt constrained to the label names, and each label scores the share of votes naming it. With multi_label, every label scoring at least threshold (default 0.5) is selected; otherwise the top label is selected when it reaches threshold (default 0). With dry_run or the X-AI-Dry-Run header, the first upstream request is returned instead of calling the model. Rate limited to 30 requests per minute per IP address.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "AI Processing"
+                ],
+                "summary": "Zero-shot classification",
+                "parameters": [
+                    {
+                        "description": "Text and candidate labels",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.ClassifyRequest"
+                        }
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Return the first upstream request without calling the model",
+                        "name": "X-AI-Dry-Run",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Label scores",
+                        "schema": {
+                            "$ref": "#/definitions/models.ClassifyResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/ai/compare": {
+            "post": {
+                "description": "Send the same request to two or more backends in parallel and return each output with its latency, estimated token usage and error, in request order. Backends are the gateway's own upstream (\"default\") and those configured in AI_COMPARE_BACKENDS as name=url pairs; all of them are used when backends is empty, and at least two are required. mode selects the upstream endpoint: complete (default), generate or chat with messages. Every pair of successful outputs is scored by word Jaccard overlap, ROUGE-L (lcs_ratio) and length ratio, and with diff the pair includes a word diff where [-...-] is only in a and {+...+} only in b. A failing backend does not fail the request. Rate limited to 30 requests per minute per IP address.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "AI Processing"
+                ],
+                "summary": "Compare models",
+                "parameters": [
+                    {
+                        "description": "Request to compare",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.CompareRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Outputs and pairwise comparisons",
+                        "schema": {
+                            "$ref": "#/definitions/models.CompareResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/ai/complete": {
+            "post": {
+                "description": "Complete text based on a given prompt, or on a stored prompt template via template_id and variables. With few_shot {\"task\",\"k\"}, the k stored examples most similar to the prompt are prepended. With dry_run or the X-AI-Dry-Run header, the rendered upstream request is returned instead of calling the model. Rate limited to 30 requests per minute per IP address.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "AI Processing"
+                ],
+                "summary": "Text completion",
+                "parameters": [
+                    {
+                        "description": "Complete request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.CompleteRequest"
+                        }
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Return the upstream request without calling the model",
+                        "name": "X-AI-Dry-Run",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successful text completion",
+                        "schema": {
+                            "$ref": "#/definitions/models.CompleteResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/ai/conversations": {
+            "get": {
+                "description": "GET lists the user's stored conversations, most recent first. POST creates a conversation, optionally seeded with messages. Conversations are scoped by the X-User-ID header or user_id parameter. Rate limited to 100 requests per minute per IP address.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Conversations"
+                ],
+                "summary": "List or create conversations",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "X-User-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "user_id",
+                        "in": "query"
+                    },
+                    {
+                        "description": "Conversation to create (POST)",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/models.CreateConversationRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Conversations",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.ConversationInfo"
+                            }
+                        }
+                    },
+                    "201": {
+                        "description": "Created conversation",
+                        "schema": {
+                            "$ref": "#/definitions/models.Conversation"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "GET lists the user's stored conversations, most recent first. POST creates a conversation, optionally seeded with messages. Conversations are scoped by the X-User-ID header or user_id parameter. Rate limited to 100 requests per minute per IP address.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Conversations"
+                ],
+                "summary": "List or create conversations",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "X-User-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "user_id",
+                        "in": "query"
+                    },
+                    {
+                        "description": "Conversation to create (POST)",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/models.CreateConversationRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Conversations",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.ConversationInfo"
+                            }
+                        }
+                    },
+                    "201": {
+                        "description": "Created conversation",
+                        "schema": {
+                            "$ref": "#/definitions/models.Conversation"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/ai/conversations/export": {
+            "get": {
+                "description": "Stream every conversation of the user in the chosen format. Output is flushed per conversation so large exports start immediately. Rate limited to 100 requests per minute per IP address.",
+                "produces": [
+                    "application/json",
+                    "text/plain"
+                ],
+                "tags": [
+                    "Conversations"
+                ],
+                "summary": "Export all conversations",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Export format: openai, sharegpt, markdown or csv (default openai)",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "X-User-ID",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Exported conversations",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/ai/conversations/import": {
+            "post": {
+                "description": "Import conversations from OpenAI fine-tuning JSONL, ShareGPT JSON, Markdown transcripts or CSV (the request body is the file). Each imported conversation becomes a new stored conversation. Rate limited to 100 requests per minute per IP address.",
+                "consumes": [
+                    "text/plain"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Conversations"
+                ],
+                "summary": "Import conversations",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Import format: openai, sharegpt, markdown or csv (default openai)",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "X-User-ID",
+                        "in": "header"
+                    },
+                    {
+                        "description": "File contents",
+                        "name": "file",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Imported conversations",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.ConversationInfo"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/ai/conversations/{id}": {
+            "get": {
+                "description": "GET returns the conversation with its message tree and the active branch as a linear message list. DELETE removes it. Rate limited to 100 requests per minute per IP address.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Conversations"
+                ],
+                "summary": "Get or delete a conversation",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Conversation ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "X-User-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "user_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Conversation",
+                        "schema": {
+                            "$ref": "#/definitions/models.Conversation"
+                        }
+                    },
+                    "204": {
+                        "description": "Conversation deleted"
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Conversation not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "GET returns the conversation with its message tree and the active branch as a linear message list. DELETE removes it. Rate limited to 100 requests per minute per IP address.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Conversations"
+                ],
+                "summary": "Get or delete a conversation",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Conversation ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "X-User-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "user_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Conversation",
+                        "schema": {
+                            "$ref": "#/definitions/models.Conversation"
+                        }
+                    },
+                    "204": {
+                        "description": "Conversation deleted"
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Conversation not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/ai/conversations/{id}/active": {
+            "post": {
+                "description": "Make the branch containing node_id active. If node_id is not a leaf, its most recent descendants are followed. Subsequent chat calls with conversation_id continue from this branch. Rate limited to 100 requests per minute per IP address.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Conversations"
+                ],
+                "summary": "Switch active branch",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Conversation ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "X-User-ID",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Node to activate",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.SwitchBranchRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "New active path",
+                        "schema": {
+                            "$ref": "#/definitions/models.ConversationPath"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Conversation or message not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/ai/conversations/{id}/branches": {
+            "get": {
+                "description": "List every branch (leaf) of the conversation tree, marking the active one and where each diverges from it. Rate limited to 100 requests per minute per IP address.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Conversations"
+                ],
+                "summary": "List conversation branches",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Conversation ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "X-User-ID",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Branches",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.ConversationBranch"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Conversation not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/ai/conversations/{id}/export": {
+            "get": {
+                "description": "Export the active branch of a conversation as OpenAI fine-tuning JSONL (openai), ShareGPT JSON (sharegpt), a Markdown transcript (markdown) or CSV (csv). Rate limited to 100 requests per minute per IP address.",
+                "produces": [
+                    "application/json",
+                    "text/plain"
+                ],
+                "tags": [
+                    "Conversations"
+                ],
+                "summary": "Export a conversation",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Conversation ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Export format: openai, sharegpt, markdown or csv (default openai)",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "X-User-ID",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Exported conversation",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Conversation not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/ai/conversations/{id}/messages/{node_id}/edit": {
+            "post": {
+                "description": "Create an edited copy of a message as a sibling branch instead of overwriting it, and make it active. With regenerate, a new assistant reply is generated for the edited branch and recorded like other AI responses; its id is returned as response_id and in the X-Response-ID header for feedback. Rate limited to 100 requests per minute per IP address, or 30 with regenerate.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Conversations"
+                ],
+                "summary": "Edit a message",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Conversation ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Message node ID",
+                        "name": "node_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "X-User-ID",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Edit request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.EditMessageRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "New active path",
+                        "schema": {
+                            "$ref": "#/definitions/models.ConversationPath"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Conversation or message not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/ai/conversations/{id}/messages/{node_id}/regenerate": {
+            "post": {
+                "description": "Generate a new assistant reply for the same history as an existing one. The new reply becomes a sibling branch and is made active. It is recorded like other AI responses, and its id is returned as response_id and in the X-Response-ID header for feedback. Rate limited to 30 requests per minute per IP address.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Conversations"
+                ],
+                "summary": "Regenerate a reply",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Conversation ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Assistant message node ID",
+                        "name": "node_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "X-User-ID",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Generation options",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/models.RegenerateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "New active path",
+                        "schema": {
+                            "$ref": "#/definitions/models.ConversationPath"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Conversation or message not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/ai/conversations/{id}/path": {
+            "get": {
+                "description": "Return the messages from the root to node_id (the active leaf when omitted) as a linear list, ready to send to /ai/chat/completions. Rate limited to 100 requests per minute per IP address.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Conversations"
+                ],
+                "summary": "Get a conversation path",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Conversation ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Node ID (defaults to the active leaf)",
+                        "name": "node_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "X-User-ID",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Path",
+                        "schema": {
+                            "$ref": "#/definitions/models.ConversationPath"
+                        }
+                    },
+                    "404": {
+                        "description": "Conversation or message not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/ai/embeddings": {
+            "post": {
+                "description": "Return an embedding vector for each input string. Requests are proxied to the upstream embeddings API when AI_UPSTREAM_CAPABILITIES includes \"embeddings\"; otherwise the built-in deterministic hashing-trick embedder (model local-hashing-v1, 256 dimensions) is used. Vectors are cached by input hash. Rate limited to 30 requests per minute per IP address.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "AI Processing"
+                ],
+                "summary": "Embeddings",
+                "parameters": [
+                    {
+                        "description": "Texts to embed",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.EmbeddingRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Vectors in input order",
+                        "schema": {
+                            "$ref": "#/definitions/models.EmbeddingResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/ai/examples": {
+            "get": {
+                "description": "GET lists stored input/output examples, optionally filtered by ?task=. POST adds one example or an array of examples. Requests to /ai/complete and /ai/generate with few_shot {\"task\",\"k\"} prepend the k examples most similar to the prompt. Adding requires the X-Admin-Token header. Rate limited to 100 requests per minute per IP address.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Examples"
+                ],
+                "summary": "List or add few-shot examples",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Task to filter by",
+                        "name": "task",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Admin token (required for POST)",
+                        "name": "X-Admin-Token",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Example or array of examples to add (POST)",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/models.ExampleRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Examples",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Example"
+                            }
+                        }
+                    },
+                    "201": {
+                        "description": "Added examples",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Example"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "413": {
+                        "description": "Request body too large",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "GET lists stored input/output examples, optionally filtered by ?task=. POST adds one example or an array of examples. Requests to /ai/complete and /ai/generate with few_shot {\"task\",\"k\"} prepend the k examples most similar to the prompt. Adding requires the X-Admin-Token header. Rate limited to 100 requests per minute per IP address.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Examples"
+                ],
+                "summary": "List or add few-shot examples",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Task to filter by",
+                        "name": "task",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Admin token (required for POST)",
+                        "name": "X-Admin-Token",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Example or array of examples to add (POST)",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/models.ExampleRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Examples",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Example"
+                            }
+                        }
+                    },
+                    "201": {
+                        "description": "Added examples",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Example"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "413": {
+                        "description": "Request body too large",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/ai/examples/{id}": {
+            "get": {
+                "description": "GET returns the example. DELETE removes it and requires the X-Admin-Token header. Rate limited to 100 requests per minute per IP address.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Examples"
+                ],
+                "summary": "Get or delete a few-shot example",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Example ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Admin token (required for DELETE)",
+                        "name": "X-Admin-Token",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Example",
+                        "schema": {
+                            "$ref": "#/definitions/models.Example"
+                        }
+                    },
+                    "204": {
+                        "description": "Example deleted"
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Example not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "GET returns the example. DELETE removes it and requires the X-Admin-Token header. Rate limited to 100 requests per minute per IP address.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Examples"
+                ],
+                "summary": "Get or delete a few-shot example",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Example ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Admin token (required for DELETE)",
+                        "name": "X-Admin-Token",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Example",
+                        "schema": {
+                            "$ref": "#/definitions/models.Example"
+                        }
+                    },
+                    "204": {
+                        "description": "Example deleted"
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Example not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/ai/extract": {
+            "post": {
+                "description": "Extract the requested fields from a text as a typed JSON object. Each field has a name, a type (string, number, integer, boolean, date or enum with options), an optional description and a required flag. The model is asked for a JSON object through the completion path; values are then coerced to their types (numbers with currency symbols and either decimal convention, dates in common written and numeric formats normalized to YYYY-MM-DD, enums matched to their options) and aligned with the text. Each field carries its source span and a confidence: 1 when found verbatim, 0.9 when found by its typed value, scaled by word overlap for approximate string matches, and 0.3 when the text does not contain it. missing lists required fields that were not found, and valid reports whether data satisfies the specification. With dry_run or the X-AI-Dry-Run header, the upstream request is returned instead of calling the model. Rate limited to 30 requests per minute per IP address.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "AI Processing"
+                ],
+                "summary": "Field extraction",
+                "parameters": [
+                    {
+                        "description": "Text and field specification",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.ExtractRequest"
+                        }
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Return the upstream request without calling the model",
+                        "name": "X-AI-Dry-Run",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Extracted fields",
+                        "schema": {
+                            "$ref": "#/definitions/models.ExtractResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/ai/feedback": {
+            "get": {
+                "description": "POST rates an AI response by the id it was returned with: rating is \"up\" or \"down\", with an optional comment and tags. The feedback stores a snapshot of the response's prompt, output, model and parameters, so responses can only be rated while they are stored (see AI_RESPONSE_RETENTION). A user rating the same response again replaces their feedback. The user is taken from the X-User-ID header or user_id. GET lists feedback, most recently updated first, filtered by rating, tag, user_id, response_id, endpoint and model, and requires the X-Admin-Token header. Rate limited to 100 requests per minute per IP address.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Feedback"
+                ],
+                "summary": "Submit or list response feedback",
+                "parameters": [
+                    {
+                        "description": "Rating (POST)",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/models.FeedbackRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "User giving the feedback",
+                        "name": "X-User-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Admin token (required for GET)",
+                        "name": "X-Admin-Token",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only up or down ratings",
+                        "name": "rating",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only feedback with this tag",
+                        "name": "tag",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only feedback from this user",
+                        "name": "user_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only feedback on this response",
+                        "name": "response_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only responses from this endpoint, e.g. /ai/complete",
+                        "name": "endpoint",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only responses from this model",
+                        "name": "model",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page size (default 50, at most 500)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Feedback to skip",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Feedback page (GET); replacing a rating (POST) returns the models.Feedback",
+                        "schema": {
+                            "$ref": "#/definitions/models.FeedbackList"
+                        }
+                    },
+                    "201": {
+                        "description": "Feedback recorded",
+                        "schema": {
+                            "$ref": "#/definitions/models.Feedback"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Response not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "507": {
+                        "description": "Feedback store is full",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "POST rates an AI response by the id it was returned with: rating is \"up\" or \"down\", with an optional comment and tags. The feedback stores a snapshot of the response's prompt, output, model and parameters, so responses can only be rated while they are stored (see AI_RESPONSE_RETENTION). A user rating the same response again replaces their feedback. The user is taken from the X-User-ID header or user_id. GET lists feedback, most recently updated first, filtered by rating, tag, user_id, response_id, endpoint and model, and requires the X-Admin-Token header. Rate limited to 100 requests per minute per IP address.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Feedback"
+                ],
+                "summary": "Submit or list response feedback",
+                "parameters": [
+                    {
+                        "description": "Rating (POST)",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/models.FeedbackRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "User giving the feedback",
+                        "name": "X-User-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Admin token (required for GET)",
+                        "name": "X-Admin-Token",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only up or down ratings",
+                        "name": "rating",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only feedback with this tag",
+                        "name": "tag",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only feedback from this user",
+                        "name": "user_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only feedback on this response",
+                        "name": "response_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only responses from this endpoint, e.g. /ai/complete",
+                        "name": "endpoint",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only responses from this model",
+                        "name": "model",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page size (default 50, at most 500)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Feedback to skip",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Feedback page (GET); replacing a rating (POST) returns the models.Feedback",
+                        "schema": {
+                            "$ref": "#/definitions/models.FeedbackList"
+                        }
+                    },
+                    "201": {
+                        "description": "Feedback recorded",
+                        "schema": {
+                            "$ref": "#/definitions/models.Feedback"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Response not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "507": {
+                        "description": "Feedback store is full",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/ai/feedback/export": {
+            "get": {
+                "description": "Download matching feedback, most recently updated first. jsonl (default) writes each feedback with its stored response as a line, csv one row per feedback, and openai writes fine-tuning examples ({\"messages\": [...]}) of each rated response's prompt and output; add rating=up to keep only approved outputs. Requires the X-Admin-Token header. Rate limited to 100 requests per minute per IP address.",
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "Feedback"
+                ],
+                "summary": "Export response feedback",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin token",
+                        "name": "X-Admin-Token",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Export format: jsonl, csv or openai (default jsonl)",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only up or down ratings",
+                        "name": "rating",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only feedback with this tag",
+                        "name": "tag",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only feedback from this user",
+                        "name": "user_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only feedback on this response",
+                        "name": "response_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only responses from this endpoint",
+                        "name": "endpoint",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only responses from this model",
+                        "name": "model",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Exported feedback",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Unsupported format",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/ai/fim": {
+            "post": {
+                "description": "Return the code to insert at the cursor given the code before it (prefix) and after it (suffix). When AI_UPSTREAM_CAPABILITIES includes \"fim\", the prompt uses the model's sentinel tokens (AI_FIM_TOKENS, default \u003cfim_prefix\u003e,\u003cfim_suffix\u003e,\u003cfim_middle\u003e); otherwise the suffix is shown to the model first and it continues the prefix in a code block. The prefix and suffix are each limited to 64 KB, of which the lines nearest the cursor are sent. The completion stops at end-of-text markers and stop sequences, before code that repeats the suffix, and at syntactic boundaries: the end of the line when the cursor is mid-line, otherwise the end of the block at the cursor. max_tokens defaults to 64 (at most 256), temperature to 0.2, and the upstream call times out after 10 seconds. With dry_run or the X-AI-Dry-Run header, the upstream request is returned instead of calling the model. Rate limited to 30 requests per minute per IP address.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "AI Processing"
+                ],
+                "summary": "Fill-in-the-middle code completion",
+                "parameters": [
+                    {
+                        "description": "Code around the cursor",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.FIMRequest"
+                        }
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Return the upstream request without calling the model",
+                        "name": "X-AI-Dry-Run",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Code to insert",
+                        "schema": {
+                            "$ref": "#/definitions/models.FIMResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "504": {
+                        "description": "Upstream timed out",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/ai/generate": {
+            "post": {
+                "description": "Generate text based on a given prompt, or on a stored prompt template via template_id and variables. With few_shot {\"task\",\"k\"}, the k stored examples most similar to the prompt are prepended. With dry_run or the X-AI-Dry-Run header, the rendered upstream request is returned instead of calling the model. Rate limited to 30 requests per minute per IP address.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "AI Processing"
+                ],
+                "summary": "Text generation",
+                "parameters": [
+                    {
+                        "description": "Generate request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.GenerateRequest"
+                        }
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Return the upstream request without calling the model",
+                        "name": "X-AI-Dry-Run",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Successful text generation",
+                        "schema": {
+                            "$ref": "#/definitions/models.GenerateResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/ai/ingest": {
+            "post": {
+                "description": "Extract clean text from uploaded PDF, HTML, Markdown, DOCX or plain text files (multipart field \"file\", repeatable), normalize whitespace and Unicode, and split it into token-bounded chunks that overlap. The format is detected from the content, content type and extension unless given. The text can also be stored directly: rag_collection ingests each file as a document of a RAG collection (ID from the filename) and vector_collection embeds each chunk into a vector collection; both require the X-Admin-Token header. Uploads are limited to 32 MB and 20 files. Rate limited to 30 requests per minute per IP address.",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "AI Processing"
+                ],
+                "summary": "Ingest documents",
+                "parameters": [
+                    {
+                        "type": "file",
+                        "description": "Document to ingest (repeatable)",
+                        "name": "file",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Format of every file: pdf, html, markdown, docx or text (detected when omitted)",
+                        "name": "format",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum tokens per chunk (default 256, between 32 and 512)",
+                        "name": "chunk_tokens",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Tokens shared by consecutive chunks (default 32, under half of chunk_tokens)",
+                        "name": "chunk_overlap",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Include the full extracted text (default true)",
+                        "name": "include_text",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "RAG collection to store the documents in",
+                        "name": "rag_collection",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Vector collection to store the chunks in",
+                        "name": "vector_collection",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Admin token (required to store documents)",
+                        "name": "X-Admin-Token",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Extracted files",
+                        "schema": {
+                            "$ref": "#/definitions/models.IngestResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Collection not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "413": {
+                        "description": "Upload too large",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported document format",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/ai/model-info": {
+            "get": {
+                "description": "Get detailed information about the current AI model being used. Rate limited to 100 requests per minute per IP address.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "System"
+                ],
+                "summary": "Get model information",
+                "responses": {
+                    "200": {
+                        "description": "Model information",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/ai/qa": {
+            "post": {
+                "description": "Answer a question from one or more passages or a single context string (not both). Passages are split into chunks and ranked against the question with BM25; the best top_k chunks that fit the context window are placed in the prompt. The model's answer is aligned back to an exact character span of a source passage: a verbatim match has confidence 1, otherwise the word window with the best token F1 is used. When the model declines or the confidence is below min_confidence (default 0.6), no_answer is set and answer is empty. With dry_run or the X-AI-Dry-Run header, the upstream request is returned instead of calling the model. Rate limited to 30 requests per minute per IP address.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "AI Processing"
+                ],
+                "summary": "Extractive question answering",
+                "parameters": [
+                    {
+                        "description": "Question and passages",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.QARequest"
+                        }
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Return the upstream request without calling the model",
+                        "name": "X-AI-Dry-Run",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Answer with its source span",
+                        "schema": {
+                            "$ref": "#/definitions/models.QAResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/ai/rag/chat": {
+            "post": {
+                "description": "Retrieve the top_k chunks of a document collection that best match the latest user message (BM25, vector or hybrid), add them to the conversation as numbered sources within the model's context budget and generate a chat completion. The answer comes back with citations to the chunk IDs and character offsets it refers to, plus every source that was placed in the prompt. With dry_run or the X-AI-Dry-Run header, the rendered upstream request is returned instead of calling the model. Rate limited to 30 requests per minute per IP address.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "RAG"
+                ],
+                "summary": "Retrieval-augmented chat",
+                "parameters": [
+                    {
+                        "description": "Collection and conversation",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.RAGChatRequest"
+                        }
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Return the upstream request without calling the model",
+                        "name": "X-AI-Dry-Run",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Answer with citations",
+                        "schema": {
+                            "$ref": "#/definitions/models.RAGChatResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Collection not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/ai/rag/collections": {
+            "get": {
+                "description": "GET lists document collections with document and chunk counts. POST creates a collection; chunk_tokens and chunk_overlap control chunking and vectors enables embedding chunks for hybrid retrieval. Creating requires the X-Admin-Token header. Rate limited to 100 requests per minute per IP address.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "RAG"
+                ],
+                "summary": "List or create document collections",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin token (required for POST)",
+                        "name": "X-Admin-Token",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Collection to create (POST)",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/models.CreateRAGCollectionRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Collections",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.RAGCollection"
+                            }
+                        }
+                    },
+                    "201": {
+                        "description": "Created collection",
+                        "schema": {
+                            "$ref": "#/definitions/models.RAGCollection"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Collection already exists",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "GET lists document collections with document and chunk counts. POST creates a collection; chunk_tokens and chunk_overlap control chunking and vectors enables embedding chunks for hybrid retrieval. Creating requires the X-Admin-Token header. Rate limited to 100 requests per minute per IP address.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "RAG"
+                ],
+                "summary": "List or create document collections",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin token (required for POST)",
+                        "name": "X-Admin-Token",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Collection to create (POST)",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/models.CreateRAGCollectionRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Collections",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.RAGCollection"
+                            }
+                        }
+                    },
+                    "201": {
+                        "description": "Created collection",
+                        "schema": {
+                            "$ref": "#/definitions/models.RAGCollection"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Collection already exists",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/ai/rag/collections/{name}": {
+            "get": {
+                "description": "GET describes the collection. DELETE removes it with all its documents and requires the X-Admin-Token header. Rate limited to 100 requests per minute per IP address.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "RAG"
+                ],
+                "summary": "Get or delete a document collection",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Collection name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Admin token (required for DELETE)",
+                        "name": "X-Admin-Token",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Collection",
+                        "schema": {
+                            "$ref": "#/definitions/models.RAGCollection"
+                        }
+                    },
+                    "204": {
+                        "description": "Collection deleted"
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Collection not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "GET describes the collection. DELETE removes it with all its documents and requires the X-Admin-Token header. Rate limited to 100 requests per minute per IP address.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "RAG"
+                ],
+                "summary": "Get or delete a document collection",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Collection name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Admin token (required for DELETE)",
+                        "name": "X-Admin-Token",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Collection",
+                        "schema": {
+                            "$ref": "#/definitions/models.RAGCollection"
+                        }
+                    },
+                    "204": {
+                        "description": "Collection deleted"
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Collection not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/ai/rag/collections/{name}/documents": {
+            "get": {
+                "description": "GET lists the collection's documents. POST ingests one document or an array of documents: each is split into token-bounded overlapping chunks, indexed with BM25 and, for collections with vectors, embedded. Ingesting an existing document ID replaces it. Ingesting requires the X-Admin-Token header. Rate limited to 100 requests per minute per IP address.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "RAG"
+                ],
+                "summary": "List or ingest documents",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Collection name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Admin token (required for POST)",
+                        "name": "X-Admin-Token",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Document or array of documents (POST)",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/models.RAGDocumentInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Documents",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.RAGDocument"
+                            }
+                        }
+                    },
+                    "201": {
+                        "description": "Ingested documents",
+                        "schema": {
+                            "$ref": "#/definitions/models.IngestDocumentsResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Collection not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "GET lists the collection's documents. POST ingests one document or an array of documents: each is split into token-bounded overlapping chunks, indexed with BM25 and, for collections with vectors, embedded. Ingesting an existing document ID replaces it. Ingesting requires the X-Admin-Token header. Rate limited to 100 requests per minute per IP address.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "RAG"
+                ],
+                "summary": "List or ingest documents",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Collection name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Admin token (required for POST)",
+                        "name": "X-Admin-Token",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Document or array of documents (POST)",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/models.RAGDocumentInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Documents",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.RAGDocument"
+                            }
+                        }
+                    },
+                    "201": {
+                        "description": "Ingested documents",
+                        "schema": {
+                            "$ref": "#/definitions/models.IngestDocumentsResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Collection not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/ai/rag/collections/{name}/documents/{id}": {
+            "get": {
+                "description": "GET returns the document with its full text and chunks, including each chunk's character offsets. DELETE removes the document and its chunks and requires the X-Admin-Token header. Rate limited to 100 requests per minute per IP address.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "RAG"
+                ],
+                "summary": "Get or delete a document",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Collection name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Document ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Admin token (required for DELETE)",
+                        "name": "X-Admin-Token",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Document",
+                        "schema": {
+                            "$ref": "#/definitions/models.RAGDocumentDetail"
+                        }
+                    },
+                    "204": {
+                        "description": "Document deleted"
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Collection or document not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "GET returns the document with its full text and chunks, including each chunk's character offsets. DELETE removes the document and its chunks and requires the X-Admin-Token header. Rate limited to 100 requests per minute per IP address.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "RAG"
+                ],
+                "summary": "Get or delete a document",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Collection name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Document ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Admin token (required for DELETE)",
+                        "name": "X-Admin-Token",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Document",
+                        "schema": {
+                            "$ref": "#/definitions/models.RAGDocumentDetail"
+                        }
+                    },
+                    "204": {
+                        "description": "Document deleted"
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Collection or document not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/ai/rag/collections/{name}/search": {
+            "post": {
+                "description": "Return the chunks that /ai/rag/chat would retrieve for a query, with BM25, vector and fused scores. Rate limited to 100 requests per minute per IP address.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "RAG"
+                ],
+                "summary": "Search a document collection",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Collection name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Query",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.RAGSearchRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Matching chunks, best first",
+                        "schema": {
+                            "$ref": "#/definitions/models.RAGSearchResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Collection not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/ai/responses/{id}": {
+            "get": {
+                "description": "Return exactly what a user saw for the response id returned in the body and X-Response-ID header of an AI endpoint: the request as processed, the response body as sent, the model, the estimated token usage and the duration. Responses are kept for AI_RESPONSE_RETENTION (default 7 days), in AI_RESPONSE_DIR when set and in memory otherwise. Requires the X-Admin-Token header. Rate limited to 100 requests per minute per IP address.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Feedback"
+                ],
+                "summary": "Get a stored response",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Response ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Admin token",
+                        "name": "X-Admin-Token",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Stored response",
+                        "schema": {
+                            "$ref": "#/definitions/models.ResponseRecord"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Response not found or past retention",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/ai/summarize": {
+            "post": {
+                "description": "Summarize text of up to 100,000 tokens. Text that does not fit in one prompt is split into token-bounded chunks that are summarized in parallel; the partial summaries are then reduced recursively until they fit in a final prompt. length is short, medium or long, style is prose or bullets, and focus restricts the summary to what the text says about a query. With dry_run or the X-AI-Dry-Run header, the first upstream request is returned instead of calling the model. Rate limited to 30 requests per minute per IP address.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "AI Processing"
+                ],
+                "summary": "Summarize long text",
+                "parameters": [
+                    {
+                        "description": "Text and summary options",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.SummarizeRequest"
+                        }
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Return the first upstream request without calling the model",
+                        "name": "X-AI-Dry-Run",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Summary",
+                        "schema": {
+                            "$ref": "#/definitions/models.SummarizeResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/ai/tasks": {
+            "get": {
+                "description": "List the custom task endpoints declared in the AI_TASKS_CONFIG file, with their input schemas, output parsers and rate limits. Rate limited to 100 requests per minute per IP address.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Tasks"
+                ],
+                "summary": "List task endpoints",
+                "responses": {
+                    "200": {
+                        "description": "Task endpoints",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.TaskInfo"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/ai/tasks/{name}": {
+            "post": {
+                "description": "Run a custom task declared in the AI_TASKS_CONFIG file. Each task has its own prompt template, input schema, output parser and per-task rate limit; the per-task operations are listed in this document under the Tasks tag. With dry_run or the X-AI-Dry-Run header, the upstream request is returned instead of calling the model.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Tasks"
+                ],
+                "summary": "Run a task endpoint",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Task name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Task input",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.TaskRequest"
+                        }
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Return the upstream request without calling the model",
+                        "name": "X-AI-Dry-Run",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Parsed task output",
+                        "schema": {
+                            "$ref": "#/definitions/models.TaskResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Input does not match the task schema",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Task not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/ai/templates": {
+            "get": {
+                "description": "GET lists all prompt templates. POST creates a template with typed variables; its body is a Go text/template such as \"Summarize for {{.audience}}: {{.text}}\". Creating requires the X-Admin-Token header. Rate limited to 100 requests per minute per IP address.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Templates"
+                ],
+                "summary": "List or create prompt templates",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin token (required for POST)",
+                        "name": "X-Admin-Token",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Template to create (POST)",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/models.PromptTemplateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Templates",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.PromptTemplate"
+                            }
+                        }
+                    },
+                    "201": {
+                        "description": "Created template",
+                        "schema": {
+                            "$ref": "#/definitions/models.PromptTemplate"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Template already exists",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "GET lists all prompt templates. POST creates a template with typed variables; its body is a Go text/template such as \"Summarize for {{.audience}}: {{.text}}\". Creating requires the X-Admin-Token header. Rate limited to 100 requests per minute per IP address.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Templates"
+                ],
+                "summary": "List or create prompt templates",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin token (required for POST)",
+                        "name": "X-Admin-Token",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Template to create (POST)",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/models.PromptTemplateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Templates",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.PromptTemplate"
+                            }
+                        }
+                    },
+                    "201": {
+                        "description": "Created template",
+                        "schema": {
+                            "$ref": "#/definitions/models.PromptTemplate"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Template already exists",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/ai/templates/{id}": {
+            "get": {
+                "description": "GET returns the template with its version history. PUT adds a new version and makes it active. DELETE removes the template. Changes require the X-Admin-Token header. Rate limited to 100 requests per minute per IP address.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Templates"
+                ],
+                "summary": "Get, update or delete a prompt template",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Template ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Admin token (required for PUT and DELETE)",
+                        "name": "X-Admin-Token",
+                        "in": "header"
+                    },
+                    {
+                        "description": "New version (PUT)",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/models.PromptTemplateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Template",
+                        "schema": {
+                            "$ref": "#/definitions/models.PromptTemplate"
+                        }
+                    },
+                    "204": {
+                        "description": "Template deleted"
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Template not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "GET returns the template with its version history. PUT adds a new version and makes it active. DELETE removes the template. Changes require the X-Admin-Token header. Rate limited to 100 requests per minute per IP address.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Templates"
+                ],
+                "summary": "Get, update or delete a prompt template",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Template ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Admin token (required for PUT and DELETE)",
+                        "name": "X-Admin-Token",
+                        "in": "header"
+                    },
+                    {
+                        "description": "New version (PUT)",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/models.PromptTemplateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Template",
+                        "schema": {
+                            "$ref": "#/definitions/models.PromptTemplate"
+                        }
+                    },
+                    "204": {
+                        "description": "Template deleted"
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Template not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "GET returns the template with its version history. PUT adds a new version and makes it active. DELETE removes the template. Changes require the X-Admin-Token header. Rate limited to 100 requests per minute per IP address.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Templates"
+                ],
+                "summary": "Get, update or delete a prompt template",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Template ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Admin token (required for PUT and DELETE)",
+                        "name": "X-Admin-Token",
+                        "in": "header"
+                    },
+                    {
+                        "description": "New version (PUT)",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/models.PromptTemplateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Template",
+                        "schema": {
+                            "$ref": "#/definitions/models.PromptTemplate"
+                        }
+                    },
+                    "204": {
+                        "description": "Template deleted"
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Template not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/ai/templates/{id}/render": {
+            "post": {
+                "description": "Render a template version (the active one by default) with the given variables without calling the model. Rate limited to 100 requests per minute per IP address.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Templates"
+                ],
+                "summary": "Render a template preview",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Template ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Variables",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.RenderTemplateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Rendered prompt",
+                        "schema": {
+                            "$ref": "#/definitions/models.RenderTemplateResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Template or version not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/ai/templates/{id}/rollback": {
+            "post": {
+                "description": "Restore a prior version by copying it forward as a new active version. Requires the X-Admin-Token header. Rate limited to 100 requests per minute per IP address.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Templates"
+                ],
+                "summary": "Roll back a template",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Template ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Admin token",
+                        "name": "X-Admin-Token",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Version to restore",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.RollbackTemplateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Template",
+                        "schema": {
+                            "$ref": "#/definitions/models.PromptTemplate"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Template or version not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/ai/templates/{id}/versions": {
+            "get": {
+                "description": "Return every version of the template, oldest first. Rate limited to 100 requests per minute per IP address.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Templates"
+                ],
+                "summary": "List template versions",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Template ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Versions",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.TemplateVersion"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Template not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/ai/vectors": {
+            "get": {
+                "description": "GET lists vector collections with their record counts. POST creates a collection with a metric (cosine, dot or l2), optional fixed dimensions and an optional embedding model used for records and queries given as text. Creating requires the X-Admin-Token header. Rate limited to 100 requests per minute per IP address.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Vectors"
+                ],
+                "summary": "List or create vector collections",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin token (required for POST)",
+                        "name": "X-Admin-Token",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Collection to create (POST)",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/models.CreateCollectionRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Collections",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.VectorCollection"
+                            }
+                        }
+                    },
+                    "201": {
+                        "description": "Created collection",
+                        "schema": {
+                            "$ref": "#/definitions/models.VectorCollection"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Collection already exists",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "GET lists vector collections with their record counts. POST creates a collection with a metric (cosine, dot or l2), optional fixed dimensions and an optional embedding model used for records and queries given as text. Creating requires the X-Admin-Token header. Rate limited to 100 requests per minute per IP address.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Vectors"
+                ],
+                "summary": "List or create vector collections",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin token (required for POST)",
+                        "name": "X-Admin-Token",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Collection to create (POST)",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/models.CreateCollectionRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Collections",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.VectorCollection"
+                            }
+                        }
+                    },
+                    "201": {
+                        "description": "Created collection",
+                        "schema": {
+                            "$ref": "#/definitions/models.VectorCollection"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Collection already exists",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/ai/vectors/{collection}": {
+            "get": {
+                "description": "GET describes the collection. DELETE removes it with its snapshot and requires the X-Admin-Token header. Rate limited to 100 requests per minute per IP address.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Vectors"
+                ],
+                "summary": "Get or delete a vector collection",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Collection name",
+                        "name": "collection",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Admin token (required for DELETE)",
+                        "name": "X-Admin-Token",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Collection",
+                        "schema": {
+                            "$ref": "#/definitions/models.VectorCollection"
+                        }
+                    },
+                    "204": {
+                        "description": "Collection deleted"
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Collection not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "GET describes the collection. DELETE removes it with its snapshot and requires the X-Admin-Token header. Rate limited to 100 requests per minute per IP address.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Vectors"
+                ],
+                "summary": "Get or delete a vector collection",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Collection name",
+                        "name": "collection",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Admin token (required for DELETE)",
+                        "name": "X-Admin-Token",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Collection",
+                        "schema": {
+                            "$ref": "#/definitions/models.VectorCollection"
+                        }
+                    },
+                    "204": {
+                        "description": "Collection deleted"
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Collection not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/ai/vectors/{collection}/delete": {
+            "post": {
+                "description": "Delete records by ID and/or by metadata filter. Requires the X-Admin-Token header. Rate limited to 100 requests per minute per IP address.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Vectors"
+                ],
+                "summary": "Delete vectors",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Collection name",
+                        "name": "collection",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Admin token",
+                        "name": "X-Admin-Token",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "IDs and/or filter",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.DeleteVectorsRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Delete result",
+                        "schema": {
+                            "$ref": "#/definitions/models.DeleteVectorsResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Collection not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/ai/vectors/{collection}/query": {
+            "post": {
+                "description": "Return the top_k records closest to a vector, or to text embedded with the collection's embedding model, optionally restricted by a metadata filter ({\"field\": value} or operators $eq, $ne, $in, $nin, $gt, $gte, $lt, $lte, $exists, $and, $or). Large collections are searched with an HNSW index; small collections, selective filters and exact=true use an exact scan. Rate limited to 100 requests per minute per IP address.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Vectors"
+                ],
+                "summary": "Query vectors",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Collection name",
+                        "name": "collection",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Query",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.VectorQueryRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Matches, closest first",
+                        "schema": {
+                            "$ref": "#/definitions/models.VectorQueryResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Collection not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/ai/vectors/{collection}/records/{id}": {
+            "get": {
+                "description": "Return a record with its vector, text and metadata. Rate limited to 100 requests per minute per IP address.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Vectors"
+                ],
+                "summary": "Get a vector record",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Collection name",
+                        "name": "collection",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Record ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Record",
+                        "schema": {
+                            "$ref": "#/definitions/models.VectorRecord"
+                        }
+                    },
+                    "404": {
+                        "description": "Collection or record not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/ai/vectors/{collection}/snapshot": {
+            "post": {
+                "description": "Write the collection to AI_VECTOR_DIR now instead of waiting for the periodic snapshot. Requires the X-Admin-Token header. Rate limited to 100 requests per minute per IP address.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Vectors"
+                ],
+                "summary": "Snapshot a vector collection",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Collection name",
+                        "name": "collection",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Admin token",
+                        "name": "X-Admin-Token",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Collection with snapshot time",
+                        "schema": {
+                            "$ref": "#/definitions/models.VectorCollection"
+                        }
+                    },
+                    "400": {
+                        "description": "Snapshots disabled",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Collection not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/ai/vectors/{collection}/upsert": {
+            "post": {
+                "description": "Insert or replace records by ID. Each record has a vector, or text that is embedded with the collection's embedding model, plus optional metadata for filtering. All records are validated before any is applied. Requires the X-Admin-Token header. Rate limited to 100 requests per minute per IP address.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Vectors"
+                ],
+                "summary": "Upsert vectors",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Collection name",
+                        "name": "collection",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Admin token",
+                        "name": "X-Admin-Token",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Records",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.UpsertVectorsRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Upsert result",
+                        "schema": {
+                            "$ref": "#/definitions/models.UpsertVectorsResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Collection not found",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/health": {
+            "get": {
+                "description": "Check the health status of the AI service. Rate limited to 200 requests per minute per IP address.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "System"
+                ],
+                "summary": "Health check",
+                "responses": {
+                    "200": {
+                        "description": "Service is healthy",
+                        "schema": {
+                            "$ref": "#/definitions/models.HealthResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limit exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "models.AgentRequest": {
+            "type": "object",
+            "properties": {
+                "max_steps": {
+                    "type": "integer"
+                },
+                "max_tokens": {
+                    "type": "integer"
+                },
+                "messages": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ChatMessage"
+                    }
+                },
+                "prompt": {
+                    "type": "string"
+                },
+                "temperature": {
+                    "type": "number"
+                },
+                "timeout_seconds": {
+                    "type": "integer"
+                },
+                "tools": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.AgentResponse": {
+            "type": "object",
+            "properties": {
+                "duration_ms": {
+                    "type": "integer"
+                },
+                "id": {
+                    "description": "ID refers to the response in /ai/feedback",
+                    "type": "string"
+                },
+                "model": {
+                    "type": "string"
+                },
+                "response": {
+                    "type": "string"
+                },
+                "steps": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.AgentStep"
+                    }
+                },
+                "stop_reason": {
+                    "type": "string"
+                },
+                "timestamp": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.AgentStep": {
+            "type": "object",
+            "properties": {
+                "arguments": {
+                    "type": "string"
+                },
+                "duration_ms": {
+                    "type": "integer"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "index": {
+                    "type": "integer"
+                },
+                "output": {
+                    "type": "string"
+                },
+                "thought": {
+                    "type": "string"
+                },
+                "tool": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.AutocompleteRequest": {
+            "type": "object",
+            "properties": {
+                "max_tokens": {
+                    "description": "MaxTokens defaults to 8, at most 16",
+                    "type": "integer"
+                },
+                "prefix": {
+                    "type": "string"
+                },
+                "session_id": {
+                    "description": "SessionID lets a newer request from the session cancel this one; the X-Session-ID header takes precedence",
+                    "type": "string"
+                },
+                "temperature": {
+                    "description": "Temperature defaults to 0.2",
+                    "type": "number"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.AutocompleteResponse": {
+            "type": "object",
+            "properties": {
+                "duration_ms": {
+                    "type": "integer"
+                },
+                "id": {
+                    "description": "ID refers to the response in /ai/feedback",
+                    "type": "string"
+                },
+                "model": {
+                    "type": "string"
+                },
+                "session_id": {
+                    "type": "string"
+                },
+                "source": {
+                    "description": "Source is upstream, coalesced or cache",
+                    "type": "string"
+                },
+                "suggestion": {
+                    "type": "string"
+                },
+                "timestamp": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.ChainDefinition": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "output": {
+                    "description": "Output is the step whose output is the chain result, default the last step",
+                    "type": "string"
+                },
+                "steps": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ChainStep"
+                    }
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.ChainRequest": {
+            "type": "object",
+            "properties": {
+                "chain": {
+                    "$ref": "#/definitions/models.ChainDefinition"
+                },
+                "input": {
+                    "type": "object"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "timeout_seconds": {
+                    "type": "integer"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.ChainResponse": {
+            "type": "object",
+            "properties": {
+                "chain": {
+                    "type": "string"
+                },
+                "duration_ms": {
+                    "type": "integer"
+                },
+                "id": {
+                    "description": "ID refers to the response in /ai/feedback",
+                    "type": "string"
+                },
+                "model": {
+                    "type": "string"
+                },
+                "output": {
+                    "type": "string"
+                },
+                "status": {
+                    "description": "Status is succeeded when the output step succeeded",
+                    "type": "string"
+                },
+                "steps": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ChainStepResult"
+                    }
+                },
+                "timestamp": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.ChainStep": {
+            "type": "object",
+            "properties": {
+                "depends_on": {
+                    "description": "DependsOn lists dependencies besides the steps the prompt references",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "id": {
+                    "type": "string"
+                },
+                "max_tokens": {
+                    "type": "integer"
+                },
+                "prompt": {
+                    "type": "string"
+                },
+                "system": {
+                    "description": "System is the system message for the chat upstream",
+                    "type": "string"
+                },
+                "temperature": {
+                    "type": "number"
+                },
+                "upstream": {
+                    "description": "Upstream is complete (default), generate or chat",
+                    "type": "string"
+                }
+            }
+        },
+        "models.ChainStepResult": {
+            "type": "object",
+            "properties": {
+                "depends_on": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "duration_ms": {
+                    "type": "integer"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "output": {
+                    "type": "string"
+                },
+                "prompt": {
+                    "type": "string"
+                },
+                "started_ms": {
+                    "description": "StartedMs is the offset from the start of the chain",
+                    "type": "integer"
+                },
+                "status": {
+                    "description": "Status is succeeded, failed or skipped",
+                    "type": "string"
+                }
+            }
+        },
+        "models.ChatCompletionRequest": {
+            "type": "object",
+            "properties": {
+                "conversation_id": {
+                    "description": "ConversationID appends this exchange to a stored conversation; Messages\nthen only needs to hold the new turn(s)",
+                    "type": "string"
+                },
+                "dry_run": {
+                    "description": "DryRun returns the upstream request instead of calling the model",
+                    "type": "boolean"
+                },
+                "max_tokens": {
+                    "type": "integer"
+                },
+                "memory": {
+                    "$ref": "#/definitions/models.MemoryOptions"
+                },
+                "messages": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ChatMessage"
+                    }
+                },
+                "temperature": {
+                    "type": "number"
+                },
+                "template_id": {
+                    "description": "TemplateID renders a stored prompt template as an additional user\nmessage, so Messages must not already end with one",
+                    "type": "string"
+                },
+                "template_version": {
+                    "type": "integer"
+                },
+                "tool_choice": {
+                    "description": "ToolChoice is \"auto\", \"none\", \"required\" or {\"type\":\"function\",\"function\":{\"name\":\"...\"}}",
+                    "type": "object"
+                },
+                "tools": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Tool"
+                    }
+                },
+                "user_id": {
+                    "type": "string"
+                },
+                "variables": {
+                    "type": "object",
+                    "additionalProperties": true
+                }
+            }
+        },
+        "models.ChatCompletionResponse": {
+            "type": "object",
+            "properties": {
+                "conversation_id": {
+                    "type": "string"
+                },
+                "finish_reason": {
+                    "type": "string"
+                },
+                "id": {
+                    "description": "ID refers to the response in /ai/feedback",
+                    "type": "string"
+                },
+                "memory": {
+                    "$ref": "#/definitions/models.MemoryInfo"
+                },
+                "message_id": {
+                    "type": "string"
+                },
+                "model": {
+                    "type": "string"
+                },
+                "response": {
+                    "type": "string"
+                },
+                "timestamp": {
+                    "type": "string"
+                },
+                "tool_calls": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ToolCall"
+                    }
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.ChatMessage": {
+            "type": "object",
+            "properties": {
+                "content": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "role": {
+                    "type": "string"
+                },
+                "tool_call_id": {
+                    "type": "string"
+                },
+                "tool_calls": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ToolCall"
+                    }
+                }
+            }
+        },
+        "models.ClassifyLabel": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.ClassifyRequest": {
+            "type": "object",
+            "properties": {
+                "dry_run": {
+                    "type": "boolean"
+                },
+                "labels": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ClassifyLabel"
+                    }
+                },
+                "multi_label": {
+                    "description": "MultiLabel scores each label independently instead of choosing one",
+                    "type": "boolean"
+                },
+                "temperature": {
+                    "type": "number"
+                },
+                "text": {
+                    "type": "string"
+                },
+                "threshold": {
+                    "description": "Threshold is the minimum score to select a label; default 0.5 with multi_label, otherwise 0",
+                    "type": "number"
+                },
+                "user_id": {
+                    "type": "string"
+                },
+                "votes": {
+                    "description": "Votes is the number of samples drawn when voting, default 5",
+                    "type": "integer"
+                }
+            }
+        },
+        "models.ClassifyResponse": {
+            "type": "object",
+            "properties": {
+                "abstained": {
+                    "description": "Abstained counts the votes that named no candidate label",
+                    "type": "integer"
+                },
+                "calls": {
+                    "description": "Calls counts the model calls made",
+                    "type": "integer"
+                },
+                "id": {
+                    "description": "ID refers to the response in /ai/feedback",
+                    "type": "string"
+                },
+                "labels": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ClassifyScore"
+                    }
+                },
+                "method": {
+                    "description": "Method is logprobs or voting",
+                    "type": "string"
+                },
+                "model": {
+                    "type": "string"
+                },
+                "multi_label": {
+                    "type": "boolean"
+                },
+                "selected": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "threshold": {
+                    "type": "number"
+                },
+                "timestamp": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.ClassifyScore": {
+            "type": "object",
+            "properties": {
+                "label": {
+                    "type": "string"
+                },
+                "score": {
+                    "type": "number"
+                },
+                "selected": {
+                    "type": "boolean"
+                },
+                "votes": {
+                    "description": "Votes counts the samples that chose the label, when voting",
+                    "type": "integer"
+                }
+            }
+        },
+        "models.ComparePair": {
+            "type": "object",
+            "properties": {
+                "a": {
+                    "type": "string"
+                },
+                "b": {
+                    "type": "string"
+                },
+                "diff": {
+                    "type": "string"
+                },
+                "jaccard": {
+                    "description": "Jaccard is the overlap of the distinct words",
+                    "type": "number"
+                },
+                "lcs_ratio": {
+                    "description": "LCSRatio is the longest common word subsequence, F1 over both lengths (ROUGE-L)",
+                    "type": "number"
+                },
+                "length_ratio": {
+                    "description": "LengthRatio is the shorter output's word count over the longer one's",
+                    "type": "number"
+                }
+            }
+        },
+        "models.CompareRequest": {
+            "type": "object",
+            "properties": {
+                "backends": {
+                    "description": "Backends lists at least two backends, default all configured ones",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "diff": {
+                    "description": "Diff includes a word diff for each pair of outputs",
+                    "type": "boolean"
+                },
+                "max_tokens": {
+                    "description": "MaxTokens is at most 1024",
+                    "type": "integer"
+                },
+                "messages": {
+                    "description": "Messages replace prompt in chat mode",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ChatMessage"
+                    }
+                },
+                "mode": {
+                    "description": "Mode is complete (default), generate or chat",
+                    "type": "string"
+                },
+                "prompt": {
+                    "type": "string"
+                },
+                "temperature": {
+                    "type": "number"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.CompareResponse": {
+            "type": "object",
+            "properties": {
+                "duration_ms": {
+                    "type": "integer"
+                },
+                "id": {
+                    "description": "ID refers to the response in /ai/feedback",
+                    "type": "string"
+                },
+                "mode": {
+                    "type": "string"
+                },
+                "pairs": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ComparePair"
+                    }
+                },
+                "results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.CompareResult"
+                    }
+                },
+                "timestamp": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.CompareResult": {
+            "type": "object",
+            "properties": {
+                "backend": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "latency_ms": {
+                    "type": "integer"
+                },
+                "model": {
+                    "type": "string"
+                },
+                "output": {
+                    "type": "string"
+                },
+                "usage": {
+                    "description": "Usage is estimated with the gateway's tokenizer so that backends are counted alike",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.TokenUsage"
+                        }
+                    ]
+                }
+            }
+        },
+        "models.CompleteRequest": {
+            "type": "object",
+            "properties": {
+                "dry_run": {
+                    "description": "DryRun returns the upstream request instead of calling the model",
+                    "type": "boolean"
+                },
+                "few_shot": {
+                    "description": "FewShot prepends the most similar stored examples of a task to the prompt",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.FewShotOptions"
+                        }
+                    ]
+                },
+                "max_tokens": {
+                    "type": "integer"
+                },
+                "prompt": {
+                    "type": "string"
+                },
+                "temperature": {
+                    "type": "number"
+                },
+                "template_id": {
+                    "description": "TemplateID renders a stored prompt template in place of Prompt, which\nmust then be empty",
+                    "type": "string"
+                },
+                "template_version": {
+                    "type": "integer"
+                },
+                "user_id": {
+                    "type": "string"
+                },
+                "variables": {
+                    "type": "object",
+                    "additionalProperties": true
+                }
+            }
+        },
+        "models.CompleteResponse": {
+            "type": "object",
+            "properties": {
+                "examples": {
+                    "description": "Examples lists the IDs of the few-shot examples used",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "id": {
+                    "description": "ID refers to the response in /ai/feedback",
+                    "type": "string"
+                },
+                "model": {
+                    "type": "string"
+                },
+                "response": {
+                    "type": "string"
+                },
+                "timestamp": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Conversation": {
+            "type": "object",
+            "properties": {
+                "active_leaf_id": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "messages": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ChatMessage"
+                    }
+                },
+                "nodes": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.MessageNode"
+                    }
+                },
+                "summary": {
+                    "$ref": "#/definitions/models.MemorySummary"
+                },
+                "title": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.ConversationBranch": {
+            "type": "object",
+            "properties": {
+                "active": {
+                    "type": "boolean"
+                },
+                "depth": {
+                    "type": "integer"
+                },
+                "diverges_from": {
+                    "type": "string"
+                },
+                "last_role": {
+                    "type": "string"
+                },
+                "leaf_id": {
+                    "type": "string"
+                },
+                "preview": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.ConversationInfo": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "message_count": {
+                    "type": "integer"
+                },
+                "title": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.ConversationPath": {
+            "type": "object",
+            "properties": {
+                "conversation_id": {
+                    "type": "string"
+                },
+                "leaf_id": {
+                    "type": "string"
+                },
+                "messages": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ChatMessage"
+                    }
+                },
+                "node_ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "response_id": {
+                    "description": "ResponseID identifies a newly generated reply for feedback; it is also\nsent in the X-Response-ID header",
+                    "type": "string"
+                }
+            }
+        },
+        "models.CreateCollectionRequest": {
+            "type": "object",
+            "properties": {
+                "dimensions": {
+                    "description": "Dimensions are taken from the first vector when omitted",
+                    "type": "integer"
+                },
+                "embedding_model": {
+                    "type": "string"
+                },
+                "metric": {
+                    "description": "Metric is cosine (default), dot or l2",
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.CreateConversationRequest": {
+            "type": "object",
+            "properties": {
+                "messages": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ChatMessage"
+                    }
+                },
+                "title": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.CreateRAGCollectionRequest": {
+            "type": "object",
+            "properties": {
+                "chunk_overlap": {
+                    "description": "ChunkOverlap defaults to 32, under half of chunk_tokens",
+                    "type": "integer"
+                },
+                "chunk_tokens": {
+                    "description": "ChunkTokens defaults to 256, between 32 and 512",
+                    "type": "integer"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "embedding_model": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "vectors": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "models.DeleteVectorsRequest": {
+            "type": "object",
+            "properties": {
+                "filter": {
+                    "type": "object"
+                },
+                "ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "models.DeleteVectorsResponse": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer"
+                },
+                "deleted": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.EditMessageRequest": {
+            "type": "object",
+            "properties": {
+                "content": {
+                    "type": "string"
+                },
+                "max_tokens": {
+                    "type": "integer"
+                },
+                "regenerate": {
+                    "type": "boolean"
+                },
+                "temperature": {
+                    "type": "number"
+                }
+            }
+        },
+        "models.Embedding": {
+            "type": "object",
+            "properties": {
+                "embedding": {
+                    "type": "array",
+                    "items": {
+                        "type": "number"
+                    }
+                },
+                "index": {
+                    "type": "integer"
+                },
+                "object": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.EmbeddingRequest": {
+            "type": "object",
+            "properties": {
+                "input": {
+                    "description": "Input is a string or an array of strings",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "model": {
+                    "description": "Model can be set to local-hashing-v1 to force the built-in embedder",
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.EmbeddingResponse": {
+            "type": "object",
+            "properties": {
+                "backend": {
+                    "description": "Backend is upstream or local",
+                    "type": "string"
+                },
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Embedding"
+                    }
+                },
+                "dimensions": {
+                    "type": "integer"
+                },
+                "model": {
+                    "type": "string"
+                },
+                "object": {
+                    "type": "string"
+                },
+                "timestamp": {
+                    "type": "string"
+                },
+                "usage": {
+                    "$ref": "#/definitions/models.EmbeddingUsage"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.EmbeddingUsage": {
+            "type": "object",
+            "properties": {
+                "cached_inputs": {
+                    "description": "CachedInputs counts the inputs served from the cache",
+                    "type": "integer"
+                },
+                "prompt_tokens": {
+                    "description": "PromptTokens is estimated",
+                    "type": "integer"
+                },
+                "total_tokens": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.ErrorResponse": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "integer"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Example": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "input": {
+                    "type": "string"
+                },
+                "output": {
+                    "type": "string"
+                },
+                "task": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.ExampleRequest": {
+            "type": "object",
+            "properties": {
+                "input": {
+                    "type": "string"
+                },
+                "output": {
+                    "type": "string"
+                },
+                "task": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.ExtractField": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "options": {
+                    "description": "Options are the allowed values of an enum field",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "required": {
+                    "type": "boolean"
+                },
+                "type": {
+                    "description": "Type is string (default), number, integer, boolean, date or enum",
+                    "type": "string"
+                }
+            }
+        },
+        "models.ExtractRequest": {
+            "type": "object",
+            "properties": {
+                "day_first": {
+                    "description": "DayFirst reads 03/04/2024 as 3 April rather than March 4",
+                    "type": "boolean"
+                },
+                "dry_run": {
+                    "type": "boolean"
+                },
+                "fields": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ExtractField"
+                    }
+                },
+                "max_tokens": {
+                    "description": "MaxTokens defaults to a value that scales with the number of fields",
+                    "type": "integer"
+                },
+                "temperature": {
+                    "description": "Temperature defaults to 0.2",
+                    "type": "number"
+                },
+                "text": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.ExtractResponse": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "object"
+                },
+                "fields": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ExtractedField"
+                    }
+                },
+                "id": {
+                    "description": "ID refers to the response in /ai/feedback",
+                    "type": "string"
+                },
+                "missing": {
+                    "description": "Missing lists the required fields that were not found",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "model": {
+                    "type": "string"
+                },
+                "raw": {
+                    "type": "string"
+                },
+                "timestamp": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "string"
+                },
+                "valid": {
+                    "description": "Valid reports whether data satisfies the field specification",
+                    "type": "boolean"
+                }
+            }
+        },
+        "models.ExtractSpan": {
+            "type": "object",
+            "properties": {
+                "end": {
+                    "type": "integer"
+                },
+                "start": {
+                    "type": "integer"
+                },
+                "text": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.ExtractedField": {
+            "type": "object",
+            "properties": {
+                "confidence": {
+                    "type": "number"
+                },
+                "error": {
+                    "description": "Error explains why the raw value could not be coerced",
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "raw": {
+                    "description": "Raw is the model's value before coercion",
+                    "type": "object"
+                },
+                "span": {
+                    "$ref": "#/definitions/models.ExtractSpan"
+                },
+                "type": {
+                    "type": "string"
+                },
+                "value": {
+                    "description": "Value is null when not found or not coercible",
+                    "type": "object"
+                }
+            }
+        },
+        "models.FIMRequest": {
+            "type": "object",
+            "properties": {
+                "dry_run": {
+                    "type": "boolean"
+                },
+                "language": {
+                    "type": "string"
+                },
+                "max_tokens": {
+                    "description": "MaxTokens defaults to 64, at most 256",
+                    "type": "integer"
+                },
+                "prefix": {
+                    "type": "string"
+                },
+                "stop": {
+                    "description": "Stop lists extra stop sequences",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "suffix": {
+                    "type": "string"
+                },
+                "temperature": {
+                    "description": "Temperature defaults to 0.2",
+                    "type": "number"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.FIMResponse": {
+            "type": "object",
+            "properties": {
+                "completion": {
+                    "type": "string"
+                },
+                "duration_ms": {
+                    "type": "integer"
+                },
+                "id": {
+                    "description": "ID refers to the response in /ai/feedback",
+                    "type": "string"
+                },
+                "language": {
+                    "type": "string"
+                },
+                "mode": {
+                    "description": "Mode is sentinel or prompt",
+                    "type": "string"
+                },
+                "model": {
+                    "type": "string"
+                },
+                "stop_reason": {
+                    "description": "StopReason is boundary, suffix, stop_sequence or length",
+                    "type": "string"
+                },
+                "timestamp": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Feedback": {
+            "type": "object",
+            "properties": {
+                "comment": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "rating": {
+                    "type": "string"
+                },
+                "response": {
+                    "description": "Response is a snapshot of the rated response without its response\nbody; long prompts, outputs and request bodies are trimmed",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.ResponseRecord"
+                        }
+                    ]
+                },
+                "response_id": {
+                    "type": "string"
+                },
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.FeedbackList": {
+            "type": "object",
+            "properties": {
+                "feedback": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Feedback"
+                    }
+                },
+                "total": {
+                    "description": "Total counts the matching feedback before paging",
+                    "type": "integer"
+                }
+            }
+        },
+        "models.FeedbackRequest": {
+            "type": "object",
+            "properties": {
+                "comment": {
+                    "type": "string"
+                },
+                "rating": {
+                    "description": "Rating is up or down",
+                    "type": "string"
+                },
+                "response_id": {
+                    "type": "string"
+                },
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "user_id": {
+                    "description": "UserID is overridden by the X-User-ID header",
+                    "type": "string"
+                }
+            }
+        },
+        "models.FewShotOptions": {
+            "type": "object",
+            "properties": {
+                "k": {
+                    "description": "K is the number of examples, default 3",
+                    "type": "integer"
+                },
+                "task": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.GenerateRequest": {
+            "type": "object",
+            "properties": {
+                "dry_run": {
+                    "description": "DryRun returns the upstream request instead of calling the model",
+                    "type": "boolean"
+                },
+                "few_shot": {
+                    "description": "FewShot prepends the most similar stored examples of a task to the prompt",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.FewShotOptions"
+                        }
+                    ]
+                },
+                "max_tokens": {
+                    "type": "integer"
+                },
+                "prompt": {
+                    "type": "string"
+                },
+                "temperature": {
+                    "type": "number"
+                },
+                "template_id": {
+                    "description": "TemplateID renders a stored prompt template in place of Prompt, which\nmust then be empty",
+                    "type": "string"
+                },
+                "template_version": {
+                    "type": "integer"
+                },
+                "user_id": {
+                    "type": "string"
+                },
+                "variables": {
+                    "type": "object",
+                    "additionalProperties": true
+                }
+            }
+        },
+        "models.GenerateResponse": {
+            "type": "object",
+            "properties": {
+                "examples": {
+                    "description": "Examples lists the IDs of the few-shot examples used",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "id": {
+                    "description": "ID refers to the response in /ai/feedback",
+                    "type": "string"
+                },
+                "model": {
+                    "type": "string"
+                },
+                "response": {
+                    "type": "string"
+                },
+                "timestamp": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.HealthResponse": {
+            "type": "object",
+            "properties": {
+                "status": {
+                    "type": "string"
+                },
+                "timestamp": {
+                    "type": "string"
+                },
+                "version": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.IngestChunk": {
+            "type": "object",
+            "properties": {
+                "end": {
+                    "type": "integer"
+                },
+                "index": {
+                    "type": "integer"
+                },
+                "start": {
+                    "type": "integer"
+                },
+                "text": {
+                    "type": "string"
+                },
+                "tokens": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.IngestDocumentsResponse": {
+            "type": "object",
+            "properties": {
+                "chunks": {
+                    "type": "integer"
+                },
+                "collection": {
+                    "type": "string"
+                },
+                "documents": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.RAGDocument"
+                    }
+                }
+            }
+        },
+        "models.IngestResponse": {
+            "type": "object",
+            "properties": {
+                "chunks": {
+                    "type": "integer"
+                },
+                "files": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.IngestedFile"
+                    }
+                },
+                "rag_collection": {
+                    "type": "string"
+                },
+                "timestamp": {
+                    "type": "string"
+                },
+                "vector_collection": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.IngestedFile": {
+            "type": "object",
+            "properties": {
+                "characters": {
+                    "type": "integer"
+                },
+                "chunks": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.IngestChunk"
+                    }
+                },
+                "document_id": {
+                    "description": "DocumentID is set when the file is stored",
+                    "type": "string"
+                },
+                "filename": {
+                    "type": "string"
+                },
+                "format": {
+                    "type": "string"
+                },
+                "pages": {
+                    "description": "Pages is set for PDFs only",
+                    "type": "integer"
+                },
+                "text": {
+                    "description": "Text is omitted when include_text is false",
+                    "type": "string"
+                },
+                "title": {
+                    "type": "string"
+                },
+                "tokens": {
+                    "type": "integer"
+                },
+                "warnings": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "models.KnowledgeEntry": {
+            "type": "object",
+            "properties": {
+                "key": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "value": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.MemoryInfo": {
+            "type": "object",
+            "properties": {
+                "prompt_tokens": {
+                    "type": "integer"
+                },
+                "refreshed": {
+                    "type": "boolean"
+                },
+                "summarized": {
+                    "type": "boolean"
+                },
+                "summarized_messages": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.MemoryOptions": {
+            "type": "object",
+            "properties": {
+                "keep_recent": {
+                    "description": "KeepRecent is the number of most recent messages kept verbatim (default 4)",
+                    "type": "integer"
+                },
+                "mode": {
+                    "description": "Mode is \"summary\" (the only supported mode) or empty to disable",
+                    "type": "string"
+                },
+                "threshold_tokens": {
+                    "description": "ThresholdTokens is the history size that triggers summarization (default 600)",
+                    "type": "integer"
+                }
+            }
+        },
+        "models.MemorySummary": {
+            "type": "object",
+            "properties": {
+                "content": {
+                    "type": "string"
+                },
+                "covered_messages": {
+                    "type": "integer"
+                },
+                "covered_node_id": {
+                    "description": "CoveredNodeID is the last message node folded into the summary",
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.MessageNode": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "message": {
+                    "$ref": "#/definitions/models.ChatMessage"
+                },
+                "parent_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.PromptTemplate": {
+            "type": "object",
+            "properties": {
+                "active_version": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "versions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.TemplateVersion"
+                    }
+                }
+            }
+        },
+        "models.PromptTemplateRequest": {
+            "type": "object",
+            "properties": {
+                "body": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "note": {
+                    "type": "string"
+                },
+                "variables": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.TemplateVariable"
+                    }
+                }
+            }
+        },
+        "models.QAPassage": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "string"
+                },
+                "text": {
+                    "type": "string"
+                },
+                "title": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.QARequest": {
+            "type": "object",
+            "properties": {
+                "context": {
+                    "type": "string"
+                },
+                "dry_run": {
+                    "type": "boolean"
+                },
+                "max_tokens": {
+                    "description": "MaxTokens defaults to 48",
+                    "type": "integer"
+                },
+                "min_confidence": {
+                    "description": "MinConfidence is the alignment score below which there is no answer, default 0.6",
+                    "type": "number"
+                },
+                "passages": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.QAPassage"
+                    }
+                },
+                "question": {
+                    "type": "string"
+                },
+                "temperature": {
+                    "type": "number"
+                },
+                "top_k": {
+                    "description": "TopK is the number of passage chunks placed in the prompt, default 3",
+                    "type": "integer"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.QAResponse": {
+            "type": "object",
+            "properties": {
+                "answer": {
+                    "type": "string"
+                },
+                "confidence": {
+                    "type": "number"
+                },
+                "id": {
+                    "description": "ID refers to the response in /ai/feedback",
+                    "type": "string"
+                },
+                "model": {
+                    "type": "string"
+                },
+                "model_answer": {
+                    "description": "ModelAnswer is the model's raw answer",
+                    "type": "string"
+                },
+                "no_answer": {
+                    "type": "boolean"
+                },
+                "sources": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.QASource"
+                    }
+                },
+                "span": {
+                    "$ref": "#/definitions/models.QASpan"
+                },
+                "timestamp": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.QASource": {
+            "type": "object",
+            "properties": {
+                "end": {
+                    "type": "integer"
+                },
+                "marker": {
+                    "type": "integer"
+                },
+                "passage_id": {
+                    "type": "string"
+                },
+                "passage_index": {
+                    "type": "integer"
+                },
+                "score": {
+                    "description": "Score is the BM25 score against the question",
+                    "type": "number"
+                },
+                "start": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.QASpan": {
+            "type": "object",
+            "properties": {
+                "end": {
+                    "type": "integer"
+                },
+                "passage_id": {
+                    "type": "string"
+                },
+                "passage_index": {
+                    "type": "integer"
+                },
+                "start": {
+                    "type": "integer"
+                },
+                "text": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.RAGChatRequest": {
+            "type": "object",
+            "properties": {
+                "collection": {
+                    "type": "string"
+                },
+                "dry_run": {
+                    "type": "boolean"
+                },
+                "filter": {
+                    "type": "object"
+                },
+                "max_tokens": {
+                    "type": "integer"
+                },
+                "messages": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ChatMessage"
+                    }
+                },
+                "mode": {
+                    "type": "string"
+                },
+                "temperature": {
+                    "type": "number"
+                },
+                "top_k": {
+                    "type": "integer"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.RAGChatResponse": {
+            "type": "object",
+            "properties": {
+                "citations": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.RAGCitation"
+                    }
+                },
+                "collection": {
+                    "type": "string"
+                },
+                "id": {
+                    "description": "ID refers to the response in /ai/feedback",
+                    "type": "string"
+                },
+                "mode": {
+                    "type": "string"
+                },
+                "model": {
+                    "type": "string"
+                },
+                "response": {
+                    "type": "string"
+                },
+                "sources": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.RAGCitation"
+                    }
+                },
+                "timestamp": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.RAGChunk": {
+            "type": "object",
+            "properties": {
+                "document_id": {
+                    "type": "string"
+                },
+                "end": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "index": {
+                    "type": "integer"
+                },
+                "start": {
+                    "type": "integer"
+                },
+                "text": {
+                    "type": "string"
+                },
+                "tokens": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.RAGCitation": {
+            "type": "object",
+            "properties": {
+                "chunk_id": {
+                    "type": "string"
+                },
+                "document_id": {
+                    "type": "string"
+                },
+                "end": {
+                    "type": "integer"
+                },
+                "marker": {
+                    "description": "Marker is the [n] marker in the prompt and answer",
+                    "type": "integer"
+                },
+                "score": {
+                    "type": "number"
+                },
+                "source": {
+                    "type": "string"
+                },
+                "start": {
+                    "type": "integer"
+                },
+                "title": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.RAGCollection": {
+            "type": "object",
+            "properties": {
+                "chunk_overlap": {
+                    "type": "integer"
+                },
+                "chunk_tokens": {
+                    "type": "integer"
+                },
+                "chunks": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "documents": {
+                    "type": "integer"
+                },
+                "embedding_model": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "vectors": {
+                    "description": "Vectors reports whether chunks are also embedded for hybrid retrieval",
+                    "type": "boolean"
+                }
+            }
+        },
+        "models.RAGDocument": {
+            "type": "object",
+            "properties": {
+                "characters": {
+                    "type": "integer"
+                },
+                "chunks": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "metadata": {
+                    "type": "object"
+                },
+                "source": {
+                    "type": "string"
+                },
+                "text": {
+                    "type": "string"
+                },
+                "title": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.RAGDocumentDetail": {
+            "type": "object",
+            "properties": {
+                "chunks": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.RAGChunk"
+                    }
+                },
+                "document": {
+                    "$ref": "#/definitions/models.RAGDocument"
+                }
+            }
+        },
+        "models.RAGDocumentInput": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "string"
+                },
+                "metadata": {
+                    "type": "object"
+                },
+                "source": {
+                    "type": "string"
+                },
+                "text": {
+                    "type": "string"
+                },
+                "title": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.RAGMatch": {
+            "type": "object",
+            "properties": {
+                "bm25_score": {
+                    "type": "number"
+                },
+                "document_id": {
+                    "type": "string"
+                },
+                "end": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "index": {
+                    "type": "integer"
+                },
+                "score": {
+                    "type": "number"
+                },
+                "source": {
+                    "type": "string"
+                },
+                "start": {
+                    "type": "integer"
+                },
+                "text": {
+                    "type": "string"
+                },
+                "title": {
+                    "type": "string"
+                },
+                "tokens": {
+                    "type": "integer"
+                },
+                "vector_score": {
+                    "type": "number"
+                }
+            }
+        },
+        "models.RAGSearchRequest": {
+            "type": "object",
+            "properties": {
+                "filter": {
+                    "type": "object"
+                },
+                "mode": {
+                    "description": "Mode is hybrid (default with vectors), bm25 or vector",
+                    "type": "string"
+                },
+                "query": {
+                    "type": "string"
+                },
+                "top_k": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.RAGSearchResponse": {
+            "type": "object",
+            "properties": {
+                "collection": {
+                    "type": "string"
+                },
+                "matches": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.RAGMatch"
+                    }
+                },
+                "mode": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.RegenerateRequest": {
+            "type": "object",
+            "properties": {
+                "max_tokens": {
+                    "type": "integer"
+                },
+                "temperature": {
+                    "type": "number"
+                }
+            }
+        },
+        "models.RenderTemplateRequest": {
+            "type": "object",
+            "properties": {
+                "variables": {
+                    "type": "object",
+                    "additionalProperties": true
+                },
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.RenderTemplateResponse": {
+            "type": "object",
+            "properties": {
+                "prompt": {
+                    "type": "string"
+                },
+                "template_id": {
+                    "type": "string"
+                },
+                "tokens": {
+                    "type": "integer"
+                },
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.ResponseRecord": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "duration_ms": {
+                    "type": "integer"
+                },
+                "endpoint": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "max_tokens": {
+                    "type": "integer"
+                },
+                "model": {
+                    "type": "string"
+                },
+                "output": {
+                    "description": "Output is the main text of the response",
+                    "type": "string"
+                },
+                "prompt": {
+                    "description": "Prompt is the text the output answers",
+                    "type": "string"
+                },
+                "request": {
+                    "description": "Request is the request as processed, with defaults applied",
+                    "type": "object"
+                },
+                "response": {
+                    "description": "Response is the response body as sent",
+                    "type": "object"
+                },
                 "temperature": {
                     "type": "number"
                 },
+                "usage": {
+                    "description": "Usage is estimated from the prompt and output",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.TokenUsage"
+                        }
+                    ]
+                },
                 "user_id": {
                     "type": "string"
                 }
             }
         },
-        "models.ChatCompletionResponse": {
+        "models.RollbackTemplateRequest": {
             "type": "object",
             "properties": {
-                "model": {
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.SummarizeRequest": {
+            "type": "object",
+            "properties": {
+                "dry_run": {
+                    "type": "boolean"
+                },
+                "focus": {
+                    "description": "Focus is a query to summarize with respect to",
                     "type": "string"
                 },
-                "response": {
+                "length": {
+                    "description": "Length is short, medium (default) or long",
                     "type": "string"
                 },
-                "timestamp": {
+                "style": {
+                    "description": "Style is prose (default) or bullets",
+                    "type": "string"
+                },
+                "temperature": {
+                    "type": "number"
+                },
+                "text": {
                     "type": "string"
                 },
                 "user_id": {
@@ -278,26 +6925,136 @@ const docTemplate = `{
                 }
             }
         },
-        "models.ChatMessage": {
+        "models.SummarizeResponse": {
             "type": "object",
             "properties": {
-                "content": {
+                "calls": {
+                    "description": "Calls counts the model calls made",
+                    "type": "integer"
+                },
+                "chunks": {
+                    "description": "Chunks counts the chunks in the first pass",
+                    "type": "integer"
+                },
+                "duration_ms": {
+                    "type": "integer"
+                },
+                "focus": {
                     "type": "string"
                 },
-                "role": {
+                "id": {
+                    "description": "ID refers to the response in /ai/feedback",
+                    "type": "string"
+                },
+                "input_tokens": {
+                    "type": "integer"
+                },
+                "length": {
+                    "type": "string"
+                },
+                "levels": {
+                    "description": "Levels counts the passes over the text, including the final one",
+                    "type": "integer"
+                },
+                "model": {
+                    "type": "string"
+                },
+                "style": {
+                    "type": "string"
+                },
+                "summary": {
+                    "type": "string"
+                },
+                "summary_tokens": {
+                    "type": "integer"
+                },
+                "timestamp": {
+                    "type": "string"
+                },
+                "user_id": {
                     "type": "string"
                 }
             }
         },
-        "models.CompleteRequest": {
+        "models.SwitchBranchRequest": {
+            "type": "object",
+            "properties": {
+                "node_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.TaskInfo": {
             "type": "object",
             "properties": {
+                "description": {
+                    "type": "string"
+                },
+                "input_schema": {
+                    "type": "object"
+                },
                 "max_tokens": {
                     "type": "integer"
                 },
+                "name": {
+                    "type": "string"
+                },
+                "output": {
+                    "$ref": "#/definitions/models.TaskOutput"
+                },
+                "path": {
+                    "type": "string"
+                },
                 "prompt": {
                     "type": "string"
                 },
+                "rate_limit": {
+                    "description": "RateLimit is in requests per minute per IP address",
+                    "type": "integer"
+                },
+                "system": {
+                    "description": "System is the system message for the chat upstream",
+                    "type": "string"
+                },
+                "temperature": {
+                    "type": "number"
+                },
+                "template_id": {
+                    "description": "TemplateID is a stored prompt template used instead of prompt",
+                    "type": "string"
+                },
+                "upstream": {
+                    "description": "Upstream is complete (default), generate or chat",
+                    "type": "string"
+                }
+            }
+        },
+        "models.TaskOutput": {
+            "type": "object",
+            "properties": {
+                "parser": {
+                    "description": "Parser is text (default), json, lines or regex",
+                    "type": "string"
+                },
+                "pattern": {
+                    "description": "Pattern is the regular expression for the regex parser; named groups become fields",
+                    "type": "string"
+                }
+            }
+        },
+        "models.TaskRequest": {
+            "type": "object",
+            "properties": {
+                "dry_run": {
+                    "description": "DryRun returns the upstream request instead of calling the model",
+                    "type": "boolean"
+                },
+                "input": {
+                    "type": "object"
+                },
+                "max_tokens": {
+                    "type": "integer"
+                },
                 "temperature": {
                     "type": "number"
                 },
@@ -306,13 +7063,23 @@ const docTemplate = `{
                 }
             }
         },
-        "models.CompleteResponse": {
+        "models.TaskResponse": {
             "type": "object",
             "properties": {
+                "id": {
+                    "description": "ID refers to the response in /ai/feedback",
+                    "type": "string"
+                },
                 "model": {
                     "type": "string"
                 },
-                "response": {
+                "output": {
+                    "type": "object"
+                },
+                "raw": {
+                    "type": "string"
+                },
+                "task": {
                     "type": "string"
                 },
                 "timestamp": {
@@ -323,65 +7090,259 @@ const docTemplate = `{
                 }
             }
         },
-        "models.ErrorResponse": {
+        "models.TemplateVariable": {
             "type": "object",
             "properties": {
-                "code": {
-                    "type": "integer"
+                "default": {
+                    "type": "string"
                 },
-                "error": {
+                "description": {
                     "type": "string"
                 },
-                "message": {
+                "name": {
+                    "type": "string"
+                },
+                "required": {
+                    "type": "boolean"
+                },
+                "type": {
+                    "description": "Type is one of string, number, integer or boolean (default string)",
                     "type": "string"
                 }
             }
         },
-        "models.GenerateRequest": {
+        "models.TemplateVersion": {
             "type": "object",
             "properties": {
-                "max_tokens": {
+                "body": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "note": {
+                    "type": "string"
+                },
+                "variables": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.TemplateVariable"
+                    }
+                },
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.TokenUsage": {
+            "type": "object",
+            "properties": {
+                "completion_tokens": {
                     "type": "integer"
                 },
-                "prompt": {
+                "prompt_tokens": {
+                    "type": "integer"
+                },
+                "total_tokens": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.Tool": {
+            "type": "object",
+            "properties": {
+                "function": {
+                    "$ref": "#/definitions/models.ToolFunction"
+                },
+                "type": {
                     "type": "string"
+                }
+            }
+        },
+        "models.ToolCall": {
+            "type": "object",
+            "properties": {
+                "function": {
+                    "$ref": "#/definitions/models.ToolCallFunction"
                 },
-                "temperature": {
-                    "type": "number"
+                "id": {
+                    "type": "string"
                 },
-                "user_id": {
+                "type": {
                     "type": "string"
                 }
             }
         },
-        "models.GenerateResponse": {
+        "models.ToolCallFunction": {
             "type": "object",
             "properties": {
-                "model": {
+                "arguments": {
                     "type": "string"
                 },
-                "response": {
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.ToolFunction": {
+            "type": "object",
+            "properties": {
+                "description": {
                     "type": "string"
                 },
-                "timestamp": {
+                "name": {
                     "type": "string"
                 },
-                "user_id": {
+                "parameters": {
+                    "type": "object",
+                    "additionalProperties": true
+                }
+            }
+        },
+        "models.UpsertVectorsRequest": {
+            "type": "object",
+            "properties": {
+                "records": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.VectorRecord"
+                    }
+                }
+            }
+        },
+        "models.UpsertVectorsResponse": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer"
+                },
+                "upserted": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.VectorCollection": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "dimensions": {
+                    "type": "integer"
+                },
+                "embedding_model": {
+                    "description": "EmbeddingModel embeds records and queries given as text",
+                    "type": "string"
+                },
+                "metric": {
+                    "description": "Metric is cosine, dot or l2",
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "snapshot_at": {
+                    "type": "string"
+                },
+                "updated_at": {
                     "type": "string"
                 }
             }
         },
-        "models.HealthResponse": {
+        "models.VectorMatch": {
             "type": "object",
             "properties": {
-                "status": {
+                "id": {
                     "type": "string"
                 },
-                "timestamp": {
+                "metadata": {
+                    "type": "object"
+                },
+                "score": {
+                    "type": "number"
+                },
+                "text": {
                     "type": "string"
                 },
-                "version": {
+                "vector": {
+                    "type": "array",
+                    "items": {
+                        "type": "number"
+                    }
+                }
+            }
+        },
+        "models.VectorQueryRequest": {
+            "type": "object",
+            "properties": {
+                "exact": {
+                    "description": "Exact scans every record instead of using the index",
+                    "type": "boolean"
+                },
+                "filter": {
+                    "type": "object"
+                },
+                "include_vectors": {
+                    "type": "boolean"
+                },
+                "text": {
+                    "type": "string"
+                },
+                "top_k": {
+                    "type": "integer"
+                },
+                "vector": {
+                    "type": "array",
+                    "items": {
+                        "type": "number"
+                    }
+                }
+            }
+        },
+        "models.VectorQueryResponse": {
+            "type": "object",
+            "properties": {
+                "collection": {
+                    "type": "string"
+                },
+                "exact": {
+                    "description": "Exact reports whether an exact scan was used",
+                    "type": "boolean"
+                },
+                "matches": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.VectorMatch"
+                    }
+                },
+                "metric": {
+                    "type": "string"
+                },
+                "took_ms": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.VectorRecord": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "string"
+                },
+                "metadata": {
+                    "type": "object"
+                },
+                "text": {
                     "type": "string"
+                },
+                "vector": {
+                    "type": "array",
+                    "items": {
+                        "type": "number"
+                    }
                 }
             }
         }
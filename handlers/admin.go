@@ -0,0 +1,23 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"net/http"
+)
+
+// requireAdmin checks the X-Admin-Token header against AI_ADMIN_TOKEN and
+// writes an error response when the request is not authorized. Admin
+// endpoints are disabled entirely when no token is configured.
+func (h *AIHandler) requireAdmin(w http.ResponseWriter, r *http.Request) bool {
+	if h.adminToken == "" {
+		h.sendErrorResponse(w, http.StatusForbidden, "Admin endpoints are disabled; set AI_ADMIN_TOKEN to enable them")
+		return false
+	}
+
+	token := r.Header.Get("X-Admin-Token")
+	if subtle.ConstantTimeCompare([]byte(token), []byte(h.adminToken)) != 1 {
+		h.sendErrorResponse(w, http.StatusUnauthorized, "Invalid or missing admin token")
+		return false
+	}
+	return true
+}
@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Ammar0144/ai/models"
+	"github.com/Ammar0144/ai/services"
+)
+
+// HandleAgent runs the server-side agent loop
+//
+//	@Summary		Agent
+//	@Description	Run a bounded reason-act loop with built-in sandboxed tools (calculator, current_time, knowledge_lookup). Returns the final answer and a full step trace. Rate limited to 30 requests per minute per IP address.
+//	@Tags			AI Processing
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		models.AgentRequest		true	"Agent request"
+//	@Success		200		{object}	models.AgentResponse	"Agent run result"
+//	@Failure		400		{object}	models.ErrorResponse	"Bad request"
+//	@Failure		429		{object}	models.ErrorResponse	"Rate limit exceeded"
+//	@Failure		500		{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/ai/agent [post]
+func (h *AIHandler) HandleAgent(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.sendErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var req models.AgentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid JSON format")
+		return
+	}
+
+	messages := req.Messages
+	if req.Prompt != "" {
+		messages = append(messages, models.ChatMessage{Role: "user", Content: req.Prompt})
+	}
+	if len(messages) == 0 {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Either prompt or messages is required")
+		return
+	}
+	if err := validateChatMessages(messages, true); err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if req.MaxSteps < 0 || req.TimeoutSeconds < 0 {
+		h.sendErrorResponse(w, http.StatusBadRequest, "max_steps and timeout_seconds cannot be negative")
+		return
+	}
+
+	maxTokens := req.MaxTokens
+	if maxTokens == 0 {
+		maxTokens = 150
+	}
+
+	temperature := req.Temperature
+	if temperature == 0 {
+		temperature = 0.7
+	}
+
+	log.Printf("Received agent request from user %s with %d messages", req.UserID, len(messages))
+
+	started := time.Now()
+	result, err := h.agent.Run(r.Context(), messages, services.AgentOptions{
+		Tools:       req.Tools,
+		MaxSteps:    req.MaxSteps,
+		Timeout:     time.Duration(req.TimeoutSeconds) * time.Second,
+		MaxTokens:   maxTokens,
+		Temperature: temperature,
+	})
+	if err != nil && result == nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		log.Printf("Error running agent: %v", err)
+		if len(result.Steps) <= 1 {
+			h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to run agent")
+			return
+		}
+	}
+
+	response := models.AgentResponse{
+		ID:         newResponseID(),
+		Response:   result.Answer,
+		StopReason: result.StopReason,
+		Steps:      result.Steps,
+		DurationMs: time.Since(started).Milliseconds(),
+		UserID:     req.UserID,
+		Timestamp:  time.Now(),
+		Model:      h.aiService.GetModel(),
+	}
+	if response.Steps == nil {
+		response.Steps = []models.AgentStep{}
+	}
+
+	h.recordResponse(w, started, models.ResponseRecord{
+		ID:          response.ID,
+		Endpoint:    "/ai/agent",
+		UserID:      req.UserID,
+		Model:       response.Model,
+		Prompt:      lastUserMessage(messages),
+		Output:      result.Answer,
+		MaxTokens:   maxTokens,
+		Temperature: temperature,
+	}, req, response)
+	h.sendJSONResponse(w, http.StatusOK, response)
+}
+
+// HandleAgentKnowledge manages the static knowledge used by the agent
+//
+//	@Summary		Agent knowledge base
+//	@Description	List (GET), add or replace (POST/PUT with {"key","value"}) and delete (DELETE ?key=) static knowledge for the agent's knowledge_lookup tool. Changes require the X-Admin-Token header. Rate limited to 100 requests per minute per IP address.
+//	@Tags			Admin
+//	@Accept			json
+//	@Produce		json
+//	@Param			X-Admin-Token	header		string					false	"Admin token (required for changes)"
+//	@Param			key				query		string					false	"Key to delete"
+//	@Param			entry			body		models.KnowledgeEntry	false	"Entry to store"
+//	@Success		200				{array}		models.KnowledgeEntry	"Knowledge entries"
+//	@Failure		400				{object}	models.ErrorResponse	"Bad request"
+//	@Failure		401				{object}	models.ErrorResponse	"Unauthorized"
+//	@Failure		404				{object}	models.ErrorResponse	"Entry not found"
+//	@Router			/ai/agent/knowledge [get]
+//	@Router			/ai/agent/knowledge [post]
+//	@Router			/ai/agent/knowledge [put]
+//	@Router			/ai/agent/knowledge [delete]
+func (h *AIHandler) HandleAgentKnowledge(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.sendJSONResponse(w, http.StatusOK, h.knowledge.List())
+
+	case http.MethodPost, http.MethodPut:
+		if !h.requireAdmin(w, r) {
+			return
+		}
+		var entry models.KnowledgeEntry
+		if err := json.NewDecoder(r.Body).Decode(&entry); err != nil {
+			h.sendErrorResponse(w, http.StatusBadRequest, "Invalid JSON format")
+			return
+		}
+		entry.Key = strings.TrimSpace(entry.Key)
+		if entry.Key == "" || entry.Value == "" {
+			h.sendErrorResponse(w, http.StatusBadRequest, "Key and value are required")
+			return
+		}
+		log.Printf("Storing agent knowledge entry %q", entry.Key)
+		h.sendJSONResponse(w, http.StatusOK, h.knowledge.Set(entry.Key, entry.Value))
+
+	case http.MethodDelete:
+		if !h.requireAdmin(w, r) {
+			return
+		}
+		key := r.URL.Query().Get("key")
+		if key == "" {
+			h.sendErrorResponse(w, http.StatusBadRequest, "Key query parameter is required")
+			return
+		}
+		if !h.knowledge.Delete(key) {
+			h.sendErrorResponse(w, http.StatusNotFound, "Knowledge entry not found")
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		h.sendErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
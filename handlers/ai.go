@@ -2,8 +2,12 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"strings"
 	"time"
 
 	"github.com/Ammar0144/ai/models"
@@ -12,28 +16,70 @@ import (
 
 // AIHandler handles AI-related HTTP requests
 type AIHandler struct {
-	aiService *services.AIService
+	aiService     *services.AIService
+	agent         *services.Agent
+	knowledge     *services.KnowledgeBase
+	conversations services.ConversationStore
+	templates     *services.TemplateStore
+	examples      *services.ExampleStore
+	chains        *services.ChainStore
+	vectors       *services.VectorStore
+	rag           *services.RAGStore
+	autocomplete  *services.Autocompleter
+	comparer      *services.Comparer
+	responses     *services.ResponseStore
+	feedback      *services.FeedbackStore
+	tasks         map[string]models.TaskDefinition
+	taskList      []models.TaskDefinition
+	adminToken    string
 }
 
 // NewAIHandler creates a new AI handler
 func NewAIHandler() *AIHandler {
+	aiService := services.NewAIService()
+	knowledge := services.NewKnowledgeBase()
+
+	taskList := services.LoadTaskDefinitions()
+	tasks := make(map[string]models.TaskDefinition, len(taskList))
+	for _, task := range taskList {
+		tasks[task.Name] = task
+	}
+
 	return &AIHandler{
-		aiService: services.NewAIService(),
+		aiService:     aiService,
+		agent:         services.NewAgent(aiService, knowledge),
+		knowledge:     knowledge,
+		conversations: services.NewConversationStore(),
+		templates:     services.NewTemplateStore(),
+		examples:      services.NewExampleStore(),
+		chains:        services.NewChainStore(),
+		vectors:       services.NewVectorStore(),
+		rag:           services.NewRAGStore(aiService),
+		autocomplete:  services.NewAutocompleter(aiService),
+		comparer:      services.NewComparer(aiService),
+		responses:     services.NewResponseStore(),
+		feedback:      services.NewFeedbackStore(),
+		tasks:         tasks,
+		taskList:      taskList,
+		adminToken:    os.Getenv("AI_ADMIN_TOKEN"),
 	}
 }
 
 // HandleChatCompletion handles chat completion requests
 //
 //	@Summary		Chat completion
-//	@Description	Generate a chat completion based on conversation history. Rate limited to 30 requests per minute per IP address.
+//	@Description	Generate a chat completion based on conversation history. Supports OpenAI-style tools and tool_calls; tools are emulated through the prompt when the upstream has no native support. When tool_choice is "required" or names a function, a reply without a matching call is retried once and then fails. With conversation_id, only the new turn needs to be sent and both turns are appended to the stored conversation. With template_id, the rendered prompt template is appended as a user message, so the messages must not already end with one. With memory.mode "summary", older turns beyond a token threshold are replaced by a cached rolling summary. With dry_run or the X-AI-Dry-Run header, the rendered upstream request is returned instead of calling the model. Rate limited to 30 requests per minute per IP address.
 //	@Tags			AI Processing
 //	@Accept			json
 //	@Produce		json
-//	@Param			request	body		models.ChatCompletionRequest	true	"Chat completion request"
-//	@Success		200		{object}	models.ChatCompletionResponse	"Successful chat completion"
-//	@Failure		400		{object}	models.ErrorResponse			"Bad request"
-//	@Failure		429		{object}	models.ErrorResponse			"Rate limit exceeded"
-//	@Failure		500		{object}	models.ErrorResponse			"Internal server error"
+//	@Param			request			body		models.ChatCompletionRequest	true	"Chat completion request"
+//	@Param			X-User-ID		header		string							false	"User ID owning conversation_id"
+//	@Param			X-AI-Dry-Run	header		bool							false	"Return the upstream request without calling the model"
+//	@Success		200				{object}	models.ChatCompletionResponse	"Successful chat completion"
+//	@Failure		400				{object}	models.ErrorResponse			"Bad request"
+//	@Failure		404				{object}	models.ErrorResponse			"Conversation not found"
+//	@Failure		429				{object}	models.ErrorResponse			"Rate limit exceeded"
+//	@Failure		500				{object}	models.ErrorResponse			"Internal server error"
 //	@Router			/ai/chat/completions [post]
 func (h *AIHandler) HandleChatCompletion(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
@@ -47,11 +93,44 @@ func (h *AIHandler) HandleChatCompletion(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	if req.TemplateID != "" {
+		if n := len(req.Messages); n > 0 && req.Messages[n-1].Role == "user" {
+			h.sendErrorResponse(w, http.StatusBadRequest, "Set either template_id or a final user message, not both")
+			return
+		}
+		prompt, ok := h.renderRequestTemplate(w, req.TemplateID, req.TemplateVersion, req.Variables)
+		if !ok {
+			return
+		}
+		req.Messages = append(req.Messages, models.ChatMessage{Role: "user", Content: prompt})
+	}
+
 	if len(req.Messages) == 0 {
 		h.sendErrorResponse(w, http.StatusBadRequest, "Messages cannot be empty")
 		return
 	}
 
+	if err := validateChatMessages(req.Messages, len(req.Tools) > 0); err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := services.ValidateTools(req.Tools); err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	toolChoice, err := services.ParseToolChoice(req.ToolChoice, req.Tools)
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if req.Memory != nil && req.Memory.Mode != "" && req.Memory.Mode != services.MemoryModeSummary {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Unsupported memory mode; use \"summary\"")
+		return
+	}
+
 	maxTokens := req.MaxTokens
 	if maxTokens == 0 {
 		maxTokens = 150
@@ -62,39 +141,151 @@ func (h *AIHandler) HandleChatCompletion(w http.ResponseWriter, r *http.Request)
 		temperature = 0.7
 	}
 
-	log.Printf("Received chat completion request from user %s with %d messages", req.UserID, len(req.Messages))
+	log.Printf("Received chat completion request from user %s with %d messages and %d tools", req.UserID, len(req.Messages), len(req.Tools))
+
+	// With a conversation_id the stored history is prepended to the new turn(s)
+	messages := req.Messages
+	userID := req.UserID
+	var nodeIDs []string
+	var cachedSummary *models.MemorySummary
+	if req.ConversationID != "" {
+		userID = requestUserID(r, req.UserID)
+		if userID == "" {
+			h.sendErrorResponse(w, http.StatusBadRequest, "User ID is required to use conversation_id")
+			return
+		}
+		conversation, err := h.conversations.Get(userID, req.ConversationID)
+		if err != nil {
+			h.sendConversationError(w, err)
+			return
+		}
+		path, err := services.GetPath(conversation, "")
+		if err != nil {
+			h.sendConversationError(w, err)
+			return
+		}
+		messages = append(path.Messages, req.Messages...)
+		nodeIDs = path.NodeIDs
+		cachedSummary = conversation.Summary
+	}
+
+	if isDryRun(r, req.DryRun) {
+		notes := templateRoutingNotes(req.TemplateID, req.TemplateVersion)
+		if req.ConversationID != "" {
+			notes = append(notes, fmt.Sprintf("conversation: %s, %d stored messages prepended", req.ConversationID, len(nodeIDs)))
+		}
+		if req.Memory != nil && req.Memory.Mode != "" {
+			notes = append(notes, "memory: summary mode not applied, because summarizing calls the model")
+		}
+		h.sendDryRun(w, h.aiService.BuildChatRequest(messages, req.Tools, toolChoice, maxTokens, temperature), "", maxTokens, temperature, notes...)
+		return
+	}
+
+	// Optional rolling summary of older turns to fit the model context
+	started := time.Now()
+	var memoryInfo *models.MemoryInfo
+	var refreshedSummary *models.MemorySummary
+	if req.Memory != nil && req.Memory.Mode != "" {
+		memory, err := h.aiService.ApplySummaryMemory(messages, nodeIDs, cachedSummary, *req.Memory)
+		if err != nil {
+			log.Printf("Error applying summary memory: %v", err)
+			h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to summarize conversation history")
+			return
+		}
+		messages = memory.Messages
+		memoryInfo = &models.MemoryInfo{
+			Summarized:         memory.Summary != nil,
+			SummarizedMessages: memory.SummarizedMessages,
+			Refreshed:          memory.Refreshed,
+			PromptTokens:       services.EstimateMessagesTokens(messages),
+		}
+		if memory.Refreshed && memory.Summary != nil && memory.Summary.CoveredNodeID != "" {
+			refreshedSummary = memory.Summary
+		}
+	}
 
-	aiResponse, err := h.aiService.GetChatCompletion(req.Messages, maxTokens, temperature)
+	result, err := h.aiService.GetChatCompletionWithTools(messages, req.Tools, toolChoice, maxTokens, temperature)
+	if errors.Is(err, services.ErrToolChoiceNotMet) {
+		log.Printf("Chat completion did not satisfy tool_choice %s %s", toolChoice.Mode, toolChoice.Name)
+		h.sendErrorResponse(w, http.StatusInternalServerError, "The model did not call the tool required by tool_choice")
+		return
+	}
 	if err != nil {
 		log.Printf("Error getting chat completion: %v", err)
 		h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to get chat completion")
 		return
 	}
 
+	messageID := ""
+	if req.ConversationID != "" {
+		reply := models.ChatMessage{Role: "assistant", Content: result.Content, ToolCalls: result.ToolCalls}
+		_, err := h.conversations.Update(userID, req.ConversationID, func(conversation *models.Conversation) error {
+			turns := append(append([]models.ChatMessage{}, req.Messages...), reply)
+			messageID = services.AppendMessages(conversation, turns...)
+			if refreshedSummary != nil {
+				conversation.Summary = refreshedSummary
+			}
+			return nil
+		})
+		if err != nil {
+			log.Printf("Error saving conversation %s: %v", req.ConversationID, err)
+			h.sendConversationError(w, err)
+			return
+		}
+	}
+
 	response := models.ChatCompletionResponse{
-		Response:  aiResponse,
-		UserID:    req.UserID,
-		Timestamp: time.Now(),
-		Model:     h.aiService.GetModel(),
+		ID:             newResponseID(),
+		Response:       result.Content,
+		ToolCalls:      result.ToolCalls,
+		FinishReason:   result.FinishReason,
+		ConversationID: req.ConversationID,
+		MessageID:      messageID,
+		Memory:         memoryInfo,
+		UserID:         userID,
+		Timestamp:      time.Now(),
+		Model:          h.aiService.GetModel(),
 	}
+	h.recordResponse(w, started, models.ResponseRecord{
+		ID:          response.ID,
+		Endpoint:    "/ai/chat/completions",
+		UserID:      userID,
+		Model:       response.Model,
+		Prompt:      lastUserMessage(messages),
+		Output:      result.Content,
+		MaxTokens:   maxTokens,
+		Temperature: temperature,
+		Usage:       chatUsage(messages, result.Content),
+	}, req, response)
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(response)
 }
 
+// chatUsage estimates the tokens of a chat call from all of its messages
+func chatUsage(messages []models.ChatMessage, output string) *models.TokenUsage {
+	promptTokens, completionTokens := services.EstimateMessagesTokens(messages), services.EstimateTokens(output)
+	return &models.TokenUsage{
+		PromptTokens:     promptTokens,
+		CompletionTokens: completionTokens,
+		TotalTokens:      promptTokens + completionTokens,
+	}
+}
+
 // HandleComplete handles text completion requests
 //
 //	@Summary		Text completion
-//	@Description	Complete text based on a given prompt. Rate limited to 30 requests per minute per IP address.
+//	@Description	Complete text based on a given prompt, or on a stored prompt template via template_id and variables. With few_shot {"task","k"}, the k stored examples most similar to the prompt are prepended. With dry_run or the X-AI-Dry-Run header, the rendered upstream request is returned instead of calling the model. Rate limited to 30 requests per minute per IP address.
 //	@Tags			AI Processing
 //	@Accept			json
 //	@Produce		json
-//	@Param			request	body		models.CompleteRequest		true	"Complete request"
-//	@Success		200		{object}	models.CompleteResponse		"Successful text completion"
-//	@Failure		400		{object}	models.ErrorResponse		"Bad request"
-//	@Failure		429		{object}	models.ErrorResponse		"Rate limit exceeded"
-//	@Failure		500		{object}	models.ErrorResponse		"Internal server error"
+//	@Param			request			body		models.CompleteRequest		true	"Complete request"
+//	@Param			X-AI-Dry-Run	header		bool						false	"Return the upstream request without calling the model"
+//	@Success		200				{object}	models.CompleteResponse		"Successful text completion"
+//	@Failure		400				{object}	models.ErrorResponse		"Bad request"
+//	@Failure		429				{object}	models.ErrorResponse		"Rate limit exceeded"
+//	@Failure		500				{object}	models.ErrorResponse		"Internal server error"
 //	@Router			/ai/complete [post]
 func (h *AIHandler) HandleComplete(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
@@ -108,6 +299,18 @@ func (h *AIHandler) HandleComplete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if req.TemplateID != "" {
+		if req.Prompt != "" {
+			h.sendErrorResponse(w, http.StatusBadRequest, "Set either prompt or template_id, not both")
+			return
+		}
+		prompt, ok := h.renderRequestTemplate(w, req.TemplateID, req.TemplateVersion, req.Variables)
+		if !ok {
+			return
+		}
+		req.Prompt = prompt
+	}
+
 	if req.Prompt == "" {
 		h.sendErrorResponse(w, http.StatusBadRequest, "Prompt cannot be empty")
 		return
@@ -123,9 +326,24 @@ func (h *AIHandler) HandleComplete(w http.ResponseWriter, r *http.Request) {
 		temperature = 0.7
 	}
 
+	prompt, exampleIDs, ok := h.applyFewShot(w, req.FewShot, req.Prompt, maxTokens)
+	if !ok {
+		return
+	}
+
+	if isDryRun(r, req.DryRun) {
+		notes := templateRoutingNotes(req.TemplateID, req.TemplateVersion)
+		if len(exampleIDs) > 0 {
+			notes = append(notes, fmt.Sprintf("few-shot: %d examples from task %s (%s)", len(exampleIDs), req.FewShot.Task, strings.Join(exampleIDs, ", ")))
+		}
+		h.sendDryRun(w, h.aiService.BuildCompleteRequest(prompt, maxTokens, temperature), prompt, maxTokens, temperature, notes...)
+		return
+	}
+
 	log.Printf("Received complete request from user %s", req.UserID)
 
-	aiResponse, err := h.aiService.GetComplete(req.Prompt, maxTokens, temperature)
+	started := time.Now()
+	aiResponse, err := h.aiService.GetComplete(prompt, maxTokens, temperature)
 	if err != nil {
 		log.Printf("Error getting completion: %v", err)
 		h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to get completion")
@@ -133,11 +351,23 @@ func (h *AIHandler) HandleComplete(w http.ResponseWriter, r *http.Request) {
 	}
 
 	response := models.CompleteResponse{
+		ID:        newResponseID(),
 		Response:  aiResponse,
+		Examples:  exampleIDs,
 		UserID:    req.UserID,
 		Timestamp: time.Now(),
 		Model:     h.aiService.GetModel(),
 	}
+	h.recordResponse(w, started, models.ResponseRecord{
+		ID:          response.ID,
+		Endpoint:    "/ai/complete",
+		UserID:      req.UserID,
+		Model:       response.Model,
+		Prompt:      prompt,
+		Output:      aiResponse,
+		MaxTokens:   maxTokens,
+		Temperature: temperature,
+	}, req, response)
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
@@ -147,15 +377,16 @@ func (h *AIHandler) HandleComplete(w http.ResponseWriter, r *http.Request) {
 // HandleGenerate handles text generation requests
 //
 //	@Summary		Text generation
-//	@Description	Generate text based on a given prompt. Rate limited to 30 requests per minute per IP address.
+//	@Description	Generate text based on a given prompt, or on a stored prompt template via template_id and variables. With few_shot {"task","k"}, the k stored examples most similar to the prompt are prepended. With dry_run or the X-AI-Dry-Run header, the rendered upstream request is returned instead of calling the model. Rate limited to 30 requests per minute per IP address.
 //	@Tags			AI Processing
 //	@Accept			json
 //	@Produce		json
-//	@Param			request	body		models.GenerateRequest		true	"Generate request"
-//	@Success		200		{object}	models.GenerateResponse		"Successful text generation"
-//	@Failure		400		{object}	models.ErrorResponse		"Bad request"
-//	@Failure		429		{object}	models.ErrorResponse		"Rate limit exceeded"
-//	@Failure		500		{object}	models.ErrorResponse		"Internal server error"
+//	@Param			request			body		models.GenerateRequest		true	"Generate request"
+//	@Param			X-AI-Dry-Run	header		bool						false	"Return the upstream request without calling the model"
+//	@Success		200				{object}	models.GenerateResponse		"Successful text generation"
+//	@Failure		400				{object}	models.ErrorResponse		"Bad request"
+//	@Failure		429				{object}	models.ErrorResponse		"Rate limit exceeded"
+//	@Failure		500				{object}	models.ErrorResponse		"Internal server error"
 //	@Router			/ai/generate [post]
 func (h *AIHandler) HandleGenerate(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
@@ -169,6 +400,18 @@ func (h *AIHandler) HandleGenerate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if req.TemplateID != "" {
+		if req.Prompt != "" {
+			h.sendErrorResponse(w, http.StatusBadRequest, "Set either prompt or template_id, not both")
+			return
+		}
+		prompt, ok := h.renderRequestTemplate(w, req.TemplateID, req.TemplateVersion, req.Variables)
+		if !ok {
+			return
+		}
+		req.Prompt = prompt
+	}
+
 	if req.Prompt == "" {
 		h.sendErrorResponse(w, http.StatusBadRequest, "Prompt cannot be empty")
 		return
@@ -184,9 +427,24 @@ func (h *AIHandler) HandleGenerate(w http.ResponseWriter, r *http.Request) {
 		temperature = 0.7
 	}
 
+	prompt, exampleIDs, ok := h.applyFewShot(w, req.FewShot, req.Prompt, maxTokens)
+	if !ok {
+		return
+	}
+
+	if isDryRun(r, req.DryRun) {
+		notes := templateRoutingNotes(req.TemplateID, req.TemplateVersion)
+		if len(exampleIDs) > 0 {
+			notes = append(notes, fmt.Sprintf("few-shot: %d examples from task %s (%s)", len(exampleIDs), req.FewShot.Task, strings.Join(exampleIDs, ", ")))
+		}
+		h.sendDryRun(w, h.aiService.BuildGenerateRequest(prompt, maxTokens, temperature), prompt, maxTokens, temperature, notes...)
+		return
+	}
+
 	log.Printf("Received generate request from user %s", req.UserID)
 
-	aiResponse, err := h.aiService.GetGenerate(req.Prompt, maxTokens, temperature)
+	started := time.Now()
+	aiResponse, err := h.aiService.GetGenerate(prompt, maxTokens, temperature)
 	if err != nil {
 		log.Printf("Error getting generation: %v", err)
 		h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to get generation")
@@ -194,11 +452,23 @@ func (h *AIHandler) HandleGenerate(w http.ResponseWriter, r *http.Request) {
 	}
 
 	response := models.GenerateResponse{
+		ID:        newResponseID(),
 		Response:  aiResponse,
+		Examples:  exampleIDs,
 		UserID:    req.UserID,
 		Timestamp: time.Now(),
 		Model:     h.aiService.GetModel(),
 	}
+	h.recordResponse(w, started, models.ResponseRecord{
+		ID:          response.ID,
+		Endpoint:    "/ai/generate",
+		UserID:      req.UserID,
+		Model:       response.Model,
+		Prompt:      prompt,
+		Output:      aiResponse,
+		MaxTokens:   maxTokens,
+		Temperature: temperature,
+	}, req, response)
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
@@ -249,6 +519,55 @@ func (h *AIHandler) HandleModelInfo(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(modelInfo)
 }
 
+// templateRoutingNotes describes the prompt template a request used, if any
+func templateRoutingNotes(templateID string, version int) []string {
+	switch {
+	case templateID == "":
+		return nil
+	case version > 0:
+		return []string{fmt.Sprintf("template: %s v%d", templateID, version)}
+	default:
+		return []string{fmt.Sprintf("template: %s (active version)", templateID)}
+	}
+}
+
+// lastUserMessage returns the content of the latest user message
+func lastUserMessage(messages []models.ChatMessage) string {
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == "user" {
+			return messages[i].Content
+		}
+	}
+	return ""
+}
+
+// validateChatMessages checks that tool messages reference a call. Other
+// roles are passed through to the model, except when tools are in use, where
+// only the roles the tool prompts understand are accepted.
+func validateChatMessages(messages []models.ChatMessage, withTools bool) error {
+	for i, msg := range messages {
+		switch msg.Role {
+		case "system", "user", "assistant":
+		case "tool":
+			if msg.ToolCallID == "" {
+				return fmt.Errorf("messages[%d]: tool message requires tool_call_id", i)
+			}
+		default:
+			if withTools {
+				return fmt.Errorf("messages[%d]: invalid role %q; with tools, use system, user, assistant or tool", i, msg.Role)
+			}
+		}
+	}
+	return nil
+}
+
+// sendJSONResponse sends a JSON response with the given status code
+func (h *AIHandler) sendJSONResponse(w http.ResponseWriter, statusCode int, payload interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(payload)
+}
+
 // sendErrorResponse sends a JSON error response
 func (h *AIHandler) sendErrorResponse(w http.ResponseWriter, statusCode int, message string) {
 	response := models.ErrorResponse{
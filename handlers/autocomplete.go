@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/Ammar0144/ai/models"
+	"github.com/Ammar0144/ai/services"
+)
+
+// HandleAutocomplete suggests a short continuation of typed text
+//
+//	@Summary		Typeahead autocomplete
+//	@Description	Suggest a short continuation of the prefix for keystroke-by-keystroke typeahead. A newer request with the same session ID (X-Session-ID header or session_id) cancels the older one, which returns 409. Identical in-flight prefixes share one upstream call (source "coalesced"), and a prefix that extends an earlier prefix by the start of its suggestion is answered from the rest of that suggestion without calling the model (source "cache"). The suggestion stops at the end of the line. The prefix is limited to 4 KB; send the text nearest the cursor. max_tokens defaults to 8 (at most 16), temperature to 0.2, and the upstream call times out after 5 seconds. Rate limited to 300 requests per minute per IP address, separately from the other AI endpoints.
+//	@Tags			AI Processing
+//	@Accept			json
+//	@Produce		json
+//	@Param			request			body		models.AutocompleteRequest	true	"Typed text"
+//	@Param			X-Session-ID	header		string						false	"Typing session; a newer request cancels the older one"
+//	@Success		200				{object}	models.AutocompleteResponse	"Suggested continuation"
+//	@Failure		400				{object}	models.ErrorResponse		"Bad request"
+//	@Failure		409				{object}	models.ErrorResponse		"Superseded by a newer request from the session"
+//	@Failure		429				{object}	models.ErrorResponse		"Rate limit exceeded"
+//	@Failure		500				{object}	models.ErrorResponse		"Internal server error"
+//	@Failure		504				{object}	models.ErrorResponse		"Upstream timed out"
+//	@Router			/ai/autocomplete [post]
+func (h *AIHandler) HandleAutocomplete(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.sendErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var req models.AutocompleteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid JSON format")
+		return
+	}
+	if sessionID := r.Header.Get("X-Session-ID"); sessionID != "" {
+		req.SessionID = sessionID
+	}
+	if err := services.ValidateAutocomplete(&req); err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	started := time.Now()
+	result, err := h.autocomplete.Suggest(r.Context(), &req)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrAutocompleteSuperseded):
+			h.sendErrorResponse(w, http.StatusConflict, "Superseded by a newer request")
+		case errors.Is(err, context.Canceled):
+			// The client went away; there is no one to answer
+		case errors.Is(err, context.DeadlineExceeded):
+			h.sendErrorResponse(w, http.StatusGatewayTimeout, "Autocomplete timed out")
+		default:
+			log.Printf("Error autocompleting for user %s: %v", req.UserID, err)
+			h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to autocomplete")
+		}
+		return
+	}
+
+	response := models.AutocompleteResponse{
+		ID:         newResponseID(),
+		Suggestion: result.Suggestion,
+		Source:     result.Source,
+		SessionID:  req.SessionID,
+		DurationMs: time.Since(started).Milliseconds(),
+		UserID:     req.UserID,
+		Timestamp:  time.Now(),
+		Model:      h.aiService.GetModel(),
+	}
+	h.recordResponse(w, started, models.ResponseRecord{
+		ID:          response.ID,
+		Endpoint:    "/ai/autocomplete",
+		UserID:      req.UserID,
+		Model:       response.Model,
+		Prompt:      req.Prefix,
+		Output:      result.Suggestion,
+		MaxTokens:   req.MaxTokens,
+		Temperature: req.Temperature,
+	}, req, response)
+	h.sendJSONResponse(w, http.StatusOK, response)
+}
@@ -0,0 +1,226 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Ammar0144/ai/models"
+	"github.com/Ammar0144/ai/services"
+)
+
+// HandleChains runs a prompt chain
+//
+//	@Summary		Run a prompt chain
+//	@Description	Run a DAG of complete, generate or chat steps in one request, either a stored chain by name or an inline chain. Step prompts are Go text/templates over {{.input}} and earlier outputs as {{.steps.<id>}}; steps run in parallel where the dependencies allow. Returns every intermediate output with per-step timings and errors. Rate limited to 30 requests per minute per IP address.
+//	@Tags			Chains
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		models.ChainRequest		true	"Chain name or inline chain, with input"
+//	@Success		200		{object}	models.ChainResponse	"Chain result"
+//	@Failure		400		{object}	models.ErrorResponse	"Bad request"
+//	@Failure		404		{object}	models.ErrorResponse	"Chain not found"
+//	@Failure		429		{object}	models.ErrorResponse	"Rate limit exceeded"
+//	@Router			/ai/chains [post]
+func (h *AIHandler) HandleChains(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.sendErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var req models.ChainRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid JSON format")
+		return
+	}
+
+	var chain *models.ChainDefinition
+	switch {
+	case req.Chain != nil && req.Name != "":
+		h.sendErrorResponse(w, http.StatusBadRequest, "Set either name or chain, not both")
+		return
+	case req.Chain != nil:
+		chain = req.Chain
+	case req.Name != "":
+		stored, err := h.chains.Get(req.Name)
+		if err != nil {
+			h.sendChainError(w, err)
+			return
+		}
+		chain = stored
+	default:
+		h.sendErrorResponse(w, http.StatusBadRequest, "A chain name or inline chain is required")
+		return
+	}
+
+	plan, err := services.ValidateChain(chain)
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	log.Printf("Received chain request from user %s with %d steps", req.UserID, len(chain.Steps))
+
+	started := time.Now()
+	ctx, cancel := context.WithTimeout(r.Context(), services.ChainTimeout(req.TimeoutSeconds))
+	defer cancel()
+	steps := h.aiService.RunChain(ctx, plan, req.Input)
+
+	output := steps[plan.OutputStep()]
+	response := models.ChainResponse{
+		ID:         newResponseID(),
+		Chain:      chain.Name,
+		Status:     output.Status,
+		Output:     output.Output,
+		Steps:      steps,
+		DurationMs: time.Since(started).Milliseconds(),
+		UserID:     req.UserID,
+		Timestamp:  time.Now(),
+		Model:      h.aiService.GetModel(),
+	}
+	h.recordResponse(w, started, models.ResponseRecord{
+		ID:       response.ID,
+		Endpoint: "/ai/chains",
+		UserID:   req.UserID,
+		Model:    response.Model,
+		Prompt:   chainInput(req.Input),
+		Output:   output.Output,
+	}, req, response)
+	h.sendJSONResponse(w, http.StatusOK, response)
+}
+
+// chainInput renders chain input variables as the prompt of a response record
+func chainInput(input map[string]interface{}) string {
+	data, err := json.Marshal(input)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// HandleChainDefinitions lists and creates stored chains
+//
+//	@Summary		List or create stored chains
+//	@Description	GET lists stored chain definitions. POST validates and stores a named chain so it can be run with {"name": ...}. Creating requires the X-Admin-Token header. Rate limited to 100 requests per minute per IP address.
+//	@Tags			Chains
+//	@Accept			json
+//	@Produce		json
+//	@Param			X-Admin-Token	header		string					false	"Admin token (required for POST)"
+//	@Param			request			body		models.ChainDefinition	false	"Chain to store (POST)"
+//	@Success		200				{array}		models.ChainDefinition	"Chains"
+//	@Success		201				{object}	models.ChainDefinition	"Created chain"
+//	@Failure		400				{object}	models.ErrorResponse	"Bad request"
+//	@Failure		401				{object}	models.ErrorResponse	"Unauthorized"
+//	@Failure		409				{object}	models.ErrorResponse	"Chain already exists"
+//	@Router			/ai/chains/definitions [get]
+//	@Router			/ai/chains/definitions [post]
+func (h *AIHandler) HandleChainDefinitions(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.sendJSONResponse(w, http.StatusOK, h.chains.List())
+
+	case http.MethodPost:
+		if !h.requireAdmin(w, r) {
+			return
+		}
+		var def models.ChainDefinition
+		if err := json.NewDecoder(r.Body).Decode(&def); err != nil {
+			h.sendErrorResponse(w, http.StatusBadRequest, "Invalid JSON format")
+			return
+		}
+		chain, err := h.chains.Put(def, true)
+		if err != nil {
+			h.sendChainError(w, err)
+			return
+		}
+		log.Printf("Created chain %s", chain.Name)
+		h.sendJSONResponse(w, http.StatusCreated, chain)
+
+	default:
+		h.sendErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// HandleChainDefinition gets, replaces or deletes a stored chain
+//
+//	@Summary		Get, replace or delete a stored chain
+//	@Description	GET returns the chain definition. PUT replaces it. DELETE removes it. Changes require the X-Admin-Token header. Rate limited to 100 requests per minute per IP address.
+//	@Tags			Chains
+//	@Accept			json
+//	@Produce		json
+//	@Param			name			path		string					true	"Chain name"
+//	@Param			X-Admin-Token	header		string					false	"Admin token (required for PUT and DELETE)"
+//	@Param			request			body		models.ChainDefinition	false	"Replacement chain (PUT)"
+//	@Success		200				{object}	models.ChainDefinition	"Chain"
+//	@Success		204				"Chain deleted"
+//	@Failure		400				{object}	models.ErrorResponse	"Bad request"
+//	@Failure		401				{object}	models.ErrorResponse	"Unauthorized"
+//	@Failure		404				{object}	models.ErrorResponse	"Chain not found"
+//	@Router			/ai/chains/definitions/{name} [get]
+//	@Router			/ai/chains/definitions/{name} [put]
+//	@Router			/ai/chains/definitions/{name} [delete]
+func (h *AIHandler) HandleChainDefinition(w http.ResponseWriter, r *http.Request) {
+	name := strings.Trim(strings.TrimPrefix(r.URL.Path, "/ai/chains/definitions/"), "/")
+	if name == "" {
+		h.HandleChainDefinitions(w, r)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		chain, err := h.chains.Get(name)
+		if err != nil {
+			h.sendChainError(w, err)
+			return
+		}
+		h.sendJSONResponse(w, http.StatusOK, chain)
+
+	case http.MethodPut:
+		if !h.requireAdmin(w, r) {
+			return
+		}
+		var def models.ChainDefinition
+		if err := json.NewDecoder(r.Body).Decode(&def); err != nil {
+			h.sendErrorResponse(w, http.StatusBadRequest, "Invalid JSON format")
+			return
+		}
+		def.Name = name
+		chain, err := h.chains.Put(def, false)
+		if err != nil {
+			h.sendChainError(w, err)
+			return
+		}
+		log.Printf("Replaced chain %s", name)
+		h.sendJSONResponse(w, http.StatusOK, chain)
+
+	case http.MethodDelete:
+		if !h.requireAdmin(w, r) {
+			return
+		}
+		if err := h.chains.Delete(name); err != nil {
+			h.sendChainError(w, err)
+			return
+		}
+		log.Printf("Deleted chain %s", name)
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		h.sendErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// sendChainError maps chain store errors to HTTP responses
+func (h *AIHandler) sendChainError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrChainNotFound):
+		h.sendErrorResponse(w, http.StatusNotFound, "Chain not found")
+	case errors.Is(err, services.ErrChainExists):
+		h.sendErrorResponse(w, http.StatusConflict, "Chain already exists")
+	default:
+		h.sendErrorResponse(w, http.StatusBadRequest, err.Error())
+	}
+}
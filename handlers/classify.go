@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Ammar0144/ai/models"
+)
+
+// HandleClassify scores a text against candidate labels
+//
+//	@Summary		Zero-shot classification
+//	@Description	Return a probability for each candidate label, highest first. When AI_UPSTREAM_CAPABILITIES includes "logprobs", labels are scored from echoed token logprobs: single-label scores are a softmax over each label name's mean token logprob, and with multi_label each label is scored by a yes/no question. Otherwise the model is sampled votes times (default 5) with a prompt constrained to the label names, and each label scores the share of votes naming it. With multi_label, every label scoring at least threshold (default 0.5) is selected; otherwise the top label is selected when it reaches threshold (default 0). With dry_run or the X-AI-Dry-Run header, the first upstream request is returned instead of calling the model. Rate limited to 30 requests per minute per IP address.
+//	@Tags			AI Processing
+//	@Accept			json
+//	@Produce		json
+//	@Param			request			body		models.ClassifyRequest	true	"Text and candidate labels"
+//	@Param			X-AI-Dry-Run	header		bool					false	"Return the first upstream request without calling the model"
+//	@Success		200				{object}	models.ClassifyResponse	"Label scores"
+//	@Failure		400				{object}	models.ErrorResponse	"Bad request"
+//	@Failure		429				{object}	models.ErrorResponse	"Rate limit exceeded"
+//	@Failure		500				{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/ai/classify [post]
+func (h *AIHandler) HandleClassify(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.sendErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var req models.ClassifyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid JSON format")
+		return
+	}
+	plan, err := h.aiService.PlanClassify(&req)
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if isDryRun(r, req.DryRun) {
+		calls := req.Votes
+		if plan.Method == models.ClassifyLogprobs {
+			calls = len(req.Labels)
+			if plan.MultiLabel {
+				calls *= 2
+			}
+		}
+		note := fmt.Sprintf("classify: %s over %d labels in %d calls; showing the first call's request", plan.Method, len(req.Labels), calls)
+		upstream, prompt, maxTokens := h.aiService.BuildClassifyRequest(plan)
+		h.sendDryRun(w, upstream, prompt, maxTokens, req.Temperature, note)
+		return
+	}
+
+	log.Printf("Received classify request from user %s with %d labels (%s)", req.UserID, len(req.Labels), plan.Method)
+
+	started := time.Now()
+	result, err := h.aiService.Classify(r.Context(), plan)
+	if err != nil {
+		log.Printf("Error classifying after %d calls: %v", result.Calls, err)
+		h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to classify text")
+		return
+	}
+
+	labels, selected := plan.Select(result)
+	response := models.ClassifyResponse{
+		ID:         newResponseID(),
+		Labels:     labels,
+		Selected:   selected,
+		MultiLabel: plan.MultiLabel,
+		Threshold:  plan.Threshold,
+		Method:     plan.Method,
+		Calls:      result.Calls,
+		Abstained:  result.Abstained,
+		UserID:     req.UserID,
+		Timestamp:  time.Now(),
+		Model:      h.aiService.GetModel(),
+	}
+	h.recordResponse(w, started, models.ResponseRecord{
+		ID:          response.ID,
+		Endpoint:    "/ai/classify",
+		UserID:      req.UserID,
+		Model:       response.Model,
+		Prompt:      req.Text,
+		Output:      strings.Join(selected, ", "),
+		Temperature: req.Temperature,
+	}, req, response)
+	h.sendJSONResponse(w, http.StatusOK, response)
+}
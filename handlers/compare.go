@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Ammar0144/ai/models"
+)
+
+// HandleCompare sends one request to several backends side by side
+//
+//	@Summary		Compare models
+//	@Description	Send the same request to two or more backends in parallel and return each output with its latency, estimated token usage and error, in request order. Backends are the gateway's own upstream ("default") and those configured in AI_COMPARE_BACKENDS as name=url pairs; all of them are used when backends is empty, and at least two are required. mode selects the upstream endpoint: complete (default), generate or chat with messages. Every pair of successful outputs is scored by word Jaccard overlap, ROUGE-L (lcs_ratio) and length ratio, and with diff the pair includes a word diff where [-...-] is only in a and {+...+} only in b. A failing backend does not fail the request. Rate limited to 30 requests per minute per IP address.
+//	@Tags			AI Processing
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		models.CompareRequest	true	"Request to compare"
+//	@Success		200		{object}	models.CompareResponse	"Outputs and pairwise comparisons"
+//	@Failure		400		{object}	models.ErrorResponse	"Bad request"
+//	@Failure		429		{object}	models.ErrorResponse	"Rate limit exceeded"
+//	@Router			/ai/compare [post]
+func (h *AIHandler) HandleCompare(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.sendErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var req models.CompareRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid JSON format")
+		return
+	}
+	if err := h.comparer.ValidateCompare(&req); err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := validateChatMessages(req.Messages, false); err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	log.Printf("Received compare request from user %s across %d backends", req.UserID, len(req.Backends))
+
+	started := time.Now()
+	results, pairs := h.comparer.Compare(r.Context(), &req)
+	response := models.CompareResponse{
+		ID:         newResponseID(),
+		Mode:       req.Mode,
+		Results:    results,
+		Pairs:      pairs,
+		DurationMs: time.Since(started).Milliseconds(),
+		UserID:     req.UserID,
+		Timestamp:  time.Now(),
+	}
+	h.recordResponse(w, started, models.ResponseRecord{
+		ID:          response.ID,
+		Endpoint:    "/ai/compare",
+		UserID:      req.UserID,
+		Model:       compareModels(results),
+		Prompt:      comparePrompt(&req),
+		MaxTokens:   req.MaxTokens,
+		Temperature: req.Temperature,
+		Usage:       compareUsage(results),
+	}, req, response)
+	h.sendJSONResponse(w, http.StatusOK, response)
+}
+
+// comparePrompt is the text a comparison answers
+func comparePrompt(req *models.CompareRequest) string {
+	if req.Mode == models.CompareChat {
+		return lastUserMessage(req.Messages)
+	}
+	return req.Prompt
+}
+
+// compareModels lists the models a comparison called
+func compareModels(results []models.CompareResult) string {
+	names := make([]string, len(results))
+	for i, result := range results {
+		names[i] = result.Model
+	}
+	return strings.Join(names, ",")
+}
+
+// compareUsage adds up the token usage of every backend
+func compareUsage(results []models.CompareResult) *models.TokenUsage {
+	usage := &models.TokenUsage{}
+	for _, result := range results {
+		if result.Usage != nil {
+			usage.PromptTokens += result.Usage.PromptTokens
+			usage.CompletionTokens += result.Usage.CompletionTokens
+			usage.TotalTokens += result.Usage.TotalTokens
+		}
+	}
+	return usage
+}
@@ -0,0 +1,708 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Ammar0144/ai/models"
+	"github.com/Ammar0144/ai/services"
+)
+
+// requestUserID resolves the caller's user ID from the X-User-ID header, the
+// user_id query parameter or the given body value, in that order
+func requestUserID(r *http.Request, bodyUserID string) string {
+	if userID := strings.TrimSpace(r.Header.Get("X-User-ID")); userID != "" {
+		return userID
+	}
+	if userID := strings.TrimSpace(r.URL.Query().Get("user_id")); userID != "" {
+		return userID
+	}
+	return strings.TrimSpace(bodyUserID)
+}
+
+// HandleConversations lists and creates conversations
+//
+//	@Summary		List or create conversations
+//	@Description	GET lists the user's stored conversations, most recent first. POST creates a conversation, optionally seeded with messages. Conversations are scoped by the X-User-ID header or user_id parameter. Rate limited to 100 requests per minute per IP address.
+//	@Tags			Conversations
+//	@Accept			json
+//	@Produce		json
+//	@Param			X-User-ID	header		string								false	"User ID"
+//	@Param			user_id		query		string								false	"User ID"
+//	@Param			request		body		models.CreateConversationRequest	false	"Conversation to create (POST)"
+//	@Success		200			{array}		models.ConversationInfo				"Conversations"
+//	@Success		201			{object}	models.Conversation					"Created conversation"
+//	@Failure		400			{object}	models.ErrorResponse				"Bad request"
+//	@Failure		429			{object}	models.ErrorResponse				"Rate limit exceeded"
+//	@Failure		500			{object}	models.ErrorResponse				"Internal server error"
+//	@Router			/ai/conversations [get]
+//	@Router			/ai/conversations [post]
+func (h *AIHandler) HandleConversations(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		userID := requestUserID(r, "")
+		if userID == "" {
+			h.sendErrorResponse(w, http.StatusBadRequest, "User ID is required (X-User-ID header or user_id parameter)")
+			return
+		}
+
+		conversations, err := h.conversations.List(userID)
+		if err != nil {
+			log.Printf("Error listing conversations: %v", err)
+			h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to list conversations")
+			return
+		}
+
+		infos := make([]models.ConversationInfo, 0, len(conversations))
+		for _, conversation := range conversations {
+			infos = append(infos, conversationInfo(conversation))
+		}
+		h.sendJSONResponse(w, http.StatusOK, infos)
+
+	case http.MethodPost:
+		var req models.CreateConversationRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			h.sendErrorResponse(w, http.StatusBadRequest, "Invalid JSON format")
+			return
+		}
+
+		userID := requestUserID(r, req.UserID)
+		if userID == "" {
+			h.sendErrorResponse(w, http.StatusBadRequest, "User ID is required (X-User-ID header or user_id parameter)")
+			return
+		}
+		if err := validateChatMessages(req.Messages, false); err != nil {
+			h.sendErrorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		conversation := services.NewConversation(userID, req.Title, req.Messages)
+		if err := h.conversations.Create(conversation); err != nil {
+			log.Printf("Error creating conversation: %v", err)
+			h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to create conversation")
+			return
+		}
+
+		log.Printf("Created conversation %s for user %s", conversation.ID, userID)
+		h.sendJSONResponse(w, http.StatusCreated, conversation)
+
+	default:
+		h.sendErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// HandleConversation routes requests for a single conversation and its branches
+func (h *AIHandler) HandleConversation(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/ai/conversations/"), "/"), "/")
+	if parts[0] == "" {
+		h.HandleConversations(w, r)
+		return
+	}
+
+	userID := requestUserID(r, "")
+	if userID == "" {
+		h.sendErrorResponse(w, http.StatusBadRequest, "User ID is required (X-User-ID header or user_id parameter)")
+		return
+	}
+
+	id := parts[0]
+	switch {
+	case len(parts) == 1 && id == "export":
+		h.handleExportConversations(w, r, userID)
+	case len(parts) == 1 && id == "import":
+		h.handleImportConversations(w, r, userID)
+	case len(parts) == 2 && parts[1] == "export":
+		h.handleExportConversation(w, r, userID, id)
+	case len(parts) == 1:
+		h.handleConversationItem(w, r, userID, id)
+	case len(parts) == 2 && parts[1] == "branches":
+		h.handleConversationBranches(w, r, userID, id)
+	case len(parts) == 2 && parts[1] == "active":
+		h.handleSwitchBranch(w, r, userID, id)
+	case len(parts) == 2 && parts[1] == "path":
+		h.handleConversationPath(w, r, userID, id)
+	case len(parts) == 4 && parts[1] == "messages" && parts[3] == "edit":
+		h.handleEditMessage(w, r, userID, id, parts[2])
+	case len(parts) == 4 && parts[1] == "messages" && parts[3] == "regenerate":
+		h.handleRegenerateMessage(w, r, userID, id, parts[2])
+	default:
+		h.sendErrorResponse(w, http.StatusNotFound, "Not found")
+	}
+}
+
+// maxEditBytes bounds the body of a message edit, which is read before rate
+// limiting to choose the bucket
+const maxEditBytes = 1 << 20
+
+// ConversationGenerates reports whether a conversation request calls the
+// model: a regenerate, or an edit with regenerate set. The body of an edit is
+// read, up to maxEditBytes, and restored for the handler.
+func ConversationGenerates(w http.ResponseWriter, r *http.Request) bool {
+	if r.Method != http.MethodPost {
+		return false
+	}
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/ai/conversations/"), "/"), "/")
+	if len(parts) != 4 || parts[1] != "messages" {
+		return false
+	}
+	switch parts[3] {
+	case "regenerate":
+		return true
+	case "edit":
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxEditBytes))
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		if err != nil {
+			// The handler rejects the truncated body; count it as generating
+			// so oversized edits get the stricter limit
+			return true
+		}
+		var req models.EditMessageRequest
+		return json.Unmarshal(body, &req) == nil && req.Regenerate
+	}
+	return false
+}
+
+// handleConversationItem gets or deletes a single conversation
+//
+//	@Summary		Get or delete a conversation
+//	@Description	GET returns the conversation with its message tree and the active branch as a linear message list. DELETE removes it. Rate limited to 100 requests per minute per IP address.
+//	@Tags			Conversations
+//	@Produce		json
+//	@Param			id			path		string					true	"Conversation ID"
+//	@Param			X-User-ID	header		string					false	"User ID"
+//	@Param			user_id		query		string					false	"User ID"
+//	@Success		200			{object}	models.Conversation		"Conversation"
+//	@Success		204			"Conversation deleted"
+//	@Failure		400			{object}	models.ErrorResponse	"Bad request"
+//	@Failure		404			{object}	models.ErrorResponse	"Conversation not found"
+//	@Failure		429			{object}	models.ErrorResponse	"Rate limit exceeded"
+//	@Failure		500			{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/ai/conversations/{id} [get]
+//	@Router			/ai/conversations/{id} [delete]
+func (h *AIHandler) handleConversationItem(w http.ResponseWriter, r *http.Request, userID, id string) {
+	switch r.Method {
+	case http.MethodGet:
+		conversation, err := h.conversations.Get(userID, id)
+		if err != nil {
+			h.sendConversationError(w, err)
+			return
+		}
+		h.sendJSONResponse(w, http.StatusOK, conversation)
+
+	case http.MethodDelete:
+		if err := h.conversations.Delete(userID, id); err != nil {
+			h.sendConversationError(w, err)
+			return
+		}
+		log.Printf("Deleted conversation %s for user %s", id, userID)
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		h.sendErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// handleConversationBranches lists the leaves of the conversation tree
+//
+//	@Summary		List conversation branches
+//	@Description	List every branch (leaf) of the conversation tree, marking the active one and where each diverges from it. Rate limited to 100 requests per minute per IP address.
+//	@Tags			Conversations
+//	@Produce		json
+//	@Param			id			path		string						true	"Conversation ID"
+//	@Param			X-User-ID	header		string						false	"User ID"
+//	@Success		200			{array}		models.ConversationBranch	"Branches"
+//	@Failure		404			{object}	models.ErrorResponse		"Conversation not found"
+//	@Router			/ai/conversations/{id}/branches [get]
+func (h *AIHandler) handleConversationBranches(w http.ResponseWriter, r *http.Request, userID, id string) {
+	if r.Method != http.MethodGet {
+		h.sendErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	conversation, err := h.conversations.Get(userID, id)
+	if err != nil {
+		h.sendConversationError(w, err)
+		return
+	}
+
+	branches := services.Branches(conversation)
+	if branches == nil {
+		branches = []models.ConversationBranch{}
+	}
+	h.sendJSONResponse(w, http.StatusOK, branches)
+}
+
+// handleSwitchBranch changes the active branch
+//
+//	@Summary		Switch active branch
+//	@Description	Make the branch containing node_id active. If node_id is not a leaf, its most recent descendants are followed. Subsequent chat calls with conversation_id continue from this branch. Rate limited to 100 requests per minute per IP address.
+//	@Tags			Conversations
+//	@Accept			json
+//	@Produce		json
+//	@Param			id			path		string						true	"Conversation ID"
+//	@Param			X-User-ID	header		string						false	"User ID"
+//	@Param			request		body		models.SwitchBranchRequest	true	"Node to activate"
+//	@Success		200			{object}	models.ConversationPath		"New active path"
+//	@Failure		400			{object}	models.ErrorResponse		"Bad request"
+//	@Failure		404			{object}	models.ErrorResponse		"Conversation or message not found"
+//	@Router			/ai/conversations/{id}/active [post]
+func (h *AIHandler) handleSwitchBranch(w http.ResponseWriter, r *http.Request, userID, id string) {
+	if r.Method != http.MethodPost && r.Method != http.MethodPut {
+		h.sendErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var req models.SwitchBranchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.NodeID == "" {
+		h.sendErrorResponse(w, http.StatusBadRequest, "node_id is required")
+		return
+	}
+
+	conversation, err := h.conversations.Update(userID, id, func(conversation *models.Conversation) error {
+		return services.SwitchBranch(conversation, req.NodeID)
+	})
+	if err != nil {
+		h.sendConversationError(w, err)
+		return
+	}
+
+	h.sendConversationPath(w, conversation, "")
+}
+
+// handleConversationPath returns the linear message list for a branch
+//
+//	@Summary		Get a conversation path
+//	@Description	Return the messages from the root to node_id (the active leaf when omitted) as a linear list, ready to send to /ai/chat/completions. Rate limited to 100 requests per minute per IP address.
+//	@Tags			Conversations
+//	@Produce		json
+//	@Param			id			path		string					true	"Conversation ID"
+//	@Param			node_id		query		string					false	"Node ID (defaults to the active leaf)"
+//	@Param			X-User-ID	header		string					false	"User ID"
+//	@Success		200			{object}	models.ConversationPath	"Path"
+//	@Failure		404			{object}	models.ErrorResponse	"Conversation or message not found"
+//	@Router			/ai/conversations/{id}/path [get]
+func (h *AIHandler) handleConversationPath(w http.ResponseWriter, r *http.Request, userID, id string) {
+	if r.Method != http.MethodGet {
+		h.sendErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	conversation, err := h.conversations.Get(userID, id)
+	if err != nil {
+		h.sendConversationError(w, err)
+		return
+	}
+
+	h.sendConversationPath(w, conversation, r.URL.Query().Get("node_id"))
+}
+
+// handleEditMessage edits a message by adding a sibling branch
+//
+//	@Summary		Edit a message
+//	@Description	Create an edited copy of a message as a sibling branch instead of overwriting it, and make it active. With regenerate, a new assistant reply is generated for the edited branch and recorded like other AI responses; its id is returned as response_id and in the X-Response-ID header for feedback. Rate limited to 100 requests per minute per IP address, or 30 with regenerate.
+//	@Tags			Conversations
+//	@Accept			json
+//	@Produce		json
+//	@Param			id			path		string						true	"Conversation ID"
+//	@Param			node_id		path		string						true	"Message node ID"
+//	@Param			X-User-ID	header		string						false	"User ID"
+//	@Param			request		body		models.EditMessageRequest	true	"Edit request"
+//	@Success		200			{object}	models.ConversationPath		"New active path"
+//	@Failure		400			{object}	models.ErrorResponse		"Bad request"
+//	@Failure		404			{object}	models.ErrorResponse		"Conversation or message not found"
+//	@Failure		500			{object}	models.ErrorResponse		"Internal server error"
+//	@Router			/ai/conversations/{id}/messages/{node_id}/edit [post]
+func (h *AIHandler) handleEditMessage(w http.ResponseWriter, r *http.Request, userID, id, nodeID string) {
+	if r.Method != http.MethodPost {
+		h.sendErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var req models.EditMessageRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid JSON format")
+		return
+	}
+	if req.Content == "" {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Content cannot be empty")
+		return
+	}
+
+	conversation, err := h.conversations.Get(userID, id)
+	if err != nil {
+		h.sendConversationError(w, err)
+		return
+	}
+	node, err := services.FindNode(conversation, nodeID)
+	if err != nil {
+		h.sendConversationError(w, err)
+		return
+	}
+
+	edited := models.ChatMessage{Role: node.Message.Role, Name: node.Message.Name, Content: req.Content}
+	parentID := node.ParentID
+
+	started := time.Now()
+	var reply *models.ChatMessage
+	var replyRequest models.ChatCompletionRequest
+	if req.Regenerate {
+		history, err := services.PathTo(conversation, parentID)
+		if err != nil {
+			h.sendConversationError(w, err)
+			return
+		}
+		messages := make([]models.ChatMessage, 0, len(history)+1)
+		for _, n := range history {
+			messages = append(messages, n.Message)
+		}
+		messages = append(messages, edited)
+
+		generated, request, err := h.generateReply(r.Context(), userID, messages, req.MaxTokens, req.Temperature)
+		if err != nil {
+			log.Printf("Error regenerating reply for edited message: %v", err)
+			h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to get chat completion")
+			return
+		}
+		reply, replyRequest = &generated, request
+	}
+
+	conversation, err = h.conversations.Update(userID, id, func(conversation *models.Conversation) error {
+		services.AddSibling(conversation, parentID, edited)
+		if reply != nil {
+			services.AppendMessages(conversation, *reply)
+		}
+		return nil
+	})
+	if err != nil {
+		h.sendConversationError(w, err)
+		return
+	}
+
+	log.Printf("Edited message %s in conversation %s", nodeID, id)
+	if reply != nil {
+		h.sendGeneratedPath(w, started, "/ai/conversations/{id}/messages/{node_id}/edit", conversation, replyRequest, *reply)
+		return
+	}
+	h.sendConversationPath(w, conversation, "")
+}
+
+// handleRegenerateMessage regenerates an assistant reply as a sibling branch
+//
+//	@Summary		Regenerate a reply
+//	@Description	Generate a new assistant reply for the same history as an existing one. The new reply becomes a sibling branch and is made active. It is recorded like other AI responses, and its id is returned as response_id and in the X-Response-ID header for feedback. Rate limited to 30 requests per minute per IP address.
+//	@Tags			Conversations
+//	@Accept			json
+//	@Produce		json
+//	@Param			id			path		string						true	"Conversation ID"
+//	@Param			node_id		path		string						true	"Assistant message node ID"
+//	@Param			X-User-ID	header		string						false	"User ID"
+//	@Param			request		body		models.RegenerateRequest	false	"Generation options"
+//	@Success		200			{object}	models.ConversationPath		"New active path"
+//	@Failure		400			{object}	models.ErrorResponse		"Bad request"
+//	@Failure		404			{object}	models.ErrorResponse		"Conversation or message not found"
+//	@Failure		500			{object}	models.ErrorResponse		"Internal server error"
+//	@Router			/ai/conversations/{id}/messages/{node_id}/regenerate [post]
+func (h *AIHandler) handleRegenerateMessage(w http.ResponseWriter, r *http.Request, userID, id, nodeID string) {
+	if r.Method != http.MethodPost {
+		h.sendErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var req models.RegenerateRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			h.sendErrorResponse(w, http.StatusBadRequest, "Invalid JSON format")
+			return
+		}
+	}
+
+	conversation, err := h.conversations.Get(userID, id)
+	if err != nil {
+		h.sendConversationError(w, err)
+		return
+	}
+	node, err := services.FindNode(conversation, nodeID)
+	if err != nil {
+		h.sendConversationError(w, err)
+		return
+	}
+	if node.Message.Role != "assistant" {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Only assistant messages can be regenerated")
+		return
+	}
+
+	history, err := services.PathTo(conversation, node.ParentID)
+	if err != nil {
+		h.sendConversationError(w, err)
+		return
+	}
+	if len(history) == 0 {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Message has no preceding history to regenerate from")
+		return
+	}
+	messages := make([]models.ChatMessage, 0, len(history))
+	for _, n := range history {
+		messages = append(messages, n.Message)
+	}
+
+	started := time.Now()
+	reply, request, err := h.generateReply(r.Context(), userID, messages, req.MaxTokens, req.Temperature)
+	if err != nil {
+		log.Printf("Error regenerating message %s: %v", nodeID, err)
+		h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to get chat completion")
+		return
+	}
+
+	conversation, err = h.conversations.Update(userID, id, func(conversation *models.Conversation) error {
+		services.AddSibling(conversation, node.ParentID, reply)
+		return nil
+	})
+	if err != nil {
+		h.sendConversationError(w, err)
+		return
+	}
+
+	log.Printf("Regenerated message %s in conversation %s", nodeID, id)
+	h.sendGeneratedPath(w, started, "/ai/conversations/{id}/messages/{node_id}/regenerate", conversation, request, reply)
+}
+
+// generateReply produces an assistant message for the given history and
+// returns the request it was generated from, with defaults applied
+func (h *AIHandler) generateReply(ctx context.Context, userID string, messages []models.ChatMessage, maxTokens int, temperature float64) (models.ChatMessage, models.ChatCompletionRequest, error) {
+	if maxTokens == 0 {
+		maxTokens = 150
+	}
+	if temperature == 0 {
+		temperature = 0.7
+	}
+	request := models.ChatCompletionRequest{Messages: messages, MaxTokens: maxTokens, Temperature: temperature, UserID: userID}
+
+	result, err := h.aiService.GetChatCompletionWithToolsContext(ctx, messages, nil, services.ToolChoice{Mode: services.ToolChoiceNone}, maxTokens, temperature)
+	if err != nil {
+		return models.ChatMessage{}, request, err
+	}
+	return models.ChatMessage{Role: "assistant", Content: result.Content}, request, nil
+}
+
+// sendGeneratedPath writes the active path after a reply was generated,
+// recording the reply like other AI responses so it can be looked up and rated
+func (h *AIHandler) sendGeneratedPath(w http.ResponseWriter, started time.Time, endpoint string, conversation *models.Conversation, request models.ChatCompletionRequest, reply models.ChatMessage) {
+	path, err := services.GetPath(conversation, "")
+	if err != nil {
+		h.sendConversationError(w, err)
+		return
+	}
+	path.ResponseID = newResponseID()
+	h.recordResponse(w, started, models.ResponseRecord{
+		ID:          path.ResponseID,
+		Endpoint:    endpoint,
+		UserID:      request.UserID,
+		Model:       h.aiService.GetModel(),
+		Prompt:      lastUserMessage(request.Messages),
+		Output:      reply.Content,
+		MaxTokens:   request.MaxTokens,
+		Temperature: request.Temperature,
+		Usage:       chatUsage(request.Messages, reply.Content),
+	}, request, path)
+	h.sendJSONResponse(w, http.StatusOK, path)
+}
+
+// sendConversationPath writes the path to nodeID (the active leaf when empty)
+func (h *AIHandler) sendConversationPath(w http.ResponseWriter, conversation *models.Conversation, nodeID string) {
+	path, err := services.GetPath(conversation, nodeID)
+	if err != nil {
+		h.sendConversationError(w, err)
+		return
+	}
+	h.sendJSONResponse(w, http.StatusOK, path)
+}
+
+// maxImportBytes bounds the size of conversation import uploads
+const maxImportBytes = 32 << 20
+
+// handleExportConversation exports a single conversation
+//
+//	@Summary		Export a conversation
+//	@Description	Export the active branch of a conversation as OpenAI fine-tuning JSONL (openai), ShareGPT JSON (sharegpt), a Markdown transcript (markdown) or CSV (csv). Rate limited to 100 requests per minute per IP address.
+//	@Tags			Conversations
+//	@Produce		json
+//	@Produce		plain
+//	@Param			id			path		string					true	"Conversation ID"
+//	@Param			format		query		string					false	"Export format: openai, sharegpt, markdown or csv (default openai)"
+//	@Param			X-User-ID	header		string					false	"User ID"
+//	@Success		200			{string}	string					"Exported conversation"
+//	@Failure		400			{object}	models.ErrorResponse	"Bad request"
+//	@Failure		404			{object}	models.ErrorResponse	"Conversation not found"
+//	@Router			/ai/conversations/{id}/export [get]
+func (h *AIHandler) handleExportConversation(w http.ResponseWriter, r *http.Request, userID, id string) {
+	if r.Method != http.MethodGet {
+		h.sendErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	format := exportFormat(r)
+	contentType, extension, err := services.ExportFormatInfo(format)
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	conversation, err := h.conversations.Get(userID, id)
+	if err != nil {
+		h.sendConversationError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, id, extension))
+	exporter, _ := services.NewConversationExporter(w, format)
+	if err := exporter.Write(conversation); err != nil {
+		log.Printf("Error exporting conversation %s: %v", id, err)
+		return
+	}
+	exporter.Close()
+}
+
+// handleExportConversations streams all of the user's conversations
+//
+//	@Summary		Export all conversations
+//	@Description	Stream every conversation of the user in the chosen format. Output is flushed per conversation so large exports start immediately. Rate limited to 100 requests per minute per IP address.
+//	@Tags			Conversations
+//	@Produce		json
+//	@Produce		plain
+//	@Param			format		query		string					false	"Export format: openai, sharegpt, markdown or csv (default openai)"
+//	@Param			X-User-ID	header		string					false	"User ID"
+//	@Success		200			{string}	string					"Exported conversations"
+//	@Failure		400			{object}	models.ErrorResponse	"Bad request"
+//	@Failure		500			{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/ai/conversations/export [get]
+func (h *AIHandler) handleExportConversations(w http.ResponseWriter, r *http.Request, userID string) {
+	if r.Method != http.MethodGet {
+		h.sendErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	format := exportFormat(r)
+	contentType, extension, err := services.ExportFormatInfo(format)
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	conversations, err := h.conversations.List(userID)
+	if err != nil {
+		log.Printf("Error listing conversations for export: %v", err)
+		h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to list conversations")
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="conversations.%s"`, extension))
+	flusher, _ := w.(http.Flusher)
+
+	exporter, _ := services.NewConversationExporter(w, format)
+	for _, conversation := range conversations {
+		if err := exporter.Write(conversation); err != nil {
+			log.Printf("Error exporting conversations for user %s: %v", userID, err)
+			return
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+	exporter.Close()
+
+	log.Printf("Exported %d conversations for user %s as %s", len(conversations), userID, format)
+}
+
+// handleImportConversations imports conversations for the user
+//
+//	@Summary		Import conversations
+//	@Description	Import conversations from OpenAI fine-tuning JSONL, ShareGPT JSON, Markdown transcripts or CSV (the request body is the file). Each imported conversation becomes a new stored conversation. Rate limited to 100 requests per minute per IP address.
+//	@Tags			Conversations
+//	@Accept			plain
+//	@Produce		json
+//	@Param			format		query		string					false	"Import format: openai, sharegpt, markdown or csv (default openai)"
+//	@Param			X-User-ID	header		string					false	"User ID"
+//	@Param			file		body		string					true	"File contents"
+//	@Success		201			{array}		models.ConversationInfo	"Imported conversations"
+//	@Failure		400			{object}	models.ErrorResponse	"Bad request"
+//	@Failure		500			{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/ai/conversations/import [post]
+func (h *AIHandler) handleImportConversations(w http.ResponseWriter, r *http.Request, userID string) {
+	if r.Method != http.MethodPost {
+		h.sendErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	imported, err := services.ImportConversations(http.MaxBytesReader(w, r.Body, maxImportBytes), exportFormat(r))
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Import failed: "+err.Error())
+		return
+	}
+	for i, conversation := range imported {
+		if err := validateChatMessages(conversation.Messages, false); err != nil {
+			h.sendErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("Import failed: conversation %d: %v", i+1, err))
+			return
+		}
+	}
+
+	infos := make([]models.ConversationInfo, 0, len(imported))
+	for _, item := range imported {
+		conversation := services.NewConversation(userID, item.Title, item.Messages)
+		if err := h.conversations.Create(conversation); err != nil {
+			log.Printf("Error storing imported conversation: %v", err)
+			h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to store imported conversations")
+			return
+		}
+		infos = append(infos, conversationInfo(conversation))
+	}
+
+	log.Printf("Imported %d conversations for user %s", len(infos), userID)
+	h.sendJSONResponse(w, http.StatusCreated, infos)
+}
+
+// conversationInfo returns the listing view of a conversation
+func conversationInfo(conversation *models.Conversation) models.ConversationInfo {
+	return models.ConversationInfo{
+		ID:           conversation.ID,
+		Title:        conversation.Title,
+		MessageCount: len(conversation.Messages),
+		CreatedAt:    conversation.CreatedAt,
+		UpdatedAt:    conversation.UpdatedAt,
+	}
+}
+
+// exportFormat reads the format query parameter, defaulting to OpenAI JSONL
+func exportFormat(r *http.Request) string {
+	format := strings.ToLower(r.URL.Query().Get("format"))
+	if format == "" || format == "jsonl" {
+		return services.FormatOpenAIJSONL
+	}
+	if format == "md" {
+		return services.FormatMarkdown
+	}
+	return format
+}
+
+// sendConversationError maps conversation store errors to HTTP responses
+func (h *AIHandler) sendConversationError(w http.ResponseWriter, err error) {
+	if errors.Is(err, services.ErrConversationNotFound) {
+		h.sendErrorResponse(w, http.StatusNotFound, "Conversation not found")
+		return
+	}
+	if errors.Is(err, services.ErrMessageNotFound) {
+		h.sendErrorResponse(w, http.StatusNotFound, "Message not found")
+		return
+	}
+	log.Printf("Conversation store error: %v", err)
+	h.sendErrorResponse(w, http.StatusInternalServerError, "Conversation storage failed")
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Ammar0144/ai/models"
+	"github.com/Ammar0144/ai/services"
+)
+
+// isDryRun reports whether a request asked for a dry run, through the
+// dry_run body flag or the X-AI-Dry-Run header; any header value other than
+// a false boolean enables it
+func isDryRun(r *http.Request, flag bool) bool {
+	if flag {
+		return true
+	}
+	header := strings.TrimSpace(r.Header.Get("X-AI-Dry-Run"))
+	if header == "" {
+		return false
+	}
+	enabled, err := strconv.ParseBool(header)
+	return err != nil || enabled
+}
+
+// sendDryRun writes the upstream request a handler would send, with token
+// estimates and the routing decisions that led to it. Handler-level notes
+// come before the service's own routing.
+func (h *AIHandler) sendDryRun(w http.ResponseWriter, upstream services.UpstreamRequest, prompt string, maxTokens int, temperature float64, notes ...string) {
+	promptTokens := services.EstimateTokens(prompt)
+	if upstream.Messages != nil {
+		promptTokens = services.EstimateMessagesTokens(upstream.Messages)
+		prompt = ""
+	}
+
+	routing := append(append([]string{}, notes...), upstream.Routing...)
+	h.sendJSONResponse(w, http.StatusOK, models.DryRunResponse{
+		DryRun:        true,
+		Method:        upstream.Method,
+		URL:           upstream.URL,
+		Backend:       strings.TrimSuffix(upstream.URL, upstream.Endpoint),
+		Endpoint:      upstream.Endpoint,
+		Model:         h.aiService.GetModel(),
+		Body:          upstream.Body,
+		Prompt:        prompt,
+		Messages:      upstream.Messages,
+		MaxTokens:     maxTokens,
+		Temperature:   temperature,
+		PromptTokens:  promptTokens,
+		ContextTokens: services.ModelContextTokens,
+		FitsContext:   promptTokens+maxTokens <= services.ModelContextTokens,
+		Routing:       routing,
+		Timestamp:     time.Now(),
+	})
+}
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/Ammar0144/ai/models"
+	"github.com/Ammar0144/ai/services"
+)
+
+// HandleEmbeddings returns vectors for one or more texts
+//
+//	@Summary		Embeddings
+//	@Description	Return an embedding vector for each input string. Requests are proxied to the upstream embeddings API when AI_UPSTREAM_CAPABILITIES includes "embeddings"; otherwise the built-in deterministic hashing-trick embedder (model local-hashing-v1, 256 dimensions) is used. Vectors are cached by input hash. Rate limited to 30 requests per minute per IP address.
+//	@Tags			AI Processing
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		models.EmbeddingRequest		true	"Texts to embed"
+//	@Success		200		{object}	models.EmbeddingResponse	"Vectors in input order"
+//	@Failure		400		{object}	models.ErrorResponse		"Bad request"
+//	@Failure		429		{object}	models.ErrorResponse		"Rate limit exceeded"
+//	@Failure		500		{object}	models.ErrorResponse		"Internal server error"
+//	@Router			/ai/embeddings [post]
+func (h *AIHandler) HandleEmbeddings(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.sendErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var req models.EmbeddingRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid JSON format")
+		return
+	}
+
+	var inputs []string
+	switch input := req.Input.(type) {
+	case string:
+		inputs = []string{input}
+	case []interface{}:
+		for _, item := range input {
+			text, ok := item.(string)
+			if !ok {
+				h.sendErrorResponse(w, http.StatusBadRequest, "Input must be a string or an array of strings")
+				return
+			}
+			inputs = append(inputs, text)
+		}
+	default:
+		h.sendErrorResponse(w, http.StatusBadRequest, "Input must be a string or an array of strings")
+		return
+	}
+
+	log.Printf("Received embeddings request from user %s with %d inputs", req.UserID, len(inputs))
+
+	result, err := h.aiService.Embed(r.Context(), inputs, req.Model)
+	if err != nil {
+		h.sendEmbeddingError(w, err)
+		return
+	}
+
+	response := models.EmbeddingResponse{
+		Object:  "list",
+		Data:    make([]models.Embedding, len(result.Vectors)),
+		Model:   result.Model,
+		Backend: result.Backend,
+		Usage: models.EmbeddingUsage{
+			PromptTokens: result.Tokens,
+			TotalTokens:  result.Tokens,
+			CachedInputs: result.CachedInputs,
+		},
+		UserID:    req.UserID,
+		Timestamp: time.Now(),
+	}
+	for i, vector := range result.Vectors {
+		response.Data[i] = models.Embedding{Object: "embedding", Index: i, Embedding: vector}
+	}
+	if len(result.Vectors) > 0 {
+		response.Dimensions = len(result.Vectors[0])
+	}
+	h.sendJSONResponse(w, http.StatusOK, response)
+}
+
+// sendEmbeddingError maps embedding errors to HTTP responses
+func (h *AIHandler) sendEmbeddingError(w http.ResponseWriter, err error) {
+	if errors.Is(err, services.ErrInvalidEmbeddingInput) {
+		h.sendErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	log.Printf("Error getting embeddings: %v", err)
+	h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to get embeddings")
+}
@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/Ammar0144/ai/models"
+	"github.com/Ammar0144/ai/services"
+)
+
+// maxExampleBytes bounds the body of a request adding examples
+const maxExampleBytes = 8 << 20
+
+// HandleExamples lists and adds few-shot examples
+//
+//	@Summary		List or add few-shot examples
+//	@Description	GET lists stored input/output examples, optionally filtered by ?task=. POST adds one example or an array of examples. Requests to /ai/complete and /ai/generate with few_shot {"task","k"} prepend the k examples most similar to the prompt. Adding requires the X-Admin-Token header. Rate limited to 100 requests per minute per IP address.
+//	@Tags			Examples
+//	@Accept			json
+//	@Produce		json
+//	@Param			task			query		string					false	"Task to filter by"
+//	@Param			X-Admin-Token	header		string					false	"Admin token (required for POST)"
+//	@Param			request			body		models.ExampleRequest	false	"Example or array of examples to add (POST)"
+//	@Success		200				{array}		models.Example			"Examples"
+//	@Success		201				{array}		models.Example			"Added examples"
+//	@Failure		400				{object}	models.ErrorResponse	"Bad request"
+//	@Failure		401				{object}	models.ErrorResponse	"Unauthorized"
+//	@Failure		413				{object}	models.ErrorResponse	"Request body too large"
+//	@Failure		500				{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/ai/examples [get]
+//	@Router			/ai/examples [post]
+func (h *AIHandler) HandleExamples(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.sendJSONResponse(w, http.StatusOK, h.examples.List(r.URL.Query().Get("task")))
+
+	case http.MethodPost:
+		if !h.requireAdmin(w, r) {
+			return
+		}
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxExampleBytes))
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				h.sendErrorResponse(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("Request body exceeds %d MB", maxExampleBytes>>20))
+				return
+			}
+			h.sendErrorResponse(w, http.StatusBadRequest, "Failed to read request body")
+			return
+		}
+
+		var reqs []models.ExampleRequest
+		if trimmed := bytes.TrimSpace(body); len(trimmed) > 0 && trimmed[0] == '[' {
+			err = json.Unmarshal(trimmed, &reqs)
+		} else {
+			var req models.ExampleRequest
+			err = json.Unmarshal(trimmed, &req)
+			reqs = append(reqs, req)
+		}
+		if err != nil {
+			h.sendErrorResponse(w, http.StatusBadRequest, "Invalid JSON format")
+			return
+		}
+		if len(reqs) == 0 {
+			h.sendErrorResponse(w, http.StatusBadRequest, "At least one example is required")
+			return
+		}
+
+		added, err := h.examples.Add(reqs...)
+		if errors.Is(err, services.ErrInvalidExample) {
+			h.sendErrorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if err != nil {
+			log.Printf("Error saving %d few-shot examples: %v", len(reqs), err)
+			h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to save examples")
+			return
+		}
+		log.Printf("Added %d few-shot examples", len(added))
+		h.sendJSONResponse(w, http.StatusCreated, added)
+
+	default:
+		h.sendErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// HandleExample gets or deletes a single few-shot example
+//
+//	@Summary		Get or delete a few-shot example
+//	@Description	GET returns the example. DELETE removes it and requires the X-Admin-Token header. Rate limited to 100 requests per minute per IP address.
+//	@Tags			Examples
+//	@Produce		json
+//	@Param			id				path		string					true	"Example ID"
+//	@Param			X-Admin-Token	header		string					false	"Admin token (required for DELETE)"
+//	@Success		200				{object}	models.Example			"Example"
+//	@Success		204				"Example deleted"
+//	@Failure		401				{object}	models.ErrorResponse	"Unauthorized"
+//	@Failure		404				{object}	models.ErrorResponse	"Example not found"
+//	@Router			/ai/examples/{id} [get]
+//	@Router			/ai/examples/{id} [delete]
+func (h *AIHandler) HandleExample(w http.ResponseWriter, r *http.Request) {
+	id := strings.Trim(strings.TrimPrefix(r.URL.Path, "/ai/examples/"), "/")
+	if id == "" {
+		h.HandleExamples(w, r)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		example, err := h.examples.Get(id)
+		if err != nil {
+			h.sendErrorResponse(w, http.StatusNotFound, "Example not found")
+			return
+		}
+		h.sendJSONResponse(w, http.StatusOK, example)
+
+	case http.MethodDelete:
+		if !h.requireAdmin(w, r) {
+			return
+		}
+		if err := h.examples.Delete(id); err != nil {
+			if errors.Is(err, services.ErrExampleNotFound) {
+				h.sendErrorResponse(w, http.StatusNotFound, "Example not found")
+				return
+			}
+			log.Printf("Error deleting example %s: %v", id, err)
+			h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to delete example")
+			return
+		}
+		log.Printf("Deleted few-shot example %s", id)
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		h.sendErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// applyFewShot prepends the requested examples to a prompt and writes an
+// error response when that fails
+func (h *AIHandler) applyFewShot(w http.ResponseWriter, opts *models.FewShotOptions, prompt string, maxTokens int) (string, []string, bool) {
+	if opts == nil {
+		return prompt, nil, true
+	}
+	if strings.TrimSpace(opts.Task) == "" {
+		h.sendErrorResponse(w, http.StatusBadRequest, "few_shot.task is required")
+		return "", nil, false
+	}
+
+	fewShotPrompt, ids, err := h.examples.FewShotPrompt(*opts, prompt, maxTokens)
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, err.Error())
+		return "", nil, false
+	}
+	return fewShotPrompt, ids, true
+}
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/Ammar0144/ai/models"
+	"github.com/Ammar0144/ai/services"
+)
+
+// HandleExtract extracts typed fields from a text
+//
+//	@Summary		Field extraction
+//	@Description	Extract the requested fields from a text as a typed JSON object. Each field has a name, a type (string, number, integer, boolean, date or enum with options), an optional description and a required flag. The model is asked for a JSON object through the completion path; values are then coerced to their types (numbers with currency symbols and either decimal convention, dates in common written and numeric formats normalized to YYYY-MM-DD, enums matched to their options) and aligned with the text. Each field carries its source span and a confidence: 1 when found verbatim, 0.9 when found by its typed value, scaled by word overlap for approximate string matches, and 0.3 when the text does not contain it. missing lists required fields that were not found, and valid reports whether data satisfies the specification. With dry_run or the X-AI-Dry-Run header, the upstream request is returned instead of calling the model. Rate limited to 30 requests per minute per IP address.
+//	@Tags			AI Processing
+//	@Accept			json
+//	@Produce		json
+//	@Param			request			body		models.ExtractRequest	true	"Text and field specification"
+//	@Param			X-AI-Dry-Run	header		bool					false	"Return the upstream request without calling the model"
+//	@Success		200				{object}	models.ExtractResponse	"Extracted fields"
+//	@Failure		400				{object}	models.ErrorResponse	"Bad request"
+//	@Failure		429				{object}	models.ErrorResponse	"Rate limit exceeded"
+//	@Failure		500				{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/ai/extract [post]
+func (h *AIHandler) HandleExtract(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.sendErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var req models.ExtractRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid JSON format")
+		return
+	}
+	plan, err := services.PlanExtract(&req)
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if isDryRun(r, req.DryRun) {
+		note := fmt.Sprintf("extract: %d fields; the prompt starts the JSON object and the answer is coerced and aligned afterwards", len(req.Fields))
+		h.sendDryRun(w, h.aiService.BuildCompleteRequest(plan.Prompt, plan.MaxTokens, plan.Temperature), plan.Prompt, plan.MaxTokens, plan.Temperature, note)
+		return
+	}
+
+	log.Printf("Received extract request from user %s with %d fields", req.UserID, len(req.Fields))
+
+	started := time.Now()
+	output, err := h.aiService.GetCompleteContext(r.Context(), plan.Prompt, plan.MaxTokens, plan.Temperature)
+	if err != nil {
+		log.Printf("Error extracting fields: %v", err)
+		h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to extract fields")
+		return
+	}
+
+	result := plan.Parse(output)
+	response := models.ExtractResponse{
+		ID:        newResponseID(),
+		Data:      result.Data,
+		Fields:    result.Fields,
+		Missing:   result.Missing,
+		Valid:     result.Valid,
+		Raw:       result.Raw,
+		UserID:    req.UserID,
+		Timestamp: time.Now(),
+		Model:     h.aiService.GetModel(),
+	}
+	h.recordResponse(w, started, models.ResponseRecord{
+		ID:          response.ID,
+		Endpoint:    "/ai/extract",
+		UserID:      req.UserID,
+		Model:       response.Model,
+		Prompt:      req.Text,
+		Output:      output,
+		MaxTokens:   plan.MaxTokens,
+		Temperature: plan.Temperature,
+	}, req, response)
+	h.sendJSONResponse(w, http.StatusOK, response)
+}
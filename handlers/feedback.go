@@ -0,0 +1,215 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Ammar0144/ai/models"
+	"github.com/Ammar0144/ai/services"
+)
+
+// Feedback listing page sizes
+const (
+	defaultFeedbackPage = 50
+	maxFeedbackPage     = 500
+)
+
+// newResponseID returns the ID of a new AI response
+func newResponseID() string {
+	return services.NewID("resp")
+}
+
+// recordResponse stores a completed call under its response ID, so that
+// support can look up exactly what the user saw and feedback can refer to
+// it, and returns the ID in the X-Response-ID header
+func (h *AIHandler) recordResponse(w http.ResponseWriter, started time.Time, record models.ResponseRecord, request, response interface{}) {
+	w.Header().Set("X-Response-ID", record.ID)
+
+	var err error
+	if record.Request, err = json.Marshal(request); err != nil {
+		log.Printf("Error recording request of response %s: %v", record.ID, err)
+	}
+	if record.Response, err = json.Marshal(response); err != nil {
+		log.Printf("Error recording response %s: %v", record.ID, err)
+	}
+	if record.Usage == nil {
+		promptTokens, completionTokens := services.EstimateTokens(record.Prompt), services.EstimateTokens(record.Output)
+		record.Usage = &models.TokenUsage{
+			PromptTokens:     promptTokens,
+			CompletionTokens: completionTokens,
+			TotalTokens:      promptTokens + completionTokens,
+		}
+	}
+	record.DurationMs = time.Since(started).Milliseconds()
+	record.CreatedAt = time.Now()
+	if err := h.responses.Add(record); err != nil {
+		log.Printf("Error storing response %s: %v", record.ID, err)
+		return
+	}
+	log.Printf("Stored response %s from %s for user %s (%d ms)", record.ID, record.Endpoint, record.UserID, record.DurationMs)
+}
+
+// HandleFeedback rates responses and lists ratings
+//
+//	@Summary		Submit or list response feedback
+//	@Description	POST rates an AI response by the id it was returned with: rating is "up" or "down", with an optional comment and tags. The feedback stores a snapshot of the response's prompt, output, model and parameters, so responses can only be rated while they are stored (see AI_RESPONSE_RETENTION). A user rating the same response again replaces their feedback. The user is taken from the X-User-ID header or user_id. GET lists feedback, most recently updated first, filtered by rating, tag, user_id, response_id, endpoint and model, and requires the X-Admin-Token header. Rate limited to 100 requests per minute per IP address.
+//	@Tags			Feedback
+//	@Accept			json
+//	@Produce		json
+//	@Param			request			body		models.FeedbackRequest	false	"Rating (POST)"
+//	@Param			X-User-ID		header		string					false	"User giving the feedback"
+//	@Param			X-Admin-Token	header		string					false	"Admin token (required for GET)"
+//	@Param			rating			query		string					false	"Only up or down ratings"
+//	@Param			tag				query		string					false	"Only feedback with this tag"
+//	@Param			user_id			query		string					false	"Only feedback from this user"
+//	@Param			response_id		query		string					false	"Only feedback on this response"
+//	@Param			endpoint		query		string					false	"Only responses from this endpoint, e.g. /ai/complete"
+//	@Param			model			query		string					false	"Only responses from this model"
+//	@Param			limit			query		int						false	"Page size (default 50, at most 500)"
+//	@Param			offset			query		int						false	"Feedback to skip"
+//	@Success		200				{object}	models.FeedbackList		"Feedback page (GET); replacing a rating (POST) returns the models.Feedback"
+//	@Success		201				{object}	models.Feedback			"Feedback recorded"
+//	@Failure		400				{object}	models.ErrorResponse	"Bad request"
+//	@Failure		401				{object}	models.ErrorResponse	"Unauthorized"
+//	@Failure		404				{object}	models.ErrorResponse	"Response not found"
+//	@Failure		429				{object}	models.ErrorResponse	"Rate limit exceeded"
+//	@Failure		507				{object}	models.ErrorResponse	"Feedback store is full"
+//	@Router			/ai/feedback [get]
+//	@Router			/ai/feedback [post]
+func (h *AIHandler) HandleFeedback(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		if !h.requireAdmin(w, r) {
+			return
+		}
+		query := r.URL.Query()
+		limit, offset := defaultFeedbackPage, 0
+		if value := query.Get("limit"); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil || parsed < 1 || parsed > maxFeedbackPage {
+				h.sendErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("limit must be between 1 and %d", maxFeedbackPage))
+				return
+			}
+			limit = parsed
+		}
+		if value := query.Get("offset"); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil || parsed < 0 {
+				h.sendErrorResponse(w, http.StatusBadRequest, "offset must be a non-negative integer")
+				return
+			}
+			offset = parsed
+		}
+		feedback, total := h.feedback.List(feedbackFilter(r), offset, limit)
+		h.sendJSONResponse(w, http.StatusOK, models.FeedbackList{Feedback: feedback, Total: total})
+
+	case http.MethodPost:
+		var req models.FeedbackRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			h.sendErrorResponse(w, http.StatusBadRequest, "Invalid JSON format")
+			return
+		}
+		req.UserID = requestUserID(r, req.UserID)
+		if err := services.ValidateFeedback(&req); err != nil {
+			h.sendErrorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		record, err := h.responses.Get(req.ResponseID)
+		if err != nil {
+			if errors.Is(err, services.ErrResponseNotFound) {
+				h.sendErrorResponse(w, http.StatusNotFound, "Response not found; it may be too old to rate")
+				return
+			}
+			log.Printf("Error looking up response %s: %v", req.ResponseID, err)
+			h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to look up response")
+			return
+		}
+
+		feedback, created, err := h.feedback.Submit(req, record)
+		if errors.Is(err, services.ErrFeedbackFull) {
+			h.sendErrorResponse(w, http.StatusInsufficientStorage, "Feedback store is full")
+			return
+		}
+		if err != nil {
+			log.Printf("Error saving feedback on response %s: %v", req.ResponseID, err)
+			h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to save feedback")
+			return
+		}
+		log.Printf("Recorded %s feedback on response %s from user %s", feedback.Rating, feedback.ResponseID, feedback.UserID)
+		status := http.StatusOK
+		if created {
+			status = http.StatusCreated
+		}
+		h.sendJSONResponse(w, status, feedback)
+
+	default:
+		h.sendErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// HandleFeedbackExport downloads feedback as a dataset
+//
+//	@Summary		Export response feedback
+//	@Description	Download matching feedback, most recently updated first. jsonl (default) writes each feedback with its stored response as a line, csv one row per feedback, and openai writes fine-tuning examples ({"messages": [...]}) of each rated response's prompt and output; add rating=up to keep only approved outputs. Requires the X-Admin-Token header. Rate limited to 100 requests per minute per IP address.
+//	@Tags			Feedback
+//	@Produce		plain
+//	@Param			X-Admin-Token	header		string					true	"Admin token"
+//	@Param			format			query		string					false	"Export format: jsonl, csv or openai (default jsonl)"
+//	@Param			rating			query		string					false	"Only up or down ratings"
+//	@Param			tag				query		string					false	"Only feedback with this tag"
+//	@Param			user_id			query		string					false	"Only feedback from this user"
+//	@Param			response_id		query		string					false	"Only feedback on this response"
+//	@Param			endpoint		query		string					false	"Only responses from this endpoint"
+//	@Param			model			query		string					false	"Only responses from this model"
+//	@Success		200				{string}	string					"Exported feedback"
+//	@Failure		400				{object}	models.ErrorResponse	"Unsupported format"
+//	@Failure		401				{object}	models.ErrorResponse	"Unauthorized"
+//	@Router			/ai/feedback/export [get]
+func (h *AIHandler) HandleFeedbackExport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.sendErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	if !h.requireAdmin(w, r) {
+		return
+	}
+
+	format := strings.ToLower(r.URL.Query().Get("format"))
+	if format == "" {
+		format = services.FeedbackFormatJSONL
+	}
+	contentType, extension, err := services.FeedbackFormatInfo(format)
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="feedback.%s"`, extension))
+	count, err := h.feedback.Export(w, format, feedbackFilter(r))
+	if err != nil {
+		// Headers are already sent, so the download is cut short
+		log.Printf("Error exporting feedback: %v", err)
+		return
+	}
+	log.Printf("Exported %d feedback entries as %s", count, format)
+}
+
+// feedbackFilter reads feedback filters from the query string
+func feedbackFilter(r *http.Request) services.FeedbackFilter {
+	query := r.URL.Query()
+	return services.FeedbackFilter{
+		Rating:     strings.ToLower(query.Get("rating")),
+		Tag:        strings.ToLower(query.Get("tag")),
+		UserID:     query.Get("user_id"),
+		ResponseID: query.Get("response_id"),
+		Endpoint:   query.Get("endpoint"),
+		Model:      query.Get("model"),
+	}
+}
@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/Ammar0144/ai/models"
+)
+
+// fimTimeout keeps editor completions from hanging on a slow upstream
+const fimTimeout = 10 * time.Second
+
+// HandleFIM completes code between a prefix and a suffix
+//
+//	@Summary		Fill-in-the-middle code completion
+//	@Description	Return the code to insert at the cursor given the code before it (prefix) and after it (suffix). When AI_UPSTREAM_CAPABILITIES includes "fim", the prompt uses the model's sentinel tokens (AI_FIM_TOKENS, default <fim_prefix>,<fim_suffix>,<fim_middle>); otherwise the suffix is shown to the model first and it continues the prefix in a code block. The prefix and suffix are each limited to 64 KB, of which the lines nearest the cursor are sent. The completion stops at end-of-text markers and stop sequences, before code that repeats the suffix, and at syntactic boundaries: the end of the line when the cursor is mid-line, otherwise the end of the block at the cursor. max_tokens defaults to 64 (at most 256), temperature to 0.2, and the upstream call times out after 10 seconds. With dry_run or the X-AI-Dry-Run header, the upstream request is returned instead of calling the model. Rate limited to 30 requests per minute per IP address.
+//	@Tags			AI Processing
+//	@Accept			json
+//	@Produce		json
+//	@Param			request			body		models.FIMRequest		true	"Code around the cursor"
+//	@Param			X-AI-Dry-Run	header		bool					false	"Return the upstream request without calling the model"
+//	@Success		200				{object}	models.FIMResponse		"Code to insert"
+//	@Failure		400				{object}	models.ErrorResponse	"Bad request"
+//	@Failure		429				{object}	models.ErrorResponse	"Rate limit exceeded"
+//	@Failure		500				{object}	models.ErrorResponse	"Internal server error"
+//	@Failure		504				{object}	models.ErrorResponse	"Upstream timed out"
+//	@Router			/ai/fim [post]
+func (h *AIHandler) HandleFIM(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.sendErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var req models.FIMRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid JSON format")
+		return
+	}
+	plan, err := h.aiService.PlanFIM(&req)
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if isDryRun(r, req.DryRun) {
+		upstream := h.aiService.BuildCompleteRequest(plan.Prompt, plan.MaxTokens, plan.Temperature)
+		if plan.Mode == models.FIMSentinel {
+			upstream.Routing = append(upstream.Routing, "fim: sentinel tokens (upstream has the fim capability)")
+		} else {
+			upstream.Routing = append(upstream.Routing, "fim: prompt approximation (upstream lacks the fim capability)")
+		}
+		h.sendDryRun(w, upstream, plan.Prompt, plan.MaxTokens, plan.Temperature)
+		return
+	}
+
+	started := time.Now()
+	ctx, cancel := context.WithTimeout(r.Context(), fimTimeout)
+	defer cancel()
+	output, err := h.aiService.GetCompleteContext(ctx, plan.Prompt, plan.MaxTokens, plan.Temperature)
+	if err != nil {
+		log.Printf("Error completing code for user %s: %v", req.UserID, err)
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			h.sendErrorResponse(w, http.StatusGatewayTimeout, "Code completion timed out")
+			return
+		}
+		h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to complete code")
+		return
+	}
+
+	completion, reason := plan.Finish(output)
+	response := models.FIMResponse{
+		ID:         newResponseID(),
+		Completion: completion,
+		StopReason: reason,
+		Mode:       plan.Mode,
+		Language:   req.Language,
+		DurationMs: time.Since(started).Milliseconds(),
+		UserID:     req.UserID,
+		Timestamp:  time.Now(),
+		Model:      h.aiService.GetModel(),
+	}
+	h.recordResponse(w, started, models.ResponseRecord{
+		ID:          response.ID,
+		Endpoint:    "/ai/fim",
+		UserID:      req.UserID,
+		Model:       response.Model,
+		Prompt:      plan.Prompt,
+		Output:      completion,
+		MaxTokens:   plan.MaxTokens,
+		Temperature: plan.Temperature,
+	}, req, response)
+	h.sendJSONResponse(w, http.StatusOK, response)
+}
@@ -0,0 +1,286 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Ammar0144/ai/models"
+	"github.com/Ammar0144/ai/services"
+)
+
+// Ingestion upload limits
+const (
+	maxIngestBytes  = 32 << 20
+	maxIngestMemory = 8 << 20
+	maxIngestFiles  = 20
+)
+
+// documentIDUnsafe matches runs of characters not kept in document IDs
+// derived from filenames
+var documentIDUnsafe = regexp.MustCompile(`[^a-z0-9_-]+`)
+
+// HandleIngest extracts text from uploaded documents
+//
+//	@Summary		Ingest documents
+//	@Description	Extract clean text from uploaded PDF, HTML, Markdown, DOCX or plain text files (multipart field "file", repeatable), normalize whitespace and Unicode, and split it into token-bounded chunks that overlap. The format is detected from the content, content type and extension unless given. The text can also be stored directly: rag_collection ingests each file as a document of a RAG collection (ID from the filename) and vector_collection embeds each chunk into a vector collection; both require the X-Admin-Token header. Uploads are limited to 32 MB and 20 files. Rate limited to 30 requests per minute per IP address.
+//	@Tags			AI Processing
+//	@Accept			mpfd
+//	@Produce		json
+//	@Param			file				formData	file					true	"Document to ingest (repeatable)"
+//	@Param			format				formData	string					false	"Format of every file: pdf, html, markdown, docx or text (detected when omitted)"
+//	@Param			chunk_tokens		formData	int						false	"Maximum tokens per chunk (default 256, between 32 and 512)"
+//	@Param			chunk_overlap		formData	int						false	"Tokens shared by consecutive chunks (default 32, under half of chunk_tokens)"
+//	@Param			include_text		formData	bool					false	"Include the full extracted text (default true)"
+//	@Param			rag_collection		formData	string					false	"RAG collection to store the documents in"
+//	@Param			vector_collection	formData	string					false	"Vector collection to store the chunks in"
+//	@Param			X-Admin-Token		header		string					false	"Admin token (required to store documents)"
+//	@Success		200					{object}	models.IngestResponse	"Extracted files"
+//	@Failure		400					{object}	models.ErrorResponse	"Bad request"
+//	@Failure		401					{object}	models.ErrorResponse	"Unauthorized"
+//	@Failure		404					{object}	models.ErrorResponse	"Collection not found"
+//	@Failure		413					{object}	models.ErrorResponse	"Upload too large"
+//	@Failure		415					{object}	models.ErrorResponse	"Unsupported document format"
+//	@Failure		429					{object}	models.ErrorResponse	"Rate limit exceeded"
+//	@Failure		500					{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/ai/ingest [post]
+func (h *AIHandler) HandleIngest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.sendErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxIngestBytes)
+	if err := r.ParseMultipartForm(maxIngestMemory); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			h.sendErrorResponse(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("Upload exceeds %d MB", maxIngestBytes>>20))
+			return
+		}
+		h.sendErrorResponse(w, http.StatusBadRequest, "Request must be multipart/form-data")
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	files := r.MultipartForm.File["file"]
+	if len(files) == 0 {
+		h.sendErrorResponse(w, http.StatusBadRequest, "At least one file is required")
+		return
+	}
+	if len(files) > maxIngestFiles {
+		h.sendErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("At most %d files can be ingested at once", maxIngestFiles))
+		return
+	}
+
+	format := ""
+	if name := r.FormValue("format"); name != "" {
+		if format = services.ParseFormat(name); format == "" {
+			h.sendErrorResponse(w, http.StatusBadRequest, "Format must be pdf, html, markdown, docx or text")
+			return
+		}
+	}
+	var chunkTokens, chunkOverlap int
+	for field, target := range map[string]*int{"chunk_tokens": &chunkTokens, "chunk_overlap": &chunkOverlap} {
+		if value := r.FormValue(field); value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				h.sendErrorResponse(w, http.StatusBadRequest, field+" must be an integer")
+				return
+			}
+			*target = n
+		}
+	}
+	chunkTokens, chunkOverlap, err := services.ResolveChunking(chunkTokens, chunkOverlap)
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	includeText := true
+	if value := r.FormValue("include_text"); value != "" {
+		if includeText, err = strconv.ParseBool(value); err != nil {
+			h.sendErrorResponse(w, http.StatusBadRequest, "include_text must be a boolean")
+			return
+		}
+	}
+
+	ragCollection := r.FormValue("rag_collection")
+	vectorCollection := r.FormValue("vector_collection")
+	var vectorInfo models.VectorCollection
+	if ragCollection != "" || vectorCollection != "" {
+		if !h.requireAdmin(w, r) {
+			return
+		}
+		if ragCollection != "" {
+			if _, err := h.rag.GetCollection(ragCollection); err != nil {
+				h.sendRAGError(w, err)
+				return
+			}
+		}
+		if vectorCollection != "" {
+			if vectorInfo, err = h.vectors.GetCollection(vectorCollection); err != nil {
+				h.sendVectorError(w, err)
+				return
+			}
+		}
+	}
+
+	response := models.IngestResponse{
+		Files:            make([]models.IngestedFile, 0, len(files)),
+		RAGCollection:    ragCollection,
+		VectorCollection: vectorCollection,
+		Timestamp:        time.Now(),
+	}
+	texts := make([]string, len(files))
+	usedIDs := make(map[string]bool)
+	for i, header := range files {
+		file, status, err := extractUpload(header, format)
+		if err != nil {
+			h.sendErrorResponse(w, status, fmt.Sprintf("File %s: %v", header.Filename, err))
+			return
+		}
+		texts[i] = file.Text
+		if !includeText {
+			file.Text = ""
+		}
+		file.Chunks = services.ChunkDocument(texts[i], chunkTokens, chunkOverlap)
+		if ragCollection != "" || vectorCollection != "" {
+			file.DocumentID = documentID(header.Filename, usedIDs)
+		}
+		response.Chunks += len(file.Chunks)
+		response.Files = append(response.Files, *file)
+	}
+
+	if ragCollection != "" {
+		var inputs []models.RAGDocumentInput
+		for i, file := range response.Files {
+			if strings.TrimSpace(texts[i]) == "" {
+				continue
+			}
+			title := file.Title
+			if title == "" {
+				title = file.Filename
+			}
+			inputs = append(inputs, models.RAGDocumentInput{
+				ID:       file.DocumentID,
+				Title:    title,
+				Source:   file.Filename,
+				Text:     texts[i],
+				Metadata: map[string]interface{}{"format": file.Format, "filename": file.Filename},
+			})
+		}
+		if len(inputs) == 0 {
+			h.sendErrorResponse(w, http.StatusBadRequest, "No text could be extracted from the uploaded files")
+			return
+		}
+		if _, err := h.rag.Ingest(r.Context(), ragCollection, inputs); err != nil {
+			h.sendRAGError(w, err)
+			return
+		}
+	}
+
+	if vectorCollection != "" {
+		var records []models.VectorRecord
+		var chunkTexts []string
+		for _, file := range response.Files {
+			for _, chunk := range file.Chunks {
+				records = append(records, models.VectorRecord{
+					ID:   fmt.Sprintf("%s#%d", file.DocumentID, chunk.Index),
+					Text: chunk.Text,
+					Metadata: map[string]interface{}{
+						"document_id": file.DocumentID,
+						"filename":    file.Filename,
+						"format":      file.Format,
+						"chunk":       chunk.Index,
+						"start":       chunk.Start,
+						"end":         chunk.End,
+					},
+				})
+				chunkTexts = append(chunkTexts, chunk.Text)
+			}
+		}
+		if len(records) == 0 {
+			h.sendErrorResponse(w, http.StatusBadRequest, "No text could be extracted from the uploaded files")
+			return
+		}
+		vectors, err := h.aiService.EmbedAll(r.Context(), chunkTexts, vectorInfo.EmbeddingModel)
+		if err != nil {
+			h.sendEmbeddingError(w, err)
+			return
+		}
+		for i, vector := range vectors {
+			records[i].Vector = vector
+		}
+		if _, err := h.vectors.Upsert(vectorCollection, records); err != nil {
+			h.sendVectorError(w, err)
+			return
+		}
+	}
+
+	log.Printf("Ingested %d files into %d chunks (rag collection %q, vector collection %q)", len(files), response.Chunks, ragCollection, vectorCollection)
+	h.sendJSONResponse(w, http.StatusOK, response)
+}
+
+// extractUpload reads and extracts one uploaded file, returning the HTTP
+// status to use on failure
+func extractUpload(header *multipart.FileHeader, format string) (*models.IngestedFile, int, error) {
+	upload, err := header.Open()
+	if err != nil {
+		return nil, http.StatusBadRequest, fmt.Errorf("failed to read upload")
+	}
+	defer upload.Close()
+	data, err := io.ReadAll(upload)
+	if err != nil {
+		return nil, http.StatusBadRequest, fmt.Errorf("failed to read upload")
+	}
+
+	if format == "" {
+		format = services.DetectFormat(header.Filename, header.Header.Get("Content-Type"), data)
+		if format == "" {
+			return nil, http.StatusUnsupportedMediaType, services.ErrUnsupportedFormat
+		}
+	}
+	doc, err := services.ExtractDocument(data, format)
+	if err != nil {
+		if errors.Is(err, services.ErrUnsupportedFormat) {
+			return nil, http.StatusUnsupportedMediaType, err
+		}
+		return nil, http.StatusBadRequest, err
+	}
+
+	return &models.IngestedFile{
+		Filename:   header.Filename,
+		Format:     doc.Format,
+		Title:      doc.Title,
+		Pages:      doc.Pages,
+		Characters: len([]rune(doc.Text)),
+		Tokens:     services.EstimateTokens(doc.Text),
+		Text:       doc.Text,
+		Warnings:   doc.Warnings,
+	}, http.StatusOK, nil
+}
+
+// documentID derives a document ID from a filename, unique among used
+func documentID(filename string, used map[string]bool) string {
+	base := strings.TrimSuffix(path.Base(strings.ReplaceAll(filename, "\\", "/")), path.Ext(filename))
+	id := strings.Trim(documentIDUnsafe.ReplaceAllString(strings.ToLower(base), "-"), "-")
+	if len(id) > 48 {
+		id = strings.Trim(id[:48], "-")
+	}
+	if id == "" {
+		id = services.NewID("doc")
+	}
+	candidate := id
+	for n := 2; used[candidate]; n++ {
+		candidate = fmt.Sprintf("%s-%d", id, n)
+	}
+	used[candidate] = true
+	return candidate
+}
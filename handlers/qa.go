@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/Ammar0144/ai/models"
+	"github.com/Ammar0144/ai/services"
+)
+
+// HandleQA answers a question from supplied passages with an exact source span
+//
+//	@Summary		Extractive question answering
+//	@Description	Answer a question from one or more passages or a single context string (not both). Passages are split into chunks and ranked against the question with BM25; the best top_k chunks that fit the context window are placed in the prompt. The model's answer is aligned back to an exact character span of a source passage: a verbatim match has confidence 1, otherwise the word window with the best token F1 is used. When the model declines or the confidence is below min_confidence (default 0.6), no_answer is set and answer is empty. With dry_run or the X-AI-Dry-Run header, the upstream request is returned instead of calling the model. Rate limited to 30 requests per minute per IP address.
+//	@Tags			AI Processing
+//	@Accept			json
+//	@Produce		json
+//	@Param			request			body		models.QARequest		true	"Question and passages"
+//	@Param			X-AI-Dry-Run	header		bool					false	"Return the upstream request without calling the model"
+//	@Success		200				{object}	models.QAResponse		"Answer with its source span"
+//	@Failure		400				{object}	models.ErrorResponse	"Bad request"
+//	@Failure		429				{object}	models.ErrorResponse	"Rate limit exceeded"
+//	@Failure		500				{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/ai/qa [post]
+func (h *AIHandler) HandleQA(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.sendErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var req models.QARequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid JSON format")
+		return
+	}
+	plan, err := services.PlanQA(&req)
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if isDryRun(r, req.DryRun) {
+		note := fmt.Sprintf("qa: %d of the best-ranked passage chunks in the prompt; the answer is aligned to a source span afterwards", len(plan.Sources()))
+		h.sendDryRun(w, h.aiService.BuildCompleteRequest(plan.Prompt, plan.MaxTokens, plan.Temperature), plan.Prompt, plan.MaxTokens, plan.Temperature, note)
+		return
+	}
+
+	log.Printf("Received QA request from user %s with %d context chunks", req.UserID, len(plan.Sources()))
+
+	started := time.Now()
+	output, err := h.aiService.GetCompleteContext(r.Context(), plan.Prompt, plan.MaxTokens, plan.Temperature)
+	if err != nil {
+		log.Printf("Error answering question: %v", err)
+		h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to answer question")
+		return
+	}
+
+	span, confidence := plan.Align(output)
+	response := models.QAResponse{
+		ID:          newResponseID(),
+		NoAnswer:    span == nil,
+		Confidence:  confidence,
+		Span:        span,
+		ModelAnswer: services.CleanQAAnswer(output),
+		Sources:     plan.Sources(),
+		UserID:      req.UserID,
+		Timestamp:   time.Now(),
+		Model:       h.aiService.GetModel(),
+	}
+	if span != nil {
+		response.Answer = span.Text
+	}
+	h.recordResponse(w, started, models.ResponseRecord{
+		ID:          response.ID,
+		Endpoint:    "/ai/qa",
+		UserID:      req.UserID,
+		Model:       response.Model,
+		Prompt:      req.Question,
+		Output:      response.Answer,
+		MaxTokens:   plan.MaxTokens,
+		Temperature: plan.Temperature,
+	}, req, response)
+	h.sendJSONResponse(w, http.StatusOK, response)
+}
@@ -0,0 +1,384 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Ammar0144/ai/models"
+	"github.com/Ammar0144/ai/services"
+)
+
+// HandleRAGChat answers the latest user message from a document collection
+//
+//	@Summary		Retrieval-augmented chat
+//	@Description	Retrieve the top_k chunks of a document collection that best match the latest user message (BM25, vector or hybrid), add them to the conversation as numbered sources within the model's context budget and generate a chat completion. The answer comes back with citations to the chunk IDs and character offsets it refers to, plus every source that was placed in the prompt. With dry_run or the X-AI-Dry-Run header, the rendered upstream request is returned instead of calling the model. Rate limited to 30 requests per minute per IP address.
+//	@Tags			RAG
+//	@Accept			json
+//	@Produce		json
+//	@Param			request			body		models.RAGChatRequest	true	"Collection and conversation"
+//	@Param			X-AI-Dry-Run	header		bool					false	"Return the upstream request without calling the model"
+//	@Success		200				{object}	models.RAGChatResponse	"Answer with citations"
+//	@Failure		400				{object}	models.ErrorResponse	"Bad request"
+//	@Failure		404				{object}	models.ErrorResponse	"Collection not found"
+//	@Failure		429				{object}	models.ErrorResponse	"Rate limit exceeded"
+//	@Failure		500				{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/ai/rag/chat [post]
+func (h *AIHandler) HandleRAGChat(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.sendErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var req models.RAGChatRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid JSON format")
+		return
+	}
+	if req.Collection == "" {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Collection is required")
+		return
+	}
+	if len(req.Messages) == 0 {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Messages cannot be empty")
+		return
+	}
+	if err := validateChatMessages(req.Messages, false); err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	query := services.LatestUserMessage(req.Messages)
+	if strings.TrimSpace(query) == "" {
+		h.sendErrorResponse(w, http.StatusBadRequest, "A user message is required")
+		return
+	}
+
+	maxTokens := req.MaxTokens
+	if maxTokens == 0 {
+		maxTokens = 150
+	}
+
+	temperature := req.Temperature
+	if temperature == 0 {
+		temperature = 0.7
+	}
+
+	log.Printf("Received RAG chat request from user %s for collection %s", req.UserID, req.Collection)
+
+	retrieved, err := h.rag.Search(r.Context(), req.Collection, models.RAGSearchRequest{
+		Query:  query,
+		TopK:   req.TopK,
+		Mode:   req.Mode,
+		Filter: req.Filter,
+	})
+	if err != nil {
+		h.sendRAGError(w, err)
+		return
+	}
+	messages, sources := services.BuildRAGMessages(req.Messages, retrieved.Matches, maxTokens)
+
+	if isDryRun(r, req.DryRun) {
+		note := fmt.Sprintf("rag: %d of %d retrieved chunks from %s fit the context budget (%s retrieval)",
+			len(sources), len(retrieved.Matches), req.Collection, retrieved.Mode)
+		h.sendDryRun(w, h.aiService.BuildChatRequest(messages, nil, services.ToolChoice{}, maxTokens, temperature), "", maxTokens, temperature, note)
+		return
+	}
+
+	started := time.Now()
+	answer, err := h.aiService.GetChatCompletionContext(r.Context(), messages, maxTokens, temperature)
+	if err != nil {
+		log.Printf("Error getting RAG chat completion: %v", err)
+		h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to get chat completion")
+		return
+	}
+
+	response := models.RAGChatResponse{
+		ID:         newResponseID(),
+		Response:   answer,
+		Citations:  services.CitedSources(answer, sources),
+		Sources:    sources,
+		Collection: req.Collection,
+		Mode:       retrieved.Mode,
+		UserID:     req.UserID,
+		Timestamp:  time.Now(),
+		Model:      h.aiService.GetModel(),
+	}
+	h.recordResponse(w, started, models.ResponseRecord{
+		ID:          response.ID,
+		Endpoint:    "/ai/rag/chat",
+		UserID:      req.UserID,
+		Model:       response.Model,
+		Prompt:      query,
+		Output:      answer,
+		MaxTokens:   maxTokens,
+		Temperature: temperature,
+	}, req, response)
+	h.sendJSONResponse(w, http.StatusOK, response)
+}
+
+// HandleRAGCollections lists and creates document collections
+//
+//	@Summary		List or create document collections
+//	@Description	GET lists document collections with document and chunk counts. POST creates a collection; chunk_tokens and chunk_overlap control chunking and vectors enables embedding chunks for hybrid retrieval. Creating requires the X-Admin-Token header. Rate limited to 100 requests per minute per IP address.
+//	@Tags			RAG
+//	@Accept			json
+//	@Produce		json
+//	@Param			X-Admin-Token	header		string								false	"Admin token (required for POST)"
+//	@Param			request			body		models.CreateRAGCollectionRequest	false	"Collection to create (POST)"
+//	@Success		200				{array}		models.RAGCollection				"Collections"
+//	@Success		201				{object}	models.RAGCollection				"Created collection"
+//	@Failure		400				{object}	models.ErrorResponse				"Bad request"
+//	@Failure		401				{object}	models.ErrorResponse				"Unauthorized"
+//	@Failure		409				{object}	models.ErrorResponse				"Collection already exists"
+//	@Router			/ai/rag/collections [get]
+//	@Router			/ai/rag/collections [post]
+func (h *AIHandler) HandleRAGCollections(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.sendJSONResponse(w, http.StatusOK, h.rag.ListCollections())
+
+	case http.MethodPost:
+		if !h.requireAdmin(w, r) {
+			return
+		}
+		var req models.CreateRAGCollectionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			h.sendErrorResponse(w, http.StatusBadRequest, "Invalid JSON format")
+			return
+		}
+		collection, err := h.rag.CreateCollection(req)
+		if err != nil {
+			h.sendRAGError(w, err)
+			return
+		}
+		log.Printf("Created RAG collection %s", collection.Name)
+		h.sendJSONResponse(w, http.StatusCreated, collection)
+
+	default:
+		h.sendErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// HandleRAGCollection routes requests under /ai/rag/collections/{name}
+//
+//	@Summary		Get or delete a document collection
+//	@Description	GET describes the collection. DELETE removes it with all its documents and requires the X-Admin-Token header. Rate limited to 100 requests per minute per IP address.
+//	@Tags			RAG
+//	@Produce		json
+//	@Param			name			path		string					true	"Collection name"
+//	@Param			X-Admin-Token	header		string					false	"Admin token (required for DELETE)"
+//	@Success		200				{object}	models.RAGCollection	"Collection"
+//	@Success		204				"Collection deleted"
+//	@Failure		401				{object}	models.ErrorResponse	"Unauthorized"
+//	@Failure		404				{object}	models.ErrorResponse	"Collection not found"
+//	@Router			/ai/rag/collections/{name} [get]
+//	@Router			/ai/rag/collections/{name} [delete]
+func (h *AIHandler) HandleRAGCollection(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/ai/rag/collections/"), "/"), "/")
+	name := parts[0]
+	if name == "" {
+		h.HandleRAGCollections(w, r)
+		return
+	}
+
+	switch {
+	case len(parts) == 1:
+	case len(parts) == 2 && parts[1] == "documents":
+		h.handleRAGDocuments(w, r, name)
+		return
+	case len(parts) == 3 && parts[1] == "documents":
+		h.handleRAGDocument(w, r, name, parts[2])
+		return
+	case len(parts) == 2 && parts[1] == "search":
+		h.handleRAGSearch(w, r, name)
+		return
+	default:
+		h.sendErrorResponse(w, http.StatusNotFound, "Not found")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		collection, err := h.rag.GetCollection(name)
+		if err != nil {
+			h.sendRAGError(w, err)
+			return
+		}
+		h.sendJSONResponse(w, http.StatusOK, collection)
+
+	case http.MethodDelete:
+		if !h.requireAdmin(w, r) {
+			return
+		}
+		if err := h.rag.DeleteCollection(name); err != nil {
+			h.sendRAGError(w, err)
+			return
+		}
+		log.Printf("Deleted RAG collection %s", name)
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		h.sendErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// handleRAGDocuments lists and ingests documents
+//
+//	@Summary		List or ingest documents
+//	@Description	GET lists the collection's documents. POST ingests one document or an array of documents: each is split into token-bounded overlapping chunks, indexed with BM25 and, for collections with vectors, embedded. Ingesting an existing document ID replaces it. Ingesting requires the X-Admin-Token header. Rate limited to 100 requests per minute per IP address.
+//	@Tags			RAG
+//	@Accept			json
+//	@Produce		json
+//	@Param			name			path		string							true	"Collection name"
+//	@Param			X-Admin-Token	header		string							false	"Admin token (required for POST)"
+//	@Param			request			body		models.RAGDocumentInput			false	"Document or array of documents (POST)"
+//	@Success		200				{array}		models.RAGDocument				"Documents"
+//	@Success		201				{object}	models.IngestDocumentsResponse	"Ingested documents"
+//	@Failure		400				{object}	models.ErrorResponse			"Bad request"
+//	@Failure		401				{object}	models.ErrorResponse			"Unauthorized"
+//	@Failure		404				{object}	models.ErrorResponse			"Collection not found"
+//	@Router			/ai/rag/collections/{name}/documents [get]
+//	@Router			/ai/rag/collections/{name}/documents [post]
+func (h *AIHandler) handleRAGDocuments(w http.ResponseWriter, r *http.Request, name string) {
+	switch r.Method {
+	case http.MethodGet:
+		documents, err := h.rag.ListDocuments(name)
+		if err != nil {
+			h.sendRAGError(w, err)
+			return
+		}
+		h.sendJSONResponse(w, http.StatusOK, documents)
+
+	case http.MethodPost:
+		if !h.requireAdmin(w, r) {
+			return
+		}
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			h.sendErrorResponse(w, http.StatusBadRequest, "Failed to read request body")
+			return
+		}
+
+		var inputs []models.RAGDocumentInput
+		if trimmed := bytes.TrimSpace(body); len(trimmed) > 0 && trimmed[0] == '[' {
+			err = json.Unmarshal(trimmed, &inputs)
+		} else {
+			var input models.RAGDocumentInput
+			err = json.Unmarshal(trimmed, &input)
+			inputs = append(inputs, input)
+		}
+		if err != nil {
+			h.sendErrorResponse(w, http.StatusBadRequest, "Invalid JSON format")
+			return
+		}
+
+		response, err := h.rag.Ingest(r.Context(), name, inputs)
+		if err != nil {
+			h.sendRAGError(w, err)
+			return
+		}
+		log.Printf("Ingested %d documents (%d chunks) into RAG collection %s", len(response.Documents), response.Chunks, name)
+		h.sendJSONResponse(w, http.StatusCreated, response)
+
+	default:
+		h.sendErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// handleRAGDocument gets or deletes a document
+//
+//	@Summary		Get or delete a document
+//	@Description	GET returns the document with its full text and chunks, including each chunk's character offsets. DELETE removes the document and its chunks and requires the X-Admin-Token header. Rate limited to 100 requests per minute per IP address.
+//	@Tags			RAG
+//	@Produce		json
+//	@Param			name			path		string						true	"Collection name"
+//	@Param			id				path		string						true	"Document ID"
+//	@Param			X-Admin-Token	header		string						false	"Admin token (required for DELETE)"
+//	@Success		200				{object}	models.RAGDocumentDetail	"Document"
+//	@Success		204				"Document deleted"
+//	@Failure		401				{object}	models.ErrorResponse		"Unauthorized"
+//	@Failure		404				{object}	models.ErrorResponse		"Collection or document not found"
+//	@Router			/ai/rag/collections/{name}/documents/{id} [get]
+//	@Router			/ai/rag/collections/{name}/documents/{id} [delete]
+func (h *AIHandler) handleRAGDocument(w http.ResponseWriter, r *http.Request, name, id string) {
+	switch r.Method {
+	case http.MethodGet:
+		document, chunks, err := h.rag.GetDocument(name, id)
+		if err != nil {
+			h.sendRAGError(w, err)
+			return
+		}
+		h.sendJSONResponse(w, http.StatusOK, models.RAGDocumentDetail{Document: document, Chunks: chunks})
+
+	case http.MethodDelete:
+		if !h.requireAdmin(w, r) {
+			return
+		}
+		if err := h.rag.DeleteDocument(name, id); err != nil {
+			h.sendRAGError(w, err)
+			return
+		}
+		log.Printf("Deleted document %s from RAG collection %s", id, name)
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		h.sendErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// handleRAGSearch retrieves chunks without calling the model
+//
+//	@Summary		Search a document collection
+//	@Description	Return the chunks that /ai/rag/chat would retrieve for a query, with BM25, vector and fused scores. Rate limited to 100 requests per minute per IP address.
+//	@Tags			RAG
+//	@Accept			json
+//	@Produce		json
+//	@Param			name		path		string						true	"Collection name"
+//	@Param			request		body		models.RAGSearchRequest		true	"Query"
+//	@Success		200			{object}	models.RAGSearchResponse	"Matching chunks, best first"
+//	@Failure		400			{object}	models.ErrorResponse		"Bad request"
+//	@Failure		404			{object}	models.ErrorResponse		"Collection not found"
+//	@Router			/ai/rag/collections/{name}/search [post]
+func (h *AIHandler) handleRAGSearch(w http.ResponseWriter, r *http.Request, name string) {
+	if r.Method != http.MethodPost {
+		h.sendErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var req models.RAGSearchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid JSON format")
+		return
+	}
+	response, err := h.rag.Search(r.Context(), name, req)
+	if err != nil {
+		h.sendRAGError(w, err)
+		return
+	}
+	h.sendJSONResponse(w, http.StatusOK, response)
+}
+
+// sendRAGError maps RAG store errors to HTTP responses
+func (h *AIHandler) sendRAGError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrRAGCollectionNotFound):
+		h.sendErrorResponse(w, http.StatusNotFound, "Collection not found")
+	case errors.Is(err, services.ErrDocumentNotFound):
+		h.sendErrorResponse(w, http.StatusNotFound, "Document not found")
+	case errors.Is(err, services.ErrRAGCollectionExists):
+		h.sendErrorResponse(w, http.StatusConflict, "Collection already exists")
+	case errors.Is(err, services.ErrInvalidRAGRequest):
+		h.sendErrorResponse(w, http.StatusBadRequest, strings.TrimPrefix(err.Error(), services.ErrInvalidRAGRequest.Error()+": "))
+	case errors.Is(err, services.ErrInvalidEmbeddingInput):
+		h.sendErrorResponse(w, http.StatusBadRequest, err.Error())
+	default:
+		log.Printf("RAG error: %v", err)
+		h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to process RAG request")
+	}
+}
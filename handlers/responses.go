@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/Ammar0144/ai/services"
+)
+
+// HandleResponse looks up a stored response
+//
+//	@Summary		Get a stored response
+//	@Description	Return exactly what a user saw for the response id returned in the body and X-Response-ID header of an AI endpoint: the request as processed, the response body as sent, the model, the estimated token usage and the duration. Responses are kept for AI_RESPONSE_RETENTION (default 7 days), in AI_RESPONSE_DIR when set and in memory otherwise. Requires the X-Admin-Token header. Rate limited to 100 requests per minute per IP address.
+//	@Tags			Feedback
+//	@Produce		json
+//	@Param			id				path		string					true	"Response ID"
+//	@Param			X-Admin-Token	header		string					true	"Admin token"
+//	@Success		200				{object}	models.ResponseRecord	"Stored response"
+//	@Failure		401				{object}	models.ErrorResponse	"Unauthorized"
+//	@Failure		404				{object}	models.ErrorResponse	"Response not found or past retention"
+//	@Router			/ai/responses/{id} [get]
+func (h *AIHandler) HandleResponse(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.sendErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	if !h.requireAdmin(w, r) {
+		return
+	}
+
+	id := strings.Trim(strings.TrimPrefix(r.URL.Path, "/ai/responses/"), "/")
+	record, err := h.responses.Get(id)
+	if err != nil {
+		if errors.Is(err, services.ErrResponseNotFound) {
+			h.sendErrorResponse(w, http.StatusNotFound, "Response not found; it may be past retention")
+			return
+		}
+		log.Printf("Error looking up response %s: %v", id, err)
+		h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to look up response")
+		return
+	}
+	h.sendJSONResponse(w, http.StatusOK, record)
+}
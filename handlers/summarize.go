@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/Ammar0144/ai/models"
+	"github.com/Ammar0144/ai/services"
+)
+
+// summarizeTimeout bounds a whole map-reduce summarization
+const summarizeTimeout = 5 * time.Minute
+
+// HandleSummarize summarizes text longer than the model context
+//
+//	@Summary		Summarize long text
+//	@Description	Summarize text of up to 100,000 tokens. Text that does not fit in one prompt is split into token-bounded chunks that are summarized in parallel; the partial summaries are then reduced recursively until they fit in a final prompt. length is short, medium or long, style is prose or bullets, and focus restricts the summary to what the text says about a query. With dry_run or the X-AI-Dry-Run header, the first upstream request is returned instead of calling the model. Rate limited to 30 requests per minute per IP address.
+//	@Tags			AI Processing
+//	@Accept			json
+//	@Produce		json
+//	@Param			request			body		models.SummarizeRequest		true	"Text and summary options"
+//	@Param			X-AI-Dry-Run	header		bool						false	"Return the first upstream request without calling the model"
+//	@Success		200				{object}	models.SummarizeResponse	"Summary"
+//	@Failure		400				{object}	models.ErrorResponse		"Bad request"
+//	@Failure		429				{object}	models.ErrorResponse		"Rate limit exceeded"
+//	@Failure		500				{object}	models.ErrorResponse		"Internal server error"
+//	@Router			/ai/summarize [post]
+func (h *AIHandler) HandleSummarize(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.sendErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var req models.SummarizeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid JSON format")
+		return
+	}
+	plan, err := services.PlanSummary(&req)
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if isDryRun(r, req.DryRun) {
+		prompt, maxTokens, chunks := plan.FirstCall()
+		note := fmt.Sprintf("summarize: %d input tokens fit one prompt", plan.InputTokens)
+		if chunks > 1 {
+			note = fmt.Sprintf("summarize: %d input tokens split into %d chunks summarized in parallel, then reduced; showing the first chunk's request", plan.InputTokens, chunks)
+		}
+		h.sendDryRun(w, h.aiService.BuildCompleteRequest(prompt, maxTokens, req.Temperature), prompt, maxTokens, req.Temperature, note)
+		return
+	}
+
+	log.Printf("Received summarize request from user %s with about %d tokens", req.UserID, plan.InputTokens)
+
+	started := time.Now()
+	ctx, cancel := context.WithTimeout(r.Context(), summarizeTimeout)
+	defer cancel()
+	result, err := h.aiService.Summarize(ctx, plan)
+	if err != nil {
+		log.Printf("Error summarizing after %d calls: %v", result.Calls, err)
+		h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to summarize text")
+		return
+	}
+
+	response := models.SummarizeResponse{
+		ID:            newResponseID(),
+		Summary:       result.Summary,
+		Length:        req.Length,
+		Style:         req.Style,
+		Focus:         req.Focus,
+		InputTokens:   plan.InputTokens,
+		Chunks:        result.Chunks,
+		Levels:        result.Levels,
+		Calls:         result.Calls,
+		SummaryTokens: services.EstimateTokens(result.Summary),
+		DurationMs:    time.Since(started).Milliseconds(),
+		UserID:        req.UserID,
+		Timestamp:     time.Now(),
+		Model:         h.aiService.GetModel(),
+	}
+	h.recordResponse(w, started, models.ResponseRecord{
+		ID:          response.ID,
+		Endpoint:    "/ai/summarize",
+		UserID:      req.UserID,
+		Model:       response.Model,
+		Prompt:      req.Text,
+		Output:      result.Summary,
+		Temperature: req.Temperature,
+	}, req, response)
+	h.sendJSONResponse(w, http.StatusOK, response)
+}
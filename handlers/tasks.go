@@ -0,0 +1,306 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Ammar0144/ai/models"
+	"github.com/Ammar0144/ai/services"
+)
+
+// swaggerSpecPath is the generated OpenAPI document served at /swagger/doc.json
+const swaggerSpecPath = "docs/swagger.json"
+
+// Tasks returns the configured task endpoints in declaration order
+func (h *AIHandler) Tasks() []models.TaskDefinition {
+	return h.taskList
+}
+
+// TaskPath returns the URL path a task is mounted at
+func TaskPath(name string) string {
+	return "/ai/tasks/" + name
+}
+
+// HandleTasks lists the configured task endpoints
+//
+//	@Summary		List task endpoints
+//	@Description	List the custom task endpoints declared in the AI_TASKS_CONFIG file, with their input schemas, output parsers and rate limits. Rate limited to 100 requests per minute per IP address.
+//	@Tags			Tasks
+//	@Produce		json
+//	@Success		200	{array}	models.TaskInfo	"Task endpoints"
+//	@Router			/ai/tasks [get]
+func (h *AIHandler) HandleTasks(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.sendErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	infos := make([]models.TaskInfo, 0, len(h.taskList))
+	for _, task := range h.taskList {
+		infos = append(infos, models.TaskInfo{TaskDefinition: task, Path: TaskPath(task.Name)})
+	}
+	h.sendJSONResponse(w, http.StatusOK, infos)
+}
+
+// HandleTask runs a config-defined task: the input is validated against the
+// task's schema, rendered into its prompt, sent to its upstream and the model
+// output is parsed
+//
+//	@Summary		Run a task endpoint
+//	@Description	Run a custom task declared in the AI_TASKS_CONFIG file. Each task has its own prompt template, input schema, output parser and per-task rate limit; the per-task operations are listed in this document under the Tasks tag. With dry_run or the X-AI-Dry-Run header, the upstream request is returned instead of calling the model.
+//	@Tags			Tasks
+//	@Accept			json
+//	@Produce		json
+//	@Param			name			path		string					true	"Task name"
+//	@Param			request			body		models.TaskRequest		true	"Task input"
+//	@Param			X-AI-Dry-Run	header		bool					false	"Return the upstream request without calling the model"
+//	@Success		200				{object}	models.TaskResponse		"Parsed task output"
+//	@Failure		400				{object}	models.ErrorResponse	"Input does not match the task schema"
+//	@Failure		404				{object}	models.ErrorResponse	"Task not found"
+//	@Failure		429				{object}	models.ErrorResponse	"Rate limit exceeded"
+//	@Failure		500				{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/ai/tasks/{name} [post]
+func (h *AIHandler) HandleTask(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, "/ai/tasks/")
+	if name == "" {
+		h.HandleTasks(w, r)
+		return
+	}
+	task, ok := h.tasks[name]
+	if !ok {
+		h.sendErrorResponse(w, http.StatusNotFound, "Task not found")
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		h.sendErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var req models.TaskRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid JSON format")
+		return
+	}
+	if req.Input == nil {
+		req.Input = map[string]interface{}{}
+	}
+
+	if task.InputSchema != nil {
+		if err := services.ValidateSchema(task.InputSchema, req.Input); err != nil {
+			h.sendErrorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	prompt, err := services.RenderTaskPrompt(task, h.templates, req.Input)
+	if err != nil {
+		if task.TemplateID != "" {
+			h.sendTemplateError(w, err)
+			return
+		}
+		h.sendErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if strings.TrimSpace(prompt) == "" {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Task rendered an empty prompt")
+		return
+	}
+
+	maxTokens := req.MaxTokens
+	if maxTokens == 0 {
+		maxTokens = task.MaxTokens
+	}
+	if maxTokens == 0 {
+		maxTokens = 150
+	}
+
+	temperature := req.Temperature
+	if temperature == 0 {
+		temperature = task.Temperature
+	}
+	if temperature == 0 {
+		temperature = 0.7
+	}
+
+	if isDryRun(r, req.DryRun) {
+		notes := []string{fmt.Sprintf("task: %s (%s upstream, %s output parser)", task.Name, task.Upstream, task.Output.Parser)}
+		notes = append(notes, templateRoutingNotes(task.TemplateID, 0)...)
+		h.sendDryRun(w, h.aiService.BuildUpstreamRequest(task.Upstream, task.System, prompt, maxTokens, temperature), prompt, maxTokens, temperature, notes...)
+		return
+	}
+
+	log.Printf("Received task %s request from user %s", task.Name, req.UserID)
+
+	started := time.Now()
+	raw, err := h.aiService.CallUpstream(task.Upstream, task.System, prompt, maxTokens, temperature)
+	if err != nil {
+		log.Printf("Error running task %s: %v", task.Name, err)
+		h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to run task")
+		return
+	}
+
+	output, err := services.ParseTaskOutput(task.Output, raw)
+	if err != nil {
+		log.Printf("Error parsing task %s output: %v", task.Name, err)
+		h.sendErrorResponse(w, http.StatusInternalServerError, fmt.Sprintf("Failed to parse task output: %v", err))
+		return
+	}
+
+	response := models.TaskResponse{
+		ID:        newResponseID(),
+		Task:      task.Name,
+		Output:    output,
+		Raw:       raw,
+		UserID:    req.UserID,
+		Timestamp: time.Now(),
+		Model:     h.aiService.GetModel(),
+	}
+	h.recordResponse(w, started, models.ResponseRecord{
+		ID:          response.ID,
+		Endpoint:    "/ai/tasks/" + task.Name,
+		UserID:      req.UserID,
+		Model:       response.Model,
+		Prompt:      prompt,
+		Output:      raw,
+		MaxTokens:   maxTokens,
+		Temperature: temperature,
+	}, req, response)
+	h.sendJSONResponse(w, http.StatusOK, response)
+}
+
+// HandleSwaggerSpec serves the generated OpenAPI document with an operation
+// added for every configured task, so task endpoints are documented without
+// regenerating the spec
+func (h *AIHandler) HandleSwaggerSpec(w http.ResponseWriter, r *http.Request) {
+	if len(h.taskList) == 0 {
+		http.ServeFile(w, r, swaggerSpecPath)
+		return
+	}
+
+	data, err := os.ReadFile(swaggerSpecPath)
+	if err != nil {
+		http.Error(w, "Swagger spec not found", http.StatusNotFound)
+		return
+	}
+	var spec map[string]interface{}
+	if err := json.Unmarshal(data, &spec); err != nil {
+		log.Printf("Error parsing swagger spec: %v", err)
+		http.ServeFile(w, r, swaggerSpecPath)
+		return
+	}
+
+	paths, _ := spec["paths"].(map[string]interface{})
+	if paths == nil {
+		paths = make(map[string]interface{})
+		spec["paths"] = paths
+	}
+	for _, task := range h.taskList {
+		paths[TaskPath(task.Name)] = map[string]interface{}{"post": taskOperation(task)}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(spec)
+}
+
+// taskOperation builds the Swagger 2.0 operation describing a task endpoint
+func taskOperation(task models.TaskDefinition) map[string]interface{} {
+	inputSchema := task.InputSchema
+	if inputSchema == nil {
+		inputSchema = map[string]interface{}{"type": "object"}
+	}
+
+	description := task.Description
+	if description != "" {
+		description += ". "
+	}
+	description += fmt.Sprintf("Config-defined task using the %s upstream with the %s output parser. With dry_run or the X-AI-Dry-Run header, the upstream request is returned instead of calling the model. Rate limited to %d requests per minute per IP address.",
+		task.Upstream, task.Output.Parser, task.RateLimit)
+
+	summary := task.Description
+	if summary == "" {
+		summary = "Task " + task.Name
+	}
+
+	errorResponse := func(description string) map[string]interface{} {
+		return map[string]interface{}{
+			"description": description,
+			"schema":      map[string]interface{}{"$ref": "#/definitions/models.ErrorResponse"},
+		}
+	}
+
+	return map[string]interface{}{
+		"summary":     summary,
+		"description": description,
+		"tags":        []string{"Tasks"},
+		"consumes":    []string{"application/json"},
+		"produces":    []string{"application/json"},
+		"parameters": []interface{}{
+			map[string]interface{}{
+				"in":          "body",
+				"name":        "request",
+				"required":    true,
+				"description": "Task input",
+				"schema": map[string]interface{}{
+					"type":     "object",
+					"required": []string{"input"},
+					"properties": map[string]interface{}{
+						"input":       inputSchema,
+						"max_tokens":  map[string]interface{}{"type": "integer"},
+						"temperature": map[string]interface{}{"type": "number"},
+						"user_id":     map[string]interface{}{"type": "string"},
+						"dry_run": map[string]interface{}{
+							"type":        "boolean",
+							"description": "Return the upstream request instead of calling the model",
+						},
+					},
+				},
+			},
+			map[string]interface{}{
+				"in":          "header",
+				"name":        "X-AI-Dry-Run",
+				"type":        "boolean",
+				"description": "Return the upstream request without calling the model",
+			},
+		},
+		"responses": map[string]interface{}{
+			"200": map[string]interface{}{
+				"description": "Parsed task output, or the upstream request with dry_run",
+				"schema": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"id":        map[string]interface{}{"type": "string", "description": "Refers to the response in /ai/feedback"},
+						"task":      map[string]interface{}{"type": "string"},
+						"output":    taskOutputSchema(task.Output),
+						"raw":       map[string]interface{}{"type": "string"},
+						"user_id":   map[string]interface{}{"type": "string"},
+						"timestamp": map[string]interface{}{"type": "string", "format": "date-time"},
+						"model":     map[string]interface{}{"type": "string"},
+					},
+				},
+			},
+			"400": errorResponse("Input does not match the task schema"),
+			"429": errorResponse("Rate limit exceeded"),
+			"500": errorResponse("Internal server error"),
+		},
+	}
+}
+
+// taskOutputSchema describes the shape produced by a task's output parser
+func taskOutputSchema(output models.TaskOutput) map[string]interface{} {
+	switch output.Parser {
+	case models.TaskOutputLines:
+		return map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}}
+	case models.TaskOutputJSON:
+		return map[string]interface{}{"type": "object"}
+	case models.TaskOutputRegex:
+		return map[string]interface{}{"type": "object", "additionalProperties": map[string]interface{}{"type": "string"}}
+	default:
+		return map[string]interface{}{"type": "string"}
+	}
+}
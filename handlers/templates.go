@@ -0,0 +1,272 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/Ammar0144/ai/models"
+	"github.com/Ammar0144/ai/services"
+)
+
+// HandleTemplates lists and creates prompt templates
+//
+//	@Summary		List or create prompt templates
+//	@Description	GET lists all prompt templates. POST creates a template with typed variables; its body is a Go text/template such as "Summarize for {{.audience}}: {{.text}}". Creating requires the X-Admin-Token header. Rate limited to 100 requests per minute per IP address.
+//	@Tags			Templates
+//	@Accept			json
+//	@Produce		json
+//	@Param			X-Admin-Token	header		string							false	"Admin token (required for POST)"
+//	@Param			request			body		models.PromptTemplateRequest	false	"Template to create (POST)"
+//	@Success		200				{array}		models.PromptTemplate			"Templates"
+//	@Success		201				{object}	models.PromptTemplate			"Created template"
+//	@Failure		400				{object}	models.ErrorResponse			"Bad request"
+//	@Failure		401				{object}	models.ErrorResponse			"Unauthorized"
+//	@Failure		409				{object}	models.ErrorResponse			"Template already exists"
+//	@Failure		500				{object}	models.ErrorResponse			"Internal server error"
+//	@Router			/ai/templates [get]
+//	@Router			/ai/templates [post]
+func (h *AIHandler) HandleTemplates(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.sendJSONResponse(w, http.StatusOK, h.templates.List())
+
+	case http.MethodPost:
+		if !h.requireAdmin(w, r) {
+			return
+		}
+		var req models.PromptTemplateRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			h.sendErrorResponse(w, http.StatusBadRequest, "Invalid JSON format")
+			return
+		}
+		tmpl, err := h.templates.Create(req)
+		if err != nil {
+			h.sendTemplateError(w, err)
+			return
+		}
+		log.Printf("Created prompt template %s", tmpl.ID)
+		h.sendJSONResponse(w, http.StatusCreated, tmpl)
+
+	default:
+		h.sendErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// HandleTemplate routes requests for a single prompt template
+func (h *AIHandler) HandleTemplate(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/ai/templates/"), "/"), "/")
+	if parts[0] == "" {
+		h.HandleTemplates(w, r)
+		return
+	}
+
+	id := parts[0]
+	switch {
+	case len(parts) == 1:
+		h.handleTemplateItem(w, r, id)
+	case len(parts) == 2 && parts[1] == "versions":
+		h.handleTemplateVersions(w, r, id)
+	case len(parts) == 2 && parts[1] == "render":
+		h.handleRenderTemplate(w, r, id)
+	case len(parts) == 2 && parts[1] == "rollback":
+		h.handleRollbackTemplate(w, r, id)
+	default:
+		h.sendErrorResponse(w, http.StatusNotFound, "Not found")
+	}
+}
+
+// handleTemplateItem gets, updates or deletes a template
+//
+//	@Summary		Get, update or delete a prompt template
+//	@Description	GET returns the template with its version history. PUT adds a new version and makes it active. DELETE removes the template. Changes require the X-Admin-Token header. Rate limited to 100 requests per minute per IP address.
+//	@Tags			Templates
+//	@Accept			json
+//	@Produce		json
+//	@Param			id				path		string							true	"Template ID"
+//	@Param			X-Admin-Token	header		string							false	"Admin token (required for PUT and DELETE)"
+//	@Param			request			body		models.PromptTemplateRequest	false	"New version (PUT)"
+//	@Success		200				{object}	models.PromptTemplate			"Template"
+//	@Success		204				"Template deleted"
+//	@Failure		400				{object}	models.ErrorResponse			"Bad request"
+//	@Failure		401				{object}	models.ErrorResponse			"Unauthorized"
+//	@Failure		404				{object}	models.ErrorResponse			"Template not found"
+//	@Failure		500				{object}	models.ErrorResponse			"Internal server error"
+//	@Router			/ai/templates/{id} [get]
+//	@Router			/ai/templates/{id} [put]
+//	@Router			/ai/templates/{id} [delete]
+func (h *AIHandler) handleTemplateItem(w http.ResponseWriter, r *http.Request, id string) {
+	switch r.Method {
+	case http.MethodGet:
+		tmpl, err := h.templates.Get(id)
+		if err != nil {
+			h.sendTemplateError(w, err)
+			return
+		}
+		h.sendJSONResponse(w, http.StatusOK, tmpl)
+
+	case http.MethodPut:
+		if !h.requireAdmin(w, r) {
+			return
+		}
+		var req models.PromptTemplateRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			h.sendErrorResponse(w, http.StatusBadRequest, "Invalid JSON format")
+			return
+		}
+		tmpl, err := h.templates.AddVersion(id, req)
+		if err != nil {
+			h.sendTemplateError(w, err)
+			return
+		}
+		log.Printf("Added version %d of prompt template %s", tmpl.ActiveVersion, id)
+		h.sendJSONResponse(w, http.StatusOK, tmpl)
+
+	case http.MethodDelete:
+		if !h.requireAdmin(w, r) {
+			return
+		}
+		if err := h.templates.Delete(id); err != nil {
+			h.sendTemplateError(w, err)
+			return
+		}
+		log.Printf("Deleted prompt template %s", id)
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		h.sendErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// handleTemplateVersions lists a template's version history
+//
+//	@Summary		List template versions
+//	@Description	Return every version of the template, oldest first. Rate limited to 100 requests per minute per IP address.
+//	@Tags			Templates
+//	@Produce		json
+//	@Param			id	path		string					true	"Template ID"
+//	@Success		200	{array}		models.TemplateVersion	"Versions"
+//	@Failure		404	{object}	models.ErrorResponse	"Template not found"
+//	@Router			/ai/templates/{id}/versions [get]
+func (h *AIHandler) handleTemplateVersions(w http.ResponseWriter, r *http.Request, id string) {
+	if r.Method != http.MethodGet {
+		h.sendErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	tmpl, err := h.templates.Get(id)
+	if err != nil {
+		h.sendTemplateError(w, err)
+		return
+	}
+	h.sendJSONResponse(w, http.StatusOK, tmpl.Versions)
+}
+
+// handleRenderTemplate previews a rendered template
+//
+//	@Summary		Render a template preview
+//	@Description	Render a template version (the active one by default) with the given variables without calling the model. Rate limited to 100 requests per minute per IP address.
+//	@Tags			Templates
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		string							true	"Template ID"
+//	@Param			request	body		models.RenderTemplateRequest	true	"Variables"
+//	@Success		200		{object}	models.RenderTemplateResponse	"Rendered prompt"
+//	@Failure		400		{object}	models.ErrorResponse			"Bad request"
+//	@Failure		404		{object}	models.ErrorResponse			"Template or version not found"
+//	@Router			/ai/templates/{id}/render [post]
+func (h *AIHandler) handleRenderTemplate(w http.ResponseWriter, r *http.Request, id string) {
+	if r.Method != http.MethodPost {
+		h.sendErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	var req models.RenderTemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid JSON format")
+		return
+	}
+
+	prompt, version, err := h.templates.Render(id, req.Version, req.Variables)
+	if err != nil {
+		h.sendTemplateError(w, err)
+		return
+	}
+	h.sendJSONResponse(w, http.StatusOK, models.RenderTemplateResponse{
+		TemplateID: id,
+		Version:    version,
+		Prompt:     prompt,
+		Tokens:     services.EstimateTokens(prompt),
+	})
+}
+
+// handleRollbackTemplate restores a prior template version
+//
+//	@Summary		Roll back a template
+//	@Description	Restore a prior version by copying it forward as a new active version. Requires the X-Admin-Token header. Rate limited to 100 requests per minute per IP address.
+//	@Tags			Templates
+//	@Accept			json
+//	@Produce		json
+//	@Param			id				path		string							true	"Template ID"
+//	@Param			X-Admin-Token	header		string							true	"Admin token"
+//	@Param			request			body		models.RollbackTemplateRequest	true	"Version to restore"
+//	@Success		200				{object}	models.PromptTemplate			"Template"
+//	@Failure		400				{object}	models.ErrorResponse			"Bad request"
+//	@Failure		401				{object}	models.ErrorResponse			"Unauthorized"
+//	@Failure		404				{object}	models.ErrorResponse			"Template or version not found"
+//	@Failure		500				{object}	models.ErrorResponse			"Internal server error"
+//	@Router			/ai/templates/{id}/rollback [post]
+func (h *AIHandler) handleRollbackTemplate(w http.ResponseWriter, r *http.Request, id string) {
+	if r.Method != http.MethodPost {
+		h.sendErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	if !h.requireAdmin(w, r) {
+		return
+	}
+	var req models.RollbackTemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Version <= 0 {
+		h.sendErrorResponse(w, http.StatusBadRequest, "A positive version is required")
+		return
+	}
+
+	tmpl, err := h.templates.Rollback(id, req.Version)
+	if err != nil {
+		h.sendTemplateError(w, err)
+		return
+	}
+	log.Printf("Rolled back prompt template %s to version %d", id, req.Version)
+	h.sendJSONResponse(w, http.StatusOK, tmpl)
+}
+
+// renderRequestTemplate renders a template referenced by an AI request and
+// writes an error response when that fails
+func (h *AIHandler) renderRequestTemplate(w http.ResponseWriter, templateID string, version int, variables map[string]interface{}) (string, bool) {
+	prompt, _, err := h.templates.Render(templateID, version, variables)
+	if err != nil {
+		h.sendTemplateError(w, err)
+		return "", false
+	}
+	if strings.TrimSpace(prompt) == "" {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Template rendered an empty prompt")
+		return "", false
+	}
+	return prompt, true
+}
+
+// sendTemplateError maps template store errors to HTTP responses
+func (h *AIHandler) sendTemplateError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrTemplateNotFound):
+		h.sendErrorResponse(w, http.StatusNotFound, "Template not found")
+	case errors.Is(err, services.ErrVersionNotFound):
+		h.sendErrorResponse(w, http.StatusNotFound, "Template version not found")
+	case errors.Is(err, services.ErrTemplateExists):
+		h.sendErrorResponse(w, http.StatusConflict, "Template already exists")
+	case errors.Is(err, services.ErrInvalidTemplateRequest):
+		h.sendErrorResponse(w, http.StatusBadRequest, strings.TrimPrefix(err.Error(), services.ErrInvalidTemplateRequest.Error()+": "))
+	default:
+		log.Printf("Template store error: %v", err)
+		h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to save template")
+	}
+}
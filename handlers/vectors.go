@@ -0,0 +1,354 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/Ammar0144/ai/models"
+	"github.com/Ammar0144/ai/services"
+)
+
+// HandleVectorCollections lists and creates vector collections
+//
+//	@Summary		List or create vector collections
+//	@Description	GET lists vector collections with their record counts. POST creates a collection with a metric (cosine, dot or l2), optional fixed dimensions and an optional embedding model used for records and queries given as text. Creating requires the X-Admin-Token header. Rate limited to 100 requests per minute per IP address.
+//	@Tags			Vectors
+//	@Accept			json
+//	@Produce		json
+//	@Param			X-Admin-Token	header		string							false	"Admin token (required for POST)"
+//	@Param			request			body		models.CreateCollectionRequest	false	"Collection to create (POST)"
+//	@Success		200				{array}		models.VectorCollection			"Collections"
+//	@Success		201				{object}	models.VectorCollection			"Created collection"
+//	@Failure		400				{object}	models.ErrorResponse			"Bad request"
+//	@Failure		401				{object}	models.ErrorResponse			"Unauthorized"
+//	@Failure		409				{object}	models.ErrorResponse			"Collection already exists"
+//	@Router			/ai/vectors [get]
+//	@Router			/ai/vectors [post]
+func (h *AIHandler) HandleVectorCollections(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.sendJSONResponse(w, http.StatusOK, h.vectors.ListCollections())
+
+	case http.MethodPost:
+		if !h.requireAdmin(w, r) {
+			return
+		}
+		var req models.CreateCollectionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			h.sendErrorResponse(w, http.StatusBadRequest, "Invalid JSON format")
+			return
+		}
+		collection, err := h.vectors.CreateCollection(req)
+		if err != nil {
+			h.sendVectorError(w, err)
+			return
+		}
+		log.Printf("Created vector collection %s (%s)", collection.Name, collection.Metric)
+		h.sendJSONResponse(w, http.StatusCreated, collection)
+
+	default:
+		h.sendErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// HandleVectorCollection routes requests under /ai/vectors/{collection}
+//
+//	@Summary		Get or delete a vector collection
+//	@Description	GET describes the collection. DELETE removes it with its snapshot and requires the X-Admin-Token header. Rate limited to 100 requests per minute per IP address.
+//	@Tags			Vectors
+//	@Produce		json
+//	@Param			collection		path		string					true	"Collection name"
+//	@Param			X-Admin-Token	header		string					false	"Admin token (required for DELETE)"
+//	@Success		200				{object}	models.VectorCollection	"Collection"
+//	@Success		204				"Collection deleted"
+//	@Failure		401				{object}	models.ErrorResponse	"Unauthorized"
+//	@Failure		404				{object}	models.ErrorResponse	"Collection not found"
+//	@Failure		500				{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/ai/vectors/{collection} [get]
+//	@Router			/ai/vectors/{collection} [delete]
+func (h *AIHandler) HandleVectorCollection(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/ai/vectors/"), "/"), "/")
+	name := parts[0]
+	if name == "" {
+		h.HandleVectorCollections(w, r)
+		return
+	}
+
+	switch {
+	case len(parts) == 1:
+	case len(parts) == 2 && parts[1] == "upsert":
+		h.handleVectorUpsert(w, r, name)
+		return
+	case len(parts) == 2 && parts[1] == "query":
+		h.handleVectorQuery(w, r, name)
+		return
+	case len(parts) == 2 && parts[1] == "delete":
+		h.handleVectorDelete(w, r, name)
+		return
+	case len(parts) == 2 && parts[1] == "snapshot":
+		h.handleVectorSnapshot(w, r, name)
+		return
+	case len(parts) == 3 && parts[1] == "records":
+		h.handleVectorRecord(w, r, name, parts[2])
+		return
+	default:
+		h.sendErrorResponse(w, http.StatusNotFound, "Not found")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		collection, err := h.vectors.GetCollection(name)
+		if err != nil {
+			h.sendVectorError(w, err)
+			return
+		}
+		h.sendJSONResponse(w, http.StatusOK, collection)
+
+	case http.MethodDelete:
+		if !h.requireAdmin(w, r) {
+			return
+		}
+		if err := h.vectors.DeleteCollection(name); err != nil {
+			h.sendVectorError(w, err)
+			return
+		}
+		log.Printf("Deleted vector collection %s", name)
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		h.sendErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// handleVectorUpsert inserts or replaces records
+//
+//	@Summary		Upsert vectors
+//	@Description	Insert or replace records by ID. Each record has a vector, or text that is embedded with the collection's embedding model, plus optional metadata for filtering. All records are validated before any is applied. Requires the X-Admin-Token header. Rate limited to 100 requests per minute per IP address.
+//	@Tags			Vectors
+//	@Accept			json
+//	@Produce		json
+//	@Param			collection		path		string							true	"Collection name"
+//	@Param			X-Admin-Token	header		string							true	"Admin token"
+//	@Param			request			body		models.UpsertVectorsRequest		true	"Records"
+//	@Success		200				{object}	models.UpsertVectorsResponse	"Upsert result"
+//	@Failure		400				{object}	models.ErrorResponse			"Bad request"
+//	@Failure		401				{object}	models.ErrorResponse			"Unauthorized"
+//	@Failure		404				{object}	models.ErrorResponse			"Collection not found"
+//	@Router			/ai/vectors/{collection}/upsert [post]
+func (h *AIHandler) handleVectorUpsert(w http.ResponseWriter, r *http.Request, name string) {
+	if r.Method != http.MethodPost {
+		h.sendErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	if !h.requireAdmin(w, r) {
+		return
+	}
+
+	var req models.UpsertVectorsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid JSON format")
+		return
+	}
+	if err := services.CheckUpsertSize(len(req.Records)); err != nil {
+		h.sendVectorError(w, err)
+		return
+	}
+	collection, err := h.vectors.GetCollection(name)
+	if err != nil {
+		h.sendVectorError(w, err)
+		return
+	}
+
+	// Embed records that only have text, in batches of the embedding limit
+	var texts []string
+	var pending []int
+	for i, record := range req.Records {
+		if len(record.Vector) == 0 && strings.TrimSpace(record.Text) != "" {
+			texts = append(texts, record.Text)
+			pending = append(pending, i)
+		}
+	}
+	if len(texts) > 0 {
+		vectors, err := h.aiService.EmbedAll(r.Context(), texts, collection.EmbeddingModel)
+		if err != nil {
+			h.sendEmbeddingError(w, err)
+			return
+		}
+		for j, i := range pending {
+			req.Records[i].Vector = vectors[j]
+		}
+	}
+
+	count, err := h.vectors.Upsert(name, req.Records)
+	if err != nil {
+		h.sendVectorError(w, err)
+		return
+	}
+	log.Printf("Upserted %d records into vector collection %s (%d embedded)", len(req.Records), name, len(texts))
+	h.sendJSONResponse(w, http.StatusOK, models.UpsertVectorsResponse{Upserted: len(req.Records), Count: count})
+}
+
+// handleVectorQuery searches a collection
+//
+//	@Summary		Query vectors
+//	@Description	Return the top_k records closest to a vector, or to text embedded with the collection's embedding model, optionally restricted by a metadata filter ({"field": value} or operators $eq, $ne, $in, $nin, $gt, $gte, $lt, $lte, $exists, $and, $or). Large collections are searched with an HNSW index; small collections, selective filters and exact=true use an exact scan. Rate limited to 100 requests per minute per IP address.
+//	@Tags			Vectors
+//	@Accept			json
+//	@Produce		json
+//	@Param			collection	path		string						true	"Collection name"
+//	@Param			request		body		models.VectorQueryRequest	true	"Query"
+//	@Success		200			{object}	models.VectorQueryResponse	"Matches, closest first"
+//	@Failure		400			{object}	models.ErrorResponse		"Bad request"
+//	@Failure		404			{object}	models.ErrorResponse		"Collection not found"
+//	@Router			/ai/vectors/{collection}/query [post]
+func (h *AIHandler) handleVectorQuery(w http.ResponseWriter, r *http.Request, name string) {
+	if r.Method != http.MethodPost {
+		h.sendErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var req models.VectorQueryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid JSON format")
+		return
+	}
+	if len(req.Vector) > 0 && req.Text != "" {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Set either vector or text, not both")
+		return
+	}
+
+	if len(req.Vector) == 0 && strings.TrimSpace(req.Text) != "" {
+		collection, err := h.vectors.GetCollection(name)
+		if err != nil {
+			h.sendVectorError(w, err)
+			return
+		}
+		result, err := h.aiService.Embed(r.Context(), []string{req.Text}, collection.EmbeddingModel)
+		if err != nil {
+			h.sendEmbeddingError(w, err)
+			return
+		}
+		req.Vector = result.Vectors[0]
+	}
+
+	response, err := h.vectors.Query(name, req)
+	if err != nil {
+		h.sendVectorError(w, err)
+		return
+	}
+	h.sendJSONResponse(w, http.StatusOK, response)
+}
+
+// handleVectorDelete deletes records
+//
+//	@Summary		Delete vectors
+//	@Description	Delete records by ID and/or by metadata filter. Requires the X-Admin-Token header. Rate limited to 100 requests per minute per IP address.
+//	@Tags			Vectors
+//	@Accept			json
+//	@Produce		json
+//	@Param			collection		path		string							true	"Collection name"
+//	@Param			X-Admin-Token	header		string							true	"Admin token"
+//	@Param			request			body		models.DeleteVectorsRequest		true	"IDs and/or filter"
+//	@Success		200				{object}	models.DeleteVectorsResponse	"Delete result"
+//	@Failure		400				{object}	models.ErrorResponse			"Bad request"
+//	@Failure		401				{object}	models.ErrorResponse			"Unauthorized"
+//	@Failure		404				{object}	models.ErrorResponse			"Collection not found"
+//	@Router			/ai/vectors/{collection}/delete [post]
+func (h *AIHandler) handleVectorDelete(w http.ResponseWriter, r *http.Request, name string) {
+	if r.Method != http.MethodPost {
+		h.sendErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	if !h.requireAdmin(w, r) {
+		return
+	}
+
+	var req models.DeleteVectorsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, "Invalid JSON format")
+		return
+	}
+	deleted, count, err := h.vectors.Delete(name, req.IDs, req.Filter)
+	if err != nil {
+		h.sendVectorError(w, err)
+		return
+	}
+	log.Printf("Deleted %d records from vector collection %s", deleted, name)
+	h.sendJSONResponse(w, http.StatusOK, models.DeleteVectorsResponse{Deleted: deleted, Count: count})
+}
+
+// handleVectorRecord returns one record
+//
+//	@Summary		Get a vector record
+//	@Description	Return a record with its vector, text and metadata. Rate limited to 100 requests per minute per IP address.
+//	@Tags			Vectors
+//	@Produce		json
+//	@Param			collection	path		string					true	"Collection name"
+//	@Param			id			path		string					true	"Record ID"
+//	@Success		200			{object}	models.VectorRecord		"Record"
+//	@Failure		404			{object}	models.ErrorResponse	"Collection or record not found"
+//	@Router			/ai/vectors/{collection}/records/{id} [get]
+func (h *AIHandler) handleVectorRecord(w http.ResponseWriter, r *http.Request, name, id string) {
+	if r.Method != http.MethodGet {
+		h.sendErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	record, err := h.vectors.GetRecord(name, id)
+	if err != nil {
+		h.sendVectorError(w, err)
+		return
+	}
+	h.sendJSONResponse(w, http.StatusOK, record)
+}
+
+// handleVectorSnapshot writes a collection snapshot immediately
+//
+//	@Summary		Snapshot a vector collection
+//	@Description	Write the collection to AI_VECTOR_DIR now instead of waiting for the periodic snapshot. Requires the X-Admin-Token header. Rate limited to 100 requests per minute per IP address.
+//	@Tags			Vectors
+//	@Produce		json
+//	@Param			collection		path		string					true	"Collection name"
+//	@Param			X-Admin-Token	header		string					true	"Admin token"
+//	@Success		200				{object}	models.VectorCollection	"Collection with snapshot time"
+//	@Failure		400				{object}	models.ErrorResponse	"Snapshots disabled"
+//	@Failure		401				{object}	models.ErrorResponse	"Unauthorized"
+//	@Failure		404				{object}	models.ErrorResponse	"Collection not found"
+//	@Failure		500				{object}	models.ErrorResponse	"Internal server error"
+//	@Router			/ai/vectors/{collection}/snapshot [post]
+func (h *AIHandler) handleVectorSnapshot(w http.ResponseWriter, r *http.Request, name string) {
+	if r.Method != http.MethodPost {
+		h.sendErrorResponse(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	if !h.requireAdmin(w, r) {
+		return
+	}
+	collection, err := h.vectors.Snapshot(name)
+	if err != nil {
+		h.sendVectorError(w, err)
+		return
+	}
+	log.Printf("Snapshotted vector collection %s with %d records", name, collection.Count)
+	h.sendJSONResponse(w, http.StatusOK, collection)
+}
+
+// sendVectorError maps vector store errors to HTTP responses
+func (h *AIHandler) sendVectorError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrCollectionNotFound):
+		h.sendErrorResponse(w, http.StatusNotFound, "Collection not found")
+	case errors.Is(err, services.ErrRecordNotFound):
+		h.sendErrorResponse(w, http.StatusNotFound, "Record not found")
+	case errors.Is(err, services.ErrCollectionExists):
+		h.sendErrorResponse(w, http.StatusConflict, "Collection already exists")
+	case errors.Is(err, services.ErrInvalidVectorRequest):
+		h.sendErrorResponse(w, http.StatusBadRequest, strings.TrimPrefix(err.Error(), services.ErrInvalidVectorRequest.Error()+": "))
+	default:
+		log.Printf("Vector store error: %v", err)
+		h.sendErrorResponse(w, http.StatusInternalServerError, "Failed to process vector request")
+	}
+}
@@ -45,10 +45,19 @@ var rateLimiter = &RateLimiter{
 
 // Rate limiting middleware
 func rateLimitMiddleware(requestsPerMinute int) func(http.HandlerFunc) http.HandlerFunc {
+	return scopedRateLimitMiddleware("", requestsPerMinute)
+}
+
+// Rate limiting middleware with its own bucket per client, so an endpoint's
+// limit is not shared with the other endpoints
+func scopedRateLimitMiddleware(scope string, requestsPerMinute int) func(http.HandlerFunc) http.HandlerFunc {
 	return func(next http.HandlerFunc) http.HandlerFunc {
 		return func(w http.ResponseWriter, r *http.Request) {
 			// Get client IP
 			clientIP := getClientIP(r)
+			if scope != "" {
+				clientIP = scope + "|" + clientIP
+			}
 
 			// Check rate limit
 			if !isAllowed(clientIP, requestsPerMinute) {
@@ -59,8 +68,8 @@ func rateLimitMiddleware(requestsPerMinute int) func(http.HandlerFunc) http.Hand
 
 				// Set CORS headers for rate limit response
 				w.Header().Set("Access-Control-Allow-Origin", "*")
-				w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-				w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+				w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+				w.Header().Set("Access-Control-Allow-Headers", "Content-Type, X-Admin-Token, X-User-ID, X-AI-Dry-Run, X-Session-ID")
 
 				http.Error(w, `{"error":"Rate limit exceeded","message":"Too many requests. Please try again later.","code":429}`, http.StatusTooManyRequests)
 				return
@@ -200,8 +209,9 @@ func corsMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Set CORS headers
 		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, X-Admin-Token, X-User-ID, X-AI-Dry-Run, X-Session-ID")
+		w.Header().Set("Access-Control-Expose-Headers", "X-Response-ID")
 
 		// Handle preflight requests
 		if r.Method == "OPTIONS" {
@@ -218,6 +228,11 @@ func protectedHandler(handler http.HandlerFunc, rateLimit int) http.HandlerFunc
 	return corsMiddleware(rateLimitMiddleware(rateLimit)(handler))
 }
 
+// Combined middleware with a rate limit bucket of its own
+func scopedProtectedHandler(handler http.HandlerFunc, rateLimit int, scope string) http.HandlerFunc {
+	return corsMiddleware(scopedRateLimitMiddleware(scope, rateLimit)(handler))
+}
+
 func main() {
 	// Get port from environment variable or use default
 	port := "8081"
@@ -233,15 +248,62 @@ func main() {
 	http.HandleFunc("/ai/chat/completions", protectedHandler(aiHandler.HandleChatCompletion, 30))
 	http.HandleFunc("/ai/complete", protectedHandler(aiHandler.HandleComplete, 30))
 	http.HandleFunc("/ai/generate", protectedHandler(aiHandler.HandleGenerate, 30))
+	http.HandleFunc("/ai/agent", protectedHandler(aiHandler.HandleAgent, 30))
+	http.HandleFunc("/ai/chains", protectedHandler(aiHandler.HandleChains, 30))
+	http.HandleFunc("/ai/embeddings", protectedHandler(aiHandler.HandleEmbeddings, 30))
+	http.HandleFunc("/ai/rag/chat", protectedHandler(aiHandler.HandleRAGChat, 30))
+	http.HandleFunc("/ai/ingest", protectedHandler(aiHandler.HandleIngest, 30))
+	http.HandleFunc("/ai/summarize", protectedHandler(aiHandler.HandleSummarize, 30))
+	http.HandleFunc("/ai/qa", protectedHandler(aiHandler.HandleQA, 30))
+	http.HandleFunc("/ai/classify", protectedHandler(aiHandler.HandleClassify, 30))
+	http.HandleFunc("/ai/extract", protectedHandler(aiHandler.HandleExtract, 30))
+	http.HandleFunc("/ai/fim", protectedHandler(aiHandler.HandleFIM, 30))
+	http.HandleFunc("/ai/compare", protectedHandler(aiHandler.HandleCompare, 30))
+	// Typeahead sends a request per keystroke, so it has a larger bucket of its own
+	http.HandleFunc("/ai/autocomplete", scopedProtectedHandler(aiHandler.HandleAutocomplete, 300, "autocomplete"))
 	http.HandleFunc("/ai/model-info", protectedHandler(aiHandler.HandleModelInfo, 100)) // Less intensive
 
+	// Stored conversations, scoped by user
+	http.HandleFunc("/ai/conversations", protectedHandler(aiHandler.HandleConversations, 100))
+	// Regenerating calls the model, so it gets the AI endpoints' limit
+	conversationHandler := protectedHandler(aiHandler.HandleConversation, 100)
+	generatingConversationHandler := protectedHandler(aiHandler.HandleConversation, 30)
+	http.HandleFunc("/ai/conversations/", func(w http.ResponseWriter, r *http.Request) {
+		if handlers.ConversationGenerates(w, r) {
+			generatingConversationHandler(w, r)
+			return
+		}
+		conversationHandler(w, r)
+	})
+
+	// Admin-managed data: changes require X-Admin-Token
+	http.HandleFunc("/ai/agent/knowledge", protectedHandler(aiHandler.HandleAgentKnowledge, 100))
+	http.HandleFunc("/ai/templates", protectedHandler(aiHandler.HandleTemplates, 100))
+	http.HandleFunc("/ai/templates/", protectedHandler(aiHandler.HandleTemplate, 100))
+	http.HandleFunc("/ai/examples", protectedHandler(aiHandler.HandleExamples, 100))
+	http.HandleFunc("/ai/examples/", protectedHandler(aiHandler.HandleExample, 100))
+	http.HandleFunc("/ai/chains/definitions", protectedHandler(aiHandler.HandleChainDefinitions, 100))
+	http.HandleFunc("/ai/chains/definitions/", protectedHandler(aiHandler.HandleChainDefinition, 100))
+	http.HandleFunc("/ai/vectors", protectedHandler(aiHandler.HandleVectorCollections, 100))
+	http.HandleFunc("/ai/vectors/", protectedHandler(aiHandler.HandleVectorCollection, 100))
+	http.HandleFunc("/ai/rag/collections", protectedHandler(aiHandler.HandleRAGCollections, 100))
+	http.HandleFunc("/ai/rag/collections/", protectedHandler(aiHandler.HandleRAGCollection, 100))
+	http.HandleFunc("/ai/feedback", protectedHandler(aiHandler.HandleFeedback, 100))
+	http.HandleFunc("/ai/feedback/export", protectedHandler(aiHandler.HandleFeedbackExport, 100))
+	http.HandleFunc("/ai/responses/", protectedHandler(aiHandler.HandleResponse, 100))
+
+	// Config-defined task endpoints, each with its own rate limit
+	http.HandleFunc("/ai/tasks", protectedHandler(aiHandler.HandleTasks, 100))
+	http.HandleFunc("/ai/tasks/", protectedHandler(aiHandler.HandleTask, 100))
+	for _, task := range aiHandler.Tasks() {
+		http.HandleFunc(handlers.TaskPath(task.Name), scopedProtectedHandler(aiHandler.HandleTask, task.RateLimit, "task:"+task.Name))
+	}
+
 	// Health endpoint: Higher limit for monitoring
 	http.HandleFunc("/health", protectedHandler(aiHandler.HandleHealth, 200))
 
 	// Swagger JSON spec endpoint
-	http.HandleFunc("/swagger/doc.json", func(w http.ResponseWriter, r *http.Request) {
-		http.ServeFile(w, r, "docs/swagger.json")
-	})
+	http.HandleFunc("/swagger/doc.json", aiHandler.HandleSwaggerSpec)
 
 	// Swagger documentation UI
 	http.HandleFunc("/swagger/", httpSwagger.Handler(
@@ -262,8 +324,8 @@ func main() {
 		if r.URL.Path == "/" {
 			// Set CORS headers for root
 			w.Header().Set("Access-Control-Allow-Origin", "*")
-			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-			w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, X-Admin-Token, X-User-ID, X-AI-Dry-Run, X-Session-ID")
 
 			if r.Method == "OPTIONS" {
 				w.WriteHeader(http.StatusOK)
@@ -286,6 +348,27 @@ func main() {
 					"chat_completions": "/ai/chat/completions",
 					"complete": "/ai/complete",
 					"generate": "/ai/generate",
+					"agent": "/ai/agent",
+					"agent_knowledge": "/ai/agent/knowledge",
+					"chains": "/ai/chains",
+					"embeddings": "/ai/embeddings",
+					"rag_chat": "/ai/rag/chat",
+					"rag_collections": "/ai/rag/collections",
+					"ingest": "/ai/ingest",
+					"summarize": "/ai/summarize",
+					"qa": "/ai/qa",
+					"classify": "/ai/classify",
+					"extract": "/ai/extract",
+					"fim": "/ai/fim",
+					"autocomplete": "/ai/autocomplete",
+					"compare": "/ai/compare",
+					"feedback": "/ai/feedback",
+					"responses": "/ai/responses/{id}",
+					"conversations": "/ai/conversations",
+					"templates": "/ai/templates",
+					"tasks": "/ai/tasks",
+					"examples": "/ai/examples",
+					"vectors": "/ai/vectors",
 					"model_info": "/ai/model-info"
 				}
 			}`))
@@ -320,6 +403,29 @@ func main() {
 	log.Printf("  - Chat Completions: http://localhost:%s/ai/chat/completions", port)
 	log.Printf("  - Text Completion: http://localhost:%s/ai/complete", port)
 	log.Printf("  - Text Generation: http://localhost:%s/ai/generate", port)
+	log.Printf("  - Agent: http://localhost:%s/ai/agent", port)
+	log.Printf("  - Prompt Chains: http://localhost:%s/ai/chains", port)
+	log.Printf("  - Embeddings: http://localhost:%s/ai/embeddings", port)
+	log.Printf("  - RAG Chat: http://localhost:%s/ai/rag/chat", port)
+	log.Printf("  - RAG Collections: http://localhost:%s/ai/rag/collections", port)
+	log.Printf("  - Document Ingestion: http://localhost:%s/ai/ingest", port)
+	log.Printf("  - Summarization: http://localhost:%s/ai/summarize", port)
+	log.Printf("  - Question answering: http://localhost:%s/ai/qa", port)
+	log.Printf("  - Classification: http://localhost:%s/ai/classify", port)
+	log.Printf("  - Extraction: http://localhost:%s/ai/extract", port)
+	log.Printf("  - Code completion (FIM): http://localhost:%s/ai/fim", port)
+	log.Printf("  - Autocomplete: http://localhost:%s/ai/autocomplete", port)
+	log.Printf("  - Model comparison: http://localhost:%s/ai/compare", port)
+	log.Printf("  - Feedback: http://localhost:%s/ai/feedback", port)
+	log.Printf("  - Stored responses: http://localhost:%s/ai/responses/{id}", port)
+	log.Printf("  - Conversations: http://localhost:%s/ai/conversations", port)
+	log.Printf("  - Prompt Templates: http://localhost:%s/ai/templates", port)
+	log.Printf("  - Few-shot Examples: http://localhost:%s/ai/examples", port)
+	log.Printf("  - Vector Store: http://localhost:%s/ai/vectors", port)
+	log.Printf("  - Tasks: http://localhost:%s/ai/tasks", port)
+	for _, task := range aiHandler.Tasks() {
+		log.Printf("    - %s: http://localhost:%s%s (%d requests/minute per IP)", task.Name, port, handlers.TaskPath(task.Name), task.RateLimit)
+	}
 	log.Printf("  - Model Information: http://localhost:%s/ai/model-info", port)
 
 	if err := http.ListenAndServe(":"+port, nil); err != nil {
@@ -0,0 +1,54 @@
+package models
+
+import "time"
+
+// Agent step types
+const (
+	AgentStepToolCall = "tool_call"
+	AgentStepFinal    = "final"
+	AgentStepError    = "error"
+)
+
+// AgentRequest represents a request to the server-side agent loop
+type AgentRequest struct {
+	Messages       []ChatMessage `json:"messages,omitempty"`
+	Prompt         string        `json:"prompt,omitempty"`
+	Tools          []string      `json:"tools,omitempty"`
+	MaxSteps       int           `json:"max_steps,omitempty"`
+	TimeoutSeconds int           `json:"timeout_seconds,omitempty"`
+	MaxTokens      int           `json:"max_tokens,omitempty"`
+	Temperature    float64       `json:"temperature,omitempty"`
+	UserID         string        `json:"user_id,omitempty"`
+}
+
+// AgentStep is a single entry in the agent's execution trace
+type AgentStep struct {
+	Index      int    `json:"index"`
+	Type       string `json:"type"`
+	Thought    string `json:"thought,omitempty"`
+	Tool       string `json:"tool,omitempty"`
+	Arguments  string `json:"arguments,omitempty"`
+	Output     string `json:"output,omitempty"`
+	Error      string `json:"error,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
+// AgentResponse represents the result of an agent run
+type AgentResponse struct {
+	// ID refers to the response in /ai/feedback
+	ID         string      `json:"id"`
+	Response   string      `json:"response"`
+	StopReason string      `json:"stop_reason"`
+	Steps      []AgentStep `json:"steps"`
+	DurationMs int64       `json:"duration_ms"`
+	UserID     string      `json:"user_id,omitempty"`
+	Timestamp  time.Time   `json:"timestamp"`
+	Model      string      `json:"model,omitempty"`
+}
+
+// KnowledgeEntry is a static fact available to the agent's knowledge_lookup tool
+type KnowledgeEntry struct {
+	Key       string    `json:"key"`
+	Value     string    `json:"value"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// Autocomplete suggestion sources
+const (
+	AutocompleteUpstream  = "upstream"  // A new model call
+	AutocompleteCoalesced = "coalesced" // Shared with an identical in-flight request
+	AutocompleteCache     = "cache"     // The rest of an earlier suggestion the prefix extends
+)
+
+// AutocompleteRequest asks for a short continuation of typed text
+type AutocompleteRequest struct {
+	Prefix string `json:"prefix"`
+	// SessionID lets a newer request from the session cancel this one; the X-Session-ID header takes precedence
+	SessionID string `json:"session_id,omitempty"`
+	// MaxTokens defaults to 8, at most 16
+	MaxTokens int `json:"max_tokens,omitempty"`
+	// Temperature defaults to 0.2
+	Temperature float64 `json:"temperature,omitempty"`
+	UserID      string  `json:"user_id,omitempty"`
+}
+
+// AutocompleteResponse is the suggested continuation
+type AutocompleteResponse struct {
+	// ID refers to the response in /ai/feedback
+	ID         string `json:"id"`
+	Suggestion string `json:"suggestion"`
+	// Source is upstream, coalesced or cache
+	Source     string    `json:"source"`
+	SessionID  string    `json:"session_id,omitempty"`
+	DurationMs int64     `json:"duration_ms"`
+	UserID     string    `json:"user_id,omitempty"`
+	Timestamp  time.Time `json:"timestamp"`
+	Model      string    `json:"model"`
+}
@@ -0,0 +1,74 @@
+package models
+
+import "time"
+
+// Chain step statuses
+const (
+	ChainStepSucceeded = "succeeded"
+	ChainStepFailed    = "failed"
+	ChainStepSkipped   = "skipped"
+)
+
+// ChainStep is one model call in a chain. Its prompt is a Go text/template
+// over {{.input}} and the outputs of earlier steps, {{.steps.<id>}}.
+type ChainStep struct {
+	ID string `json:"id"`
+	// Upstream is complete (default), generate or chat
+	Upstream string `json:"upstream,omitempty"`
+	Prompt   string `json:"prompt"`
+	// System is the system message for the chat upstream
+	System string `json:"system,omitempty"`
+	// DependsOn lists dependencies besides the steps the prompt references
+	DependsOn   []string `json:"depends_on,omitempty"`
+	MaxTokens   int      `json:"max_tokens,omitempty"`
+	Temperature float64  `json:"temperature,omitempty"`
+}
+
+// ChainDefinition is a DAG of steps
+type ChainDefinition struct {
+	Name        string      `json:"name,omitempty"`
+	Description string      `json:"description,omitempty"`
+	Steps       []ChainStep `json:"steps"`
+	// Output is the step whose output is the chain result, default the last step
+	Output    string    `json:"output,omitempty"`
+	CreatedAt time.Time `json:"created_at,omitempty"`
+	UpdatedAt time.Time `json:"updated_at,omitempty"`
+}
+
+// ChainRequest runs a stored chain by name or an inline chain definition
+type ChainRequest struct {
+	Name           string                 `json:"name,omitempty"`
+	Chain          *ChainDefinition       `json:"chain,omitempty"`
+	Input          map[string]interface{} `json:"input,omitempty" swaggertype:"object"`
+	TimeoutSeconds int                    `json:"timeout_seconds,omitempty"`
+	UserID         string                 `json:"user_id,omitempty"`
+}
+
+// ChainStepResult reports the outcome of one step
+type ChainStepResult struct {
+	ID string `json:"id"`
+	// Status is succeeded, failed or skipped
+	Status    string   `json:"status"`
+	DependsOn []string `json:"depends_on,omitempty"`
+	Prompt    string   `json:"prompt,omitempty"`
+	Output    string   `json:"output,omitempty"`
+	Error     string   `json:"error,omitempty"`
+	// StartedMs is the offset from the start of the chain
+	StartedMs  int64 `json:"started_ms"`
+	DurationMs int64 `json:"duration_ms"`
+}
+
+// ChainResponse is the result of running a chain
+type ChainResponse struct {
+	// ID refers to the response in /ai/feedback
+	ID    string `json:"id"`
+	Chain string `json:"chain,omitempty"`
+	// Status is succeeded when the output step succeeded
+	Status     string            `json:"status"`
+	Output     string            `json:"output"`
+	Steps      []ChainStepResult `json:"steps"`
+	DurationMs int64             `json:"duration_ms"`
+	UserID     string            `json:"user_id,omitempty"`
+	Timestamp  time.Time         `json:"timestamp"`
+	Model      string            `json:"model"`
+}
@@ -0,0 +1,60 @@
+package models
+
+import "time"
+
+// Classification scoring methods
+const (
+	ClassifyLogprobs = "logprobs"
+	ClassifyVoting   = "voting"
+)
+
+// ClassifyLabel is a candidate label. The description, when given, is shown
+// to the model to disambiguate similar labels.
+type ClassifyLabel struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+// ClassifyRequest scores a text against candidate labels
+type ClassifyRequest struct {
+	Text   string          `json:"text"`
+	Labels []ClassifyLabel `json:"labels"`
+	// MultiLabel scores each label independently instead of choosing one
+	MultiLabel bool `json:"multi_label,omitempty"`
+	// Threshold is the minimum score to select a label; default 0.5 with multi_label, otherwise 0
+	Threshold float64 `json:"threshold,omitempty"`
+	// Votes is the number of samples drawn when voting, default 5
+	Votes       int     `json:"votes,omitempty"`
+	Temperature float64 `json:"temperature,omitempty"`
+	UserID      string  `json:"user_id,omitempty"`
+	DryRun      bool    `json:"dry_run,omitempty"`
+}
+
+// ClassifyScore is the probability of one label
+type ClassifyScore struct {
+	Label string  `json:"label"`
+	Score float64 `json:"score"`
+	// Votes counts the samples that chose the label, when voting
+	Votes    int  `json:"votes,omitempty"`
+	Selected bool `json:"selected"`
+}
+
+// ClassifyResponse holds label scores, highest first. With a single label,
+// scores sum to at most 1; with multi_label each is independent.
+type ClassifyResponse struct {
+	// ID refers to the response in /ai/feedback
+	ID         string          `json:"id"`
+	Labels     []ClassifyScore `json:"labels"`
+	Selected   []string        `json:"selected"`
+	MultiLabel bool            `json:"multi_label"`
+	Threshold  float64         `json:"threshold"`
+	// Method is logprobs or voting
+	Method string `json:"method"`
+	// Calls counts the model calls made
+	Calls int `json:"calls"`
+	// Abstained counts the votes that named no candidate label
+	Abstained int       `json:"abstained,omitempty"`
+	UserID    string    `json:"user_id,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+	Model     string    `json:"model"`
+}
@@ -0,0 +1,64 @@
+package models
+
+import "time"
+
+// Comparison modes, matching the upstream endpoint each backend is called on
+const (
+	CompareComplete = "complete"
+	CompareGenerate = "generate"
+	CompareChat     = "chat"
+)
+
+// CompareRequest sends one request to several backends
+type CompareRequest struct {
+	// Mode is complete (default), generate or chat
+	Mode   string `json:"mode,omitempty"`
+	Prompt string `json:"prompt,omitempty"`
+	// Messages replace prompt in chat mode
+	Messages []ChatMessage `json:"messages,omitempty"`
+	// Backends lists at least two backends, default all configured ones
+	Backends []string `json:"backends,omitempty"`
+	// MaxTokens is at most 1024
+	MaxTokens   int     `json:"max_tokens,omitempty"`
+	Temperature float64 `json:"temperature,omitempty"`
+	// Diff includes a word diff for each pair of outputs
+	Diff   bool   `json:"diff,omitempty"`
+	UserID string `json:"user_id,omitempty"`
+}
+
+// CompareResult is the output of one backend
+type CompareResult struct {
+	Backend   string `json:"backend"`
+	Model     string `json:"model"`
+	Output    string `json:"output,omitempty"`
+	LatencyMs int64  `json:"latency_ms"`
+	// Usage is estimated with the gateway's tokenizer so that backends are counted alike
+	Usage *TokenUsage `json:"usage,omitempty"`
+	Error string      `json:"error,omitempty"`
+}
+
+// ComparePair compares the outputs of two backends
+type ComparePair struct {
+	A string `json:"a"`
+	B string `json:"b"`
+	// Jaccard is the overlap of the distinct words
+	Jaccard float64 `json:"jaccard"`
+	// LCSRatio is the longest common word subsequence, F1 over both lengths (ROUGE-L)
+	LCSRatio float64 `json:"lcs_ratio"`
+	// LengthRatio is the shorter output's word count over the longer one's
+	LengthRatio float64 `json:"length_ratio"`
+	Diff        string  `json:"diff,omitempty"`
+}
+
+// CompareResponse holds each backend's output in request order and a
+// comparison of every pair of backends that succeeded
+type CompareResponse struct {
+	// ID refers to the response in /ai/feedback
+	ID         string          `json:"id"`
+	Mode       string          `json:"mode"`
+	Results    []CompareResult `json:"results"`
+	Pairs      []ComparePair   `json:"pairs"`
+	DurationMs int64           `json:"duration_ms"`
+	UserID     string          `json:"user_id,omitempty"`
+	Timestamp  time.Time       `json:"timestamp"`
+}
@@ -0,0 +1,92 @@
+package models
+
+import "time"
+
+// Conversation is a stored chat history owned by a single user. Messages form
+// a tree: editing or regenerating a message adds a sibling branch. Messages
+// always holds the active branch as a linear list.
+type Conversation struct {
+	ID           string         `json:"id"`
+	UserID       string         `json:"user_id"`
+	Title        string         `json:"title,omitempty"`
+	Messages     []ChatMessage  `json:"messages"`
+	Nodes        []MessageNode  `json:"nodes,omitempty"`
+	ActiveLeafID string         `json:"active_leaf_id,omitempty"`
+	Summary      *MemorySummary `json:"summary,omitempty"`
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+}
+
+// MemorySummary is the cached rolling summary of a conversation's older turns
+type MemorySummary struct {
+	Content string `json:"content"`
+	// CoveredNodeID is the last message node folded into the summary
+	CoveredNodeID   string    `json:"covered_node_id"`
+	CoveredMessages int       `json:"covered_messages"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// MessageNode is a message in the conversation tree
+type MessageNode struct {
+	ID        string      `json:"id"`
+	ParentID  string      `json:"parent_id,omitempty"`
+	Message   ChatMessage `json:"message"`
+	CreatedAt time.Time   `json:"created_at"`
+}
+
+// ConversationBranch describes a leaf of the conversation tree
+type ConversationBranch struct {
+	LeafID       string    `json:"leaf_id"`
+	Depth        int       `json:"depth"`
+	LastRole     string    `json:"last_role"`
+	Preview      string    `json:"preview"`
+	Active       bool      `json:"active"`
+	UpdatedAt    time.Time `json:"updated_at"`
+	DivergesFrom string    `json:"diverges_from,omitempty"`
+}
+
+// ConversationPath is a linear message list from the root to a node
+type ConversationPath struct {
+	ConversationID string        `json:"conversation_id"`
+	LeafID         string        `json:"leaf_id"`
+	NodeIDs        []string      `json:"node_ids"`
+	Messages       []ChatMessage `json:"messages"`
+	// ResponseID identifies a newly generated reply for feedback; it is also
+	// sent in the X-Response-ID header
+	ResponseID string `json:"response_id,omitempty"`
+}
+
+// EditMessageRequest edits a message by creating a sibling branch
+type EditMessageRequest struct {
+	Content     string  `json:"content"`
+	Regenerate  bool    `json:"regenerate,omitempty"`
+	MaxTokens   int     `json:"max_tokens,omitempty"`
+	Temperature float64 `json:"temperature,omitempty"`
+}
+
+// RegenerateRequest regenerates an assistant reply as a sibling branch
+type RegenerateRequest struct {
+	MaxTokens   int     `json:"max_tokens,omitempty"`
+	Temperature float64 `json:"temperature,omitempty"`
+}
+
+// SwitchBranchRequest makes the branch containing a node active
+type SwitchBranchRequest struct {
+	NodeID string `json:"node_id"`
+}
+
+// ConversationInfo is the listing view of a conversation
+type ConversationInfo struct {
+	ID           string    `json:"id"`
+	Title        string    `json:"title,omitempty"`
+	MessageCount int       `json:"message_count"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// CreateConversationRequest represents a request to create a conversation
+type CreateConversationRequest struct {
+	Title    string        `json:"title,omitempty"`
+	Messages []ChatMessage `json:"messages,omitempty"`
+	UserID   string        `json:"user_id,omitempty"`
+}
@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// DryRunResponse describes the upstream request an AI endpoint would send,
+// returned instead of calling the model when dry_run or X-AI-Dry-Run is set
+type DryRunResponse struct {
+	DryRun   bool   `json:"dry_run"`
+	Method   string `json:"method"`
+	URL      string `json:"url"`
+	Backend  string `json:"backend"`
+	Endpoint string `json:"endpoint"`
+	Model    string `json:"model"`
+	// Body is the exact JSON body that would be sent
+	Body        interface{}   `json:"body" swaggertype:"object"`
+	Prompt      string        `json:"prompt,omitempty"`
+	Messages    []ChatMessage `json:"messages,omitempty"`
+	MaxTokens   int           `json:"max_tokens"`
+	Temperature float64       `json:"temperature"`
+	// PromptTokens is estimated
+	PromptTokens int `json:"prompt_tokens"`
+	// ContextTokens is the model context window
+	ContextTokens int `json:"context_tokens"`
+	// FitsContext reports whether the prompt plus max_tokens fit the context window
+	FitsContext bool `json:"fits_context"`
+	// Routing lists the decisions that shaped the request, in order
+	Routing   []string  `json:"routing"`
+	Timestamp time.Time `json:"timestamp"`
+}
@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+// EmbeddingRequest asks for vectors for one or more texts
+type EmbeddingRequest struct {
+	// Input is a string or an array of strings
+	Input interface{} `json:"input" swaggertype:"array,string"`
+	// Model can be set to local-hashing-v1 to force the built-in embedder
+	Model  string `json:"model,omitempty"`
+	UserID string `json:"user_id,omitempty"`
+}
+
+// Embedding is the vector for one input
+type Embedding struct {
+	Object    string    `json:"object"`
+	Index     int       `json:"index"`
+	Embedding []float64 `json:"embedding"`
+}
+
+// EmbeddingUsage reports the work done for an embeddings request
+type EmbeddingUsage struct {
+	// PromptTokens is estimated
+	PromptTokens int `json:"prompt_tokens"`
+	TotalTokens  int `json:"total_tokens"`
+	// CachedInputs counts the inputs served from the cache
+	CachedInputs int `json:"cached_inputs"`
+}
+
+// EmbeddingResponse holds the vectors in input order
+type EmbeddingResponse struct {
+	Object string      `json:"object"`
+	Data   []Embedding `json:"data"`
+	Model  string      `json:"model"`
+	// Backend is upstream or local
+	Backend    string         `json:"backend"`
+	Dimensions int            `json:"dimensions"`
+	Usage      EmbeddingUsage `json:"usage"`
+	UserID     string         `json:"user_id,omitempty"`
+	Timestamp  time.Time      `json:"timestamp"`
+}
@@ -0,0 +1,69 @@
+package models
+
+import "time"
+
+// EvalCase is one line of an evaluation dataset
+type EvalCase struct {
+	ID string `json:"id"`
+	// Mode is complete (default), generate or chat
+	Mode   string `json:"mode,omitempty"`
+	Prompt string `json:"prompt,omitempty"`
+	// Messages replace prompt in chat mode
+	Messages    []ChatMessage `json:"messages,omitempty"`
+	Expected    string        `json:"expected"`
+	MaxTokens   int           `json:"max_tokens,omitempty"`
+	Temperature float64       `json:"temperature,omitempty"`
+}
+
+// EvalCaseResult is the output and scores of one case
+type EvalCaseResult struct {
+	ID         string  `json:"id"`
+	Output     string  `json:"output,omitempty"`
+	Expected   string  `json:"expected"`
+	LatencyMs  int64   `json:"latency_ms"`
+	Error      string  `json:"error,omitempty"`
+	ExactMatch bool    `json:"exact_match"`
+	TokenF1    float64 `json:"token_f1"`
+	RougeL     float64 `json:"rouge_l"`
+	// BLEU is smoothed sentence BLEU-4
+	BLEU float64 `json:"bleu"`
+}
+
+// EvalMetrics aggregates a run. Quality scores average over every case,
+// with failed cases scoring zero; BLEU is corpus BLEU-4 over the successful
+// cases. Latency percentiles cover the successful cases.
+type EvalMetrics struct {
+	Cases        int     `json:"cases"`
+	Failures     int     `json:"failures"`
+	FailureRate  float64 `json:"failure_rate"`
+	ExactMatch   float64 `json:"exact_match"`
+	TokenF1      float64 `json:"token_f1"`
+	RougeL       float64 `json:"rouge_l"`
+	BLEU         float64 `json:"bleu"`
+	LatencyP50Ms int64   `json:"latency_p50_ms"`
+	LatencyP90Ms int64   `json:"latency_p90_ms"`
+	LatencyP95Ms int64   `json:"latency_p95_ms"`
+	LatencyP99Ms int64   `json:"latency_p99_ms"`
+}
+
+// EvalRegression is a metric or case that got worse than the baseline
+type EvalRegression struct {
+	Metric   string  `json:"metric"`
+	CaseID   string  `json:"case_id,omitempty"`
+	Baseline float64 `json:"baseline"`
+	Current  float64 `json:"current"`
+	Detail   string  `json:"detail"`
+}
+
+// EvalReport is the result of running a dataset against a backend
+type EvalReport struct {
+	Dataset     string           `json:"dataset"`
+	Backend     string           `json:"backend"`
+	Model       string           `json:"model"`
+	StartedAt   time.Time        `json:"started_at"`
+	DurationMs  int64            `json:"duration_ms"`
+	Metrics     EvalMetrics      `json:"metrics"`
+	Results     []EvalCaseResult `json:"results"`
+	Baseline    string           `json:"baseline,omitempty"`
+	Regressions []EvalRegression `json:"regressions,omitempty"`
+}
@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// Example is a stored input/output pair used for few-shot prompting
+type Example struct {
+	ID        string    `json:"id"`
+	Task      string    `json:"task"`
+	Input     string    `json:"input"`
+	Output    string    `json:"output"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ExampleRequest adds an example to a task's example set
+type ExampleRequest struct {
+	Task   string `json:"task"`
+	Input  string `json:"input"`
+	Output string `json:"output"`
+}
+
+// FewShotOptions selects stored examples to prepend to a prompt
+type FewShotOptions struct {
+	Task string `json:"task"`
+	// K is the number of examples, default 3
+	K int `json:"k,omitempty"`
+}
@@ -0,0 +1,77 @@
+package models
+
+import "time"
+
+// Extraction field types
+const (
+	FieldString  = "string"
+	FieldNumber  = "number"
+	FieldInteger = "integer"
+	FieldBoolean = "boolean"
+	FieldDate    = "date"
+	FieldEnum    = "enum"
+)
+
+// ExtractField specifies one field to extract
+type ExtractField struct {
+	Name string `json:"name"`
+	// Type is string (default), number, integer, boolean, date or enum
+	Type        string `json:"type,omitempty"`
+	Description string `json:"description,omitempty"`
+	Required    bool   `json:"required,omitempty"`
+	// Options are the allowed values of an enum field
+	Options []string `json:"options,omitempty"`
+}
+
+// ExtractRequest extracts typed fields from a text
+type ExtractRequest struct {
+	Text   string         `json:"text"`
+	Fields []ExtractField `json:"fields"`
+	// DayFirst reads 03/04/2024 as 3 April rather than March 4
+	DayFirst bool `json:"day_first,omitempty"`
+	// MaxTokens defaults to a value that scales with the number of fields
+	MaxTokens int `json:"max_tokens,omitempty"`
+	// Temperature defaults to 0.2
+	Temperature float64 `json:"temperature,omitempty"`
+	UserID      string  `json:"user_id,omitempty"`
+	DryRun      bool    `json:"dry_run,omitempty"`
+}
+
+// ExtractSpan is where a value was found. Start and End are character
+// offsets into the text, End exclusive.
+type ExtractSpan struct {
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+	Text  string `json:"text"`
+}
+
+// ExtractedField is one field's typed value with its provenance
+type ExtractedField struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	// Value is null when not found or not coercible
+	Value interface{} `json:"value" swaggertype:"object"`
+	// Raw is the model's value before coercion
+	Raw        interface{}  `json:"raw,omitempty" swaggertype:"object"`
+	Span       *ExtractSpan `json:"span,omitempty"`
+	Confidence float64      `json:"confidence"`
+	// Error explains why the raw value could not be coerced
+	Error string `json:"error,omitempty"`
+}
+
+// ExtractResponse holds the typed object and per-field details in request
+// order. Data contains only the fields that were found.
+type ExtractResponse struct {
+	// ID refers to the response in /ai/feedback
+	ID     string                 `json:"id"`
+	Data   map[string]interface{} `json:"data" swaggertype:"object"`
+	Fields []ExtractedField       `json:"fields"`
+	// Missing lists the required fields that were not found
+	Missing []string `json:"missing"`
+	// Valid reports whether data satisfies the field specification
+	Valid     bool      `json:"valid"`
+	Raw       string    `json:"raw"`
+	UserID    string    `json:"user_id,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+	Model     string    `json:"model"`
+}
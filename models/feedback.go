@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+// Feedback ratings
+const (
+	FeedbackUp   = "up"
+	FeedbackDown = "down"
+)
+
+// FeedbackRequest rates an AI response by its ID
+type FeedbackRequest struct {
+	ResponseID string `json:"response_id"`
+	// Rating is up or down
+	Rating  string   `json:"rating"`
+	Comment string   `json:"comment,omitempty"`
+	Tags    []string `json:"tags,omitempty"`
+	// UserID is overridden by the X-User-ID header
+	UserID string `json:"user_id,omitempty"`
+}
+
+// Feedback is a rating of a response together with what the response was
+// generated from. A user has at most one feedback per response; rating
+// again replaces it.
+type Feedback struct {
+	ID         string   `json:"id"`
+	ResponseID string   `json:"response_id"`
+	Rating     string   `json:"rating"`
+	Comment    string   `json:"comment,omitempty"`
+	Tags       []string `json:"tags,omitempty"`
+	UserID     string   `json:"user_id,omitempty"`
+	// Response is a snapshot of the rated response without its response
+	// body; long prompts, outputs and request bodies are trimmed
+	Response  ResponseRecord `json:"response"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+}
+
+// FeedbackList is a page of feedback, most recent first
+type FeedbackList struct {
+	Feedback []Feedback `json:"feedback"`
+	// Total counts the matching feedback before paging
+	Total int `json:"total"`
+}
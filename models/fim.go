@@ -0,0 +1,48 @@
+package models
+
+import "time"
+
+// Fill-in-the-middle prompt modes
+const (
+	FIMSentinel = "sentinel"
+	FIMPrompt   = "prompt"
+)
+
+// Fill-in-the-middle stop reasons
+const (
+	FIMStopBoundary = "boundary"      // A syntactic boundary: end of line or end of block
+	FIMStopSuffix   = "suffix"        // The completion ran into the code after the cursor
+	FIMStopSequence = "stop_sequence" // A stop sequence or end-of-text marker
+	FIMStopLength   = "length"        // max_tokens was reached
+)
+
+// FIMRequest asks for the code between a prefix and a suffix
+type FIMRequest struct {
+	Prefix   string `json:"prefix"`
+	Suffix   string `json:"suffix,omitempty"`
+	Language string `json:"language,omitempty"`
+	// MaxTokens defaults to 64, at most 256
+	MaxTokens int `json:"max_tokens,omitempty"`
+	// Temperature defaults to 0.2
+	Temperature float64 `json:"temperature,omitempty"`
+	// Stop lists extra stop sequences
+	Stop   []string `json:"stop,omitempty"`
+	UserID string   `json:"user_id,omitempty"`
+	DryRun bool     `json:"dry_run,omitempty"`
+}
+
+// FIMResponse is the code to insert at the cursor
+type FIMResponse struct {
+	// ID refers to the response in /ai/feedback
+	ID         string `json:"id"`
+	Completion string `json:"completion"`
+	// StopReason is boundary, suffix, stop_sequence or length
+	StopReason string `json:"stop_reason"`
+	// Mode is sentinel or prompt
+	Mode       string    `json:"mode"`
+	Language   string    `json:"language,omitempty"`
+	DurationMs int64     `json:"duration_ms"`
+	UserID     string    `json:"user_id,omitempty"`
+	Timestamp  time.Time `json:"timestamp"`
+	Model      string    `json:"model"`
+}
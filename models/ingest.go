@@ -0,0 +1,49 @@
+package models
+
+import "time"
+
+// Document formats accepted for ingestion
+const (
+	FormatPDF      = "pdf"
+	FormatHTML     = "html"
+	FormatMarkdown = "markdown"
+	FormatDOCX     = "docx"
+	FormatText     = "text"
+)
+
+// IngestChunk is a token-bounded span of an ingested file's text. Start and
+// End are character offsets into the text, End exclusive.
+type IngestChunk struct {
+	Index  int    `json:"index"`
+	Text   string `json:"text"`
+	Start  int    `json:"start"`
+	End    int    `json:"end"`
+	Tokens int    `json:"tokens"`
+}
+
+// IngestedFile is the extracted text of one uploaded file
+type IngestedFile struct {
+	Filename string `json:"filename"`
+	Format   string `json:"format"`
+	Title    string `json:"title,omitempty"`
+	// Pages is set for PDFs only
+	Pages      int `json:"pages,omitempty"`
+	Characters int `json:"characters"`
+	Tokens     int `json:"tokens"`
+	// Text is omitted when include_text is false
+	Text   string        `json:"text,omitempty"`
+	Chunks []IngestChunk `json:"chunks"`
+	// DocumentID is set when the file is stored
+	DocumentID string   `json:"document_id,omitempty"`
+	Warnings   []string `json:"warnings,omitempty"`
+}
+
+// IngestResponse reports the files extracted by an ingestion and where their
+// text was stored
+type IngestResponse struct {
+	Files            []IngestedFile `json:"files"`
+	Chunks           int            `json:"chunks"`
+	RAGCollection    string         `json:"rag_collection,omitempty"`
+	VectorCollection string         `json:"vector_collection,omitempty"`
+	Timestamp        time.Time      `json:"timestamp"`
+}
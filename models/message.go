@@ -18,8 +18,37 @@ type HealthResponse struct {
 
 // ChatMessage represents a single chat message
 type ChatMessage struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role       string     `json:"role"`
+	Content    string     `json:"content"`
+	Name       string     `json:"name,omitempty"`
+	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string     `json:"tool_call_id,omitempty"`
+}
+
+// Tool represents a tool the model may call (OpenAI-compatible)
+type Tool struct {
+	Type     string       `json:"type"`
+	Function ToolFunction `json:"function"`
+}
+
+// ToolFunction describes a callable function and its JSON Schema parameters
+type ToolFunction struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	Parameters  map[string]interface{} `json:"parameters,omitempty"`
+}
+
+// ToolCall represents a tool invocation requested by the model
+type ToolCall struct {
+	ID       string           `json:"id"`
+	Type     string           `json:"type"`
+	Function ToolCallFunction `json:"function"`
+}
+
+// ToolCallFunction holds the name and JSON-encoded arguments of a tool call
+type ToolCallFunction struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
 }
 
 // ChatCompletionRequest represents a chat completion request
@@ -28,14 +57,53 @@ type ChatCompletionRequest struct {
 	MaxTokens   int           `json:"max_tokens,omitempty"`
 	Temperature float64       `json:"temperature,omitempty"`
 	UserID      string        `json:"user_id,omitempty"`
+	// ConversationID appends this exchange to a stored conversation; Messages
+	// then only needs to hold the new turn(s)
+	ConversationID string `json:"conversation_id,omitempty"`
+	Tools          []Tool `json:"tools,omitempty"`
+	// ToolChoice is "auto", "none", "required" or {"type":"function","function":{"name":"..."}}
+	ToolChoice interface{}    `json:"tool_choice,omitempty" swaggertype:"object"`
+	Memory     *MemoryOptions `json:"memory,omitempty"`
+	// TemplateID renders a stored prompt template as an additional user
+	// message, so Messages must not already end with one
+	TemplateID      string                 `json:"template_id,omitempty"`
+	TemplateVersion int                    `json:"template_version,omitempty"`
+	Variables       map[string]interface{} `json:"variables,omitempty"`
+	// DryRun returns the upstream request instead of calling the model
+	DryRun bool `json:"dry_run,omitempty"`
+}
+
+// MemoryOptions enables rolling summarization of long histories
+type MemoryOptions struct {
+	// Mode is "summary" (the only supported mode) or empty to disable
+	Mode string `json:"mode"`
+	// ThresholdTokens is the history size that triggers summarization (default 600)
+	ThresholdTokens int `json:"threshold_tokens,omitempty"`
+	// KeepRecent is the number of most recent messages kept verbatim (default 4)
+	KeepRecent int `json:"keep_recent,omitempty"`
+}
+
+// MemoryInfo reports how memory was applied to a chat completion
+type MemoryInfo struct {
+	Summarized         bool `json:"summarized"`
+	SummarizedMessages int  `json:"summarized_messages"`
+	Refreshed          bool `json:"refreshed"`
+	PromptTokens       int  `json:"prompt_tokens"`
 }
 
 // ChatCompletionResponse represents a chat completion response
 type ChatCompletionResponse struct {
-	Response  string    `json:"response"`
-	UserID    string    `json:"user_id,omitempty"`
-	Timestamp time.Time `json:"timestamp"`
-	Model     string    `json:"model,omitempty"`
+	// ID refers to the response in /ai/feedback
+	ID             string      `json:"id"`
+	Response       string      `json:"response"`
+	ToolCalls      []ToolCall  `json:"tool_calls,omitempty"`
+	FinishReason   string      `json:"finish_reason,omitempty"`
+	ConversationID string      `json:"conversation_id,omitempty"`
+	MessageID      string      `json:"message_id,omitempty"`
+	Memory         *MemoryInfo `json:"memory,omitempty"`
+	UserID         string      `json:"user_id,omitempty"`
+	Timestamp      time.Time   `json:"timestamp"`
+	Model          string      `json:"model,omitempty"`
 }
 
 // CompleteRequest represents a text completion request
@@ -44,11 +112,24 @@ type CompleteRequest struct {
 	MaxTokens   int     `json:"max_tokens,omitempty"`
 	Temperature float64 `json:"temperature,omitempty"`
 	UserID      string  `json:"user_id,omitempty"`
+	// TemplateID renders a stored prompt template in place of Prompt, which
+	// must then be empty
+	TemplateID      string                 `json:"template_id,omitempty"`
+	TemplateVersion int                    `json:"template_version,omitempty"`
+	Variables       map[string]interface{} `json:"variables,omitempty"`
+	// FewShot prepends the most similar stored examples of a task to the prompt
+	FewShot *FewShotOptions `json:"few_shot,omitempty"`
+	// DryRun returns the upstream request instead of calling the model
+	DryRun bool `json:"dry_run,omitempty"`
 }
 
 // CompleteResponse represents a text completion response
 type CompleteResponse struct {
-	Response  string    `json:"response"`
+	// ID refers to the response in /ai/feedback
+	ID       string `json:"id"`
+	Response string `json:"response"`
+	// Examples lists the IDs of the few-shot examples used
+	Examples  []string  `json:"examples,omitempty"`
 	UserID    string    `json:"user_id,omitempty"`
 	Timestamp time.Time `json:"timestamp"`
 	Model     string    `json:"model,omitempty"`
@@ -60,11 +141,24 @@ type GenerateRequest struct {
 	MaxTokens   int     `json:"max_tokens,omitempty"`
 	Temperature float64 `json:"temperature,omitempty"`
 	UserID      string  `json:"user_id,omitempty"`
+	// TemplateID renders a stored prompt template in place of Prompt, which
+	// must then be empty
+	TemplateID      string                 `json:"template_id,omitempty"`
+	TemplateVersion int                    `json:"template_version,omitempty"`
+	Variables       map[string]interface{} `json:"variables,omitempty"`
+	// FewShot prepends the most similar stored examples of a task to the prompt
+	FewShot *FewShotOptions `json:"few_shot,omitempty"`
+	// DryRun returns the upstream request instead of calling the model
+	DryRun bool `json:"dry_run,omitempty"`
 }
 
 // GenerateResponse represents a text generation response
 type GenerateResponse struct {
-	Response  string    `json:"response"`
+	// ID refers to the response in /ai/feedback
+	ID       string `json:"id"`
+	Response string `json:"response"`
+	// Examples lists the IDs of the few-shot examples used
+	Examples  []string  `json:"examples,omitempty"`
 	UserID    string    `json:"user_id,omitempty"`
 	Timestamp time.Time `json:"timestamp"`
 	Model     string    `json:"model,omitempty"`
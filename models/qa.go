@@ -0,0 +1,66 @@
+package models
+
+import "time"
+
+// QAPassage is a context passage to answer from
+type QAPassage struct {
+	ID    string `json:"id,omitempty"`
+	Title string `json:"title,omitempty"`
+	Text  string `json:"text"`
+}
+
+// QARequest asks a question about supplied passages. Context is shorthand
+// for a single passage and cannot be combined with passages.
+type QARequest struct {
+	Question string      `json:"question"`
+	Passages []QAPassage `json:"passages,omitempty"`
+	Context  string      `json:"context,omitempty"`
+	// TopK is the number of passage chunks placed in the prompt, default 3
+	TopK int `json:"top_k,omitempty"`
+	// MinConfidence is the alignment score below which there is no answer, default 0.6
+	MinConfidence float64 `json:"min_confidence,omitempty"`
+	// MaxTokens defaults to 48
+	MaxTokens   int     `json:"max_tokens,omitempty"`
+	Temperature float64 `json:"temperature,omitempty"`
+	UserID      string  `json:"user_id,omitempty"`
+	DryRun      bool    `json:"dry_run,omitempty"`
+}
+
+// QASpan is the exact source text of an answer. Start and End are character
+// offsets into the passage text, End exclusive.
+type QASpan struct {
+	PassageIndex int    `json:"passage_index"`
+	PassageID    string `json:"passage_id,omitempty"`
+	Start        int    `json:"start"`
+	End          int    `json:"end"`
+	Text         string `json:"text"`
+}
+
+// QASource is a passage chunk that was placed in the prompt
+type QASource struct {
+	Marker       int    `json:"marker"`
+	PassageIndex int    `json:"passage_index"`
+	PassageID    string `json:"passage_id,omitempty"`
+	Start        int    `json:"start"`
+	End          int    `json:"end"`
+	// Score is the BM25 score against the question
+	Score float64 `json:"score"`
+}
+
+// QAResponse is an answer aligned to its source span. Answer is the span
+// text; when the model's answer cannot be aligned with enough confidence,
+// no_answer is set and answer is empty.
+type QAResponse struct {
+	// ID refers to the response in /ai/feedback
+	ID         string  `json:"id"`
+	Answer     string  `json:"answer"`
+	NoAnswer   bool    `json:"no_answer"`
+	Confidence float64 `json:"confidence"`
+	Span       *QASpan `json:"span,omitempty"`
+	// ModelAnswer is the model's raw answer
+	ModelAnswer string     `json:"model_answer"`
+	Sources     []QASource `json:"sources"`
+	UserID      string     `json:"user_id,omitempty"`
+	Timestamp   time.Time  `json:"timestamp"`
+	Model       string     `json:"model"`
+}
@@ -0,0 +1,153 @@
+package models
+
+import "time"
+
+// Retrieval modes
+const (
+	RetrievalHybrid = "hybrid"
+	RetrievalBM25   = "bm25"
+	RetrievalVector = "vector"
+)
+
+// RAGCollection describes a collection of documents indexed for retrieval
+type RAGCollection struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	// Vectors reports whether chunks are also embedded for hybrid retrieval
+	Vectors        bool      `json:"vectors"`
+	EmbeddingModel string    `json:"embedding_model,omitempty"`
+	ChunkTokens    int       `json:"chunk_tokens"`
+	ChunkOverlap   int       `json:"chunk_overlap"`
+	Documents      int       `json:"documents"`
+	Chunks         int       `json:"chunks"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// CreateRAGCollectionRequest creates a document collection
+type CreateRAGCollectionRequest struct {
+	Name           string `json:"name"`
+	Description    string `json:"description,omitempty"`
+	Vectors        bool   `json:"vectors,omitempty"`
+	EmbeddingModel string `json:"embedding_model,omitempty"`
+	// ChunkTokens defaults to 256, between 32 and 512
+	ChunkTokens int `json:"chunk_tokens,omitempty"`
+	// ChunkOverlap defaults to 32, under half of chunk_tokens
+	ChunkOverlap int `json:"chunk_overlap,omitempty"`
+}
+
+// RAGDocumentInput is a document to ingest. Ingesting an existing ID
+// replaces the document.
+type RAGDocumentInput struct {
+	ID       string                 `json:"id,omitempty"`
+	Title    string                 `json:"title,omitempty"`
+	Source   string                 `json:"source,omitempty"`
+	Text     string                 `json:"text"`
+	Metadata map[string]interface{} `json:"metadata,omitempty" swaggertype:"object"`
+}
+
+// RAGDocument is an ingested document. Text is only included when a single
+// document is fetched.
+type RAGDocument struct {
+	ID         string                 `json:"id"`
+	Title      string                 `json:"title,omitempty"`
+	Source     string                 `json:"source,omitempty"`
+	Metadata   map[string]interface{} `json:"metadata,omitempty" swaggertype:"object"`
+	Characters int                    `json:"characters"`
+	Chunks     int                    `json:"chunks"`
+	Text       string                 `json:"text,omitempty"`
+	CreatedAt  time.Time              `json:"created_at"`
+}
+
+// IngestDocumentsResponse reports an ingestion
+type IngestDocumentsResponse struct {
+	Collection string        `json:"collection"`
+	Documents  []RAGDocument `json:"documents"`
+	Chunks     int           `json:"chunks"`
+}
+
+// RAGChunk is a retrievable span of a document. Start and End are character
+// offsets into the document text, End exclusive.
+type RAGChunk struct {
+	ID         string `json:"id"`
+	DocumentID string `json:"document_id"`
+	Index      int    `json:"index"`
+	Text       string `json:"text"`
+	Start      int    `json:"start"`
+	End        int    `json:"end"`
+	Tokens     int    `json:"tokens"`
+}
+
+// RAGSearchRequest retrieves chunks from a collection
+type RAGSearchRequest struct {
+	Query string `json:"query"`
+	TopK  int    `json:"top_k,omitempty"`
+	// Mode is hybrid (default with vectors), bm25 or vector
+	Mode   string                 `json:"mode,omitempty"`
+	Filter map[string]interface{} `json:"filter,omitempty" swaggertype:"object"`
+}
+
+// RAGMatch is a retrieved chunk with its document and scores. Hybrid
+// scores are reciprocal rank fusion scores.
+type RAGMatch struct {
+	RAGChunk
+	Title       string  `json:"title,omitempty"`
+	Source      string  `json:"source,omitempty"`
+	Score       float64 `json:"score"`
+	BM25Score   float64 `json:"bm25_score,omitempty"`
+	VectorScore float64 `json:"vector_score,omitempty"`
+}
+
+// RAGSearchResponse holds retrieved chunks, best first
+type RAGSearchResponse struct {
+	Collection string     `json:"collection"`
+	Mode       string     `json:"mode"`
+	Matches    []RAGMatch `json:"matches"`
+}
+
+// RAGChatRequest answers the latest user message from a collection
+type RAGChatRequest struct {
+	Collection  string                 `json:"collection"`
+	Messages    []ChatMessage          `json:"messages"`
+	TopK        int                    `json:"top_k,omitempty"`
+	Mode        string                 `json:"mode,omitempty"`
+	Filter      map[string]interface{} `json:"filter,omitempty" swaggertype:"object"`
+	MaxTokens   int                    `json:"max_tokens,omitempty"`
+	Temperature float64                `json:"temperature,omitempty"`
+	UserID      string                 `json:"user_id,omitempty"`
+	DryRun      bool                   `json:"dry_run,omitempty"`
+}
+
+// RAGCitation points from an answer to the chunk it used
+type RAGCitation struct {
+	// Marker is the [n] marker in the prompt and answer
+	Marker     int     `json:"marker"`
+	ChunkID    string  `json:"chunk_id"`
+	DocumentID string  `json:"document_id"`
+	Title      string  `json:"title,omitempty"`
+	Source     string  `json:"source,omitempty"`
+	Start      int     `json:"start"`
+	End        int     `json:"end"`
+	Score      float64 `json:"score"`
+}
+
+// RAGChatResponse is a grounded answer. Citations are the sources the answer
+// refers to by marker; Sources are every chunk placed in the prompt.
+type RAGChatResponse struct {
+	// ID refers to the response in /ai/feedback
+	ID         string        `json:"id"`
+	Response   string        `json:"response"`
+	Citations  []RAGCitation `json:"citations"`
+	Sources    []RAGCitation `json:"sources"`
+	Collection string        `json:"collection"`
+	Mode       string        `json:"mode"`
+	UserID     string        `json:"user_id,omitempty"`
+	Timestamp  time.Time     `json:"timestamp"`
+	Model      string        `json:"model,omitempty"`
+}
+
+// RAGDocumentDetail is a document with its text and chunks
+type RAGDocumentDetail struct {
+	Document RAGDocument `json:"document"`
+	Chunks   []RAGChunk  `json:"chunks"`
+}
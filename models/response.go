@@ -0,0 +1,35 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// TokenUsage is the estimated token usage of a call
+type TokenUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// ResponseRecord is a completed AI call as the user saw it, stored by its ID
+type ResponseRecord struct {
+	ID       string `json:"id"`
+	Endpoint string `json:"endpoint"`
+	UserID   string `json:"user_id,omitempty"`
+	Model    string `json:"model"`
+	// Prompt is the text the output answers
+	Prompt string `json:"prompt"`
+	// Output is the main text of the response
+	Output      string  `json:"output"`
+	MaxTokens   int     `json:"max_tokens,omitempty"`
+	Temperature float64 `json:"temperature,omitempty"`
+	// Request is the request as processed, with defaults applied
+	Request json.RawMessage `json:"request" swaggertype:"object"`
+	// Response is the response body as sent
+	Response json.RawMessage `json:"response" swaggertype:"object"`
+	// Usage is estimated from the prompt and output
+	Usage      *TokenUsage `json:"usage,omitempty"`
+	DurationMs int64       `json:"duration_ms"`
+	CreatedAt  time.Time   `json:"created_at"`
+}
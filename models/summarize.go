@@ -0,0 +1,52 @@
+package models
+
+import "time"
+
+// Summary lengths
+const (
+	SummaryShort  = "short"
+	SummaryMedium = "medium"
+	SummaryLong   = "long"
+)
+
+// Summary styles
+const (
+	SummaryProse   = "prose"
+	SummaryBullets = "bullets"
+)
+
+// SummarizeRequest summarizes a text of any length
+type SummarizeRequest struct {
+	Text string `json:"text"`
+	// Length is short, medium (default) or long
+	Length string `json:"length,omitempty"`
+	// Style is prose (default) or bullets
+	Style string `json:"style,omitempty"`
+	// Focus is a query to summarize with respect to
+	Focus       string  `json:"focus,omitempty"`
+	Temperature float64 `json:"temperature,omitempty"`
+	UserID      string  `json:"user_id,omitempty"`
+	DryRun      bool    `json:"dry_run,omitempty"`
+}
+
+// SummarizeResponse is the summary with statistics of the map-reduce passes
+type SummarizeResponse struct {
+	// ID refers to the response in /ai/feedback
+	ID          string `json:"id"`
+	Summary     string `json:"summary"`
+	Length      string `json:"length"`
+	Style       string `json:"style"`
+	Focus       string `json:"focus,omitempty"`
+	InputTokens int    `json:"input_tokens"`
+	// Chunks counts the chunks in the first pass
+	Chunks int `json:"chunks"`
+	// Levels counts the passes over the text, including the final one
+	Levels int `json:"levels"`
+	// Calls counts the model calls made
+	Calls         int       `json:"calls"`
+	SummaryTokens int       `json:"summary_tokens"`
+	DurationMs    int64     `json:"duration_ms"`
+	UserID        string    `json:"user_id,omitempty"`
+	Timestamp     time.Time `json:"timestamp"`
+	Model         string    `json:"model"`
+}
@@ -0,0 +1,73 @@
+package models
+
+import "time"
+
+// Task output parsers
+const (
+	TaskOutputText  = "text"
+	TaskOutputJSON  = "json"
+	TaskOutputLines = "lines"
+	TaskOutputRegex = "regex"
+)
+
+// Task upstreams
+const (
+	TaskUpstreamComplete = "complete"
+	TaskUpstreamGenerate = "generate"
+	TaskUpstreamChat     = "chat"
+)
+
+// TaskOutput configures how a task's model output is parsed
+type TaskOutput struct {
+	// Parser is text (default), json, lines or regex
+	Parser string `json:"parser"`
+	// Pattern is the regular expression for the regex parser; named groups become fields
+	Pattern string `json:"pattern,omitempty"`
+}
+
+// TaskDefinition declares a custom endpoint mounted at /ai/tasks/{name}
+type TaskDefinition struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	// Upstream is complete (default), generate or chat
+	Upstream string `json:"upstream,omitempty"`
+	Prompt   string `json:"prompt,omitempty"`
+	// System is the system message for the chat upstream
+	System string `json:"system,omitempty"`
+	// TemplateID is a stored prompt template used instead of prompt
+	TemplateID  string                 `json:"template_id,omitempty"`
+	InputSchema map[string]interface{} `json:"input_schema,omitempty" swaggertype:"object"`
+	Output      TaskOutput             `json:"output"`
+	// RateLimit is in requests per minute per IP address
+	RateLimit   int     `json:"rate_limit,omitempty"`
+	MaxTokens   int     `json:"max_tokens,omitempty"`
+	Temperature float64 `json:"temperature,omitempty"`
+}
+
+// TaskInfo describes a mounted task endpoint
+type TaskInfo struct {
+	TaskDefinition
+	Path string `json:"path"`
+}
+
+// TaskRequest is the body of a call to a task endpoint
+type TaskRequest struct {
+	Input       map[string]interface{} `json:"input" swaggertype:"object"`
+	MaxTokens   int                    `json:"max_tokens,omitempty"`
+	Temperature float64                `json:"temperature,omitempty"`
+	UserID      string                 `json:"user_id,omitempty"`
+	// DryRun returns the upstream request instead of calling the model
+	DryRun bool `json:"dry_run,omitempty"`
+}
+
+// TaskResponse is the parsed result of a task call
+type TaskResponse struct {
+	// ID refers to the response in /ai/feedback
+	ID        string      `json:"id"`
+	Task      string      `json:"task"`
+	Output    interface{} `json:"output" swaggertype:"object"`
+	Raw       string      `json:"raw"`
+	UserID    string      `json:"user_id,omitempty"`
+	Timestamp time.Time   `json:"timestamp"`
+	Model     string      `json:"model"`
+}
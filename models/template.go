@@ -0,0 +1,62 @@
+package models
+
+import "time"
+
+// TemplateVariable declares a typed variable of a prompt template
+type TemplateVariable struct {
+	Name string `json:"name"`
+	// Type is one of string, number, integer or boolean (default string)
+	Type        string      `json:"type,omitempty"`
+	Description string      `json:"description,omitempty"`
+	Required    bool        `json:"required,omitempty"`
+	Default     interface{} `json:"default,omitempty" swaggertype:"string"`
+}
+
+// TemplateVersion is an immutable revision of a prompt template
+type TemplateVersion struct {
+	Version   int                `json:"version"`
+	Body      string             `json:"body"`
+	Variables []TemplateVariable `json:"variables,omitempty"`
+	Note      string             `json:"note,omitempty"`
+	CreatedAt time.Time          `json:"created_at"`
+}
+
+// PromptTemplate is a named prompt template with its version history
+type PromptTemplate struct {
+	ID            string            `json:"id"`
+	Name          string            `json:"name,omitempty"`
+	Description   string            `json:"description,omitempty"`
+	ActiveVersion int               `json:"active_version"`
+	Versions      []TemplateVersion `json:"versions"`
+	CreatedAt     time.Time         `json:"created_at"`
+	UpdatedAt     time.Time         `json:"updated_at"`
+}
+
+// PromptTemplateRequest creates a template or adds a new version to it
+type PromptTemplateRequest struct {
+	ID          string             `json:"id,omitempty"`
+	Name        string             `json:"name,omitempty"`
+	Description string             `json:"description,omitempty"`
+	Body        string             `json:"body"`
+	Variables   []TemplateVariable `json:"variables,omitempty"`
+	Note        string             `json:"note,omitempty"`
+}
+
+// RenderTemplateRequest previews a template with the given variables
+type RenderTemplateRequest struct {
+	Version   int                    `json:"version,omitempty"`
+	Variables map[string]interface{} `json:"variables,omitempty"`
+}
+
+// RenderTemplateResponse is a rendered template preview
+type RenderTemplateResponse struct {
+	TemplateID string `json:"template_id"`
+	Version    int    `json:"version"`
+	Prompt     string `json:"prompt"`
+	Tokens     int    `json:"tokens"`
+}
+
+// RollbackTemplateRequest restores a prior template version
+type RollbackTemplateRequest struct {
+	Version int `json:"version"`
+}
@@ -0,0 +1,97 @@
+package models
+
+import "time"
+
+// Vector similarity metrics
+const (
+	MetricCosine = "cosine"
+	MetricDot    = "dot"
+	MetricL2     = "l2"
+)
+
+// VectorCollection describes a collection of vectors
+type VectorCollection struct {
+	Name       string `json:"name"`
+	Dimensions int    `json:"dimensions"`
+	// Metric is cosine, dot or l2
+	Metric string `json:"metric"`
+	// EmbeddingModel embeds records and queries given as text
+	EmbeddingModel string     `json:"embedding_model,omitempty"`
+	Count          int        `json:"count"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	SnapshotAt     *time.Time `json:"snapshot_at,omitempty"`
+}
+
+// CreateCollectionRequest creates a vector collection
+type CreateCollectionRequest struct {
+	Name string `json:"name"`
+	// Dimensions are taken from the first vector when omitted
+	Dimensions int `json:"dimensions,omitempty"`
+	// Metric is cosine (default), dot or l2
+	Metric         string `json:"metric,omitempty"`
+	EmbeddingModel string `json:"embedding_model,omitempty"`
+}
+
+// VectorRecord is a vector with its ID, optional source text and metadata.
+// Records given only text are embedded with the collection's embedding model.
+type VectorRecord struct {
+	ID       string                 `json:"id"`
+	Vector   []float64              `json:"vector,omitempty"`
+	Text     string                 `json:"text,omitempty"`
+	Metadata map[string]interface{} `json:"metadata,omitempty" swaggertype:"object"`
+}
+
+// UpsertVectorsRequest inserts or replaces records
+type UpsertVectorsRequest struct {
+	Records []VectorRecord `json:"records"`
+}
+
+// UpsertVectorsResponse reports an upsert
+type UpsertVectorsResponse struct {
+	Upserted int `json:"upserted"`
+	Count    int `json:"count"`
+}
+
+// DeleteVectorsRequest deletes records by ID and/or metadata filter
+type DeleteVectorsRequest struct {
+	IDs    []string               `json:"ids,omitempty"`
+	Filter map[string]interface{} `json:"filter,omitempty" swaggertype:"object"`
+}
+
+// DeleteVectorsResponse reports a deletion
+type DeleteVectorsResponse struct {
+	Deleted int `json:"deleted"`
+	Count   int `json:"count"`
+}
+
+// VectorQueryRequest searches a collection by vector or text
+type VectorQueryRequest struct {
+	Vector []float64              `json:"vector,omitempty"`
+	Text   string                 `json:"text,omitempty"`
+	TopK   int                    `json:"top_k,omitempty"`
+	Filter map[string]interface{} `json:"filter,omitempty" swaggertype:"object"`
+	// Exact scans every record instead of using the index
+	Exact          bool `json:"exact,omitempty"`
+	IncludeVectors bool `json:"include_vectors,omitempty"`
+}
+
+// VectorMatch is a query result. Score is the cosine similarity or dot
+// product (higher is closer), or the Euclidean distance for l2 (lower is closer).
+type VectorMatch struct {
+	ID       string                 `json:"id"`
+	Score    float64                `json:"score"`
+	Text     string                 `json:"text,omitempty"`
+	Metadata map[string]interface{} `json:"metadata,omitempty" swaggertype:"object"`
+	Vector   []float64              `json:"vector,omitempty"`
+}
+
+// VectorQueryResponse holds query results, closest first
+type VectorQueryResponse struct {
+	Collection string        `json:"collection"`
+	Metric     string        `json:"metric"`
+	Matches    []VectorMatch `json:"matches"`
+	// Exact reports whether an exact scan was used
+	Exact  bool  `json:"exact"`
+	TookMs int64 `json:"took_ms"`
+}
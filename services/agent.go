@@ -0,0 +1,429 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/Ammar0144/ai/models"
+)
+
+// Agent loop defaults and hard limits
+const (
+	DefaultAgentMaxSteps = 5
+	MaxAgentSteps        = 10
+	DefaultAgentTimeout  = 60 * time.Second
+	MaxAgentTimeout      = 120 * time.Second
+)
+
+// Agent stop reasons
+const (
+	AgentStopFinal    = "final"
+	AgentStopMaxSteps = "max_steps"
+	AgentStopTimeout  = "timeout"
+	AgentStopError    = "error"
+)
+
+// AgentTool is a built-in tool the agent can execute server-side
+type AgentTool struct {
+	Definition models.Tool
+	Run        func(args map[string]interface{}) (string, error)
+}
+
+// AgentOptions controls a single agent run
+type AgentOptions struct {
+	Tools       []string
+	MaxSteps    int
+	Timeout     time.Duration
+	MaxTokens   int
+	Temperature float64
+}
+
+// AgentResult is the outcome of an agent run
+type AgentResult struct {
+	Answer     string
+	Steps      []models.AgentStep
+	StopReason string
+}
+
+// Agent runs a bounded reason-act loop on top of chat completions
+type Agent struct {
+	aiService *AIService
+	tools     map[string]AgentTool
+}
+
+// NewAgent creates an agent with the built-in sandboxed tools
+func NewAgent(aiService *AIService, knowledge *KnowledgeBase) *Agent {
+	agent := &Agent{
+		aiService: aiService,
+		tools:     make(map[string]AgentTool),
+	}
+	for _, tool := range []AgentTool{calculatorTool(), currentTimeTool(), knowledgeLookupTool(knowledge)} {
+		agent.tools[tool.Definition.Function.Name] = tool
+	}
+	return agent
+}
+
+// ToolNames returns the names of the built-in tools in sorted order
+func (a *Agent) ToolNames() []string {
+	names := make([]string, 0, len(a.tools))
+	for name := range a.tools {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Run executes the agent loop until the model answers without calling a
+// tool, the step limit is reached, the wall-clock budget runs out or ctx is
+// canceled
+func (a *Agent) Run(ctx context.Context, messages []models.ChatMessage, opts AgentOptions) (*AgentResult, error) {
+	tools, err := a.selectTools(opts.Tools)
+	if err != nil {
+		return nil, err
+	}
+
+	maxSteps := opts.MaxSteps
+	if maxSteps <= 0 {
+		maxSteps = DefaultAgentMaxSteps
+	}
+	if maxSteps > MaxAgentSteps {
+		maxSteps = MaxAgentSteps
+	}
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = DefaultAgentTimeout
+	}
+	if timeout > MaxAgentTimeout {
+		timeout = MaxAgentTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	definitions := make([]models.Tool, 0, len(tools))
+	for _, tool := range tools {
+		definitions = append(definitions, tool.Definition)
+	}
+
+	history := append([]models.ChatMessage(nil), messages...)
+	result := &AgentResult{}
+	lastContent := ""
+
+	for iteration := 0; iteration < maxSteps; iteration++ {
+		if errors.Is(ctx.Err(), context.Canceled) {
+			result.StopReason = AgentStopError
+			return result, ctx.Err()
+		}
+		if ctx.Err() != nil {
+			result.StopReason = AgentStopTimeout
+			result.Answer = lastContent
+			return result, nil
+		}
+
+		started := time.Now()
+		chat, err := a.aiService.GetChatCompletionWithToolsContext(ctx, history, definitions, ToolChoice{Mode: ToolChoiceAuto}, opts.MaxTokens, opts.Temperature)
+		if err != nil {
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				result.StopReason = AgentStopTimeout
+				result.Answer = lastContent
+				return result, nil
+			}
+			result.Steps = append(result.Steps, models.AgentStep{
+				Index:      len(result.Steps),
+				Type:       models.AgentStepError,
+				Error:      err.Error(),
+				DurationMs: time.Since(started).Milliseconds(),
+			})
+			result.StopReason = AgentStopError
+			return result, err
+		}
+
+		if len(chat.ToolCalls) == 0 {
+			result.Steps = append(result.Steps, models.AgentStep{
+				Index:      len(result.Steps),
+				Type:       models.AgentStepFinal,
+				Output:     chat.Content,
+				DurationMs: time.Since(started).Milliseconds(),
+			})
+			result.Answer = chat.Content
+			result.StopReason = AgentStopFinal
+			return result, nil
+		}
+
+		lastContent = chat.Content
+		history = append(history, models.ChatMessage{
+			Role:      "assistant",
+			Content:   chat.Content,
+			ToolCalls: chat.ToolCalls,
+		})
+
+		for _, call := range chat.ToolCalls {
+			toolStarted := time.Now()
+			output, toolErr := a.execute(call)
+			step := models.AgentStep{
+				Index:      len(result.Steps),
+				Type:       models.AgentStepToolCall,
+				Thought:    chat.Content,
+				Tool:       call.Function.Name,
+				Arguments:  call.Function.Arguments,
+				Output:     output,
+				DurationMs: time.Since(toolStarted).Milliseconds(),
+			}
+			content := output
+			if toolErr != nil {
+				step.Error = toolErr.Error()
+				content = "error: " + toolErr.Error()
+			}
+			result.Steps = append(result.Steps, step)
+			history = append(history, models.ChatMessage{
+				Role:       "tool",
+				Name:       call.Function.Name,
+				ToolCallID: call.ID,
+				Content:    content,
+			})
+		}
+	}
+
+	log.Printf("Agent: stopped after reaching %d steps", maxSteps)
+	result.StopReason = AgentStopMaxSteps
+	result.Answer = lastContent
+	return result, nil
+}
+
+// selectTools resolves the requested tool names, defaulting to all tools
+func (a *Agent) selectTools(names []string) ([]AgentTool, error) {
+	if len(names) == 0 {
+		names = a.ToolNames()
+	}
+	tools := make([]AgentTool, 0, len(names))
+	for _, name := range names {
+		tool, ok := a.tools[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown tool %q", name)
+		}
+		tools = append(tools, tool)
+	}
+	return tools, nil
+}
+
+// execute runs a single tool call
+func (a *Agent) execute(call models.ToolCall) (string, error) {
+	tool, ok := a.tools[call.Function.Name]
+	if !ok {
+		return "", fmt.Errorf("unknown tool %q", call.Function.Name)
+	}
+
+	args := make(map[string]interface{})
+	if call.Function.Arguments != "" {
+		if err := json.Unmarshal([]byte(call.Function.Arguments), &args); err != nil {
+			return "", fmt.Errorf("invalid arguments: %w", err)
+		}
+	}
+	return tool.Run(args)
+}
+
+// calculatorTool evaluates arithmetic expressions
+func calculatorTool() AgentTool {
+	return AgentTool{
+		Definition: models.Tool{
+			Type: "function",
+			Function: models.ToolFunction{
+				Name:        "calculator",
+				Description: "Evaluate an arithmetic expression, e.g. (3 + 4) * 2 or sqrt(16)",
+				Parameters: map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"expression": map[string]interface{}{"type": "string"},
+					},
+					"required": []string{"expression"},
+				},
+			},
+		},
+		Run: func(args map[string]interface{}) (string, error) {
+			expression, _ := args["expression"].(string)
+			value, err := EvaluateExpression(expression)
+			if err != nil {
+				return "", err
+			}
+			return strconv.FormatFloat(value, 'g', -1, 64), nil
+		},
+	}
+}
+
+// currentTimeTool reports the current date and time
+func currentTimeTool() AgentTool {
+	return AgentTool{
+		Definition: models.Tool{
+			Type: "function",
+			Function: models.ToolFunction{
+				Name:        "current_time",
+				Description: "Get the current date and time, optionally in an IANA timezone such as Europe/Paris",
+				Parameters: map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"timezone": map[string]interface{}{"type": "string"},
+					},
+				},
+			},
+		},
+		Run: func(args map[string]interface{}) (string, error) {
+			now := time.Now().UTC()
+			if name, _ := args["timezone"].(string); name != "" {
+				location, err := time.LoadLocation(name)
+				if err != nil {
+					return "", fmt.Errorf("unknown timezone %q", name)
+				}
+				now = now.In(location)
+			}
+			return fmt.Sprintf("%s (%s, %s)", now.Format(time.RFC3339), now.Weekday(), now.Format("January 2, 2006")), nil
+		},
+	}
+}
+
+// knowledgeLookupTool looks up admin-registered static knowledge
+func knowledgeLookupTool(knowledge *KnowledgeBase) AgentTool {
+	return AgentTool{
+		Definition: models.Tool{
+			Type: "function",
+			Function: models.ToolFunction{
+				Name:        "knowledge_lookup",
+				Description: "Look up a fact in the curated knowledge base by key or keyword",
+				Parameters: map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"query": map[string]interface{}{"type": "string"},
+					},
+					"required": []string{"query"},
+				},
+			},
+		},
+		Run: func(args map[string]interface{}) (string, error) {
+			query, _ := args["query"].(string)
+			if query == "" {
+				query, _ = args["key"].(string)
+			}
+			if strings.TrimSpace(query) == "" {
+				return "", fmt.Errorf("query is required")
+			}
+			entries := knowledge.Lookup(query)
+			if len(entries) == 0 {
+				return "no matching entry", nil
+			}
+			lines := make([]string, 0, len(entries))
+			for _, entry := range entries {
+				lines = append(lines, entry.Key+": "+entry.Value)
+			}
+			return strings.Join(lines, "\n"), nil
+		},
+	}
+}
+
+// KnowledgeBase is a thread-safe store of admin-registered key/value facts
+type KnowledgeBase struct {
+	entries map[string]models.KnowledgeEntry
+	mutex   sync.RWMutex
+}
+
+// NewKnowledgeBase creates an empty knowledge base
+func NewKnowledgeBase() *KnowledgeBase {
+	return &KnowledgeBase{
+		entries: make(map[string]models.KnowledgeEntry),
+	}
+}
+
+// Set stores or replaces an entry
+func (k *KnowledgeBase) Set(key, value string) models.KnowledgeEntry {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+
+	entry := models.KnowledgeEntry{Key: key, Value: value, UpdatedAt: time.Now()}
+	k.entries[strings.ToLower(key)] = entry
+	return entry
+}
+
+// Delete removes an entry and reports whether it existed
+func (k *KnowledgeBase) Delete(key string) bool {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+
+	normalized := strings.ToLower(key)
+	if _, ok := k.entries[normalized]; !ok {
+		return false
+	}
+	delete(k.entries, normalized)
+	return true
+}
+
+// List returns all entries sorted by key
+func (k *KnowledgeBase) List() []models.KnowledgeEntry {
+	k.mutex.RLock()
+	defer k.mutex.RUnlock()
+
+	entries := make([]models.KnowledgeEntry, 0, len(k.entries))
+	for _, entry := range k.entries {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Key < entries[j].Key })
+	return entries
+}
+
+// Lookup returns the entry whose key matches the query exactly, or else all
+// entries whose key starts with the query or shares it as whole words, in
+// either direction (case-insensitive)
+func (k *KnowledgeBase) Lookup(query string) []models.KnowledgeEntry {
+	k.mutex.RLock()
+	defer k.mutex.RUnlock()
+
+	normalized := strings.ToLower(strings.TrimSpace(query))
+	if entry, ok := k.entries[normalized]; ok {
+		return []models.KnowledgeEntry{entry}
+	}
+
+	queryWords := lookupWords(normalized)
+	var matches []models.KnowledgeEntry
+	for key, entry := range k.entries {
+		keyWords := lookupWords(key)
+		if strings.HasPrefix(key, normalized) || containsWords(keyWords, queryWords) || containsWords(queryWords, keyWords) {
+			matches = append(matches, entry)
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool { return matches[i].Key < matches[j].Key })
+	return matches
+}
+
+// lookupWords splits text into words, ignoring punctuation
+func lookupWords(text string) []string {
+	return strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// containsWords reports whether phrase occurs in words as a run of whole
+// words; an empty phrase never matches
+func containsWords(words, phrase []string) bool {
+	if len(phrase) == 0 {
+		return false
+	}
+	for start := 0; start+len(phrase) <= len(words); start++ {
+		matched := true
+		for i, word := range phrase {
+			if words[start+i] != word {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
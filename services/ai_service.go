@@ -2,11 +2,13 @@ package services
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
+	"os"
 	"strings"
 	"time"
 
@@ -15,9 +17,12 @@ import (
 
 // AIService handles communication with AI providers
 type AIService struct {
-	client     *http.Client
-	llmBaseURL string
-	model      string
+	client       *http.Client
+	llmBaseURL   string
+	model        string
+	capabilities map[string]bool
+	embeddings   *embeddingCache
+	fimSentinels fimSentinels
 }
 
 // LLMRequest represents the request to local LLM server
@@ -42,17 +47,60 @@ func NewAIService() *AIService {
 
 	log.Printf("AI Service initialized with LLM server: %s", llmURL)
 
+	// Optional upstream features, e.g. AI_UPSTREAM_CAPABILITIES=tools
+	capabilities := parseCapabilities(os.Getenv("AI_UPSTREAM_CAPABILITIES"))
+	if len(capabilities) > 0 {
+		log.Printf("AI Service upstream capabilities: %s", os.Getenv("AI_UPSTREAM_CAPABILITIES"))
+	}
+
+	// Sentinel tokens for fill-in-the-middle models, e.g.
+	// AI_FIM_TOKENS=<PRE>,<SUF>,<MID>
+	sentinels := defaultFIMSentinels
+	if value := os.Getenv("AI_FIM_TOKENS"); value != "" {
+		parsed, err := parseFIMSentinels(value)
+		if err != nil {
+			log.Printf("AI Service: ignoring AI_FIM_TOKENS: %v", err)
+		} else {
+			sentinels = parsed
+		}
+	}
+
 	return &AIService{
 		client: &http.Client{
 			Timeout: 30 * time.Second,
 		},
-		llmBaseURL: llmURL,
-		model:      "distilgpt2",
+		llmBaseURL:   llmURL,
+		model:        "distilgpt2",
+		capabilities: capabilities,
+		embeddings:   newEmbeddingCache(embeddingCacheEntries),
+		fimSentinels: sentinels,
+	}
+}
+
+// parseCapabilities parses a comma-separated capability list
+func parseCapabilities(value string) map[string]bool {
+	capabilities := make(map[string]bool)
+	for _, name := range strings.Split(value, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name != "" {
+			capabilities[name] = true
+		}
 	}
+	return capabilities
+}
+
+// HasCapability reports whether the upstream LLM server natively supports a feature
+func (s *AIService) HasCapability(name string) bool {
+	return s.capabilities[name]
 }
 
 // GetChatCompletion generates a chat completion based on conversation history
 func (s *AIService) GetChatCompletion(messages []models.ChatMessage, maxTokens int, temperature float64) (string, error) {
+	return s.GetChatCompletionContext(context.Background(), messages, maxTokens, temperature)
+}
+
+// GetChatCompletionContext is GetChatCompletion with a caller-controlled context
+func (s *AIService) GetChatCompletionContext(ctx context.Context, messages []models.ChatMessage, maxTokens int, temperature float64) (string, error) {
 	if len(messages) == 0 {
 		log.Printf("GetChatCompletion: no messages provided")
 		return "", fmt.Errorf("messages cannot be empty")
@@ -61,13 +109,9 @@ func (s *AIService) GetChatCompletion(messages []models.ChatMessage, maxTokens i
 	log.Printf("GetChatCompletion: processing %d messages", len(messages))
 
 	// Use the new chat/completions endpoint with proper structure
-	request := map[string]interface{}{
-		"messages":    messages,
-		"max_tokens":  maxTokens,
-		"temperature": temperature,
-	}
+	request := chatRequestBody(messages, nil, ToolChoice{}, maxTokens, temperature)
 
-	response, err := s.callLLMEndpointWithJSON("/chat/completions", request)
+	response, err := s.callLLMEndpointWithJSONContext(ctx, "/chat/completions", request)
 	if err != nil {
 		log.Printf("GetChatCompletion: LLM call failed: %v", err)
 		return "", fmt.Errorf("chat completion failed: %w", err)
@@ -89,27 +133,19 @@ func (s *AIService) GetChatCompletion(messages []models.ChatMessage, maxTokens i
 
 // GetComplete sends a completion request to the LLM server
 func (s *AIService) GetComplete(prompt string, maxTokens int, temperature float64) (string, error) {
+	return s.GetCompleteContext(context.Background(), prompt, maxTokens, temperature)
+}
+
+// GetCompleteContext is GetComplete with a caller-controlled context
+func (s *AIService) GetCompleteContext(ctx context.Context, prompt string, maxTokens int, temperature float64) (string, error) {
 	log.Printf("GetComplete: processing prompt")
 
 	if prompt == "" {
 		return "", fmt.Errorf("prompt cannot be empty")
 	}
 
-	// Set defaults
-	if maxTokens == 0 {
-		maxTokens = 150
-	}
-	if temperature == 0 {
-		temperature = 0.7
-	}
-
 	// Prepare request
-	request := LLMRequest{
-		Prompt:      prompt,
-		MaxLength:   maxTokens,
-		Temperature: temperature,
-		DoSample:    true,
-	}
+	request := newLLMRequest(prompt, maxTokens, temperature)
 
 	// Call the complete endpoint which returns a different format
 	jsonData, err := json.Marshal(request)
@@ -119,7 +155,7 @@ func (s *AIService) GetComplete(prompt string, maxTokens int, temperature float6
 	}
 
 	url := s.llmBaseURL + "/complete"
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
 	if err != nil {
 		log.Printf("GetComplete: failed to create request: %v", err)
 		return "", fmt.Errorf("failed to create request: %w", err)
@@ -164,29 +200,21 @@ func (s *AIService) GetComplete(prompt string, maxTokens int, temperature float6
 
 // GetGenerate sends a generation request to the LLM server
 func (s *AIService) GetGenerate(prompt string, maxTokens int, temperature float64) (string, error) {
+	return s.GetGenerateContext(context.Background(), prompt, maxTokens, temperature)
+}
+
+// GetGenerateContext is GetGenerate with a caller-controlled context
+func (s *AIService) GetGenerateContext(ctx context.Context, prompt string, maxTokens int, temperature float64) (string, error) {
 	log.Printf("GetGenerate: processing prompt")
 
 	if prompt == "" {
 		return "", fmt.Errorf("prompt cannot be empty")
 	}
 
-	// Set defaults
-	if maxTokens == 0 {
-		maxTokens = 150
-	}
-	if temperature == 0 {
-		temperature = 0.7
-	}
-
 	// Prepare request
-	request := LLMRequest{
-		Prompt:      prompt,
-		MaxLength:   maxTokens,
-		Temperature: temperature,
-		DoSample:    true,
-	}
+	request := newLLMRequest(prompt, maxTokens, temperature)
 
-	response, err := s.callLLMEndpoint("/generate", request)
+	response, err := s.callLLMEndpointContext(ctx, "/generate", request)
 	if err != nil {
 		log.Printf("GetGenerate: LLM call failed: %v", err)
 		return "", fmt.Errorf("generation failed: %w", err)
@@ -242,6 +270,11 @@ func (s *AIService) GetModel() string {
 
 // callLLMEndpointWithJSON makes a generic JSON request to LLM endpoints
 func (s *AIService) callLLMEndpointWithJSON(endpoint string, request interface{}) (map[string]interface{}, error) {
+	return s.callLLMEndpointWithJSONContext(context.Background(), endpoint, request)
+}
+
+// callLLMEndpointWithJSONContext is callLLMEndpointWithJSON with a caller-controlled context
+func (s *AIService) callLLMEndpointWithJSONContext(ctx context.Context, endpoint string, request interface{}) (map[string]interface{}, error) {
 	log.Printf("callLLMEndpointWithJSON: making request to %s%s", s.llmBaseURL, endpoint)
 
 	jsonData, err := json.Marshal(request)
@@ -252,7 +285,7 @@ func (s *AIService) callLLMEndpointWithJSON(endpoint string, request interface{}
 
 	// Create HTTP request
 	url := s.llmBaseURL + endpoint
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
 	if err != nil {
 		log.Printf("callLLMEndpointWithJSON: failed to create request: %v", err)
 		return nil, fmt.Errorf("failed to create request: %w", err)
@@ -295,6 +328,11 @@ func (s *AIService) callLLMEndpointWithJSON(endpoint string, request interface{}
 
 // callLLMEndpoint is a helper method to make requests to different LLM endpoints
 func (s *AIService) callLLMEndpoint(endpoint string, request LLMRequest) (string, error) {
+	return s.callLLMEndpointContext(context.Background(), endpoint, request)
+}
+
+// callLLMEndpointContext is callLLMEndpoint with a caller-controlled context
+func (s *AIService) callLLMEndpointContext(ctx context.Context, endpoint string, request LLMRequest) (string, error) {
 	log.Printf("callLLMEndpoint: making request to %s%s", s.llmBaseURL, endpoint)
 
 	jsonData, err := json.Marshal(request)
@@ -305,7 +343,7 @@ func (s *AIService) callLLMEndpoint(endpoint string, request LLMRequest) (string
 
 	// Create HTTP request
 	url := s.llmBaseURL + endpoint
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
 	if err != nil {
 		log.Printf("callLLMEndpoint: failed to create request: %v", err)
 		return "", fmt.Errorf("failed to create request: %w", err)
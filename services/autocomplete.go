@@ -0,0 +1,280 @@
+package services
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Ammar0144/ai/models"
+)
+
+// Autocomplete limits and defaults, sized for keystroke latency. Only the
+// tail of the prefix that fits the model context is sent, so longer prefixes
+// are rejected rather than trimmed on every keystroke.
+const (
+	maxAutocompleteTokens         = 16
+	maxAutocompletePrefixBytes    = 4 * 1024
+	maxAutocompleteSessionBytes   = 128
+	defaultAutocompleteTokens     = 8
+	defaultAutocompleteTemp       = 0.2
+	autocompleteTimeout           = 5 * time.Second
+	autocompleteCacheEntries      = 4096
+	autocompleteCacheTTL          = 10 * time.Minute
+	maxAutocompleteExtensionBytes = 128
+)
+
+// ErrAutocompleteSuperseded is returned to a request cancelled by a newer
+// request from the same session
+var ErrAutocompleteSuperseded = errors.New("superseded by a newer request from the same session")
+
+// AutocompleteResult is a suggestion and where it came from
+type AutocompleteResult struct {
+	Suggestion string
+	Source     string
+}
+
+// Autocompleter serves keystroke-by-keystroke suggestions. Each session has
+// at most one waiting request, identical prefixes share one upstream call,
+// and a prefix that extends an earlier prefix by the start of its suggestion
+// is answered from the rest of that suggestion.
+type Autocompleter struct {
+	ai       *AIService
+	sessions map[string]*autocompleteWaiter
+	flights  map[string]*autocompleteFlight
+	cache    *suggestionCache
+	mutex    sync.Mutex
+}
+
+// autocompleteWaiter is the latest request of a session
+type autocompleteWaiter struct {
+	cancel context.CancelCauseFunc
+}
+
+// autocompleteFlight is an upstream call shared by identical requests. The
+// call is cancelled when every waiter has gone.
+type autocompleteFlight struct {
+	done       chan struct{}
+	suggestion string
+	err        error
+	waiters    int
+	cancel     context.CancelFunc
+}
+
+// NewAutocompleter creates an autocompleter over the AI service
+func NewAutocompleter(ai *AIService) *Autocompleter {
+	return &Autocompleter{
+		ai:       ai,
+		sessions: make(map[string]*autocompleteWaiter),
+		flights:  make(map[string]*autocompleteFlight),
+		cache:    newSuggestionCache(autocompleteCacheEntries, autocompleteCacheTTL),
+	}
+}
+
+// ValidateAutocomplete checks an autocomplete request and fills in defaults
+func ValidateAutocomplete(req *models.AutocompleteRequest) error {
+	if strings.TrimSpace(req.Prefix) == "" {
+		return fmt.Errorf("prefix cannot be empty")
+	}
+	if len(req.Prefix) > maxAutocompletePrefixBytes {
+		return fmt.Errorf("prefix cannot exceed %d bytes", maxAutocompletePrefixBytes)
+	}
+	if len(req.SessionID) > maxAutocompleteSessionBytes {
+		return fmt.Errorf("session_id cannot exceed %d bytes", maxAutocompleteSessionBytes)
+	}
+	if req.MaxTokens == 0 {
+		req.MaxTokens = defaultAutocompleteTokens
+	}
+	if req.MaxTokens < 1 || req.MaxTokens > maxAutocompleteTokens {
+		return fmt.Errorf("max_tokens must be between 1 and %d", maxAutocompleteTokens)
+	}
+	if req.Temperature == 0 {
+		req.Temperature = defaultAutocompleteTemp
+	}
+	if req.Temperature < 0 || req.Temperature > 2 {
+		return fmt.Errorf("temperature must be between 0 and 2")
+	}
+	return nil
+}
+
+// Suggest returns a continuation of a validated request's prefix. A newer
+// request with the same session ID makes this one return
+// ErrAutocompleteSuperseded.
+func (a *Autocompleter) Suggest(ctx context.Context, req *models.AutocompleteRequest) (*AutocompleteResult, error) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	if req.SessionID != "" {
+		waiter := &autocompleteWaiter{cancel: cancel}
+		a.mutex.Lock()
+		if previous := a.sessions[req.SessionID]; previous != nil {
+			previous.cancel(ErrAutocompleteSuperseded)
+		}
+		a.sessions[req.SessionID] = waiter
+		a.mutex.Unlock()
+		defer func() {
+			a.mutex.Lock()
+			if a.sessions[req.SessionID] == waiter {
+				delete(a.sessions, req.SessionID)
+			}
+			a.mutex.Unlock()
+		}()
+	}
+
+	params := strconv.Itoa(req.MaxTokens) + "|" + strconv.FormatFloat(req.Temperature, 'g', -1, 64) + "|"
+	if suggestion, ok := a.cache.extend(params, req.Prefix); ok {
+		return &AutocompleteResult{Suggestion: suggestion, Source: models.AutocompleteCache}, nil
+	}
+
+	key := params + req.Prefix
+	a.mutex.Lock()
+	flight, shared := a.flights[key]
+	if !shared {
+		flight = a.startFlight(key, params, req)
+	}
+	flight.waiters++
+	a.mutex.Unlock()
+
+	select {
+	case <-flight.done:
+		if flight.err != nil {
+			return nil, flight.err
+		}
+		source := models.AutocompleteUpstream
+		if shared {
+			source = models.AutocompleteCoalesced
+		}
+		return &AutocompleteResult{Suggestion: flight.suggestion, Source: source}, nil
+	case <-ctx.Done():
+		a.mutex.Lock()
+		flight.waiters--
+		if flight.waiters == 0 {
+			// A later identical request starts afresh rather than joining the cancelled call
+			flight.cancel()
+			if a.flights[key] == flight {
+				delete(a.flights, key)
+			}
+		}
+		a.mutex.Unlock()
+		return nil, context.Cause(ctx)
+	}
+}
+
+// startFlight begins the upstream call for a prefix; the caller holds the mutex
+func (a *Autocompleter) startFlight(key, params string, req *models.AutocompleteRequest) *autocompleteFlight {
+	ctx, cancel := context.WithTimeout(context.Background(), autocompleteTimeout)
+	flight := &autocompleteFlight{done: make(chan struct{}), cancel: cancel}
+	a.flights[key] = flight
+
+	prefix, maxTokens, temperature := req.Prefix, req.MaxTokens, req.Temperature
+	go func() {
+		defer cancel()
+		prompt := tailToTokens(prefix, ModelContextTokens-maxTokens)
+		output, err := a.ai.GetCompleteContext(ctx, prompt, maxTokens, temperature)
+		if err == nil {
+			flight.suggestion = trimSuggestion(output)
+			a.cache.put(params, prefix, flight.suggestion)
+		} else if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			err = fmt.Errorf("%w: %v", context.DeadlineExceeded, err)
+		}
+		flight.err = err
+
+		a.mutex.Lock()
+		if a.flights[key] == flight {
+			delete(a.flights, key)
+		}
+		a.mutex.Unlock()
+		close(flight.done)
+	}()
+	return flight
+}
+
+// trimSuggestion keeps a suggestion to the rest of the current line
+func trimSuggestion(output string) string {
+	start := len(output) - len(strings.TrimLeft(output, " \t\r\n"))
+	if i := strings.IndexByte(output[start:], '\n'); i >= 0 {
+		output = output[:start+i]
+	}
+	return strings.TrimRight(output, " \t\r\n")
+}
+
+// suggestionCache is a thread-safe LRU of suggestions by prefix with expiry
+type suggestionCache struct {
+	capacity int
+	ttl      time.Duration
+	entries  map[string]*list.Element
+	order    *list.List
+	mutex    sync.Mutex
+}
+
+type suggestionEntry struct {
+	key        string
+	suggestion string
+	expires    time.Time
+}
+
+func newSuggestionCache(capacity int, ttl time.Duration) *suggestionCache {
+	return &suggestionCache{
+		capacity: capacity,
+		ttl:      ttl,
+		entries:  make(map[string]*list.Element),
+		order:    list.New(),
+	}
+}
+
+func (c *suggestionCache) put(params, prefix, suggestion string) {
+	if suggestion == "" {
+		return
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	key := params + prefix
+	entry := &suggestionEntry{key: key, suggestion: suggestion, expires: time.Now().Add(c.ttl)}
+	if element, ok := c.entries[key]; ok {
+		element.Value = entry
+		c.order.MoveToFront(element)
+		return
+	}
+	c.entries[key] = c.order.PushFront(entry)
+	if c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*suggestionEntry).key)
+	}
+}
+
+// extend finds an earlier prefix that this prefix extends by the start of
+// its suggestion and returns the rest of that suggestion. An exact repeat
+// of a cached prefix returns its whole suggestion.
+func (c *suggestionCache) extend(params, prefix string) (string, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	// Indexing with a converted byte slice does not allocate per lookup
+	key := []byte(params + prefix)
+	now := time.Now()
+	for cut := len(prefix); cut >= 0 && len(prefix)-cut <= maxAutocompleteExtensionBytes; cut-- {
+		element, ok := c.entries[string(key[:len(params)+cut])]
+		if !ok {
+			continue
+		}
+		entry := element.Value.(*suggestionEntry)
+		if now.After(entry.expires) {
+			c.order.Remove(element)
+			delete(c.entries, entry.key)
+			continue
+		}
+		typed := prefix[cut:]
+		if !strings.HasPrefix(entry.suggestion, typed) || len(typed) == len(entry.suggestion) {
+			continue
+		}
+		c.order.MoveToFront(element)
+		return entry.suggestion[len(typed):], true
+	}
+	return "", false
+}
@@ -0,0 +1,114 @@
+package services
+
+import (
+	"math"
+	"sort"
+)
+
+// BM25 parameters: term frequency saturation and length normalization
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// bm25Index is an inverted index scoring documents with Okapi BM25. It is
+// not safe for concurrent writes; the owner holds a lock.
+type bm25Index struct {
+	postings    map[string]map[string]int // term -> document ID -> term frequency
+	lengths     map[string]int
+	totalLength int
+}
+
+// rankedID is an ID with a relevance score
+type rankedID struct {
+	id    string
+	score float64
+}
+
+func newBM25Index() *bm25Index {
+	return &bm25Index{
+		postings: make(map[string]map[string]int),
+		lengths:  make(map[string]int),
+	}
+}
+
+// add indexes a document; an ID already present must be removed first
+func (b *bm25Index) add(id, text string) {
+	terms := Tokenize(text)
+	for _, term := range terms {
+		postings, ok := b.postings[term]
+		if !ok {
+			postings = make(map[string]int)
+			b.postings[term] = postings
+		}
+		postings[id]++
+	}
+	b.lengths[id] = len(terms)
+	b.totalLength += len(terms)
+}
+
+// remove drops a document given the text it was indexed with
+func (b *bm25Index) remove(id, text string) {
+	length, ok := b.lengths[id]
+	if !ok {
+		return
+	}
+	for _, term := range Tokenize(text) {
+		if postings, ok := b.postings[term]; ok {
+			delete(postings, id)
+			if len(postings) == 0 {
+				delete(b.postings, term)
+			}
+		}
+	}
+	delete(b.lengths, id)
+	b.totalLength -= length
+}
+
+// search returns up to k documents accepted by the filter, best first
+func (b *bm25Index) search(query string, k int, accept func(id string) bool) []rankedID {
+	if len(b.lengths) == 0 {
+		return nil
+	}
+	n := float64(len(b.lengths))
+	avgLength := float64(b.totalLength) / n
+	if avgLength == 0 {
+		avgLength = 1
+	}
+
+	scores := make(map[string]float64)
+	seen := make(map[string]bool)
+	for _, term := range Tokenize(query) {
+		if seen[term] {
+			continue
+		}
+		seen[term] = true
+		postings := b.postings[term]
+		if len(postings) == 0 {
+			continue
+		}
+		df := float64(len(postings))
+		idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+		for id, tf := range postings {
+			norm := bm25K1 * (1 - bm25B + bm25B*float64(b.lengths[id])/avgLength)
+			scores[id] += idf * float64(tf) * (bm25K1 + 1) / (float64(tf) + norm)
+		}
+	}
+
+	matches := make([]rankedID, 0, len(scores))
+	for id, score := range scores {
+		if accept == nil || accept(id) {
+			matches = append(matches, rankedID{id, score})
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].score != matches[j].score {
+			return matches[i].score > matches[j].score
+		}
+		return matches[i].id < matches[j].id
+	})
+	if len(matches) > k {
+		matches = matches[:k]
+	}
+	return matches
+}
@@ -0,0 +1,304 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Limits keep expression evaluation cheap and bounded
+const (
+	maxExpressionLength = 256
+	maxExpressionDepth  = 32
+)
+
+// calculatorFunctions are the functions available to arithmetic expressions
+var calculatorFunctions = map[string]func(args []float64) (float64, error){
+	"sqrt":  unaryFunction(math.Sqrt),
+	"abs":   unaryFunction(math.Abs),
+	"floor": unaryFunction(math.Floor),
+	"ceil":  unaryFunction(math.Ceil),
+	"round": unaryFunction(math.Round),
+	"exp":   unaryFunction(math.Exp),
+	"ln":    unaryFunction(math.Log),
+	"log":   unaryFunction(math.Log10),
+	"sin":   unaryFunction(math.Sin),
+	"cos":   unaryFunction(math.Cos),
+	"tan":   unaryFunction(math.Tan),
+	"pow": func(args []float64) (float64, error) {
+		if len(args) != 2 {
+			return 0, fmt.Errorf("pow expects 2 arguments")
+		}
+		return math.Pow(args[0], args[1]), nil
+	},
+	"min": func(args []float64) (float64, error) {
+		if len(args) == 0 {
+			return 0, fmt.Errorf("min expects at least 1 argument")
+		}
+		result := args[0]
+		for _, arg := range args[1:] {
+			result = math.Min(result, arg)
+		}
+		return result, nil
+	},
+	"max": func(args []float64) (float64, error) {
+		if len(args) == 0 {
+			return 0, fmt.Errorf("max expects at least 1 argument")
+		}
+		result := args[0]
+		for _, arg := range args[1:] {
+			result = math.Max(result, arg)
+		}
+		return result, nil
+	},
+}
+
+// calculatorConstants are the named constants available to expressions
+var calculatorConstants = map[string]float64{
+	"pi": math.Pi,
+	"e":  math.E,
+}
+
+func unaryFunction(fn func(float64) float64) func(args []float64) (float64, error) {
+	return func(args []float64) (float64, error) {
+		if len(args) != 1 {
+			return 0, fmt.Errorf("function expects 1 argument")
+		}
+		return fn(args[0]), nil
+	}
+}
+
+// EvaluateExpression safely evaluates an arithmetic expression. It supports
+// + - * / % ^, parentheses, the constants pi and e and a fixed set of math
+// functions; nothing else is reachable from the expression.
+func EvaluateExpression(expression string) (float64, error) {
+	if strings.TrimSpace(expression) == "" {
+		return 0, fmt.Errorf("expression cannot be empty")
+	}
+	if len(expression) > maxExpressionLength {
+		return 0, fmt.Errorf("expression exceeds %d characters", maxExpressionLength)
+	}
+
+	p := &expressionParser{input: expression}
+	value, err := p.parseExpression(0)
+	if err != nil {
+		return 0, err
+	}
+	p.skipSpaces()
+	if p.pos < len(p.input) {
+		return 0, fmt.Errorf("unexpected %q at position %d", p.input[p.pos], p.pos)
+	}
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return 0, fmt.Errorf("result is not a finite number")
+	}
+	return value, nil
+}
+
+// expressionParser is a recursive descent parser over arithmetic expressions
+type expressionParser struct {
+	input string
+	pos   int
+}
+
+func (p *expressionParser) skipSpaces() {
+	for p.pos < len(p.input) && unicode.IsSpace(rune(p.input[p.pos])) {
+		p.pos++
+	}
+}
+
+func (p *expressionParser) peek() byte {
+	p.skipSpaces()
+	if p.pos < len(p.input) {
+		return p.input[p.pos]
+	}
+	return 0
+}
+
+// parseExpression handles addition and subtraction
+func (p *expressionParser) parseExpression(depth int) (float64, error) {
+	if depth > maxExpressionDepth {
+		return 0, fmt.Errorf("expression is nested too deeply")
+	}
+	left, err := p.parseTerm(depth)
+	if err != nil {
+		return 0, err
+	}
+	for {
+		op := p.peek()
+		if op != '+' && op != '-' {
+			return left, nil
+		}
+		p.pos++
+		right, err := p.parseTerm(depth)
+		if err != nil {
+			return 0, err
+		}
+		if op == '+' {
+			left += right
+		} else {
+			left -= right
+		}
+	}
+}
+
+// parseTerm handles multiplication, division and modulo
+func (p *expressionParser) parseTerm(depth int) (float64, error) {
+	left, err := p.parseUnary(depth)
+	if err != nil {
+		return 0, err
+	}
+	for {
+		op := p.peek()
+		if op != '*' && op != '/' && op != '%' {
+			return left, nil
+		}
+		p.pos++
+		right, err := p.parseUnary(depth)
+		if err != nil {
+			return 0, err
+		}
+		switch op {
+		case '*':
+			left *= right
+		case '/':
+			if right == 0 {
+				return 0, fmt.Errorf("division by zero")
+			}
+			left /= right
+		case '%':
+			if right == 0 {
+				return 0, fmt.Errorf("modulo by zero")
+			}
+			left = math.Mod(left, right)
+		}
+	}
+}
+
+// parseUnary handles leading signs
+func (p *expressionParser) parseUnary(depth int) (float64, error) {
+	if depth > maxExpressionDepth {
+		return 0, fmt.Errorf("expression is nested too deeply")
+	}
+	switch p.peek() {
+	case '-':
+		p.pos++
+		value, err := p.parseUnary(depth + 1)
+		return -value, err
+	case '+':
+		p.pos++
+		return p.parseUnary(depth + 1)
+	}
+	return p.parsePower(depth)
+}
+
+// parsePower handles right-associative exponentiation
+func (p *expressionParser) parsePower(depth int) (float64, error) {
+	base, err := p.parsePrimary(depth)
+	if err != nil {
+		return 0, err
+	}
+	if p.peek() == '^' {
+		p.pos++
+		exponent, err := p.parseUnary(depth + 1)
+		if err != nil {
+			return 0, err
+		}
+		return math.Pow(base, exponent), nil
+	}
+	return base, nil
+}
+
+// parsePrimary handles numbers, constants, function calls and parentheses
+func (p *expressionParser) parsePrimary(depth int) (float64, error) {
+	c := p.peek()
+	switch {
+	case c == '(':
+		p.pos++
+		value, err := p.parseExpression(depth + 1)
+		if err != nil {
+			return 0, err
+		}
+		if p.peek() != ')' {
+			return 0, fmt.Errorf("missing closing parenthesis")
+		}
+		p.pos++
+		return value, nil
+	case c == '.' || (c >= '0' && c <= '9'):
+		return p.parseNumber()
+	case unicode.IsLetter(rune(c)):
+		return p.parseIdentifier(depth)
+	case c == 0:
+		return 0, fmt.Errorf("unexpected end of expression")
+	}
+	return 0, fmt.Errorf("unexpected %q at position %d", c, p.pos)
+}
+
+func (p *expressionParser) parseNumber() (float64, error) {
+	start := p.pos
+	for p.pos < len(p.input) {
+		c := p.input[p.pos]
+		if (c >= '0' && c <= '9') || c == '.' {
+			p.pos++
+			continue
+		}
+		// Scientific notation such as 1e3 or 2.5E-4
+		if (c == 'e' || c == 'E') && p.pos+1 < len(p.input) {
+			next := p.input[p.pos+1]
+			if (next >= '0' && next <= '9') || next == '-' || next == '+' {
+				p.pos += 2
+				continue
+			}
+		}
+		break
+	}
+	value, err := strconv.ParseFloat(p.input[start:p.pos], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number %q", p.input[start:p.pos])
+	}
+	return value, nil
+}
+
+func (p *expressionParser) parseIdentifier(depth int) (float64, error) {
+	start := p.pos
+	for p.pos < len(p.input) && (unicode.IsLetter(rune(p.input[p.pos])) || unicode.IsDigit(rune(p.input[p.pos]))) {
+		p.pos++
+	}
+	name := strings.ToLower(p.input[start:p.pos])
+
+	if p.peek() != '(' {
+		if value, ok := calculatorConstants[name]; ok {
+			return value, nil
+		}
+		return 0, fmt.Errorf("unknown identifier %q", name)
+	}
+
+	fn, ok := calculatorFunctions[name]
+	if !ok {
+		return 0, fmt.Errorf("unknown function %q", name)
+	}
+
+	p.pos++ // consume '('
+	var args []float64
+	if p.peek() == ')' {
+		p.pos++
+		return fn(args)
+	}
+	for {
+		arg, err := p.parseExpression(depth + 1)
+		if err != nil {
+			return 0, err
+		}
+		args = append(args, arg)
+		switch p.peek() {
+		case ',':
+			p.pos++
+		case ')':
+			p.pos++
+			return fn(args)
+		default:
+			return 0, fmt.Errorf("expected ',' or ')' in call to %s", name)
+		}
+	}
+}
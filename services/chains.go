@@ -0,0 +1,422 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/Ammar0144/ai/models"
+)
+
+// Chain limits
+const (
+	maxChainSteps         = 20
+	maxChainParallelSteps = 4
+	defaultChainTimeout   = 60 * time.Second
+	maxChainTimeout       = 120 * time.Second
+)
+
+// Chain store errors
+var (
+	ErrChainNotFound = errors.New("chain not found")
+	ErrChainExists   = errors.New("chain already exists")
+)
+
+// chainStepIDPattern keeps step IDs addressable as {{.steps.<id>}}
+var chainStepIDPattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]{0,63}$`)
+
+// stepReferencePatterns find the steps a prompt template reads from
+var stepReferencePatterns = []*regexp.Regexp{
+	regexp.MustCompile(`\.steps\.([A-Za-z][A-Za-z0-9_]*)`),
+	regexp.MustCompile(`index\s+\.steps\s+"([^"]+)"`),
+}
+
+// ChainPlan is a validated chain ready to run
+type ChainPlan struct {
+	steps     []models.ChainStep
+	deps      [][]int
+	templates []*template.Template
+	output    int
+}
+
+// ValidateChain checks a chain definition, fills in defaults and returns its
+// execution plan. Dependencies are the union of depends_on and the steps the
+// prompt references; cycles are rejected.
+func ValidateChain(def *models.ChainDefinition) (*ChainPlan, error) {
+	if len(def.Steps) == 0 {
+		return nil, fmt.Errorf("chain must have at least one step")
+	}
+	if len(def.Steps) > maxChainSteps {
+		return nil, fmt.Errorf("chain cannot have more than %d steps", maxChainSteps)
+	}
+
+	index := make(map[string]int, len(def.Steps))
+	for i := range def.Steps {
+		step := &def.Steps[i]
+		if !chainStepIDPattern.MatchString(step.ID) {
+			return nil, fmt.Errorf("step %d: id must start with a letter and contain only letters, digits and underscores", i)
+		}
+		if _, dup := index[step.ID]; dup {
+			return nil, fmt.Errorf("duplicate step id %q", step.ID)
+		}
+		index[step.ID] = i
+
+		switch step.Upstream {
+		case "":
+			step.Upstream = models.TaskUpstreamComplete
+		case models.TaskUpstreamComplete, models.TaskUpstreamGenerate, models.TaskUpstreamChat:
+		default:
+			return nil, fmt.Errorf("step %q: unsupported upstream %q", step.ID, step.Upstream)
+		}
+		if strings.TrimSpace(step.Prompt) == "" {
+			return nil, fmt.Errorf("step %q: prompt is required", step.ID)
+		}
+		if step.MaxTokens < 0 {
+			return nil, fmt.Errorf("step %q: max_tokens cannot be negative", step.ID)
+		}
+	}
+
+	plan := &ChainPlan{
+		steps:     def.Steps,
+		deps:      make([][]int, len(def.Steps)),
+		templates: make([]*template.Template, len(def.Steps)),
+	}
+	for i, step := range def.Steps {
+		parsed, err := template.New(step.ID).Funcs(taskFuncs).Option("missingkey=error").Parse(step.Prompt)
+		if err != nil {
+			return nil, fmt.Errorf("step %q: invalid prompt template: %w", step.ID, err)
+		}
+		plan.templates[i] = parsed
+
+		names := append([]string{}, step.DependsOn...)
+		for _, pattern := range stepReferencePatterns {
+			for _, match := range pattern.FindAllStringSubmatch(step.Prompt, -1) {
+				names = append(names, match[1])
+			}
+		}
+		seen := make(map[int]bool)
+		for _, name := range names {
+			dep, ok := index[name]
+			if !ok {
+				return nil, fmt.Errorf("step %q: depends on unknown step %q", step.ID, name)
+			}
+			if dep == i {
+				return nil, fmt.Errorf("step %q: cannot depend on itself", step.ID)
+			}
+			if !seen[dep] {
+				seen[dep] = true
+				plan.deps[i] = append(plan.deps[i], dep)
+			}
+		}
+		sort.Ints(plan.deps[i])
+	}
+
+	if cycle := findChainCycle(plan); cycle != "" {
+		return nil, fmt.Errorf("chain has a dependency cycle through step %q", cycle)
+	}
+
+	if def.Output == "" {
+		def.Output = def.Steps[len(def.Steps)-1].ID
+	}
+	output, ok := index[def.Output]
+	if !ok {
+		return nil, fmt.Errorf("output refers to unknown step %q", def.Output)
+	}
+	plan.output = output
+	return plan, nil
+}
+
+// findChainCycle returns the ID of a step on a dependency cycle, if any
+func findChainCycle(plan *ChainPlan) string {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make([]int, len(plan.steps))
+	var visit func(int) bool
+	visit = func(i int) bool {
+		switch state[i] {
+		case visiting:
+			return true
+		case visited:
+			return false
+		}
+		state[i] = visiting
+		for _, dep := range plan.deps[i] {
+			if visit(dep) {
+				return true
+			}
+		}
+		state[i] = visited
+		return false
+	}
+	for i := range plan.steps {
+		if visit(i) {
+			return plan.steps[i].ID
+		}
+	}
+	return ""
+}
+
+// ChainTimeout clamps a requested timeout in seconds to the allowed range
+func ChainTimeout(seconds int) time.Duration {
+	if seconds <= 0 {
+		return defaultChainTimeout
+	}
+	timeout := time.Duration(seconds) * time.Second
+	if timeout > maxChainTimeout {
+		return maxChainTimeout
+	}
+	return timeout
+}
+
+// RunChain executes a validated chain. Each step starts as soon as all of its
+// dependencies have succeeded, with up to four steps in flight at once; steps
+// whose dependencies failed are skipped. Results are returned in definition
+// order.
+func (s *AIService) RunChain(ctx context.Context, plan *ChainPlan, input map[string]interface{}) []models.ChainStepResult {
+	started := time.Now()
+	results := make([]models.ChainStepResult, len(plan.steps))
+	done := make([]chan struct{}, len(plan.steps))
+	for i := range done {
+		done[i] = make(chan struct{})
+	}
+	if input == nil {
+		input = map[string]interface{}{}
+	}
+
+	semaphore := make(chan struct{}, maxChainParallelSteps)
+	var wg sync.WaitGroup
+	for i := range plan.steps {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer close(done[i])
+
+			step := plan.steps[i]
+			result := &results[i]
+			result.ID = step.ID
+			for _, dep := range plan.deps[i] {
+				result.DependsOn = append(result.DependsOn, plan.steps[dep].ID)
+			}
+
+			// Wait for dependencies; their results are final once done is closed.
+			// Upstream calls honour ctx, so every step finishes after a timeout.
+			outputs := make(map[string]string, len(plan.deps[i]))
+			for _, dep := range plan.deps[i] {
+				<-done[dep]
+				if results[dep].Status != models.ChainStepSucceeded {
+					result.Status = models.ChainStepSkipped
+					result.Error = fmt.Sprintf("dependency %q did not succeed", plan.steps[dep].ID)
+					result.StartedMs = time.Since(started).Milliseconds()
+					return
+				}
+				outputs[plan.steps[dep].ID] = results[dep].Output
+			}
+
+			select {
+			case semaphore <- struct{}{}:
+				defer func() { <-semaphore }()
+			case <-ctx.Done():
+				result.Status = models.ChainStepSkipped
+				result.Error = "chain timed out"
+				result.StartedMs = time.Since(started).Milliseconds()
+				return
+			}
+
+			stepStarted := time.Now()
+			result.StartedMs = stepStarted.Sub(started).Milliseconds()
+			defer func() { result.DurationMs = time.Since(stepStarted).Milliseconds() }()
+
+			var prompt strings.Builder
+			data := map[string]interface{}{"input": input, "steps": outputs}
+			if err := plan.templates[i].Execute(&prompt, data); err != nil {
+				result.Status = models.ChainStepFailed
+				result.Error = fmt.Sprintf("failed to render prompt: %v", err)
+				return
+			}
+			result.Prompt = prompt.String()
+
+			maxTokens := step.MaxTokens
+			if maxTokens == 0 {
+				maxTokens = 150
+			}
+			temperature := step.Temperature
+			if temperature == 0 {
+				temperature = 0.7
+			}
+
+			output, err := s.CallUpstreamContext(ctx, step.Upstream, step.System, result.Prompt, maxTokens, temperature)
+			if err != nil {
+				log.Printf("Chain step %s failed: %v", step.ID, err)
+				result.Status = models.ChainStepFailed
+				result.Error = err.Error()
+				return
+			}
+			result.Status = models.ChainStepSucceeded
+			result.Output = strings.TrimSpace(output)
+		}(i)
+	}
+	wg.Wait()
+	return results
+}
+
+// OutputStep returns the index of the step whose output is the chain result
+func (p *ChainPlan) OutputStep() int {
+	return p.output
+}
+
+// ChainStore is a thread-safe registry of named chain definitions,
+// optionally persisted to a JSON file
+type ChainStore struct {
+	chains map[string]*models.ChainDefinition
+	path   string
+	mutex  sync.RWMutex
+}
+
+// NewChainStore creates a chain store, loading AI_CHAINS_FILE when set
+func NewChainStore() *ChainStore {
+	store := &ChainStore{
+		chains: make(map[string]*models.ChainDefinition),
+		path:   os.Getenv("AI_CHAINS_FILE"),
+	}
+
+	if store.path != "" {
+		data, err := os.ReadFile(store.path)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+		case err != nil:
+			log.Printf("Chain store: failed to read %s: %v", store.path, err)
+		default:
+			var chains []*models.ChainDefinition
+			if err := json.Unmarshal(data, &chains); err != nil {
+				log.Printf("Chain store: failed to parse %s: %v", store.path, err)
+			}
+			for _, chain := range chains {
+				store.chains[chain.Name] = chain
+			}
+			log.Printf("Chain store: loaded %d chains from %s", len(chains), store.path)
+		}
+	}
+	return store
+}
+
+// save persists the registry when a file is configured; callers hold the lock
+func (c *ChainStore) save() error {
+	if c.path == "" {
+		return nil
+	}
+	return writeJSONFileAtomic(c.path, c.sorted())
+}
+
+// sorted returns the chains ordered by name; callers hold the lock
+func (c *ChainStore) sorted() []*models.ChainDefinition {
+	chains := make([]*models.ChainDefinition, 0, len(c.chains))
+	for _, chain := range c.chains {
+		chains = append(chains, chain)
+	}
+	sort.Slice(chains, func(i, j int) bool { return chains[i].Name < chains[j].Name })
+	return chains
+}
+
+// Put validates and stores a chain. With create set it fails if the name is
+// taken; otherwise it replaces an existing chain.
+func (c *ChainStore) Put(def models.ChainDefinition, create bool) (*models.ChainDefinition, error) {
+	if !templateIDPattern.MatchString(def.Name) {
+		return nil, fmt.Errorf("name must be a lowercase slug")
+	}
+	if _, err := ValidateChain(&def); err != nil {
+		return nil, err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	previous, exists := c.chains[def.Name]
+	switch {
+	case create && exists:
+		return nil, ErrChainExists
+	case !create && !exists:
+		return nil, ErrChainNotFound
+	}
+
+	now := time.Now()
+	def.CreatedAt = now
+	if exists {
+		def.CreatedAt = previous.CreatedAt
+	}
+	def.UpdatedAt = now
+	c.chains[def.Name] = &def
+	if err := c.save(); err != nil {
+		if exists {
+			c.chains[def.Name] = previous
+		} else {
+			delete(c.chains, def.Name)
+		}
+		return nil, err
+	}
+	stored := def
+	return &stored, nil
+}
+
+// Get returns a copy of a stored chain
+func (c *ChainStore) Get(name string) (*models.ChainDefinition, error) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	chain, ok := c.chains[name]
+	if !ok {
+		return nil, ErrChainNotFound
+	}
+	return cloneChain(chain), nil
+}
+
+// List returns copies of all stored chains ordered by name
+func (c *ChainStore) List() []*models.ChainDefinition {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	chains := c.sorted()
+	for i, chain := range chains {
+		chains[i] = cloneChain(chain)
+	}
+	return chains
+}
+
+// Delete removes a stored chain
+func (c *ChainStore) Delete(name string) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	chain, ok := c.chains[name]
+	if !ok {
+		return ErrChainNotFound
+	}
+	delete(c.chains, name)
+	if err := c.save(); err != nil {
+		c.chains[name] = chain
+		return err
+	}
+	return nil
+}
+
+// cloneChain deep-copies a chain definition so callers cannot mutate the store
+func cloneChain(chain *models.ChainDefinition) *models.ChainDefinition {
+	clone := *chain
+	clone.Steps = make([]models.ChainStep, len(chain.Steps))
+	for i, step := range chain.Steps {
+		step.DependsOn = append([]string(nil), step.DependsOn...)
+		clone.Steps[i] = step
+	}
+	return &clone
+}
@@ -0,0 +1,228 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Chunking defaults, in estimated tokens
+const (
+	DefaultChunkTokens  = 256
+	DefaultChunkOverlap = 32
+	minChunkTokens      = 32
+	maxChunkTokens      = ModelContextTokens / 2
+)
+
+// TextChunk is a span of a larger text. Start and End are character (rune)
+// offsets into the original text, End exclusive.
+type TextChunk struct {
+	Text   string
+	Start  int
+	End    int
+	Tokens int
+}
+
+// ResolveChunking applies the defaults to zero chunk sizes and checks that
+// chunks fit the model context and overlap less than half a chunk
+func ResolveChunking(tokens, overlap int) (int, int, error) {
+	if tokens == 0 {
+		tokens = DefaultChunkTokens
+	}
+	if tokens < minChunkTokens || tokens > maxChunkTokens {
+		return 0, 0, fmt.Errorf("chunk_tokens must be between %d and %d", minChunkTokens, maxChunkTokens)
+	}
+	if overlap == 0 {
+		overlap = DefaultChunkOverlap
+	}
+	if overlap < 0 || overlap*2 >= tokens {
+		return 0, 0, fmt.Errorf("chunk_overlap must be less than half of chunk_tokens")
+	}
+	return tokens, overlap, nil
+}
+
+// textSegment is a byte range of text that chunking does not split
+type textSegment struct {
+	start  int
+	end    int
+	tokens int
+}
+
+// ChunkText splits text into chunks of at most maxTokens estimated tokens.
+// Chunks break at sentence and line boundaries where possible, falling back to
+// word boundaries for long sentences, and consecutive chunks share up to
+// overlap tokens of trailing sentences so context is not lost at a cut.
+func ChunkText(text string, maxTokens, overlap int) []TextChunk {
+	if maxTokens <= 0 {
+		maxTokens = DefaultChunkTokens
+	}
+	if overlap < 0 || overlap >= maxTokens {
+		overlap = 0
+	}
+
+	segments := splitSegments(text, maxTokens)
+	offsets := newRuneOffsets(text)
+	var chunks []TextChunk
+	for i := 0; i < len(segments); {
+		j, tokens := i, 0
+		for j < len(segments) && (j == i || tokens+segments[j].tokens <= maxTokens) {
+			tokens += segments[j].tokens
+			j++
+		}
+
+		start, end := segments[i].start, segments[j-1].end
+		for start < end {
+			r, size := utf8.DecodeRuneInString(text[start:])
+			if !unicode.IsSpace(r) {
+				break
+			}
+			start += size
+		}
+		for end > start {
+			r, size := utf8.DecodeLastRuneInString(text[:end])
+			if !unicode.IsSpace(r) {
+				break
+			}
+			end -= size
+		}
+		if start < end {
+			chunks = append(chunks, TextChunk{
+				Text:   text[start:end],
+				Start:  offsets.start(start),
+				End:    offsets.end(end),
+				Tokens: EstimateTokens(text[start:end]),
+			})
+		}
+		if j >= len(segments) {
+			break
+		}
+
+		// Step back over trailing segments that fit in the overlap, always
+		// moving forward by at least one segment and leaving room for the
+		// next new segment
+		k, overlapTokens := j, 0
+		for k-1 > i && overlapTokens+segments[k-1].tokens <= overlap && overlapTokens+segments[k-1].tokens+segments[j].tokens <= maxTokens {
+			overlapTokens += segments[k-1].tokens
+			k--
+		}
+		i = k
+	}
+	return chunks
+}
+
+// splitSegments divides text into sentences and lines, splitting any segment
+// over maxTokens at word boundaries
+func splitSegments(text string, maxTokens int) []textSegment {
+	var segments []textSegment
+	add := func(start, end int) {
+		if start >= end || strings.TrimSpace(text[start:end]) == "" {
+			return
+		}
+		tokens := EstimateTokens(text[start:end])
+		if tokens <= maxTokens {
+			segments = append(segments, textSegment{start, end, tokens})
+			return
+		}
+		segments = append(segments, splitWords(text, start, end, maxTokens)...)
+	}
+
+	start := 0
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		i += size
+		boundary := r == '\n'
+		if r == '.' || r == '!' || r == '?' || r == '。' {
+			next, _ := utf8.DecodeRuneInString(text[i:])
+			boundary = i >= len(text) || unicode.IsSpace(next)
+		}
+		if !boundary {
+			continue
+		}
+		// Keep trailing whitespace with the segment it follows
+		for i < len(text) {
+			next, nextSize := utf8.DecodeRuneInString(text[i:])
+			if !unicode.IsSpace(next) {
+				break
+			}
+			i += nextSize
+		}
+		add(start, i)
+		start = i
+	}
+	add(start, len(text))
+	return segments
+}
+
+// splitWords divides text[start:end] into pieces of at most maxTokens at
+// whitespace, cutting single words that are longer than that
+func splitWords(text string, start, end, maxTokens int) []textSegment {
+	var pieces []textSegment
+	pieceStart, tokens := start, 0
+	for i := start; i < end; {
+		wordEnd := i
+		for wordEnd < end {
+			r, size := utf8.DecodeRuneInString(text[wordEnd:])
+			if unicode.IsSpace(r) && wordEnd > i {
+				break
+			}
+			wordEnd += size
+		}
+		for wordEnd < end {
+			r, size := utf8.DecodeRuneInString(text[wordEnd:])
+			if !unicode.IsSpace(r) {
+				break
+			}
+			wordEnd += size
+		}
+
+		wordTokens := EstimateTokens(text[i:wordEnd])
+		if tokens > 0 && tokens+wordTokens > maxTokens {
+			pieces = append(pieces, textSegment{pieceStart, i, tokens})
+			pieceStart, tokens = i, 0
+		}
+		for wordTokens > maxTokens {
+			cut := i + len(TruncateToTokens(text[i:wordEnd], maxTokens))
+			if cut == i {
+				_, size := utf8.DecodeRuneInString(text[i:])
+				cut = i + size
+			}
+			pieces = append(pieces, textSegment{i, cut, EstimateTokens(text[i:cut])})
+			i, pieceStart = cut, cut
+			wordTokens = EstimateTokens(text[i:wordEnd])
+		}
+		tokens += wordTokens
+		i = wordEnd
+	}
+	if pieceStart < end {
+		pieces = append(pieces, textSegment{pieceStart, end, tokens})
+	}
+	return pieces
+}
+
+// runeOffsets converts increasing byte offsets to rune offsets without
+// rescanning the text from the start each time. Chunk starts and chunk ends
+// each increase, so they get separate cursors.
+type runeOffsets struct {
+	text      string
+	startByte int
+	startRune int
+	endByte   int
+	endRune   int
+}
+
+func newRuneOffsets(text string) *runeOffsets {
+	return &runeOffsets{text: text}
+}
+
+func (o *runeOffsets) start(b int) int {
+	o.startRune += utf8.RuneCountInString(o.text[o.startByte:b])
+	o.startByte = b
+	return o.startRune
+}
+
+func (o *runeOffsets) end(b int) int {
+	o.endRune += utf8.RuneCountInString(o.text[o.endByte:b])
+	o.endByte = b
+	return o.endRune
+}
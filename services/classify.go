@@ -0,0 +1,456 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Ammar0144/ai/models"
+)
+
+// Classification limits and defaults
+const (
+	maxClassifyLabels            = 32
+	maxClassifyLabelTokens       = 16
+	maxClassifyDescriptionTokens = 64
+	maxClassifyVotes             = 15
+	maxClassifyParallel          = 4
+	defaultClassifyVotes         = 5
+	defaultMultiLabelThreshold   = 0.5
+	classifyPromptOverhead       = 48
+)
+
+// classifyListSeparator splits a multi-label answer into label names
+var classifyListSeparator = regexp.MustCompile(`\s*(?:[,;/|\n]|\band\b)\s*`)
+
+// ClassifyPlan is a validated classification request
+type ClassifyPlan struct {
+	text        string
+	labels      []models.ClassifyLabel
+	votes       int
+	temperature float64
+	answerSize  int
+
+	MultiLabel bool
+	Threshold  float64
+	Method     string
+}
+
+// ClassifyResult holds one score per label in request order
+type ClassifyResult struct {
+	Scores    []float64
+	Votes     []int
+	Calls     int
+	Abstained int
+}
+
+// PlanClassify validates a classification request and fills in defaults.
+// Labels are scored from token logprobs when the upstream has the "logprobs"
+// capability and by voting over sampled constrained answers otherwise.
+func (s *AIService) PlanClassify(req *models.ClassifyRequest) (*ClassifyPlan, error) {
+	req.Text = strings.TrimSpace(req.Text)
+	if req.Text == "" {
+		return nil, fmt.Errorf("text cannot be empty")
+	}
+	minLabels := 2
+	if req.MultiLabel {
+		minLabels = 1
+	}
+	if len(req.Labels) < minLabels || len(req.Labels) > maxClassifyLabels {
+		return nil, fmt.Errorf("between %d and %d labels are required", minLabels, maxClassifyLabels)
+	}
+	seen := make(map[string]bool)
+	answerSize := 0
+	for i := range req.Labels {
+		label := &req.Labels[i]
+		label.Name = strings.TrimSpace(label.Name)
+		label.Description = strings.TrimSpace(label.Description)
+		if label.Name == "" {
+			return nil, fmt.Errorf("label %d has no name", i)
+		}
+		if strings.ContainsAny(label.Name, ",;/|\n") {
+			return nil, fmt.Errorf("label %q cannot contain , ; / | or newlines", label.Name)
+		}
+		key := strings.ToLower(label.Name)
+		if seen[key] {
+			return nil, fmt.Errorf("duplicate label %q", label.Name)
+		}
+		seen[key] = true
+		tokens := EstimateTokens(label.Name)
+		if tokens > maxClassifyLabelTokens {
+			return nil, fmt.Errorf("label %q exceeds %d tokens", label.Name, maxClassifyLabelTokens)
+		}
+		if EstimateTokens(label.Description) > maxClassifyDescriptionTokens {
+			return nil, fmt.Errorf("description of label %q exceeds %d tokens", label.Name, maxClassifyDescriptionTokens)
+		}
+		if req.MultiLabel {
+			answerSize += tokens + 1
+		} else if tokens > answerSize {
+			answerSize = tokens
+		}
+	}
+	if req.Threshold == 0 && req.MultiLabel {
+		req.Threshold = defaultMultiLabelThreshold
+	}
+	if req.Threshold < 0 || req.Threshold > 1 {
+		return nil, fmt.Errorf("threshold must be between 0 and 1")
+	}
+	if req.Votes == 0 {
+		req.Votes = defaultClassifyVotes
+	}
+	if req.Votes < 1 || req.Votes > maxClassifyVotes {
+		return nil, fmt.Errorf("votes must be between 1 and %d", maxClassifyVotes)
+	}
+	if req.Temperature == 0 {
+		req.Temperature = 0.7
+	}
+
+	plan := &ClassifyPlan{
+		text:        req.Text,
+		labels:      req.Labels,
+		votes:       req.Votes,
+		temperature: req.Temperature,
+		answerSize:  answerSize + 4,
+		MultiLabel:  req.MultiLabel,
+		Threshold:   req.Threshold,
+		Method:      models.ClassifyVoting,
+	}
+	if s.HasCapability("logprobs") {
+		plan.Method = models.ClassifyLogprobs
+	}
+	budget := ModelContextTokens - plan.answerSize - EstimateTokens(plan.labelList()) - classifyPromptOverhead
+	if tokens := EstimateTokens(req.Text); tokens > budget {
+		return nil, fmt.Errorf("text cannot exceed %d tokens with these labels (got about %d)", budget, tokens)
+	}
+	return plan, nil
+}
+
+// labelList renders the candidate labels with their descriptions
+func (p *ClassifyPlan) labelList() string {
+	var b strings.Builder
+	for _, label := range p.labels {
+		b.WriteString("- " + label.Name)
+		if label.Description != "" {
+			b.WriteString(": " + label.Description)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// choicePrompt asks for the single best label, or every label that applies
+func (p *ClassifyPlan) choicePrompt() string {
+	if p.MultiLabel {
+		return fmt.Sprintf("Classify the text into the categories below. Reply with the names of all categories that apply, separated by commas, or \"none\".\n\nCategories:\n%s\nText:\n%s\n\nCategories:", p.labelList(), p.text)
+	}
+	return fmt.Sprintf("Classify the text into exactly one of the categories below. Reply with the category name only.\n\nCategories:\n%s\nText:\n%s\n\nCategory:", p.labelList(), p.text)
+}
+
+// yesNoPrompt asks whether one label applies, for multi-label logprobs
+func (p *ClassifyPlan) yesNoPrompt(label models.ClassifyLabel) string {
+	name := label.Name
+	if label.Description != "" {
+		name += " (" + label.Description + ")"
+	}
+	return fmt.Sprintf("Does the text belong to the category %s? Answer yes or no.\n\nText:\n%s\n\nAnswer:", name, p.text)
+}
+
+// scoringRequest asks the upstream to echo prompt and continuation back with
+// per-token logprobs without generating anything
+func scoringRequest(prompt, continuation string) map[string]interface{} {
+	return map[string]interface{}{
+		"prompt":     prompt + continuation,
+		"max_length": 0,
+		"echo":       true,
+		"logprobs":   0,
+	}
+}
+
+// BuildClassifyRequest returns the first upstream request Classify would
+// send, with the prompt and max tokens it carries
+func (s *AIService) BuildClassifyRequest(plan *ClassifyPlan) (UpstreamRequest, string, int) {
+	if plan.Method == models.ClassifyVoting {
+		prompt := plan.choicePrompt()
+		request := s.BuildCompleteRequest(prompt, plan.answerSize, plan.temperature)
+		request.Routing = append(request.Routing, "classify: voting over sampled answers (upstream lacks the logprobs capability)")
+		return request, prompt, plan.answerSize
+	}
+	prompt, continuation := plan.choicePrompt(), " "+plan.labels[0].Name
+	if plan.MultiLabel {
+		prompt, continuation = plan.yesNoPrompt(plan.labels[0]), " yes"
+	}
+	request := s.newUpstreamRequest("/complete", scoringRequest(prompt, continuation), "upstream: /complete", "classify: scored from echoed token logprobs (upstream has the logprobs capability)")
+	return request, prompt + continuation, 0
+}
+
+// Classify scores every label. With logprobs, single-label scores are a
+// softmax over the mean token logprob of each label name after the choice
+// prompt, and multi-label scores compare " yes" and " no" after a per-label
+// question. Voting samples the choice prompt and scores each label by the
+// share of votes naming it; votes naming no label are abstentions and count
+// against every label.
+func (s *AIService) Classify(ctx context.Context, plan *ClassifyPlan) (*ClassifyResult, error) {
+	if plan.Method == models.ClassifyLogprobs {
+		return s.classifyLogprobs(ctx, plan)
+	}
+	return s.classifyVoting(ctx, plan)
+}
+
+func (s *AIService) classifyLogprobs(ctx context.Context, plan *ClassifyPlan) (*ClassifyResult, error) {
+	type scoring struct{ prompt, continuation string }
+	var requests []scoring
+	for _, label := range plan.labels {
+		if plan.MultiLabel {
+			prompt := plan.yesNoPrompt(label)
+			requests = append(requests, scoring{prompt, " yes"}, scoring{prompt, " no"})
+		} else {
+			requests = append(requests, scoring{plan.choicePrompt(), " " + label.Name})
+		}
+	}
+
+	logprobs := make([]float64, len(requests))
+	calls, err := runParallel(ctx, len(requests), maxClassifyParallel, func(ctx context.Context, i int) error {
+		logprob, err := s.continuationLogprob(ctx, requests[i].prompt, requests[i].continuation)
+		logprobs[i] = logprob
+		return err
+	})
+	result := &ClassifyResult{Scores: make([]float64, len(plan.labels)), Calls: calls}
+	if err != nil {
+		return result, fmt.Errorf("failed to score labels: %w", err)
+	}
+
+	if plan.MultiLabel {
+		for i := range plan.labels {
+			yes, no := logprobs[2*i], logprobs[2*i+1]
+			result.Scores[i] = 1 / (1 + math.Exp(no-yes))
+		}
+		return result, nil
+	}
+	top := math.Inf(-1)
+	for _, logprob := range logprobs {
+		top = math.Max(top, logprob)
+	}
+	var total float64
+	for i, logprob := range logprobs {
+		result.Scores[i] = math.Exp(logprob - top)
+		total += result.Scores[i]
+	}
+	for i := range result.Scores {
+		result.Scores[i] /= total
+	}
+	return result, nil
+}
+
+// continuationLogprob returns the mean logprob of the continuation's tokens
+// after the prompt. Both the OpenAI completions shape
+// ({"choices": [{"logprobs": {...}}]}) and a top-level "logprobs" object
+// with "tokens" and "token_logprobs" are accepted.
+func (s *AIService) continuationLogprob(ctx context.Context, prompt, continuation string) (float64, error) {
+	response, err := s.callLLMEndpointWithJSONContext(ctx, "/complete", scoringRequest(prompt, continuation))
+	if err != nil {
+		return 0, err
+	}
+
+	type tokenLogprobs struct {
+		Tokens        []string   `json:"tokens"`
+		TokenLogprobs []*float64 `json:"token_logprobs"`
+	}
+	var parsed struct {
+		Choices []struct {
+			Logprobs tokenLogprobs `json:"logprobs"`
+		} `json:"choices"`
+		Logprobs tokenLogprobs `json:"logprobs"`
+	}
+	data, _ := json.Marshal(response)
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		return 0, fmt.Errorf("failed to parse logprobs: %w", err)
+	}
+	logprobs := parsed.Logprobs
+	if len(parsed.Choices) > 0 {
+		logprobs = parsed.Choices[0].Logprobs
+	}
+	if len(logprobs.Tokens) == 0 || len(logprobs.Tokens) != len(logprobs.TokenLogprobs) {
+		return 0, fmt.Errorf("upstream returned no token logprobs")
+	}
+
+	// Walk back from the end until the tokens cover the continuation
+	want := len([]rune(continuation))
+	covered, count := 0, 0
+	var sum float64
+	for i := len(logprobs.Tokens) - 1; i >= 0 && covered < want; i-- {
+		covered += len([]rune(logprobs.Tokens[i]))
+		if logprobs.TokenLogprobs[i] != nil {
+			sum += *logprobs.TokenLogprobs[i]
+			count++
+		}
+	}
+	if count == 0 {
+		return 0, fmt.Errorf("upstream returned no logprobs for the continuation")
+	}
+	return sum / float64(count), nil
+}
+
+func (s *AIService) classifyVoting(ctx context.Context, plan *ClassifyPlan) (*ClassifyResult, error) {
+	prompt := plan.choicePrompt()
+	answers := make([][]int, plan.votes)
+	abstained := make([]bool, plan.votes)
+	calls, err := runParallel(ctx, plan.votes, maxClassifyParallel, func(ctx context.Context, i int) error {
+		output, err := s.GetCompleteContext(ctx, prompt, plan.answerSize, plan.temperature)
+		if err != nil {
+			return err
+		}
+		answers[i], abstained[i] = plan.parseAnswer(output)
+		return nil
+	})
+	result := &ClassifyResult{
+		Scores: make([]float64, len(plan.labels)),
+		Votes:  make([]int, len(plan.labels)),
+		Calls:  calls,
+	}
+	if err != nil {
+		return result, fmt.Errorf("failed to collect votes: %w", err)
+	}
+
+	for i, answer := range answers {
+		if abstained[i] {
+			result.Abstained++
+			continue
+		}
+		for _, label := range answer {
+			result.Votes[label]++
+		}
+	}
+	for i, votes := range result.Votes {
+		result.Scores[i] = float64(votes) / float64(plan.votes)
+	}
+	log.Printf("Classify: %d votes, %d abstained", plan.votes, result.Abstained)
+	return result, nil
+}
+
+// parseAnswer maps a sampled answer onto label indexes. A single-label
+// answer names one label by name or list position; a multi-label answer is
+// a list of names or "none". Answers naming no label abstain.
+func (p *ClassifyPlan) parseAnswer(output string) ([]int, bool) {
+	answer := strings.TrimSpace(output)
+	if i := strings.IndexByte(answer, '\n'); i >= 0 && !p.MultiLabel {
+		answer = answer[:i]
+	}
+	answer = strings.TrimSpace(answer)
+	if answer == "" {
+		return nil, true
+	}
+
+	if !p.MultiLabel {
+		if label := p.matchLabel(answer); label >= 0 {
+			return []int{label}, false
+		}
+		if labels := p.mentionedLabels(answer); len(labels) > 0 {
+			return labels[:1], false
+		}
+		return nil, true
+	}
+
+	if i := strings.Index(answer, "\n\n"); i >= 0 {
+		answer = answer[:i]
+	}
+	if p.matchLabel(answer) < 0 && strings.HasPrefix(strings.ToLower(strings.Trim(answer, " .\"'")), "none") {
+		return nil, false
+	}
+	var labels []int
+	chosen := make(map[int]bool)
+	for _, item := range classifyListSeparator.Split(answer, -1) {
+		if label := p.matchLabel(item); label >= 0 && !chosen[label] {
+			chosen[label] = true
+			labels = append(labels, label)
+		}
+	}
+	if len(labels) == 0 {
+		labels = p.mentionedLabels(answer)
+	}
+	return labels, len(labels) == 0
+}
+
+// matchLabel matches an answer that is exactly a label name, ignoring case,
+// quotes, list markers and trailing punctuation, or a 1-based list position
+func (p *ClassifyPlan) matchLabel(answer string) int {
+	answer = strings.TrimSpace(bulletMarker.ReplaceAllString(answer, ""))
+	answer = strings.Trim(answer, " \t\"'`*.:!")
+	if answer == "" {
+		return -1
+	}
+	for i, label := range p.labels {
+		if strings.EqualFold(answer, label.Name) {
+			return i
+		}
+	}
+	if n, err := strconv.Atoi(answer); err == nil && n >= 1 && n <= len(p.labels) {
+		return n - 1
+	}
+	return -1
+}
+
+// mentionedLabels finds label names appearing as whole words in free text,
+// in order of first appearance; a name inside a longer mentioned name (e.g.
+// "bug" in "bug report") does not count separately
+func (p *ClassifyPlan) mentionedLabels(text string) []int {
+	lower := strings.ToLower(text)
+	type mention struct{ label, start, end int }
+	var mentions []mention
+	for i, label := range p.labels {
+		pattern := regexp.MustCompile(`(?:^|[^\pL\pN])(` + regexp.QuoteMeta(strings.ToLower(label.Name)) + `)(?:$|[^\pL\pN])`)
+		if loc := pattern.FindStringSubmatchIndex(lower); loc != nil {
+			mentions = append(mentions, mention{i, loc[2], loc[3]})
+		}
+	}
+	sort.SliceStable(mentions, func(a, b int) bool {
+		if mentions[a].start != mentions[b].start {
+			return mentions[a].start < mentions[b].start
+		}
+		return mentions[a].end > mentions[b].end
+	})
+	var labels []int
+	for i, m := range mentions {
+		contained := false
+		for j, other := range mentions {
+			if j != i && other.end-other.start > m.end-m.start && other.start <= m.start && m.end <= other.end {
+				contained = true
+				break
+			}
+		}
+		if !contained {
+			labels = append(labels, m.label)
+		}
+	}
+	return labels
+}
+
+// Select returns the labels in descending score order with the selected
+// ones marked: every label at or above the threshold with multi_label,
+// otherwise only the top label when it reaches the threshold. Labels with
+// no support are never selected.
+func (p *ClassifyPlan) Select(result *ClassifyResult) ([]models.ClassifyScore, []string) {
+	scores := make([]models.ClassifyScore, len(p.labels))
+	for i, label := range p.labels {
+		scores[i] = models.ClassifyScore{Label: label.Name, Score: result.Scores[i]}
+		if result.Votes != nil {
+			scores[i].Votes = result.Votes[i]
+		}
+	}
+	sort.SliceStable(scores, func(a, b int) bool { return scores[a].Score > scores[b].Score })
+
+	selected := []string{}
+	for i := range scores {
+		if scores[i].Score <= 0 || scores[i].Score < p.Threshold || (!p.MultiLabel && i > 0) {
+			break
+		}
+		scores[i].Selected = true
+		selected = append(selected, scores[i].Label)
+	}
+	return scores, selected
+}
@@ -0,0 +1,395 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Ammar0144/ai/models"
+)
+
+// Comparison limits
+const (
+	// DefaultCompareBackend names the gateway's own upstream
+	DefaultCompareBackend = "default"
+	maxCompareBackends    = 8
+	maxCompareDiffWords   = 2000
+	maxCompareTokens      = ModelContextTokens
+)
+
+// compareBackend is a named upstream that requests can be compared across
+type compareBackend struct {
+	Name    string
+	Service *AIService
+}
+
+// Comparer sends one request to several backends in parallel
+type Comparer struct {
+	backends map[string]*AIService
+	names    []string
+}
+
+// NewComparer creates a comparer over the gateway's own upstream and the
+// backends listed in AI_COMPARE_BACKENDS as comma-separated name=url pairs,
+// e.g. "phi-2=http://phi-server:8082". The name is reported as the model.
+func NewComparer(primary *AIService) *Comparer {
+	c := &Comparer{
+		backends: map[string]*AIService{DefaultCompareBackend: primary},
+		names:    []string{DefaultCompareBackend},
+	}
+	for _, backend := range parseCompareBackends(os.Getenv("AI_COMPARE_BACKENDS"), primary) {
+		if _, exists := c.backends[backend.Name]; exists {
+			log.Printf("Compare: ignoring duplicate backend %q", backend.Name)
+			continue
+		}
+		c.backends[backend.Name] = backend.Service
+		c.names = append(c.names, backend.Name)
+	}
+	if len(c.names) > 1 {
+		log.Printf("Compare backends: %s", strings.Join(c.names, ", "))
+	}
+	return c
+}
+
+// parseCompareBackends parses name=url pairs, logging and skipping bad ones
+func parseCompareBackends(value string, primary *AIService) []compareBackend {
+	var backends []compareBackend
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, url, ok := strings.Cut(entry, "=")
+		name, url = strings.TrimSpace(name), strings.TrimRight(strings.TrimSpace(url), "/")
+		if !ok || name == "" || !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+			log.Printf("Compare: ignoring backend %q; expected name=http(s)://host", entry)
+			continue
+		}
+		backends = append(backends, compareBackend{Name: name, Service: primary.withUpstream(url, name)})
+	}
+	return backends
+}
+
+// withUpstream returns a service for another LLM server. Capabilities are
+// not inherited, so the backend is called through the plain endpoints.
+func (s *AIService) withUpstream(baseURL, model string) *AIService {
+	return &AIService{
+		client:       s.client,
+		llmBaseURL:   baseURL,
+		model:        model,
+		capabilities: make(map[string]bool),
+		embeddings:   newEmbeddingCache(embeddingCacheEntries),
+		fimSentinels: s.fimSentinels,
+	}
+}
+
+// Backends returns the configured backend names, the default first
+func (c *Comparer) Backends() []string {
+	return append([]string(nil), c.names...)
+}
+
+// Backend returns the service of a configured backend
+func (c *Comparer) Backend(name string) (*AIService, bool) {
+	service, ok := c.backends[name]
+	return service, ok
+}
+
+// ValidateCompare checks a comparison request and fills in defaults
+func (c *Comparer) ValidateCompare(req *models.CompareRequest) error {
+	switch req.Mode {
+	case "":
+		req.Mode = models.CompareComplete
+	case models.CompareComplete, models.CompareGenerate, models.CompareChat:
+	default:
+		return fmt.Errorf("mode must be %q, %q or %q", models.CompareComplete, models.CompareGenerate, models.CompareChat)
+	}
+	if req.Mode == models.CompareChat {
+		if len(req.Messages) == 0 {
+			return fmt.Errorf("messages cannot be empty in chat mode")
+		}
+	} else if req.Prompt == "" {
+		return fmt.Errorf("prompt cannot be empty")
+	}
+
+	if len(req.Backends) == 0 {
+		req.Backends = c.Backends()
+	}
+	if len(req.Backends) < 2 {
+		if len(c.names) < 2 {
+			return fmt.Errorf("at least two backends are required; configure more in AI_COMPARE_BACKENDS")
+		}
+		return fmt.Errorf("at least two backends are required; configured backends are %s", strings.Join(c.names, ", "))
+	}
+	if len(req.Backends) > maxCompareBackends {
+		return fmt.Errorf("at most %d backends can be compared", maxCompareBackends)
+	}
+	seen := make(map[string]bool)
+	for _, name := range req.Backends {
+		if c.backends[name] == nil {
+			return fmt.Errorf("unknown backend %q; configured backends are %s", name, strings.Join(c.names, ", "))
+		}
+		if seen[name] {
+			return fmt.Errorf("backend %q is listed twice", name)
+		}
+		seen[name] = true
+	}
+
+	if req.MaxTokens < 0 || req.MaxTokens > maxCompareTokens {
+		return fmt.Errorf("max_tokens must be between 1 and %d", maxCompareTokens)
+	}
+	if req.MaxTokens == 0 {
+		req.MaxTokens = 150
+	}
+	if req.Temperature == 0 {
+		req.Temperature = 0.7
+	}
+	return nil
+}
+
+// Compare sends a validated request to each of its backends at once and
+// compares every pair of successful outputs. A failing backend is reported
+// in its result and does not affect the others.
+func (c *Comparer) Compare(ctx context.Context, req *models.CompareRequest) ([]models.CompareResult, []models.ComparePair) {
+	promptTokens := EstimateTokens(req.Prompt)
+	if req.Mode == models.CompareChat {
+		promptTokens = EstimateMessagesTokens(req.Messages)
+	}
+
+	results := make([]models.CompareResult, len(req.Backends))
+	var wg sync.WaitGroup
+	for i, name := range req.Backends {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			service := c.backends[name]
+			started := time.Now()
+			output, err := callCompareBackend(ctx, service, req)
+			result := models.CompareResult{
+				Backend:   name,
+				Model:     service.GetModel(),
+				LatencyMs: time.Since(started).Milliseconds(),
+			}
+			if err != nil {
+				log.Printf("Compare: backend %s failed: %v", name, err)
+				result.Error = err.Error()
+			} else {
+				completionTokens := EstimateTokens(output)
+				result.Output = output
+				result.Usage = &models.TokenUsage{
+					PromptTokens:     promptTokens,
+					CompletionTokens: completionTokens,
+					TotalTokens:      promptTokens + completionTokens,
+				}
+			}
+			results[i] = result
+		}(i, name)
+	}
+	wg.Wait()
+
+	pairs := []models.ComparePair{}
+	for i := range results {
+		for j := i + 1; j < len(results); j++ {
+			if results[i].Error != "" || results[j].Error != "" {
+				continue
+			}
+			pairs = append(pairs, compareOutputs(results[i], results[j], req.Diff))
+		}
+	}
+	return results, pairs
+}
+
+// callCompareBackend calls the upstream endpoint for the request's mode
+func callCompareBackend(ctx context.Context, service *AIService, req *models.CompareRequest) (string, error) {
+	switch req.Mode {
+	case models.CompareGenerate:
+		return service.GetGenerateContext(ctx, req.Prompt, req.MaxTokens, req.Temperature)
+	case models.CompareChat:
+		return service.GetChatCompletionContext(ctx, req.Messages, req.MaxTokens, req.Temperature)
+	default:
+		return service.GetCompleteContext(ctx, req.Prompt, req.MaxTokens, req.Temperature)
+	}
+}
+
+// compareOutputs scores the similarity of two outputs and optionally diffs them
+func compareOutputs(a, b models.CompareResult, diff bool) models.ComparePair {
+	pair := models.ComparePair{A: a.Backend, B: b.Backend}
+	termsA, termsB := Tokenize(a.Output), Tokenize(b.Output)
+	pair.Jaccard = round3(jaccard(termsA, termsB))
+	pair.LCSRatio = round3(lcsF1(termsA, termsB))
+	if shorter, longer := len(termsA), len(termsB); shorter+longer == 0 {
+		pair.LengthRatio = 1
+	} else {
+		if shorter > longer {
+			shorter, longer = longer, shorter
+		}
+		pair.LengthRatio = round3(float64(shorter) / float64(longer))
+	}
+	if diff {
+		pair.Diff = wordDiff(a.Output, b.Output)
+	}
+	return pair
+}
+
+// jaccard is the overlap of two sets of terms; two empty sets are identical
+func jaccard(a, b []string) float64 {
+	setA := make(map[string]bool, len(a))
+	for _, term := range a {
+		setA[term] = true
+	}
+	setB := make(map[string]bool, len(b))
+	union := len(setA)
+	shared := 0
+	for _, term := range b {
+		if setB[term] {
+			continue
+		}
+		setB[term] = true
+		if setA[term] {
+			shared++
+		} else {
+			union++
+		}
+	}
+	if union == 0 {
+		return 1
+	}
+	return float64(shared) / float64(union)
+}
+
+// lcsF1 is ROUGE-L: the F1 of the longest common subsequence's length over
+// the lengths of both sequences
+func lcsF1(a, b []string) float64 {
+	if len(a) == 0 && len(b) == 0 {
+		return 1
+	}
+	common := lcsLength(a, b)
+	if common == 0 {
+		return 0
+	}
+	precision := float64(common) / float64(len(b))
+	recall := float64(common) / float64(len(a))
+	return 2 * precision * recall / (precision + recall)
+}
+
+// lcsLength is the length of the longest common subsequence in O(len(b)) memory
+func lcsLength(a, b []string) int {
+	return lcsRow(a, b)[len(b)]
+}
+
+// lcsRow returns the LCS lengths of a and every prefix of b, in O(len(b))
+// memory
+func lcsRow(a, b []string) []int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for i := range a {
+		for j := range b {
+			if a[i] == b[j] {
+				current[j+1] = previous[j] + 1
+			} else {
+				current[j+1] = max(previous[j+1], current[j])
+			}
+		}
+		previous, current = current, previous
+	}
+	return previous
+}
+
+// reversedWords returns a reversed copy of words
+func reversedWords(words []string) []string {
+	reversed := make([]string, len(words))
+	for i, word := range words {
+		reversed[len(words)-1-i] = word
+	}
+	return reversed
+}
+
+// wordEdit is one step of a word diff: a word kept, removed or added
+type wordEdit struct {
+	op   byte
+	word string
+}
+
+// wordEdits appends the edits turning a into b along a longest common
+// subsequence. It splits a in half and b where the LCS of both halves is
+// longest (Hirschberg's algorithm), so memory stays linear in len(b).
+func wordEdits(a, b []string, edits []wordEdit) []wordEdit {
+	switch {
+	case len(a) == 0:
+		for _, word := range b {
+			edits = append(edits, wordEdit{'+', word})
+		}
+		return edits
+	case len(b) == 0:
+		for _, word := range a {
+			edits = append(edits, wordEdit{'-', word})
+		}
+		return edits
+	case len(a) == 1:
+		for k, word := range b {
+			if word == a[0] {
+				edits = wordEdits(nil, b[:k], edits)
+				edits = append(edits, wordEdit{'=', word})
+				return wordEdits(nil, b[k+1:], edits)
+			}
+		}
+		edits = wordEdits(a, nil, edits)
+		return wordEdits(nil, b, edits)
+	}
+
+	middle := len(a) / 2
+	forward := lcsRow(a[:middle], b)
+	backward := lcsRow(reversedWords(a[middle:]), reversedWords(b))
+	split, best := 0, -1
+	for k := range forward {
+		if common := forward[k] + backward[len(b)-k]; common > best {
+			split, best = k, common
+		}
+	}
+	edits = wordEdits(a[:middle], b[:split], edits)
+	return wordEdits(a[middle:], b[split:], edits)
+}
+
+// wordDiff renders a word-level diff in the style of git --word-diff=plain:
+// words only in a are wrapped in [-...-] and words only in b in {+...+}.
+// Outputs too long for the quadratic running time are diffed as a whole.
+func wordDiff(a, b string) string {
+	wordsA, wordsB := strings.Fields(a), strings.Fields(b)
+	if len(wordsA) > maxCompareDiffWords || len(wordsB) > maxCompareDiffWords {
+		return "[-" + strings.Join(wordsA, " ") + "-] {+" + strings.Join(wordsB, " ") + "+}"
+	}
+
+	var parts, removed, added []string
+	flush := func() {
+		if len(removed) > 0 {
+			parts = append(parts, "[-"+strings.Join(removed, " ")+"-]")
+			removed = removed[:0]
+		}
+		if len(added) > 0 {
+			parts = append(parts, "{+"+strings.Join(added, " ")+"+}")
+			added = added[:0]
+		}
+	}
+	for _, edit := range wordEdits(wordsA, wordsB, nil) {
+		switch edit.op {
+		case '=':
+			flush()
+			parts = append(parts, edit.word)
+		case '-':
+			removed = append(removed, edit.word)
+		default:
+			added = append(added, edit.word)
+		}
+	}
+	flush()
+	return strings.Join(parts, " ")
+}
+
+// round3 rounds a score to three decimals for readable responses
+func round3(value float64) float64 {
+	return math.Round(value*1000) / 1000
+}
@@ -0,0 +1,477 @@
+package services
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/Ammar0144/ai/models"
+)
+
+// Conversation export and import formats
+const (
+	FormatOpenAIJSONL = "openai"
+	FormatShareGPT    = "sharegpt"
+	FormatMarkdown    = "markdown"
+	FormatCSV         = "csv"
+)
+
+// csvHeader is the column layout of CSV exports
+var csvHeader = []string{"conversation_id", "title", "index", "role", "content"}
+
+// ExportFormatInfo returns the content type and file extension of a format
+func ExportFormatInfo(format string) (contentType, extension string, err error) {
+	switch format {
+	case FormatOpenAIJSONL:
+		return "application/x-ndjson", "jsonl", nil
+	case FormatShareGPT:
+		return "application/json", "json", nil
+	case FormatMarkdown:
+		return "text/markdown; charset=utf-8", "md", nil
+	case FormatCSV:
+		return "text/csv; charset=utf-8", "csv", nil
+	}
+	return "", "", fmt.Errorf("unsupported format %q; use openai, sharegpt, markdown or csv", format)
+}
+
+// ImportedConversation is a conversation parsed from an import file
+type ImportedConversation struct {
+	Title    string
+	Messages []models.ChatMessage
+}
+
+// ConversationExporter writes conversations one at a time so large exports
+// can be streamed. Only the active branch of each conversation is exported.
+type ConversationExporter struct {
+	w      io.Writer
+	format string
+	csv    *csv.Writer
+	count  int
+}
+
+// NewConversationExporter creates an exporter for the given format
+func NewConversationExporter(w io.Writer, format string) (*ConversationExporter, error) {
+	if _, _, err := ExportFormatInfo(format); err != nil {
+		return nil, err
+	}
+	exporter := &ConversationExporter{w: w, format: format}
+	if format == FormatCSV {
+		exporter.csv = csv.NewWriter(w)
+	}
+	return exporter, nil
+}
+
+// Write exports a single conversation
+func (e *ConversationExporter) Write(conversation *models.Conversation) error {
+	defer func() { e.count++ }()
+
+	switch e.format {
+	case FormatOpenAIJSONL:
+		data, err := json.Marshal(map[string]interface{}{"messages": conversation.Messages})
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(e.w, "%s\n", data)
+		return err
+
+	case FormatShareGPT:
+		turns := make([]map[string]string, 0, len(conversation.Messages))
+		for _, msg := range conversation.Messages {
+			turns = append(turns, map[string]string{"from": shareGPTRole(msg.Role), "value": messageText(msg)})
+		}
+		data, err := json.Marshal(map[string]interface{}{"id": conversation.ID, "title": conversation.Title, "conversations": turns})
+		if err != nil {
+			return err
+		}
+		prefix := ",\n"
+		if e.count == 0 {
+			prefix = "[\n"
+		}
+		_, err = fmt.Fprintf(e.w, "%s%s", prefix, data)
+		return err
+
+	case FormatMarkdown:
+		var b strings.Builder
+		if e.count > 0 {
+			b.WriteString("\n---\n\n")
+		}
+		title := strings.Join(strings.Fields(conversation.Title), " ")
+		if title == "" {
+			title = "Conversation"
+		}
+		fmt.Fprintf(&b, "# %s\n\n", title)
+		fmt.Fprintf(&b, "_Conversation %s, created %s_\n\n", conversation.ID, conversation.CreatedAt.Format("2006-01-02 15:04 MST"))
+		for _, msg := range conversation.Messages {
+			heading := capitalize(msg.Role)
+			if msg.Role == "tool" && msg.ToolCallID != "" {
+				heading += " (" + msg.ToolCallID + ")"
+			}
+			fmt.Fprintf(&b, "### %s\n\n%s\n\n", heading, escapeMarkdownBody(strings.TrimSpace(messageText(msg))))
+		}
+		_, err := io.WriteString(e.w, b.String())
+		return err
+
+	case FormatCSV:
+		if e.count == 0 {
+			if err := e.csv.Write(csvHeader); err != nil {
+				return err
+			}
+		}
+		for i, msg := range conversation.Messages {
+			record := []string{conversation.ID, conversation.Title, strconv.Itoa(i), msg.Role, messageText(msg)}
+			if err := e.csv.Write(record); err != nil {
+				return err
+			}
+		}
+		e.csv.Flush()
+		return e.csv.Error()
+	}
+	return nil
+}
+
+// Close finishes the export, writing any trailing syntax
+func (e *ConversationExporter) Close() error {
+	switch e.format {
+	case FormatShareGPT:
+		if e.count == 0 {
+			_, err := io.WriteString(e.w, "[]\n")
+			return err
+		}
+		_, err := io.WriteString(e.w, "\n]\n")
+		return err
+	case FormatCSV:
+		if e.count == 0 {
+			if err := e.csv.Write(csvHeader); err != nil {
+				return err
+			}
+		}
+		e.csv.Flush()
+		return e.csv.Error()
+	}
+	return nil
+}
+
+// ImportConversations parses conversations from any supported format
+func ImportConversations(r io.Reader, format string) ([]ImportedConversation, error) {
+	var conversations []ImportedConversation
+	var err error
+
+	switch format {
+	case FormatOpenAIJSONL:
+		conversations, err = importOpenAIJSONL(r)
+	case FormatShareGPT:
+		conversations, err = importShareGPT(r)
+	case FormatMarkdown:
+		conversations, err = importMarkdown(r)
+	case FormatCSV:
+		conversations, err = importCSV(r)
+	default:
+		_, _, err = ExportFormatInfo(format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for i, conversation := range conversations {
+		if len(conversation.Messages) == 0 {
+			return nil, fmt.Errorf("conversation %d has no messages", i+1)
+		}
+		// Formats without call IDs still need one for tool messages to be valid
+		for j := range conversation.Messages {
+			if conversation.Messages[j].Role == "tool" && conversation.Messages[j].ToolCallID == "" {
+				conversation.Messages[j].ToolCallID = "imported"
+			}
+		}
+	}
+	return conversations, nil
+}
+
+func importOpenAIJSONL(r io.Reader) ([]ImportedConversation, error) {
+	var conversations []ImportedConversation
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var record struct {
+			Title    string               `json:"title"`
+			Messages []models.ChatMessage `json:"messages"`
+		}
+		if err := json.Unmarshal([]byte(text), &record); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		conversations = append(conversations, ImportedConversation{Title: record.Title, Messages: record.Messages})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return conversations, nil
+}
+
+func importShareGPT(r io.Reader) ([]ImportedConversation, error) {
+	type shareGPTRecord struct {
+		Title         string `json:"title"`
+		Conversations []struct {
+			From  string `json:"from"`
+			Value string `json:"value"`
+		} `json:"conversations"`
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []shareGPTRecord
+	if err := json.Unmarshal(data, &records); err != nil {
+		// Also accept a single conversation object
+		var record shareGPTRecord
+		if errSingle := json.Unmarshal(data, &record); errSingle != nil {
+			return nil, fmt.Errorf("invalid ShareGPT JSON: %w", err)
+		}
+		records = []shareGPTRecord{record}
+	}
+
+	conversations := make([]ImportedConversation, 0, len(records))
+	for i, record := range records {
+		conversation := ImportedConversation{Title: record.Title}
+		for j, turn := range record.Conversations {
+			role, ok := roleFromShareGPT(turn.From)
+			if !ok {
+				return nil, fmt.Errorf("conversation %d, turn %d: unknown speaker %q", i+1, j+1, turn.From)
+			}
+			conversation.Messages = append(conversation.Messages, models.ChatMessage{Role: role, Content: turn.Value})
+		}
+		conversations = append(conversations, conversation)
+	}
+	return conversations, nil
+}
+
+func importMarkdown(r io.Reader) ([]ImportedConversation, error) {
+	var conversations []ImportedConversation
+	var current *ImportedConversation
+	var message *models.ChatMessage
+	var body []string
+
+	finishMessage := func() {
+		if message != nil && current != nil {
+			message.Content = strings.TrimSpace(strings.Join(body, "\n"))
+			current.Messages = append(current.Messages, *message)
+		}
+		message = nil
+		body = nil
+	}
+	finishConversation := func() {
+		finishMessage()
+		if current != nil && len(current.Messages) > 0 {
+			conversations = append(conversations, *current)
+		}
+		current = nil
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+
+		role, toolCallID, isHeading := markdownRoleHeading(trimmed)
+		switch {
+		case trimmed == "---" && message != nil:
+			finishConversation()
+		case strings.HasPrefix(trimmed, "# ") && message == nil:
+			finishConversation()
+			current = &ImportedConversation{Title: strings.TrimSpace(trimmed[2:])}
+		case isHeading:
+			finishMessage()
+			if current == nil {
+				current = &ImportedConversation{}
+			}
+			message = &models.ChatMessage{Role: role, ToolCallID: toolCallID}
+		default:
+			if message != nil {
+				body = append(body, unescapeMarkdownLine(line))
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	finishConversation()
+
+	if len(conversations) == 0 {
+		return nil, fmt.Errorf("no conversations found; expected \"### User\" / \"### Assistant\" headings")
+	}
+	return conversations, nil
+}
+
+// markdownRoleHeading parses a "### Role" or "### Tool (call ID)" message
+// heading of a trimmed line
+func markdownRoleHeading(trimmed string) (role, toolCallID string, ok bool) {
+	if !strings.HasPrefix(trimmed, "### ") {
+		return "", "", false
+	}
+	heading := strings.TrimSpace(trimmed[4:])
+	role = heading
+	if open := strings.Index(heading, "("); open > 0 && strings.HasSuffix(heading, ")") {
+		role = strings.TrimSpace(heading[:open])
+		toolCallID = heading[open+1 : len(heading)-1]
+	}
+	role = strings.ToLower(role)
+	if role != "system" && role != "user" && role != "assistant" && role != "tool" {
+		return "", "", false
+	}
+	return role, toolCallID, true
+}
+
+// isMarkdownBoundary reports whether a line of message content would be read
+// as a conversation rule or message heading, once any backslashes in front
+// of it are removed
+func isMarkdownBoundary(line string) bool {
+	core := strings.TrimLeft(strings.TrimSpace(line), "\\")
+	_, _, isHeading := markdownRoleHeading(core)
+	return core == "---" || isHeading
+}
+
+// escapeMarkdownBody puts a backslash before message lines that would be
+// read as boundaries on import. Markdown renders the escaped line as the
+// original text.
+func escapeMarkdownBody(text string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if isMarkdownBoundary(line) {
+			indent := len(line) - len(strings.TrimLeft(line, " \t"))
+			lines[i] = line[:indent] + "\\" + line[indent:]
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// unescapeMarkdownLine removes the backslash escapeMarkdownBody added
+func unescapeMarkdownLine(line string) string {
+	indent := len(line) - len(strings.TrimLeft(line, " \t"))
+	if strings.HasPrefix(line[indent:], "\\") && isMarkdownBoundary(line) {
+		return line[:indent] + line[indent+1:]
+	}
+	return line
+}
+
+func importCSV(r io.Reader) ([]ImportedConversation, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	roleColumn, hasRole := columns["role"]
+	contentColumn, hasContent := columns["content"]
+	if !hasRole || !hasContent {
+		return nil, fmt.Errorf("CSV must have role and content columns")
+	}
+	idColumn, hasID := columns["conversation_id"]
+	titleColumn, hasTitle := columns["title"]
+
+	field := func(record []string, column int) string {
+		if column < len(record) {
+			return record[column]
+		}
+		return ""
+	}
+
+	var conversations []ImportedConversation
+	index := make(map[string]int)
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		id := ""
+		if hasID {
+			id = field(record, idColumn)
+		}
+		i, ok := index[id]
+		if !ok {
+			title := ""
+			if hasTitle {
+				title = field(record, titleColumn)
+			}
+			conversations = append(conversations, ImportedConversation{Title: title})
+			i = len(conversations) - 1
+			index[id] = i
+		}
+
+		role := strings.ToLower(strings.TrimSpace(field(record, roleColumn)))
+		if role != "system" && role != "user" && role != "assistant" && role != "tool" {
+			return nil, fmt.Errorf("invalid role %q", role)
+		}
+		conversations[i].Messages = append(conversations[i].Messages, models.ChatMessage{
+			Role:    role,
+			Content: field(record, contentColumn),
+		})
+	}
+	return conversations, nil
+}
+
+// shareGPTRole maps chat roles to ShareGPT speakers
+func shareGPTRole(role string) string {
+	switch role {
+	case "user":
+		return "human"
+	case "assistant":
+		return "gpt"
+	}
+	return role
+}
+
+// roleFromShareGPT maps ShareGPT speakers to chat roles
+func roleFromShareGPT(from string) (string, bool) {
+	switch strings.ToLower(from) {
+	case "human", "user":
+		return "user", true
+	case "gpt", "assistant", "chatgpt", "bard", "bing", "model":
+		return "assistant", true
+	case "system":
+		return "system", true
+	case "tool", "function", "observation", "function_response":
+		return "tool", true
+	}
+	return "", false
+}
+
+// messageText renders a message's content including any tool calls
+func messageText(msg models.ChatMessage) string {
+	text := msg.Content
+	for _, call := range msg.ToolCalls {
+		if text != "" {
+			text += "\n"
+		}
+		text += formatEmulatedCall(call)
+	}
+	return text
+}
+
+// capitalize upper-cases the first letter of an ASCII word
+func capitalize(word string) string {
+	if word == "" {
+		return word
+	}
+	return strings.ToUpper(word[:1]) + word[1:]
+}
@@ -0,0 +1,354 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Ammar0144/ai/models"
+)
+
+// ErrConversationNotFound is returned when a conversation does not exist for the user
+var ErrConversationNotFound = errors.New("conversation not found")
+
+// conversationIDPattern matches identifiers generated by NewConversation
+var conversationIDPattern = regexp.MustCompile(`^conv_[0-9a-f]+$`)
+
+// ConversationStore persists conversations scoped by user
+type ConversationStore interface {
+	// Create stores a new conversation
+	Create(conversation *models.Conversation) error
+	// Get returns a copy of the user's conversation
+	Get(userID, id string) (*models.Conversation, error)
+	// List returns copies of all of the user's conversations, most recently updated first
+	List(userID string) ([]*models.Conversation, error)
+	// Update applies fn to the stored conversation atomically and saves the result
+	Update(userID, id string, fn func(conversation *models.Conversation) error) (*models.Conversation, error)
+	// Delete removes the user's conversation
+	Delete(userID, id string) error
+}
+
+// NewConversationStore returns a file-backed store when AI_CONVERSATION_DIR is
+// set and an in-memory store otherwise
+func NewConversationStore() ConversationStore {
+	if dir := os.Getenv("AI_CONVERSATION_DIR"); dir != "" {
+		store, err := NewFileConversationStore(dir)
+		if err == nil {
+			log.Printf("Conversation store: on-disk at %s", dir)
+			return store
+		}
+		log.Printf("Conversation store: failed to use %s, falling back to memory: %v", dir, err)
+	}
+	log.Printf("Conversation store: in-memory")
+	return NewMemoryConversationStore()
+}
+
+// NewConversation creates an unsaved conversation with a fresh ID
+func NewConversation(userID, title string, messages []models.ChatMessage) *models.Conversation {
+	now := time.Now()
+	if title == "" {
+		title = conversationTitle(messages)
+	}
+	conversation := &models.Conversation{
+		ID:        NewID("conv"),
+		UserID:    userID,
+		Title:     title,
+		Messages:  []models.ChatMessage{},
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	AppendMessages(conversation, messages...)
+	return conversation
+}
+
+// ValidConversationID reports whether id has the format of a generated conversation ID
+func ValidConversationID(id string) bool {
+	return conversationIDPattern.MatchString(id)
+}
+
+// conversationTitle derives a title from the first user message
+func conversationTitle(messages []models.ChatMessage) string {
+	for _, msg := range messages {
+		if msg.Role != "user" {
+			continue
+		}
+		return preview(msg.Content, 60)
+	}
+	return ""
+}
+
+// cloneConversation deep-copies a conversation so callers cannot mutate stored state
+func cloneConversation(conversation *models.Conversation) *models.Conversation {
+	data, err := json.Marshal(conversation)
+	if err != nil {
+		copied := *conversation
+		return &copied
+	}
+	var copied models.Conversation
+	if err := json.Unmarshal(data, &copied); err != nil {
+		copied = *conversation
+	}
+	return &copied
+}
+
+// sortConversations orders conversations by most recent update
+func sortConversations(conversations []*models.Conversation) {
+	sort.Slice(conversations, func(i, j int) bool {
+		return conversations[i].UpdatedAt.After(conversations[j].UpdatedAt)
+	})
+}
+
+// MemoryConversationStore keeps conversations in process memory
+type MemoryConversationStore struct {
+	conversations map[string]map[string]*models.Conversation
+	mutex         sync.RWMutex
+}
+
+// NewMemoryConversationStore creates an empty in-memory store
+func NewMemoryConversationStore() *MemoryConversationStore {
+	return &MemoryConversationStore{
+		conversations: make(map[string]map[string]*models.Conversation),
+	}
+}
+
+// Create stores a new conversation
+func (m *MemoryConversationStore) Create(conversation *models.Conversation) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.conversations[conversation.UserID] == nil {
+		m.conversations[conversation.UserID] = make(map[string]*models.Conversation)
+	}
+	m.conversations[conversation.UserID][conversation.ID] = cloneConversation(conversation)
+	return nil
+}
+
+// Get returns a copy of the user's conversation
+func (m *MemoryConversationStore) Get(userID, id string) (*models.Conversation, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	conversation, ok := m.conversations[userID][id]
+	if !ok {
+		return nil, ErrConversationNotFound
+	}
+	return cloneConversation(conversation), nil
+}
+
+// List returns copies of all of the user's conversations
+func (m *MemoryConversationStore) List(userID string) ([]*models.Conversation, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	conversations := make([]*models.Conversation, 0, len(m.conversations[userID]))
+	for _, conversation := range m.conversations[userID] {
+		conversations = append(conversations, cloneConversation(conversation))
+	}
+	sortConversations(conversations)
+	return conversations, nil
+}
+
+// Update applies fn to the stored conversation atomically
+func (m *MemoryConversationStore) Update(userID, id string, fn func(conversation *models.Conversation) error) (*models.Conversation, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	stored, ok := m.conversations[userID][id]
+	if !ok {
+		return nil, ErrConversationNotFound
+	}
+
+	updated := cloneConversation(stored)
+	if err := fn(updated); err != nil {
+		return nil, err
+	}
+	updated.ID = stored.ID
+	updated.UserID = stored.UserID
+	updated.UpdatedAt = time.Now()
+	m.conversations[userID][id] = updated
+	return cloneConversation(updated), nil
+}
+
+// Delete removes the user's conversation
+func (m *MemoryConversationStore) Delete(userID, id string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if _, ok := m.conversations[userID][id]; !ok {
+		return ErrConversationNotFound
+	}
+	delete(m.conversations[userID], id)
+	return nil
+}
+
+// FileConversationStore keeps one JSON file per conversation under
+// <dir>/<hex(user id)>/<conversation id>.json
+type FileConversationStore struct {
+	dir   string
+	mutex sync.RWMutex
+}
+
+// NewFileConversationStore creates a store rooted at dir, creating it if needed
+func NewFileConversationStore(dir string) (*FileConversationStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create conversation directory: %w", err)
+	}
+	return &FileConversationStore{dir: dir}, nil
+}
+
+// maxUserDirName bounds user directory names well below the 255-byte file
+// name limit of common file systems
+const maxUserDirName = 128
+
+// userDir returns the directory holding a user's conversations. User IDs are
+// hex-encoded so that any value maps to a safe directory name; IDs too long
+// to encode use a SHA-256 hash instead, prefixed so it cannot collide with an
+// encoded ID.
+func (f *FileConversationStore) userDir(userID string) string {
+	name := hex.EncodeToString([]byte(userID))
+	if len(name) > maxUserDirName {
+		sum := sha256.Sum256([]byte(userID))
+		name = "sha256-" + hex.EncodeToString(sum[:])
+	}
+	return filepath.Join(f.dir, name)
+}
+
+func (f *FileConversationStore) path(userID, id string) (string, error) {
+	if !ValidConversationID(id) {
+		return "", ErrConversationNotFound
+	}
+	return filepath.Join(f.userDir(userID), id+".json"), nil
+}
+
+func (f *FileConversationStore) read(path string) (*models.Conversation, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrConversationNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read conversation: %w", err)
+	}
+	var conversation models.Conversation
+	if err := json.Unmarshal(data, &conversation); err != nil {
+		return nil, fmt.Errorf("failed to parse conversation: %w", err)
+	}
+	// Conversations saved before branching support are a flat message list
+	EnsureTree(&conversation)
+	return &conversation, nil
+}
+
+// write saves a conversation atomically
+func (f *FileConversationStore) write(conversation *models.Conversation) error {
+	path, err := f.path(conversation.UserID, conversation.ID)
+	if err != nil {
+		return err
+	}
+	if err := writeJSONFileAtomic(path, conversation); err != nil {
+		return fmt.Errorf("failed to save conversation: %w", err)
+	}
+	return nil
+}
+
+// Create stores a new conversation
+func (f *FileConversationStore) Create(conversation *models.Conversation) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	return f.write(conversation)
+}
+
+// Get returns the user's conversation
+func (f *FileConversationStore) Get(userID, id string) (*models.Conversation, error) {
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
+
+	path, err := f.path(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	return f.read(path)
+}
+
+// List returns all of the user's conversations
+func (f *FileConversationStore) List(userID string) ([]*models.Conversation, error) {
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
+
+	entries, err := os.ReadDir(f.userDir(userID))
+	if errors.Is(err, os.ErrNotExist) {
+		return []*models.Conversation{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list conversations: %w", err)
+	}
+
+	conversations := make([]*models.Conversation, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		conversation, err := f.read(filepath.Join(f.userDir(userID), entry.Name()))
+		if err != nil {
+			log.Printf("FileConversationStore: skipping %s: %v", entry.Name(), err)
+			continue
+		}
+		conversations = append(conversations, conversation)
+	}
+	sortConversations(conversations)
+	return conversations, nil
+}
+
+// Update applies fn to the stored conversation atomically
+func (f *FileConversationStore) Update(userID, id string, fn func(conversation *models.Conversation) error) (*models.Conversation, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	path, err := f.path(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	conversation, err := f.read(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := fn(conversation); err != nil {
+		return nil, err
+	}
+	conversation.ID = id
+	conversation.UserID = userID
+	conversation.UpdatedAt = time.Now()
+
+	if err := f.write(conversation); err != nil {
+		return nil, err
+	}
+	return conversation, nil
+}
+
+// Delete removes the user's conversation
+func (f *FileConversationStore) Delete(userID, id string) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	path, err := f.path(userID, id)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return ErrConversationNotFound
+		}
+		return fmt.Errorf("failed to delete conversation: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,228 @@
+package services
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Ammar0144/ai/models"
+)
+
+// ErrMessageNotFound is returned when a node ID is not part of the conversation
+var ErrMessageNotFound = errors.New("message not found")
+
+// EnsureTree converts a conversation stored as a flat message list into a
+// single-branch tree. Conversations that already have nodes are unchanged.
+func EnsureTree(conversation *models.Conversation) {
+	if len(conversation.Nodes) > 0 || len(conversation.Messages) == 0 {
+		return
+	}
+	messages := conversation.Messages
+	conversation.Messages = nil
+	conversation.ActiveLeafID = ""
+	AppendMessages(conversation, messages...)
+}
+
+// AppendMessages adds messages to the end of the active branch and returns the
+// ID of the last node added
+func AppendMessages(conversation *models.Conversation, messages ...models.ChatMessage) string {
+	EnsureTree(conversation)
+
+	now := time.Now()
+	parentID := conversation.ActiveLeafID
+	for _, msg := range messages {
+		node := models.MessageNode{
+			ID:        NewID("msg"),
+			ParentID:  parentID,
+			Message:   msg,
+			CreatedAt: now,
+		}
+		conversation.Nodes = append(conversation.Nodes, node)
+		parentID = node.ID
+	}
+	conversation.ActiveLeafID = parentID
+	syncActiveMessages(conversation)
+	return parentID
+}
+
+// AddSibling adds msg as a new child of parentID (the root when empty), makes
+// it the active leaf and returns its ID
+func AddSibling(conversation *models.Conversation, parentID string, msg models.ChatMessage) string {
+	EnsureTree(conversation)
+
+	node := models.MessageNode{
+		ID:        NewID("msg"),
+		ParentID:  parentID,
+		Message:   msg,
+		CreatedAt: time.Now(),
+	}
+	conversation.Nodes = append(conversation.Nodes, node)
+	conversation.ActiveLeafID = node.ID
+	syncActiveMessages(conversation)
+	return node.ID
+}
+
+// FindNode returns the node with the given ID
+func FindNode(conversation *models.Conversation, nodeID string) (*models.MessageNode, error) {
+	EnsureTree(conversation)
+
+	for i := range conversation.Nodes {
+		if conversation.Nodes[i].ID == nodeID {
+			return &conversation.Nodes[i], nil
+		}
+	}
+	return nil, ErrMessageNotFound
+}
+
+// PathTo returns the nodes from the root down to nodeID
+func PathTo(conversation *models.Conversation, nodeID string) ([]models.MessageNode, error) {
+	EnsureTree(conversation)
+	if nodeID == "" {
+		return nil, nil
+	}
+
+	index := make(map[string]int, len(conversation.Nodes))
+	for i, node := range conversation.Nodes {
+		index[node.ID] = i
+	}
+
+	var path []models.MessageNode
+	for id := nodeID; id != ""; {
+		i, ok := index[id]
+		if !ok || len(path) > len(conversation.Nodes) {
+			return nil, ErrMessageNotFound
+		}
+		path = append(path, conversation.Nodes[i])
+		id = conversation.Nodes[i].ParentID
+	}
+
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path, nil
+}
+
+// GetPath returns the linear message list from the root to nodeID. An empty
+// nodeID selects the active branch.
+func GetPath(conversation *models.Conversation, nodeID string) (*models.ConversationPath, error) {
+	EnsureTree(conversation)
+	if nodeID == "" {
+		nodeID = conversation.ActiveLeafID
+	}
+
+	nodes, err := PathTo(conversation, nodeID)
+	if err != nil {
+		return nil, err
+	}
+
+	path := &models.ConversationPath{
+		ConversationID: conversation.ID,
+		LeafID:         nodeID,
+		NodeIDs:        make([]string, 0, len(nodes)),
+		Messages:       make([]models.ChatMessage, 0, len(nodes)),
+	}
+	for _, node := range nodes {
+		path.NodeIDs = append(path.NodeIDs, node.ID)
+		path.Messages = append(path.Messages, node.Message)
+	}
+	return path, nil
+}
+
+// SwitchBranch makes the branch containing nodeID active. When nodeID is not a
+// leaf, the most recently created descendants are followed down to a leaf.
+func SwitchBranch(conversation *models.Conversation, nodeID string) error {
+	if _, err := FindNode(conversation, nodeID); err != nil {
+		return err
+	}
+
+	children := childIndex(conversation)
+	leaf := nodeID
+	for len(children[leaf]) > 0 {
+		kids := children[leaf]
+		leaf = kids[len(kids)-1].ID
+	}
+
+	conversation.ActiveLeafID = leaf
+	syncActiveMessages(conversation)
+	return nil
+}
+
+// Branches lists every leaf of the conversation tree
+func Branches(conversation *models.Conversation) []models.ConversationBranch {
+	EnsureTree(conversation)
+
+	children := childIndex(conversation)
+	activePath := make(map[string]bool)
+	if nodes, err := PathTo(conversation, conversation.ActiveLeafID); err == nil {
+		for _, node := range nodes {
+			activePath[node.ID] = true
+		}
+	}
+
+	var branches []models.ConversationBranch
+	for _, node := range conversation.Nodes {
+		if len(children[node.ID]) > 0 {
+			continue
+		}
+		path, err := PathTo(conversation, node.ID)
+		if err != nil {
+			continue
+		}
+
+		branch := models.ConversationBranch{
+			LeafID:    node.ID,
+			Depth:     len(path),
+			LastRole:  node.Message.Role,
+			Preview:   preview(node.Message.Content, 80),
+			Active:    node.ID == conversation.ActiveLeafID,
+			UpdatedAt: node.CreatedAt,
+		}
+		// The deepest ancestor shared with the active branch
+		if !branch.Active {
+			for i := len(path) - 1; i >= 0; i-- {
+				if activePath[path[i].ID] {
+					branch.DivergesFrom = path[i].ID
+					break
+				}
+			}
+		}
+		branches = append(branches, branch)
+	}
+
+	sort.SliceStable(branches, func(i, j int) bool {
+		return branches[i].UpdatedAt.Before(branches[j].UpdatedAt)
+	})
+	return branches
+}
+
+// childIndex maps each node ID (and "" for the root) to its children in creation order
+func childIndex(conversation *models.Conversation) map[string][]models.MessageNode {
+	children := make(map[string][]models.MessageNode)
+	for _, node := range conversation.Nodes {
+		children[node.ParentID] = append(children[node.ParentID], node)
+	}
+	return children
+}
+
+// syncActiveMessages rebuilds the linear Messages view of the active branch
+func syncActiveMessages(conversation *models.Conversation) {
+	nodes, err := PathTo(conversation, conversation.ActiveLeafID)
+	if err != nil {
+		return
+	}
+	conversation.Messages = make([]models.ChatMessage, 0, len(nodes))
+	for _, node := range nodes {
+		conversation.Messages = append(conversation.Messages, node.Message)
+	}
+}
+
+// preview shortens text for listings
+func preview(text string, limit int) string {
+	text = strings.Join(strings.Fields(text), " ")
+	runes := []rune(text)
+	if len(runes) <= limit {
+		return text
+	}
+	return string(runes[:limit]) + "..."
+}
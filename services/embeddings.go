@@ -0,0 +1,297 @@
+package services
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"math"
+	"strings"
+	"sync"
+)
+
+// Built-in embedder settings
+const (
+	LocalEmbeddingModel      = "local-hashing-v1"
+	LocalEmbeddingDimensions = 256
+
+	// Embedding backends
+	EmbeddingBackendUpstream = "upstream"
+	EmbeddingBackendLocal    = "local"
+
+	maxEmbeddingInputs    = 256
+	embeddingCacheEntries = 10000
+)
+
+// ErrInvalidEmbeddingInput marks errors caused by the request rather than the backend
+var ErrInvalidEmbeddingInput = errors.New("invalid embedding request")
+
+// localStopwords are down-weighted by the local embedder in place of corpus
+// IDF statistics, which a stateless embedder cannot have
+var localStopwords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true, "be": true,
+	"by": true, "for": true, "from": true, "has": true, "he": true, "in": true, "is": true,
+	"it": true, "its": true, "of": true, "on": true, "or": true, "that": true, "the": true,
+	"this": true, "to": true, "was": true, "were": true, "will": true, "with": true,
+	"i": true, "you": true, "we": true, "they": true, "do": true, "does": true, "what": true,
+}
+
+// EmbeddingResult holds vectors in input order
+type EmbeddingResult struct {
+	Vectors      [][]float64
+	Model        string
+	Backend      string
+	Tokens       int
+	CachedInputs int
+}
+
+// Embed returns a vector for each input. The upstream embeddings endpoint is
+// used when the upstream has the "embeddings" capability, unless model asks
+// for the built-in embedder; vectors from the two are not comparable, so an
+// upstream failure is an error rather than a silent fallback. Vectors are
+// cached by a hash of backend and input.
+func (s *AIService) Embed(ctx context.Context, inputs []string, model string) (*EmbeddingResult, error) {
+	if len(inputs) == 0 {
+		return nil, fmt.Errorf("%w: input cannot be empty", ErrInvalidEmbeddingInput)
+	}
+	if len(inputs) > maxEmbeddingInputs {
+		return nil, fmt.Errorf("%w: at most %d inputs are allowed per request", ErrInvalidEmbeddingInput, maxEmbeddingInputs)
+	}
+	for i, input := range inputs {
+		if strings.TrimSpace(input) == "" {
+			return nil, fmt.Errorf("%w: input %d is empty", ErrInvalidEmbeddingInput, i)
+		}
+	}
+
+	useUpstream := s.HasCapability("embeddings") && model != LocalEmbeddingModel
+	if model != "" && model != LocalEmbeddingModel && !s.HasCapability("embeddings") {
+		return nil, fmt.Errorf("%w: unknown embedding model %q; only %s is available", ErrInvalidEmbeddingInput, model, LocalEmbeddingModel)
+	}
+
+	result := &EmbeddingResult{
+		Vectors: make([][]float64, len(inputs)),
+		Model:   LocalEmbeddingModel,
+		Backend: EmbeddingBackendLocal,
+	}
+	if useUpstream {
+		result.Backend = EmbeddingBackendUpstream
+		result.Model = model
+		if result.Model == "" {
+			result.Model = s.model
+		}
+	}
+
+	var missing []int
+	for i, input := range inputs {
+		result.Tokens += EstimateTokens(input)
+		if vector, ok := s.embeddings.get(embeddingCacheKey(result.Backend, result.Model, input)); ok {
+			result.Vectors[i] = vector
+			result.CachedInputs++
+			continue
+		}
+		missing = append(missing, i)
+	}
+	if len(missing) == 0 {
+		return result, nil
+	}
+
+	if useUpstream {
+		texts := make([]string, len(missing))
+		for j, i := range missing {
+			texts[j] = inputs[i]
+		}
+		vectors, err := s.upstreamEmbeddings(ctx, texts, model)
+		if err != nil {
+			return nil, err
+		}
+		for j, i := range missing {
+			result.Vectors[i] = vectors[j]
+		}
+	} else {
+		for _, i := range missing {
+			result.Vectors[i] = LocalEmbedding(inputs[i])
+		}
+	}
+
+	for _, i := range missing {
+		s.embeddings.put(embeddingCacheKey(result.Backend, result.Model, inputs[i]), result.Vectors[i])
+	}
+	return result, nil
+}
+
+// EmbedAll embeds any number of inputs in batches of the per-call limit,
+// returning the vectors in input order
+func (s *AIService) EmbedAll(ctx context.Context, inputs []string, model string) ([][]float64, error) {
+	vectors := make([][]float64, 0, len(inputs))
+	for start := 0; start < len(inputs); start += maxEmbeddingInputs {
+		end := start + maxEmbeddingInputs
+		if end > len(inputs) {
+			end = len(inputs)
+		}
+		result, err := s.Embed(ctx, inputs[start:end], model)
+		if err != nil {
+			return nil, err
+		}
+		vectors = append(vectors, result.Vectors...)
+	}
+	return vectors, nil
+}
+
+// upstreamEmbeddings calls the LLM server's /embeddings endpoint. Both the
+// OpenAI shape ({"data": [{"index", "embedding"}]}) and a bare
+// {"embeddings": [[...]]} are accepted.
+func (s *AIService) upstreamEmbeddings(ctx context.Context, inputs []string, model string) ([][]float64, error) {
+	request := map[string]interface{}{"input": inputs}
+	if model != "" {
+		request["model"] = model
+	}
+
+	response, err := s.callLLMEndpointWithJSONContext(ctx, "/embeddings", request)
+	if err != nil {
+		log.Printf("upstreamEmbeddings: LLM call failed: %v", err)
+		return nil, fmt.Errorf("embeddings request failed: %w", err)
+	}
+
+	var parsed struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float64 `json:"embedding"`
+		} `json:"data"`
+		Embeddings [][]float64 `json:"embeddings"`
+	}
+	data, _ := json.Marshal(response)
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		return nil, fmt.Errorf("failed to parse embeddings response: %w", err)
+	}
+
+	vectors := parsed.Embeddings
+	if len(parsed.Data) > 0 {
+		vectors = make([][]float64, len(parsed.Data))
+		for i, item := range parsed.Data {
+			index := item.Index
+			if index < 0 || index >= len(vectors) {
+				index = i
+			}
+			vectors[index] = item.Embedding
+		}
+	}
+	if len(vectors) != len(inputs) {
+		return nil, fmt.Errorf("upstream returned %d embeddings for %d inputs", len(vectors), len(inputs))
+	}
+	for i, vector := range vectors {
+		if len(vector) == 0 {
+			return nil, fmt.Errorf("upstream returned an empty embedding for input %d", i)
+		}
+	}
+	return vectors, nil
+}
+
+// LocalEmbedding is the built-in deterministic embedder: a hashing-trick
+// vectorizer over words, word bigrams and character trigrams with sublinear
+// term frequency, signed hashing to reduce collision bias and L2
+// normalization, so cosine similarity reflects lexical overlap
+func LocalEmbedding(text string) []float64 {
+	vector := make([]float64, LocalEmbeddingDimensions)
+	counts := make(map[string]float64)
+
+	words := Tokenize(text)
+	for i, word := range words {
+		weight := 1.0
+		if localStopwords[word] {
+			weight = 0.1
+		}
+		counts["w:"+word] += weight
+		if i > 0 {
+			counts["b:"+words[i-1]+" "+word] += 0.5
+		}
+		padded := []rune("^" + word + "$")
+		for j := 0; j+3 <= len(padded); j++ {
+			counts["c:"+string(padded[j:j+3])] += 0.25 * weight
+		}
+	}
+
+	for feature, count := range counts {
+		hash := fnv.New64a()
+		hash.Write([]byte(feature))
+		sum := hash.Sum64()
+		index := sum % LocalEmbeddingDimensions
+		sign := 1.0
+		if sum&(1<<63) != 0 {
+			sign = -1.0
+		}
+		vector[index] += sign * (1 + math.Log(1+count))
+	}
+
+	var norm float64
+	for _, value := range vector {
+		norm += value * value
+	}
+	if norm > 0 {
+		norm = math.Sqrt(norm)
+		for i := range vector {
+			vector[i] /= norm
+		}
+	}
+	return vector
+}
+
+// embeddingCacheKey hashes the backend, model and input
+func embeddingCacheKey(backend, model, input string) string {
+	sum := sha256.Sum256([]byte(backend + "\x00" + model + "\x00" + input))
+	return hex.EncodeToString(sum[:])
+}
+
+// embeddingCache is a thread-safe LRU cache of vectors
+type embeddingCache struct {
+	capacity int
+	entries  map[string]*list.Element
+	order    *list.List
+	mutex    sync.Mutex
+}
+
+type embeddingCacheEntry struct {
+	key    string
+	vector []float64
+}
+
+func newEmbeddingCache(capacity int) *embeddingCache {
+	return &embeddingCache{
+		capacity: capacity,
+		entries:  make(map[string]*list.Element),
+		order:    list.New(),
+	}
+}
+
+func (c *embeddingCache) get(key string) ([]float64, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(element)
+	return append([]float64(nil), element.Value.(*embeddingCacheEntry).vector...), true
+}
+
+func (c *embeddingCache) put(key string, vector []float64) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if element, ok := c.entries[key]; ok {
+		element.Value.(*embeddingCacheEntry).vector = vector
+		c.order.MoveToFront(element)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&embeddingCacheEntry{key: key, vector: vector})
+	for c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*embeddingCacheEntry).key)
+	}
+}
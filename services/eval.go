@@ -0,0 +1,492 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Ammar0144/ai/models"
+)
+
+// Evaluation limits
+const (
+	bleuMaxOrder        = 4
+	maxEvalWorstCases   = 10
+	maxEvalMarkdownText = 120
+)
+
+// EvalOptions controls an evaluation run
+type EvalOptions struct {
+	Parallel    int
+	Timeout     time.Duration // Per case
+	MaxTokens   int           // Default for cases without max_tokens
+	Temperature float64       // Default for cases without temperature
+}
+
+// LoadEvalDataset reads JSONL evaluation cases. Cases without an ID are
+// named by their line number, and IDs must be unique so that runs can be
+// compared case by case.
+func LoadEvalDataset(r io.Reader) ([]models.EvalCase, error) {
+	var cases []models.EvalCase
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var c models.EvalCase
+		if err := json.Unmarshal([]byte(text), &c); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if c.ID == "" {
+			c.ID = "line-" + strconv.Itoa(line)
+		}
+		if seen[c.ID] {
+			return nil, fmt.Errorf("line %d: duplicate id %q", line, c.ID)
+		}
+		seen[c.ID] = true
+
+		switch c.Mode {
+		case "":
+			c.Mode = models.CompareComplete
+		case models.CompareComplete, models.CompareGenerate, models.CompareChat:
+		default:
+			return nil, fmt.Errorf("line %d: mode must be %q, %q or %q", line, models.CompareComplete, models.CompareGenerate, models.CompareChat)
+		}
+		if c.Mode == models.CompareChat && len(c.Messages) == 0 {
+			return nil, fmt.Errorf("line %d: messages cannot be empty in chat mode", line)
+		}
+		if c.Mode != models.CompareChat && c.Prompt == "" {
+			return nil, fmt.Errorf("line %d: prompt cannot be empty", line)
+		}
+		cases = append(cases, c)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(cases) == 0 {
+		return nil, fmt.Errorf("dataset has no cases")
+	}
+	return cases, nil
+}
+
+// RunEval sends every case to the service and scores the outputs against
+// the expected text. A failing case is recorded in its result.
+func RunEval(ctx context.Context, service *AIService, cases []models.EvalCase, options EvalOptions) *models.EvalReport {
+	report := &models.EvalReport{
+		Model:     service.GetModel(),
+		StartedAt: time.Now(),
+		Results:   make([]models.EvalCaseResult, len(cases)),
+	}
+
+	parallel := options.Parallel
+	if parallel < 1 {
+		parallel = 1
+	}
+	semaphore := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+	for i, c := range cases {
+		wg.Add(1)
+		go func(i int, c models.EvalCase) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+			report.Results[i] = runEvalCase(ctx, service, c, options)
+		}(i, c)
+	}
+	wg.Wait()
+
+	report.DurationMs = time.Since(report.StartedAt).Milliseconds()
+	report.Metrics = ScoreEval(report.Results)
+	return report
+}
+
+// runEvalCase calls the model for one case and scores its output
+func runEvalCase(ctx context.Context, service *AIService, c models.EvalCase, options EvalOptions) models.EvalCaseResult {
+	req := &models.CompareRequest{
+		Mode:        c.Mode,
+		Prompt:      c.Prompt,
+		Messages:    c.Messages,
+		MaxTokens:   c.MaxTokens,
+		Temperature: c.Temperature,
+	}
+	if req.MaxTokens == 0 {
+		req.MaxTokens = options.MaxTokens
+	}
+	if req.Temperature == 0 {
+		req.Temperature = options.Temperature
+	}
+	if options.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, options.Timeout)
+		defer cancel()
+	}
+
+	result := models.EvalCaseResult{ID: c.ID, Expected: c.Expected}
+	started := time.Now()
+	output, err := callCompareBackend(ctx, service, req)
+	result.LatencyMs = time.Since(started).Milliseconds()
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	result.Output = output
+	outputTerms, expectedTerms := Tokenize(output), Tokenize(c.Expected)
+	result.ExactMatch = strings.Join(outputTerms, " ") == strings.Join(expectedTerms, " ")
+	result.TokenF1 = round3(tokenF1(outputTerms, expectedTerms))
+	result.RougeL = round3(lcsF1(expectedTerms, outputTerms))
+	result.BLEU = round3(sentenceBLEU(outputTerms, expectedTerms))
+	return result
+}
+
+// ScoreEval aggregates case results into run metrics
+func ScoreEval(results []models.EvalCaseResult) models.EvalMetrics {
+	metrics := models.EvalMetrics{Cases: len(results)}
+	if len(results) == 0 {
+		return metrics
+	}
+
+	var exact, f1, rouge float64
+	var latencies []int64
+	var candidates, references [][]string
+	for _, result := range results {
+		if result.Error != "" {
+			// A failed case scores as an empty output in every metric,
+			// including BLEU's brevity penalty
+			metrics.Failures++
+			candidates = append(candidates, nil)
+			references = append(references, Tokenize(result.Expected))
+			continue
+		}
+		if result.ExactMatch {
+			exact++
+		}
+		f1 += result.TokenF1
+		rouge += result.RougeL
+		latencies = append(latencies, result.LatencyMs)
+		candidates = append(candidates, Tokenize(result.Output))
+		references = append(references, Tokenize(result.Expected))
+	}
+
+	count := float64(len(results))
+	metrics.FailureRate = round3(float64(metrics.Failures) / count)
+	metrics.ExactMatch = round3(exact / count)
+	metrics.TokenF1 = round3(f1 / count)
+	metrics.RougeL = round3(rouge / count)
+	metrics.BLEU = round3(corpusBLEU(candidates, references))
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	metrics.LatencyP50Ms = percentile(latencies, 50)
+	metrics.LatencyP90Ms = percentile(latencies, 90)
+	metrics.LatencyP95Ms = percentile(latencies, 95)
+	metrics.LatencyP99Ms = percentile(latencies, 99)
+	return metrics
+}
+
+// percentile is the nearest-rank percentile of sorted values
+func percentile(sorted []int64, p int) int64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// tokenF1 is the F1 of the bag-of-words overlap, as in SQuAD scoring. Two
+// empty texts match.
+func tokenF1(candidate, reference []string) float64 {
+	if len(candidate) == 0 || len(reference) == 0 {
+		if len(candidate) == len(reference) {
+			return 1
+		}
+		return 0
+	}
+	counts := make(map[string]int, len(reference))
+	for _, term := range reference {
+		counts[term]++
+	}
+	shared := 0
+	for _, term := range candidate {
+		if counts[term] > 0 {
+			counts[term]--
+			shared++
+		}
+	}
+	if shared == 0 {
+		return 0
+	}
+	precision := float64(shared) / float64(len(candidate))
+	recall := float64(shared) / float64(len(reference))
+	return 2 * precision * recall / (precision + recall)
+}
+
+// ngramMatches returns the clipped n-gram matches and the candidate n-gram
+// count for each order from 1 to bleuMaxOrder
+func ngramMatches(candidate, reference []string) (matches, totals [bleuMaxOrder]int) {
+	for n := 1; n <= bleuMaxOrder; n++ {
+		counts := make(map[string]int)
+		for i := 0; i+n <= len(reference); i++ {
+			counts[strings.Join(reference[i:i+n], " ")]++
+		}
+		for i := 0; i+n <= len(candidate); i++ {
+			totals[n-1]++
+			gram := strings.Join(candidate[i:i+n], " ")
+			if counts[gram] > 0 {
+				counts[gram]--
+				matches[n-1]++
+			}
+		}
+	}
+	return matches, totals
+}
+
+// bleu combines n-gram precisions with the brevity penalty. smoothing adds
+// one to the higher-order counts so short texts do not score zero.
+func bleu(matches, totals [bleuMaxOrder]int, candidateLength, referenceLength int, smoothing bool) float64 {
+	if candidateLength == 0 {
+		return 0
+	}
+	logPrecision := 0.0
+	for n := 0; n < bleuMaxOrder; n++ {
+		matched, total := float64(matches[n]), float64(totals[n])
+		if smoothing && n > 0 {
+			matched++
+			total++
+		}
+		if matched == 0 {
+			return 0
+		}
+		logPrecision += math.Log(matched/total) / bleuMaxOrder
+	}
+	brevity := 1.0
+	if candidateLength < referenceLength {
+		brevity = math.Exp(1 - float64(referenceLength)/float64(candidateLength))
+	}
+	return brevity * math.Exp(logPrecision)
+}
+
+// sentenceBLEU is BLEU-4 of one output with add-one smoothing
+func sentenceBLEU(candidate, reference []string) float64 {
+	matches, totals := ngramMatches(candidate, reference)
+	return bleu(matches, totals, len(candidate), len(reference), true)
+}
+
+// corpusBLEU is BLEU-4 with n-gram counts and lengths summed over all outputs
+func corpusBLEU(candidates, references [][]string) float64 {
+	var matches, totals [bleuMaxOrder]int
+	candidateLength, referenceLength := 0, 0
+	for i := range candidates {
+		m, t := ngramMatches(candidates[i], references[i])
+		for n := 0; n < bleuMaxOrder; n++ {
+			matches[n] += m[n]
+			totals[n] += t[n]
+		}
+		candidateLength += len(candidates[i])
+		referenceLength += len(references[i])
+	}
+	return bleu(matches, totals, candidateLength, referenceLength, false)
+}
+
+// CompareEvalReports lists what got worse from the baseline to the current
+// run. Quality scores and the failure rate regress when they move by more
+// than tolerance, p95 latency when it grows by more than latencyTolerance
+// as a fraction. Cases are matched by ID: a case regresses when it stops
+// matching exactly or starts failing.
+func CompareEvalReports(baseline, current *models.EvalReport, tolerance, latencyTolerance float64) []models.EvalRegression {
+	var regressions []models.EvalRegression
+	higherIsBetter := []struct {
+		name              string
+		baseline, current float64
+	}{
+		{"exact_match", baseline.Metrics.ExactMatch, current.Metrics.ExactMatch},
+		{"token_f1", baseline.Metrics.TokenF1, current.Metrics.TokenF1},
+		{"rouge_l", baseline.Metrics.RougeL, current.Metrics.RougeL},
+		{"bleu", baseline.Metrics.BLEU, current.Metrics.BLEU},
+	}
+	for _, metric := range higherIsBetter {
+		if metric.current < metric.baseline-tolerance {
+			regressions = append(regressions, models.EvalRegression{
+				Metric:   metric.name,
+				Baseline: metric.baseline,
+				Current:  metric.current,
+				Detail:   fmt.Sprintf("dropped by %.3f", metric.baseline-metric.current),
+			})
+		}
+	}
+	if current.Metrics.FailureRate > baseline.Metrics.FailureRate+tolerance {
+		regressions = append(regressions, models.EvalRegression{
+			Metric:   "failure_rate",
+			Baseline: baseline.Metrics.FailureRate,
+			Current:  current.Metrics.FailureRate,
+			Detail:   fmt.Sprintf("rose by %.3f", current.Metrics.FailureRate-baseline.Metrics.FailureRate),
+		})
+	}
+	baselineP95, currentP95 := float64(baseline.Metrics.LatencyP95Ms), float64(current.Metrics.LatencyP95Ms)
+	if baselineP95 > 0 && currentP95 > baselineP95*(1+latencyTolerance) {
+		regressions = append(regressions, models.EvalRegression{
+			Metric:   "latency_p95_ms",
+			Baseline: baselineP95,
+			Current:  currentP95,
+			Detail:   fmt.Sprintf("%.0f%% slower", (currentP95/baselineP95-1)*100),
+		})
+	}
+
+	previous := make(map[string]models.EvalCaseResult, len(baseline.Results))
+	for _, result := range baseline.Results {
+		previous[result.ID] = result
+	}
+	for _, result := range current.Results {
+		before, ok := previous[result.ID]
+		if !ok || before.Error != "" {
+			continue
+		}
+		switch {
+		case result.Error != "":
+			regressions = append(regressions, models.EvalRegression{
+				Metric: "failure",
+				CaseID: result.ID,
+				Detail: result.Error,
+			})
+		case before.ExactMatch && !result.ExactMatch:
+			regressions = append(regressions, models.EvalRegression{
+				Metric:   "exact_match",
+				CaseID:   result.ID,
+				Baseline: 1,
+				Current:  0,
+				Detail:   fmt.Sprintf("token F1 %.3f", result.TokenF1),
+			})
+		}
+	}
+	return regressions
+}
+
+// RenderEvalMarkdown formats a report for humans: the metrics, beside the
+// baseline's when given, then regressions, failures and the weakest cases
+func RenderEvalMarkdown(report *models.EvalReport, baseline *models.EvalReport) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Evaluation: %s\n\n", report.Dataset)
+	fmt.Fprintf(&b, "- Backend: %s (%s)\n", report.Backend, report.Model)
+	fmt.Fprintf(&b, "- Started: %s\n", report.StartedAt.UTC().Format(time.RFC3339))
+	fmt.Fprintf(&b, "- Duration: %dms\n", report.DurationMs)
+	if report.Baseline != "" {
+		fmt.Fprintf(&b, "- Baseline: %s\n", report.Baseline)
+	}
+
+	rows := []struct {
+		name              string
+		current, previous float64
+		integer           bool
+	}{
+		{"Cases", float64(report.Metrics.Cases), 0, true},
+		{"Failure rate", report.Metrics.FailureRate, 0, false},
+		{"Exact match", report.Metrics.ExactMatch, 0, false},
+		{"Token F1", report.Metrics.TokenF1, 0, false},
+		{"ROUGE-L", report.Metrics.RougeL, 0, false},
+		{"BLEU", report.Metrics.BLEU, 0, false},
+		{"Latency p50 (ms)", float64(report.Metrics.LatencyP50Ms), 0, true},
+		{"Latency p90 (ms)", float64(report.Metrics.LatencyP90Ms), 0, true},
+		{"Latency p95 (ms)", float64(report.Metrics.LatencyP95Ms), 0, true},
+		{"Latency p99 (ms)", float64(report.Metrics.LatencyP99Ms), 0, true},
+	}
+	if baseline != nil {
+		previous := []float64{
+			float64(baseline.Metrics.Cases), baseline.Metrics.FailureRate, baseline.Metrics.ExactMatch,
+			baseline.Metrics.TokenF1, baseline.Metrics.RougeL, baseline.Metrics.BLEU,
+			float64(baseline.Metrics.LatencyP50Ms), float64(baseline.Metrics.LatencyP90Ms),
+			float64(baseline.Metrics.LatencyP95Ms), float64(baseline.Metrics.LatencyP99Ms),
+		}
+		for i := range rows {
+			rows[i].previous = previous[i]
+		}
+	}
+	format := func(value float64, integer bool) string {
+		if integer {
+			return strconv.FormatFloat(value, 'f', 0, 64)
+		}
+		return strconv.FormatFloat(value, 'f', 3, 64)
+	}
+
+	b.WriteString("\n## Metrics\n\n")
+	if baseline != nil {
+		b.WriteString("| Metric | Baseline | Current | Change |\n|---|---|---|---|\n")
+		for _, row := range rows {
+			change := row.current - row.previous
+			sign := ""
+			if change > 0 {
+				sign = "+"
+			}
+			fmt.Fprintf(&b, "| %s | %s | %s | %s%s |\n", row.name, format(row.previous, row.integer), format(row.current, row.integer), sign, format(change, row.integer))
+		}
+	} else {
+		b.WriteString("| Metric | Value |\n|---|---|\n")
+		for _, row := range rows {
+			fmt.Fprintf(&b, "| %s | %s |\n", row.name, format(row.current, row.integer))
+		}
+	}
+
+	if baseline != nil {
+		b.WriteString("\n## Regressions\n\n")
+		if len(report.Regressions) == 0 {
+			b.WriteString("None.\n")
+		}
+		for _, regression := range report.Regressions {
+			if regression.CaseID != "" {
+				fmt.Fprintf(&b, "- Case `%s`: %s (%s)\n", regression.CaseID, regression.Metric, markdownCell(regression.Detail))
+			} else {
+				fmt.Fprintf(&b, "- %s: %s → %s (%s)\n", regression.Metric, format(regression.Baseline, false), format(regression.Current, false), regression.Detail)
+			}
+		}
+	}
+
+	var failures, scored []models.EvalCaseResult
+	for _, result := range report.Results {
+		if result.Error != "" {
+			failures = append(failures, result)
+		} else if !result.ExactMatch {
+			scored = append(scored, result)
+		}
+	}
+	if len(failures) > 0 {
+		b.WriteString("\n## Failures\n\n| Case | Error |\n|---|---|\n")
+		for _, result := range failures {
+			fmt.Fprintf(&b, "| %s | %s |\n", markdownCell(result.ID), markdownCell(result.Error))
+		}
+	}
+	if len(scored) > 0 {
+		sort.SliceStable(scored, func(i, j int) bool { return scored[i].TokenF1 < scored[j].TokenF1 })
+		if len(scored) > maxEvalWorstCases {
+			scored = scored[:maxEvalWorstCases]
+		}
+		b.WriteString("\n## Lowest-scoring cases\n\n| Case | Token F1 | ROUGE-L | Expected | Output |\n|---|---|---|---|---|\n")
+		for _, result := range scored {
+			fmt.Fprintf(&b, "| %s | %.3f | %.3f | %s | %s |\n", markdownCell(result.ID), result.TokenF1, result.RougeL, markdownCell(result.Expected), markdownCell(result.Output))
+		}
+	}
+	return b.String()
+}
+
+// markdownCell shortens text to one table cell
+func markdownCell(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if runes := []rune(text); len(runes) > maxEvalMarkdownText {
+		text = string(runes[:maxEvalMarkdownText]) + "…"
+	}
+	return strings.ReplaceAll(text, "|", "\\|")
+}
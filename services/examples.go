@@ -0,0 +1,258 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Ammar0144/ai/models"
+)
+
+// Few-shot selection limits
+const (
+	defaultFewShotExamples = 3
+	maxFewShotExamples     = 10
+)
+
+// Example store errors
+var (
+	ErrExampleNotFound = errors.New("example not found")
+	ErrNoExamples      = errors.New("no examples stored for task")
+	// ErrInvalidExample wraps validation failures of examples being added
+	ErrInvalidExample = errors.New("invalid example")
+)
+
+// ExampleStore is a thread-safe set of input/output examples grouped by
+// task, optionally persisted to a JSON file
+type ExampleStore struct {
+	examples map[string]*models.Example
+	path     string
+	mutex    sync.RWMutex
+}
+
+// NewExampleStore creates an example store, loading AI_EXAMPLES_FILE when set
+func NewExampleStore() *ExampleStore {
+	store := &ExampleStore{
+		examples: make(map[string]*models.Example),
+		path:     os.Getenv("AI_EXAMPLES_FILE"),
+	}
+
+	if store.path != "" {
+		data, err := os.ReadFile(store.path)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+		case err != nil:
+			log.Printf("Example store: failed to read %s: %v", store.path, err)
+		default:
+			var examples []*models.Example
+			if err := json.Unmarshal(data, &examples); err != nil {
+				log.Printf("Example store: failed to parse %s: %v", store.path, err)
+			}
+			for _, example := range examples {
+				store.examples[example.ID] = example
+			}
+			log.Printf("Example store: loaded %d examples from %s", len(examples), store.path)
+		}
+	}
+	return store
+}
+
+// save persists the store when a file is configured; callers hold the lock
+func (e *ExampleStore) save() error {
+	if e.path == "" {
+		return nil
+	}
+	return writeJSONFileAtomic(e.path, e.sorted(""))
+}
+
+// sorted returns the examples of a task (or all tasks), oldest first;
+// callers hold the lock
+func (e *ExampleStore) sorted(task string) []*models.Example {
+	examples := make([]*models.Example, 0, len(e.examples))
+	for _, example := range e.examples {
+		if task == "" || example.Task == task {
+			examples = append(examples, example)
+		}
+	}
+	sort.Slice(examples, func(i, j int) bool {
+		if examples[i].Task != examples[j].Task {
+			return examples[i].Task < examples[j].Task
+		}
+		if !examples[i].CreatedAt.Equal(examples[j].CreatedAt) {
+			return examples[i].CreatedAt.Before(examples[j].CreatedAt)
+		}
+		return examples[i].ID < examples[j].ID
+	})
+	return examples
+}
+
+// Add stores new examples; either all of them are added or none
+func (e *ExampleStore) Add(reqs ...models.ExampleRequest) ([]models.Example, error) {
+	added := make([]*models.Example, 0, len(reqs))
+	for i, req := range reqs {
+		task := strings.TrimSpace(req.Task)
+		if !templateIDPattern.MatchString(task) {
+			return nil, fmt.Errorf("%w %d: task must be a lowercase slug", ErrInvalidExample, i)
+		}
+		if strings.TrimSpace(req.Input) == "" || strings.TrimSpace(req.Output) == "" {
+			return nil, fmt.Errorf("%w %d: input and output are required", ErrInvalidExample, i)
+		}
+		added = append(added, &models.Example{
+			ID:        NewID("ex"),
+			Task:      task,
+			Input:     req.Input,
+			Output:    req.Output,
+			CreatedAt: time.Now(),
+		})
+	}
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	for _, example := range added {
+		e.examples[example.ID] = example
+	}
+	if err := e.save(); err != nil {
+		for _, example := range added {
+			delete(e.examples, example.ID)
+		}
+		return nil, err
+	}
+
+	result := make([]models.Example, len(added))
+	for i, example := range added {
+		result[i] = *example
+	}
+	return result, nil
+}
+
+// Get returns a single example
+func (e *ExampleStore) Get(id string) (models.Example, error) {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+
+	example, ok := e.examples[id]
+	if !ok {
+		return models.Example{}, ErrExampleNotFound
+	}
+	return *example, nil
+}
+
+// List returns the examples of a task, or of every task when task is empty
+func (e *ExampleStore) List(task string) []models.Example {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+
+	examples := e.sorted(task)
+	result := make([]models.Example, len(examples))
+	for i, example := range examples {
+		result[i] = *example
+	}
+	return result
+}
+
+// Delete removes an example
+func (e *ExampleStore) Delete(id string) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	example, ok := e.examples[id]
+	if !ok {
+		return ErrExampleNotFound
+	}
+	delete(e.examples, id)
+	if err := e.save(); err != nil {
+		e.examples[id] = example
+		return err
+	}
+	return nil
+}
+
+// Select returns the k examples of a task whose inputs are most similar to
+// the query by TF-IDF cosine similarity, most similar first
+func (e *ExampleStore) Select(task, query string, k int) ([]models.Example, error) {
+	candidates := e.List(task)
+	if len(candidates) == 0 {
+		return nil, ErrNoExamples
+	}
+
+	inputs := make([]string, len(candidates))
+	for i, example := range candidates {
+		inputs[i] = example.Input
+	}
+	scores := TFIDFSimilarities(query, inputs)
+
+	order := make([]int, len(candidates))
+	for i := range order {
+		order[i] = i
+	}
+	// Stable sort keeps older examples first among equal scores
+	sort.SliceStable(order, func(a, b int) bool { return scores[order[a]] > scores[order[b]] })
+
+	if k > len(order) {
+		k = len(order)
+	}
+	selected := make([]models.Example, k)
+	for i := 0; i < k; i++ {
+		selected[i] = candidates[order[i]]
+	}
+	return selected, nil
+}
+
+// FewShotPrompt prepends the most similar examples of a task to the prompt in
+// an Input/Output format the model can continue. Examples that would push
+// the prompt and the response budget past the model context are dropped,
+// least similar first. It returns the prompt and the IDs of the examples used.
+func (e *ExampleStore) FewShotPrompt(opts models.FewShotOptions, prompt string, maxTokens int) (string, []string, error) {
+	k := opts.K
+	if k == 0 {
+		k = defaultFewShotExamples
+	}
+	if k < 0 || k > maxFewShotExamples {
+		return "", nil, fmt.Errorf("few_shot.k must be between 1 and %d", maxFewShotExamples)
+	}
+
+	examples, err := e.Select(strings.TrimSpace(opts.Task), prompt, k)
+	if err != nil {
+		return "", nil, err
+	}
+
+	query := formatExample(prompt, "")
+	budget := ModelContextTokens - maxTokens - EstimateTokens(query)
+	for len(examples) > 0 {
+		used := 0
+		for _, example := range examples {
+			used += EstimateTokens(formatExample(example.Input, example.Output))
+		}
+		if used <= budget {
+			break
+		}
+		examples = examples[:len(examples)-1]
+	}
+
+	// The most similar example goes last, next to the query
+	var b strings.Builder
+	ids := make([]string, 0, len(examples))
+	for i := len(examples) - 1; i >= 0; i-- {
+		b.WriteString(formatExample(examples[i].Input, examples[i].Output))
+		ids = append(ids, examples[i].ID)
+	}
+	b.WriteString(query)
+	return b.String(), ids, nil
+}
+
+// formatExample renders one Input/Output pair; an empty output leaves the
+// pair open for the model to complete
+func formatExample(input, output string) string {
+	text := "Input: " + strings.TrimSpace(input) + "\nOutput:"
+	if output == "" {
+		return text
+	}
+	return text + " " + strings.TrimSpace(output) + "\n\n"
+}
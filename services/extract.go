@@ -0,0 +1,546 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/Ammar0144/ai/models"
+)
+
+// Extraction limits and defaults
+const (
+	maxExtractFields            = 30
+	maxExtractDescriptionTokens = 64
+	maxExtractMaxTokens         = 512
+	extractTokensPerField       = 24
+	extractPromptOverhead       = 64
+	defaultExtractTemperature   = 0.2
+
+	// Confidence of a value found verbatim, found by its typed value, and
+	// not found in the text at all; fuzzy matches scale with their overlap
+	extractExactConfidence       = 1.0
+	extractEquivalentConfidence  = 0.9
+	extractUnsupportedConfidence = 0.3
+	minExtractAlignment          = 0.5
+)
+
+// extractFieldName is a JSON-friendly field name
+var extractFieldName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]{0,63}$`)
+
+// extractNumberPattern matches a number with optional sign, grouping and
+// decimal separators
+var extractNumberPattern = regexp.MustCompile(`\(?[-−+]?\d(?:[\d.,'\x{00A0}\x{202F}]*\d)?\)?`)
+
+// extractLinePattern matches "name: value" lines when the model did not
+// produce JSON
+var extractLinePattern = regexp.MustCompile(`(?m)^\s*["']?([A-Za-z_][A-Za-z0-9_ ]*?)["']?\s*[:=]\s*(.*?)\s*,?\s*$`)
+
+// ordinalSuffix strips "st", "nd", "rd" and "th" from day numbers
+var ordinalSuffix = regexp.MustCompile(`(?i)(\d)(st|nd|rd|th)\b`)
+
+// numericDate matches day, month and year separated by / . or -
+var numericDate = regexp.MustCompile(`^(\d{1,2})[/.\-](\d{1,2})[/.\-](\d{2}|\d{4})$`)
+
+// extractDatePattern finds date-like text to align a date value with
+var extractDatePattern = regexp.MustCompile(`(?i)\b(?:\d{4}[-/.]\d{1,2}[-/.]\d{1,2}|\d{1,2}[-/.]\d{1,2}[-/.](?:\d{4}|\d{2})|(?:mon|tues|wednes|thurs|fri|satur|sun)?(?:day,?\s+)?(?:jan|feb|mar|apr|may|jun|jul|aug|sep|sept|oct|nov|dec)[a-z]*\.?\s+\d{1,2}(?:st|nd|rd|th)?,?\s+\d{4}|\d{1,2}(?:st|nd|rd|th)?(?:\s+of)?\s+(?:jan|feb|mar|apr|may|jun|jul|aug|sep|sept|oct|nov|dec)[a-z]*\.?,?\s+\d{4})\b`)
+
+// dateLayouts are tried in order after ordinals, weekdays and abbreviation
+// dots are removed
+var dateLayouts = []string{
+	"2006-01-02", time.RFC3339, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02 15:04", "2006/01/02", "2006.01.02",
+	"January 2, 2006", "January 2 2006", "Jan 2, 2006", "Jan 2 2006",
+	"2 January 2006", "2 January, 2006", "2 Jan 2006", "2 Jan, 2006",
+	"Monday, January 2, 2006", "Mon, 2 Jan 2006", "Mon, Jan 2, 2006",
+}
+
+// emptyValues are model answers meaning the field is absent
+var emptyValues = map[string]bool{"": true, "null": true, "none": true, "n/a": true, "na": true, "unknown": true, "not found": true, "not specified": true, "not mentioned": true, "-": true}
+
+// ExtractPlan is a validated extraction request with its prompt
+type ExtractPlan struct {
+	text     string
+	fields   []models.ExtractField
+	dayFirst bool
+	schema   map[string]interface{}
+
+	Prompt      string
+	MaxTokens   int
+	Temperature float64
+}
+
+// ExtractResult is the typed object with per-field details in request order
+type ExtractResult struct {
+	Data    map[string]interface{}
+	Fields  []models.ExtractedField
+	Missing []string
+	Valid   bool
+	Raw     string
+}
+
+// PlanExtract validates an extraction request, fills in defaults and
+// builds the prompt. The prompt asks for a JSON object and starts it, so
+// the model continues with the first key.
+func PlanExtract(req *models.ExtractRequest) (*ExtractPlan, error) {
+	req.Text = strings.TrimSpace(req.Text)
+	if req.Text == "" {
+		return nil, fmt.Errorf("text cannot be empty")
+	}
+	if len(req.Fields) == 0 || len(req.Fields) > maxExtractFields {
+		return nil, fmt.Errorf("between 1 and %d fields are required", maxExtractFields)
+	}
+
+	properties := make(map[string]interface{})
+	required := []interface{}{}
+	seen := make(map[string]bool)
+	for i := range req.Fields {
+		field := &req.Fields[i]
+		if !extractFieldName.MatchString(field.Name) {
+			return nil, fmt.Errorf("field %d: name must be a letter or underscore followed by up to 63 letters, digits or underscores", i)
+		}
+		if seen[strings.ToLower(field.Name)] {
+			return nil, fmt.Errorf("duplicate field %q", field.Name)
+		}
+		seen[strings.ToLower(field.Name)] = true
+		if field.Type == "" {
+			field.Type = models.FieldString
+		}
+		property := map[string]interface{}{}
+		switch field.Type {
+		case models.FieldString, models.FieldDate:
+			property["type"] = "string"
+		case models.FieldNumber, models.FieldInteger, models.FieldBoolean:
+			property["type"] = field.Type
+		case models.FieldEnum:
+			if len(field.Options) == 0 {
+				return nil, fmt.Errorf("field %q: enum fields need options", field.Name)
+			}
+			options := make([]interface{}, len(field.Options))
+			for j, option := range field.Options {
+				if strings.TrimSpace(option) == "" {
+					return nil, fmt.Errorf("field %q: options cannot be empty", field.Name)
+				}
+				options[j] = option
+			}
+			property["type"] = "string"
+			property["enum"] = options
+		default:
+			return nil, fmt.Errorf("field %q: type must be string, number, integer, boolean, date or enum", field.Name)
+		}
+		if field.Type != models.FieldEnum && len(field.Options) > 0 {
+			return nil, fmt.Errorf("field %q: only enum fields take options", field.Name)
+		}
+		if EstimateTokens(field.Description) > maxExtractDescriptionTokens {
+			return nil, fmt.Errorf("field %q: description cannot exceed %d tokens", field.Name, maxExtractDescriptionTokens)
+		}
+		properties[field.Name] = property
+		if field.Required {
+			required = append(required, field.Name)
+		}
+	}
+
+	if req.MaxTokens == 0 {
+		req.MaxTokens = minInt(32+extractTokensPerField*len(req.Fields), maxExtractMaxTokens)
+	}
+	if req.MaxTokens < 1 || req.MaxTokens > maxExtractMaxTokens {
+		return nil, fmt.Errorf("max_tokens must be between 1 and %d", maxExtractMaxTokens)
+	}
+	if req.Temperature == 0 {
+		req.Temperature = defaultExtractTemperature
+	}
+
+	plan := &ExtractPlan{
+		text:     req.Text,
+		fields:   req.Fields,
+		dayFirst: req.DayFirst,
+		schema: map[string]interface{}{
+			"type":       "object",
+			"properties": properties,
+			"required":   required,
+		},
+		MaxTokens:   req.MaxTokens,
+		Temperature: req.Temperature,
+	}
+	fieldList := plan.fieldList()
+	budget := ModelContextTokens - req.MaxTokens - EstimateTokens(fieldList) - extractPromptOverhead
+	if tokens := EstimateTokens(req.Text); tokens > budget {
+		return nil, fmt.Errorf("text cannot exceed %d tokens with these fields (got about %d)", budget, tokens)
+	}
+	plan.Prompt = fmt.Sprintf("Extract the fields below from the text. Reply with one JSON object with exactly these keys. Copy each value from the text as written, and use null for a field the text does not contain.\n\nFields:\n%s\nText:\n%s\n\nJSON:\n{", fieldList, req.Text)
+	return plan, nil
+}
+
+// fieldList describes each field for the prompt
+func (p *ExtractPlan) fieldList() string {
+	var b strings.Builder
+	for _, field := range p.fields {
+		kind := field.Type
+		if field.Type == models.FieldEnum {
+			kind = "one of: " + strings.Join(field.Options, ", ")
+		}
+		fmt.Fprintf(&b, "- %s (%s)", field.Name, kind)
+		if field.Description != "" {
+			b.WriteString(": " + field.Description)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// Parse reads the model's answer, coerces each field to its type and aligns
+// it with the text. Values found verbatim have confidence 1; values found by
+// their typed value (a number or date written differently) 0.9; string and
+// enum values matched by word overlap scale with the overlap; values not in
+// the text at all 0.3. The typed object is checked with ValidateSchema.
+func (p *ExtractPlan) Parse(output string) *ExtractResult {
+	raw := "{" + output
+	object := parseExtractObject(raw)
+	result := &ExtractResult{
+		Data:    make(map[string]interface{}),
+		Fields:  make([]models.ExtractedField, len(p.fields)),
+		Missing: []string{},
+		Raw:     raw,
+	}
+
+	for i, field := range p.fields {
+		extracted := models.ExtractedField{Name: field.Name, Type: field.Type}
+		value, found := lookupExtractKey(object, field.Name)
+		if found && !isEmptyValue(value) {
+			extracted.Raw = value
+			typed, err := coerceField(field, value, p.dayFirst)
+			if err != nil {
+				extracted.Error = err.Error()
+			} else {
+				extracted.Value = typed
+				extracted.Span, extracted.Confidence = p.locate(field, value, typed)
+				result.Data[field.Name] = typed
+			}
+		}
+		if extracted.Value == nil && field.Required {
+			result.Missing = append(result.Missing, field.Name)
+		}
+		result.Fields[i] = extracted
+	}
+	result.Valid = ValidateSchema(p.schema, result.Data) == nil
+	return result
+}
+
+// parseExtractObject returns the first JSON object in the output, falling
+// back to "name: value" lines
+func parseExtractObject(raw string) map[string]interface{} {
+	for _, span := range findJSONObjects(raw) {
+		var object map[string]interface{}
+		if err := json.Unmarshal([]byte(raw[span[0]:span[1]]), &object); err == nil {
+			return object
+		}
+	}
+	// An unterminated object is often only missing its closing brace
+	var object map[string]interface{}
+	if err := json.Unmarshal([]byte(strings.TrimRight(strings.TrimSpace(raw), ",")+"}"), &object); err == nil {
+		return object
+	}
+
+	object = make(map[string]interface{})
+	for _, match := range extractLinePattern.FindAllStringSubmatch(strings.TrimPrefix(raw, "{"), -1) {
+		value := strings.Trim(match[2], `"'`)
+		if _, exists := object[match[1]]; !exists {
+			object[match[1]] = value
+		}
+	}
+	return object
+}
+
+// lookupExtractKey finds a key by exact name, then ignoring case
+func lookupExtractKey(object map[string]interface{}, name string) (interface{}, bool) {
+	if value, ok := object[name]; ok {
+		return value, true
+	}
+	for key, value := range object {
+		if strings.EqualFold(strings.TrimSpace(key), name) {
+			return value, true
+		}
+	}
+	return nil, false
+}
+
+func isEmptyValue(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case string:
+		return emptyValues[strings.ToLower(strings.Trim(strings.TrimSpace(v), "."))]
+	}
+	return false
+}
+
+// coerceField converts a model value to the field's type
+func coerceField(field models.ExtractField, value interface{}, dayFirst bool) (interface{}, error) {
+	switch field.Type {
+	case models.FieldNumber, models.FieldInteger:
+		number, err := coerceNumber(value)
+		if err != nil {
+			return nil, err
+		}
+		if field.Type == models.FieldInteger && number != math.Trunc(number) {
+			return nil, fmt.Errorf("%v is not an integer", number)
+		}
+		return number, nil
+	case models.FieldBoolean:
+		return coerceBoolean(value)
+	case models.FieldDate:
+		date, err := ParseDate(valueString(value), dayFirst)
+		if err != nil {
+			return nil, err
+		}
+		return date.Format("2006-01-02"), nil
+	case models.FieldEnum:
+		return coerceEnum(valueString(value), field.Options)
+	default:
+		if _, isObject := value.(map[string]interface{}); isObject {
+			return nil, fmt.Errorf("expected a string, got an object")
+		}
+		return strings.TrimSpace(valueString(value)), nil
+	}
+}
+
+// valueString renders a decoded JSON value as text
+func valueString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case nil:
+		return ""
+	}
+	data, _ := json.Marshal(value)
+	return string(data)
+}
+
+func coerceNumber(value interface{}) (float64, error) {
+	switch v := value.(type) {
+	case float64:
+		return v, nil
+	case string:
+		locs := findNumbers(v)
+		if len(locs) == 0 {
+			return 0, fmt.Errorf("%q is not a number", v)
+		}
+		return ParseNumber(v[locs[0][0]:locs[0][1]])
+	}
+	return 0, fmt.Errorf("%s is not a number", valueString(value))
+}
+
+// findNumbers returns the byte ranges of numbers in text. A hyphen joined
+// to a preceding letter or digit, as in "INV-2041", is not a minus sign.
+func findNumbers(text string) [][]int {
+	locs := extractNumberPattern.FindAllStringIndex(text, -1)
+	for _, loc := range locs {
+		if loc[0] == 0 || (text[loc[0]] != '-' && !strings.HasPrefix(text[loc[0]:], "−")) {
+			continue
+		}
+		previous, _ := utf8.DecodeLastRuneInString(text[:loc[0]])
+		if unicode.IsLetter(previous) || unicode.IsDigit(previous) {
+			_, size := utf8.DecodeRuneInString(text[loc[0]:])
+			loc[0] += size
+		}
+	}
+	return locs
+}
+
+// ParseNumber parses a number written with grouping separators, either
+// decimal convention ("1,234.50" or "1.234,50"), a leading sign or
+// accounting parentheses for negatives
+func ParseNumber(text string) (float64, error) {
+	s := strings.TrimSpace(text)
+	negative := false
+	if strings.HasPrefix(s, "(") && strings.HasSuffix(s, ")") {
+		negative = true
+		s = s[1 : len(s)-1]
+	}
+	s = strings.Trim(s, "()")
+	if strings.HasPrefix(s, "-") || strings.HasPrefix(s, "−") {
+		negative = !negative
+		s = strings.TrimLeft(s, "-−")
+	}
+	s = strings.TrimPrefix(s, "+")
+	s = strings.NewReplacer("'", "", " ", "", " ", "").Replace(s)
+
+	comma, dot := strings.LastIndex(s, ","), strings.LastIndex(s, ".")
+	switch {
+	case comma >= 0 && dot >= 0:
+		// The later separator is the decimal point
+		if comma > dot {
+			s = strings.Replace(strings.ReplaceAll(s, ".", ""), ",", ".", 1)
+		} else {
+			s = strings.ReplaceAll(s, ",", "")
+		}
+	case comma >= 0:
+		if strings.Count(s, ",") == 1 && len(s)-comma-1 != 3 {
+			s = strings.Replace(s, ",", ".", 1)
+		} else {
+			s = strings.ReplaceAll(s, ",", "")
+		}
+	case strings.Count(s, ".") > 1:
+		s = strings.ReplaceAll(s, ".", "")
+	}
+
+	number, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a number", text)
+	}
+	if negative {
+		number = -number
+	}
+	return number, nil
+}
+
+func coerceBoolean(value interface{}) (bool, error) {
+	switch v := value.(type) {
+	case bool:
+		return v, nil
+	case float64:
+		if v == 0 || v == 1 {
+			return v == 1, nil
+		}
+	case string:
+		switch strings.ToLower(strings.Trim(strings.TrimSpace(v), ".!")) {
+		case "true", "yes", "y", "1", "on", "checked":
+			return true, nil
+		case "false", "no", "n", "0", "off", "unchecked":
+			return false, nil
+		}
+	}
+	return false, fmt.Errorf("%s is not a boolean", valueString(value))
+}
+
+// coerceEnum matches a value to an option ignoring case and punctuation, or
+// to the only option named within it
+func coerceEnum(value string, options []string) (string, error) {
+	value = strings.TrimSpace(value)
+	normalized := normalizeEnum(value)
+	for _, option := range options {
+		if strings.EqualFold(value, option) || normalizeEnum(option) == normalized {
+			return option, nil
+		}
+	}
+	var named []string
+	words := " " + strings.Join(Tokenize(value), " ") + " "
+	for _, option := range options {
+		if optionWords := strings.Join(Tokenize(option), " "); optionWords != "" && strings.Contains(words, " "+optionWords+" ") {
+			named = append(named, option)
+		}
+	}
+	if len(named) == 1 {
+		return named[0], nil
+	}
+	return "", fmt.Errorf("%q is not one of %s", value, strings.Join(options, ", "))
+}
+
+func normalizeEnum(value string) string {
+	return strings.Join(Tokenize(value), "")
+}
+
+// ParseDate parses common written and numeric date formats. Numeric dates
+// are read month first unless dayFirst is set or the first number cannot be
+// a month; two-digit years are placed in 1970-2069.
+func ParseDate(text string, dayFirst bool) (time.Time, error) {
+	s := strings.Join(strings.Fields(ordinalSuffix.ReplaceAllString(text, "$1")), " ")
+	s = strings.TrimPrefix(strings.Trim(s, " ."), "the ")
+	s = strings.NewReplacer(" of ", " ", "Sept ", "Sep ", "sept ", "sep ", ". ", " ").Replace(s)
+
+	if match := numericDate.FindStringSubmatch(s); match != nil {
+		first, _ := strconv.Atoi(match[1])
+		second, _ := strconv.Atoi(match[2])
+		year, _ := strconv.Atoi(match[3])
+		if len(match[3]) == 2 {
+			year += 2000
+			if year >= 2070 {
+				year -= 100
+			}
+		}
+		month, day := first, second
+		if first > 12 || (dayFirst && second <= 12) {
+			month, day = second, first
+		}
+		date := time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+		if month < 1 || month > 12 || date.Day() != day {
+			return time.Time{}, fmt.Errorf("%q is not a valid date", text)
+		}
+		return date, nil
+	}
+
+	for _, layout := range dateLayouts {
+		if date, err := time.Parse(layout, s); err == nil {
+			return date, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%q is not a recognized date", text)
+}
+
+// locate finds where a value came from in the text and how sure that is
+func (p *ExtractPlan) locate(field models.ExtractField, raw, typed interface{}) (*models.ExtractSpan, float64) {
+	runes := []rune(p.text)
+	rawText := strings.TrimSpace(valueString(raw))
+	if field.Type == models.FieldNumber || field.Type == models.FieldInteger {
+		// Numbers are matched whole so "7" is not found inside "1,275"
+		for _, loc := range findNumbers(p.text) {
+			if number, err := ParseNumber(p.text[loc[0]:loc[1]]); err == nil && number == typed.(float64) {
+				span := p.byteSpan(runes, loc)
+				if strings.Contains(rawText, span.Text) {
+					return span, extractExactConfidence
+				}
+				return span, extractEquivalentConfidence
+			}
+		}
+		return nil, extractUnsupportedConfidence
+	}
+	if start := indexFoldRunes(runes, []rune(rawText)); start >= 0 && field.Type != models.FieldBoolean {
+		end := start + utf8.RuneCountInString(rawText)
+		return p.span(runes, start, end), extractExactConfidence
+	}
+
+	switch field.Type {
+	case models.FieldDate:
+		for _, loc := range extractDatePattern.FindAllStringIndex(p.text, -1) {
+			if date, err := ParseDate(p.text[loc[0]:loc[1]], p.dayFirst); err == nil && date.Format("2006-01-02") == typed.(string) {
+				return p.byteSpan(runes, loc), extractEquivalentConfidence
+			}
+		}
+	case models.FieldEnum, models.FieldString:
+		candidate := typed.(string)
+		if start := indexFoldRunes(runes, []rune(candidate)); start >= 0 && candidate != rawText {
+			return p.span(runes, start, start+utf8.RuneCountInString(candidate)), extractEquivalentConfidence
+		}
+		if start, end, score := alignSpan(p.text, rawText); score >= minExtractAlignment {
+			return p.span(runes, start, end), extractEquivalentConfidence * score
+		}
+	}
+	return nil, extractUnsupportedConfidence
+}
+
+func (p *ExtractPlan) span(runes []rune, start, end int) *models.ExtractSpan {
+	return &models.ExtractSpan{Start: start, End: end, Text: string(runes[start:end])}
+}
+
+// byteSpan converts a regexp byte range to a rune span, trimming the
+// parentheses and spaces a pattern may include
+func (p *ExtractPlan) byteSpan(runes []rune, loc []int) *models.ExtractSpan {
+	start := utf8.RuneCountInString(p.text[:loc[0]])
+	end := start + utf8.RuneCountInString(p.text[loc[0]:loc[1]])
+	for start < end && (unicode.IsSpace(runes[start]) || runes[start] == '(' && runes[end-1] != ')') {
+		start++
+	}
+	for end > start && (unicode.IsSpace(runes[end-1]) || runes[end-1] == ')' && runes[start] != '(') {
+		end--
+	}
+	return p.span(runes, start, end)
+}
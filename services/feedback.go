@@ -0,0 +1,435 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Ammar0144/ai/models"
+)
+
+// Feedback limits and export formats
+const (
+	maxFeedbackCommentBytes = 4000
+	maxFeedbackTags         = 16
+	// Snapshots of rated responses keep at most this much of the prompt and
+	// output, and drop request bodies larger than it
+	maxFeedbackSnapshotBytes  = 16 << 10
+	defaultFeedbackMaxEntries = 10000
+	// The log is compacted once its superseded lines outnumber the live
+	// feedback by this many
+	feedbackCompactSlack = 1000
+	FeedbackFormatJSONL  = "jsonl"
+	FeedbackFormatCSV    = "csv"
+	FeedbackFormatOpenAI = "openai"
+)
+
+// feedbackTagPattern keeps tags short, lowercase and filterable
+var feedbackTagPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_.:-]{0,63}$`)
+
+// feedbackCSVHeader is the column layout of CSV exports
+var feedbackCSVHeader = []string{
+	"feedback_id", "response_id", "rating", "comment", "tags", "user_id", "endpoint", "model",
+	"prompt", "output", "max_tokens", "temperature", "response_created_at", "created_at", "updated_at",
+}
+
+// ErrFeedbackFull is returned when the store holds its maximum number of
+// ratings and a new one is submitted
+var ErrFeedbackFull = errors.New("feedback store is full")
+
+// FeedbackFilter selects feedback; empty fields match everything
+type FeedbackFilter struct {
+	Rating     string
+	Tag        string
+	UserID     string
+	ResponseID string
+	Endpoint   string
+	Model      string
+}
+
+// matches reports whether feedback passes the filter
+func (f FeedbackFilter) matches(feedback *models.Feedback) bool {
+	if f.Rating != "" && feedback.Rating != f.Rating ||
+		f.UserID != "" && feedback.UserID != f.UserID ||
+		f.ResponseID != "" && feedback.ResponseID != f.ResponseID ||
+		f.Endpoint != "" && feedback.Response.Endpoint != f.Endpoint ||
+		f.Model != "" && feedback.Response.Model != f.Model {
+		return false
+	}
+	if f.Tag == "" {
+		return true
+	}
+	for _, tag := range feedback.Tags {
+		if tag == f.Tag {
+			return true
+		}
+	}
+	return false
+}
+
+// FeedbackStore is a thread-safe set of response ratings, optionally
+// persisted to a JSON Lines log that is compacted as ratings are replaced
+type FeedbackStore struct {
+	feedback map[string]*models.Feedback
+	// byResponse maps a response ID and user ID to the user's feedback ID
+	byResponse map[string]string
+	path       string
+	maxEntries int
+	// logged counts the lines in the log, including superseded ones
+	logged int
+	mutex  sync.RWMutex
+}
+
+// NewFeedbackStore creates a feedback store, loading AI_FEEDBACK_FILE when
+// set and keeping at most AI_FEEDBACK_MAX_ENTRIES ratings
+func NewFeedbackStore() *FeedbackStore {
+	store := &FeedbackStore{
+		feedback:   make(map[string]*models.Feedback),
+		byResponse: make(map[string]string),
+		path:       os.Getenv("AI_FEEDBACK_FILE"),
+		maxEntries: defaultFeedbackMaxEntries,
+	}
+	if value := os.Getenv("AI_FEEDBACK_MAX_ENTRIES"); value != "" {
+		maxEntries, err := strconv.Atoi(value)
+		if err != nil || maxEntries <= 0 {
+			log.Printf("Feedback store: ignoring AI_FEEDBACK_MAX_ENTRIES %q; expected a positive integer", value)
+		} else {
+			store.maxEntries = maxEntries
+		}
+	}
+
+	if store.path != "" {
+		if err := store.load(); err != nil {
+			log.Printf("Feedback store: failed to read %s: %v", store.path, err)
+		} else if len(store.feedback) > 0 {
+			log.Printf("Feedback store: loaded %d ratings from %s", len(store.feedback), store.path)
+		}
+	}
+	return store
+}
+
+// load replays the log; later lines replace earlier ones with the same ID
+func (f *FeedbackStore) load() error {
+	file, err := os.Open(f.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	for lineNumber := 1; ; lineNumber++ {
+		line, err := reader.ReadBytes('\n')
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			var entry models.Feedback
+			if jsonErr := json.Unmarshal(line, &entry); jsonErr != nil || entry.ID == "" {
+				log.Printf("Feedback store: skipping malformed line %d of %s", lineNumber, f.path)
+			} else {
+				f.put(&entry)
+				f.logged++
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// feedbackKey identifies a user's feedback on a response
+func feedbackKey(responseID, userID string) string {
+	return responseID + "\x00" + userID
+}
+
+// put stores feedback in memory; callers hold the lock
+func (f *FeedbackStore) put(entry *models.Feedback) {
+	f.feedback[entry.ID] = entry
+	f.byResponse[feedbackKey(entry.ResponseID, entry.UserID)] = entry.ID
+}
+
+// appendLog writes feedback as a line at the end of the log, removing any
+// partial line on failure; callers hold the lock
+func (f *FeedbackStore) appendLog(entry *models.Feedback) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal feedback: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(f.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	file, err := os.OpenFile(f.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		file.Truncate(info.Size())
+		file.Close()
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	f.logged++
+	return nil
+}
+
+// compact rewrites the log with only the live feedback once superseded
+// lines outnumber it; callers hold the lock
+func (f *FeedbackStore) compact() {
+	if f.logged <= 2*len(f.feedback)+feedbackCompactSlack {
+		return
+	}
+	var data bytes.Buffer
+	encoder := json.NewEncoder(&data)
+	for _, entry := range f.sorted(FeedbackFilter{}) {
+		if err := encoder.Encode(entry); err != nil {
+			log.Printf("Feedback store: failed to compact %s: %v", f.path, err)
+			return
+		}
+	}
+	if err := writeFileAtomic(f.path, data.Bytes()); err != nil {
+		// The log is still complete, so compaction is retried on a later write
+		log.Printf("Feedback store: failed to compact %s: %v", f.path, err)
+		return
+	}
+	f.logged = len(f.feedback)
+}
+
+// sorted returns matching feedback, most recently updated first; callers
+// hold the lock
+func (f *FeedbackStore) sorted(filter FeedbackFilter) []*models.Feedback {
+	feedback := make([]*models.Feedback, 0, len(f.feedback))
+	for _, entry := range f.feedback {
+		if filter.matches(entry) {
+			feedback = append(feedback, entry)
+		}
+	}
+	sort.Slice(feedback, func(i, j int) bool {
+		if !feedback[i].UpdatedAt.Equal(feedback[j].UpdatedAt) {
+			return feedback[i].UpdatedAt.After(feedback[j].UpdatedAt)
+		}
+		return feedback[i].ID < feedback[j].ID
+	})
+	return feedback
+}
+
+// ValidateFeedback checks a feedback request and normalizes its rating and tags
+func ValidateFeedback(req *models.FeedbackRequest) error {
+	if strings.TrimSpace(req.ResponseID) == "" {
+		return fmt.Errorf("response_id is required")
+	}
+	req.Rating = strings.ToLower(strings.TrimSpace(req.Rating))
+	if req.Rating != models.FeedbackUp && req.Rating != models.FeedbackDown {
+		return fmt.Errorf("rating must be %q or %q", models.FeedbackUp, models.FeedbackDown)
+	}
+	req.Comment = strings.TrimSpace(req.Comment)
+	if len(req.Comment) > maxFeedbackCommentBytes {
+		return fmt.Errorf("comment cannot exceed %d bytes", maxFeedbackCommentBytes)
+	}
+	if len(req.Tags) > maxFeedbackTags {
+		return fmt.Errorf("at most %d tags are allowed", maxFeedbackTags)
+	}
+	seen := make(map[string]bool)
+	tags := make([]string, 0, len(req.Tags))
+	for _, tag := range req.Tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if !feedbackTagPattern.MatchString(tag) {
+			return fmt.Errorf("tag %q must be a lowercase word of at most 64 characters", tag)
+		}
+		if !seen[tag] {
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+	req.Tags = tags
+	return nil
+}
+
+// feedbackSnapshot trims a response to what feedback keeps: the raw
+// response body is dropped, large request bodies are dropped, and the prompt
+// and output are cut to maxFeedbackSnapshotBytes
+func feedbackSnapshot(record models.ResponseRecord) models.ResponseRecord {
+	record.Response = nil
+	if len(record.Request) > maxFeedbackSnapshotBytes {
+		record.Request = nil
+	}
+	record.Prompt = truncateUTF8(record.Prompt, maxFeedbackSnapshotBytes)
+	record.Output = truncateUTF8(record.Output, maxFeedbackSnapshotBytes)
+	return record
+}
+
+// truncateUTF8 cuts text to at most limit bytes without splitting a rune
+func truncateUTF8(text string, limit int) string {
+	if len(text) <= limit {
+		return text
+	}
+	for limit > 0 && !utf8.RuneStart(text[limit]) {
+		limit--
+	}
+	return text[:limit]
+}
+
+// Submit records a validated rating of a response. A user's earlier rating
+// of the same response is replaced, and created reports whether none existed.
+// New ratings fail with ErrFeedbackFull once the store holds its maximum.
+func (f *FeedbackStore) Submit(req models.FeedbackRequest, record models.ResponseRecord) (models.Feedback, bool, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	existing := f.feedback[f.byResponse[feedbackKey(record.ID, req.UserID)]]
+	if existing == nil && len(f.feedback) >= f.maxEntries {
+		return models.Feedback{}, false, ErrFeedbackFull
+	}
+
+	now := time.Now()
+	entry := &models.Feedback{
+		ID:         NewID("fb"),
+		ResponseID: record.ID,
+		Rating:     req.Rating,
+		Comment:    req.Comment,
+		Tags:       req.Tags,
+		UserID:     req.UserID,
+		Response:   feedbackSnapshot(record),
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	if existing != nil {
+		entry.ID = existing.ID
+		entry.CreatedAt = existing.CreatedAt
+	}
+
+	if f.path != "" {
+		if err := f.appendLog(entry); err != nil {
+			return models.Feedback{}, false, err
+		}
+	}
+	f.put(entry)
+	if f.path != "" {
+		f.compact()
+	}
+	return *entry, existing == nil, nil
+}
+
+// List returns a page of matching feedback and the number of matches
+func (f *FeedbackStore) List(filter FeedbackFilter, offset, limit int) ([]models.Feedback, int) {
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
+
+	matching := f.sorted(filter)
+	total := len(matching)
+	if offset > total {
+		offset = total
+	}
+	if limit > total-offset {
+		limit = total - offset
+	}
+	page := make([]models.Feedback, limit)
+	for i := range page {
+		page[i] = *matching[offset+i]
+	}
+	return page, total
+}
+
+// FeedbackFormatInfo returns the content type and file extension of an
+// export format
+func FeedbackFormatInfo(format string) (contentType, extension string, err error) {
+	switch format {
+	case FeedbackFormatJSONL, FeedbackFormatOpenAI:
+		return "application/x-ndjson", "jsonl", nil
+	case FeedbackFormatCSV:
+		return "text/csv; charset=utf-8", "csv", nil
+	}
+	return "", "", fmt.Errorf("unsupported format %q; use jsonl, csv or openai", format)
+}
+
+// Export writes matching feedback, most recent first. jsonl writes each
+// feedback as a line, csv one row per feedback, and openai writes
+// fine-tuning examples of the prompts and outputs; filter by rating "up" to
+// keep only approved outputs.
+func (f *FeedbackStore) Export(w io.Writer, format string, filter FeedbackFilter) (int, error) {
+	if _, _, err := FeedbackFormatInfo(format); err != nil {
+		return 0, err
+	}
+	f.mutex.RLock()
+	feedback := f.sorted(filter)
+	f.mutex.RUnlock()
+
+	switch format {
+	case FeedbackFormatCSV:
+		writer := csv.NewWriter(w)
+		if err := writer.Write(feedbackCSVHeader); err != nil {
+			return 0, err
+		}
+		for _, entry := range feedback {
+			record := entry.Response
+			row := []string{
+				entry.ID, entry.ResponseID, entry.Rating, entry.Comment, strings.Join(entry.Tags, ";"), entry.UserID,
+				record.Endpoint, record.Model, record.Prompt, record.Output,
+				strconv.Itoa(record.MaxTokens), strconv.FormatFloat(record.Temperature, 'g', -1, 64),
+				record.CreatedAt.Format(time.RFC3339), entry.CreatedAt.Format(time.RFC3339), entry.UpdatedAt.Format(time.RFC3339),
+			}
+			if err := writer.Write(row); err != nil {
+				return 0, err
+			}
+		}
+		writer.Flush()
+		return len(feedback), writer.Error()
+
+	default:
+		encoder := json.NewEncoder(w)
+		count := 0
+		for _, entry := range feedback {
+			var line interface{} = entry
+			if format == FeedbackFormatOpenAI {
+				// Comparisons have no single output to learn from
+				if entry.Response.Output == "" {
+					continue
+				}
+				line = map[string]interface{}{"messages": fineTuningMessages(entry.Response)}
+			}
+			if err := encoder.Encode(line); err != nil {
+				return count, err
+			}
+			count++
+		}
+		return count, nil
+	}
+}
+
+// fineTuningMessages turns a response into a chat example: the request's
+// messages when it had them, otherwise the prompt, followed by the output
+func fineTuningMessages(record models.ResponseRecord) []models.ChatMessage {
+	var request struct {
+		Messages []models.ChatMessage `json:"messages"`
+	}
+	json.Unmarshal(record.Request, &request)
+
+	messages := request.Messages
+	if len(messages) == 0 {
+		messages = []models.ChatMessage{{Role: "user", Content: record.Prompt}}
+	}
+	return append(messages, models.ChatMessage{Role: "assistant", Content: record.Output})
+}
@@ -0,0 +1,335 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/Ammar0144/ai/models"
+)
+
+// Fill-in-the-middle limits and defaults, sized for editor latency
+const (
+	maxFIMTokens          = 256
+	maxFIMStops           = 4
+	maxFIMPrefixBytes     = 64 * 1024
+	maxFIMSuffixBytes     = 64 * 1024
+	defaultFIMTokens      = 64
+	defaultFIMTemperature = 0.2
+	fimPromptOverhead     = 32
+	fimTabWidth           = 4
+)
+
+// fimSentinels are the special tokens of a fill-in-the-middle model, in
+// prefix-suffix-middle order
+type fimSentinels struct {
+	prefix string
+	suffix string
+	middle string
+}
+
+// defaultFIMSentinels are the StarCoder tokens, overridable with AI_FIM_TOKENS
+var defaultFIMSentinels = fimSentinels{"<fim_prefix>", "<fim_suffix>", "<fim_middle>"}
+
+// fimEndMarkers end a completion when the model emits them
+var fimEndMarkers = []string{"<|endoftext|>", "</s>", "<EOT>", "<file_sep>", "<fim_pad>", "<|fim_pad|>"}
+
+// fimLanguages maps language names and file extensions to a canonical name
+var fimLanguages = map[string]string{
+	"go": "go", "golang": "go", "python": "python", "py": "python",
+	"javascript": "javascript", "js": "javascript", "jsx": "javascript",
+	"typescript": "typescript", "ts": "typescript", "tsx": "typescript",
+	"java": "java", "kotlin": "kotlin", "kt": "kotlin", "swift": "swift", "scala": "scala",
+	"c": "c", "h": "c", "cpp": "cpp", "c++": "cpp", "cc": "cpp", "hpp": "cpp",
+	"csharp": "csharp", "c#": "csharp", "cs": "csharp", "rust": "rust", "rs": "rust",
+	"php": "php", "ruby": "ruby", "rb": "ruby", "shell": "shell", "bash": "shell", "sh": "shell",
+	"sql": "sql", "yaml": "yaml", "yml": "yaml", "json": "json", "html": "html", "css": "css",
+}
+
+// indentBlockLanguages delimit blocks by indentation rather than brackets
+var indentBlockLanguages = map[string]bool{"python": true, "yaml": true}
+
+// fimLanguagePattern bounds unknown language names
+var fimLanguagePattern = regexp.MustCompile(`^[a-z0-9+#._-]{1,32}$`)
+
+// parseFIMSentinels parses AI_FIM_TOKENS: prefix, suffix and middle tokens
+// separated by commas
+func parseFIMSentinels(value string) (fimSentinels, error) {
+	parts := strings.Split(value, ",")
+	if len(parts) != 3 {
+		return fimSentinels{}, fmt.Errorf("expected three comma-separated tokens, got %d", len(parts))
+	}
+	for i := range parts {
+		parts[i] = strings.TrimSpace(parts[i])
+		if parts[i] == "" {
+			return fimSentinels{}, fmt.Errorf("token %d is empty", i+1)
+		}
+	}
+	return fimSentinels{parts[0], parts[1], parts[2]}, nil
+}
+
+// FIMPlan is a validated fill-in-the-middle request with its prompt
+type FIMPlan struct {
+	prefix   string
+	suffix   string
+	stops    []string
+	markers  []string
+	language string
+
+	Prompt      string
+	Mode        string
+	MaxTokens   int
+	Temperature float64
+}
+
+// PlanFIM validates a fill-in-the-middle request and builds its prompt. With
+// the "fim" capability the prefix and suffix are wrapped in the model's
+// sentinel tokens; otherwise the suffix is shown first and the model
+// continues the prefix in a code block. The prefix keeps its last lines and
+// the suffix its first lines when they do not fit the context.
+func (s *AIService) PlanFIM(req *models.FIMRequest) (*FIMPlan, error) {
+	if strings.TrimSpace(req.Prefix) == "" && strings.TrimSpace(req.Suffix) == "" {
+		return nil, fmt.Errorf("prefix and suffix cannot both be empty")
+	}
+	if len(req.Prefix) > maxFIMPrefixBytes {
+		return nil, fmt.Errorf("prefix cannot exceed %d bytes", maxFIMPrefixBytes)
+	}
+	if len(req.Suffix) > maxFIMSuffixBytes {
+		return nil, fmt.Errorf("suffix cannot exceed %d bytes", maxFIMSuffixBytes)
+	}
+	language := strings.ToLower(strings.TrimSpace(req.Language))
+	if canonical, ok := fimLanguages[language]; ok {
+		language = canonical
+	} else if language != "" && !fimLanguagePattern.MatchString(language) {
+		return nil, fmt.Errorf("language must be a name of up to 32 characters")
+	}
+	req.Language = language
+	if req.MaxTokens == 0 {
+		req.MaxTokens = defaultFIMTokens
+	}
+	if req.MaxTokens < 1 || req.MaxTokens > maxFIMTokens {
+		return nil, fmt.Errorf("max_tokens must be between 1 and %d", maxFIMTokens)
+	}
+	if req.Temperature == 0 {
+		req.Temperature = defaultFIMTemperature
+	}
+	if len(req.Stop) > maxFIMStops {
+		return nil, fmt.Errorf("at most %d stop sequences are allowed", maxFIMStops)
+	}
+	for _, stop := range req.Stop {
+		if stop == "" {
+			return nil, fmt.Errorf("stop sequences cannot be empty")
+		}
+	}
+
+	budget := ModelContextTokens - req.MaxTokens - fimPromptOverhead
+	suffix := headToTokens(req.Suffix, budget/4)
+	prefix := tailToTokens(req.Prefix, budget-EstimateTokens(suffix))
+
+	plan := &FIMPlan{
+		prefix:      req.Prefix,
+		suffix:      req.Suffix,
+		stops:       req.Stop,
+		markers:     fimEndMarkers,
+		language:    language,
+		MaxTokens:   req.MaxTokens,
+		Temperature: req.Temperature,
+	}
+	if s.HasCapability("fim") {
+		sentinels := s.fimSentinels
+		plan.Mode = models.FIMSentinel
+		plan.Prompt = sentinels.prefix + prefix + sentinels.suffix + suffix + sentinels.middle
+		plan.markers = append([]string{sentinels.prefix, sentinels.suffix, sentinels.middle}, fimEndMarkers...)
+		return plan, nil
+	}
+
+	plan.Mode = models.FIMPrompt
+	plan.markers = append([]string{"```"}, fimEndMarkers...)
+	if strings.TrimSpace(suffix) == "" {
+		plan.Prompt = fmt.Sprintf("```%s\n%s", language, prefix)
+	} else {
+		plan.Prompt = fmt.Sprintf("The code that follows the gap is:\n```%s\n%s\n```\n\nComplete the code up to the gap:\n```%s\n%s", language, suffix, language, prefix)
+	}
+	return plan, nil
+}
+
+// headToTokens keeps the first whole lines of text that fit in maxTokens
+func headToTokens(text string, maxTokens int) string {
+	if EstimateTokens(text) <= maxTokens {
+		return text
+	}
+	head := TruncateToTokens(text, maxTokens)
+	if i := strings.LastIndexByte(head, '\n'); i >= 0 {
+		return head[:i+1]
+	}
+	return head
+}
+
+// tailToTokens keeps the last whole lines of text that fit in maxTokens,
+// or the end of the last line when even that is too long
+func tailToTokens(text string, maxTokens int) string {
+	if EstimateTokens(text) <= maxTokens {
+		return text
+	}
+	lines := strings.SplitAfter(text, "\n")
+	start, tokens := len(lines), 0
+	for start > 0 {
+		cost := EstimateTokens(lines[start-1])
+		if tokens+cost > maxTokens {
+			break
+		}
+		tokens += cost
+		start--
+	}
+	if start < len(lines) {
+		return strings.Join(lines[start:], "")
+	}
+	return TruncateTailToTokens(lines[len(lines)-1], maxTokens)
+}
+
+// Finish cuts a raw completion to the code to insert: at end-of-text
+// markers and stop sequences, before code that repeats the suffix, and at
+// syntactic boundaries. A cursor in the middle of a line completes only that
+// line. After a line that opens a block the completion stops once the block
+// is closed; otherwise it stops before a line that dedents past the cursor
+// or after two blank lines.
+func (p *FIMPlan) Finish(output string) (string, string) {
+	completion := output
+	reason := models.FIMStopLength
+	if EstimateTokens(output) < p.MaxTokens-1 {
+		// The model stopped by itself before its budget ran out
+		reason = models.FIMStopSequence
+	}
+	cut := func(at int, why string) {
+		if at >= 0 && at < len(completion) {
+			completion = completion[:at]
+			reason = why
+		}
+	}
+
+	for _, marker := range append(append([]string{}, p.markers...), p.stops...) {
+		cut(strings.Index(completion, marker), models.FIMStopSequence)
+	}
+
+	cursorLine := p.prefix[strings.LastIndexByte(p.prefix, '\n')+1:]
+	restOfLine := p.suffix
+	if i := strings.IndexByte(restOfLine, '\n'); i >= 0 {
+		restOfLine = restOfLine[:i]
+	}
+
+	if strings.TrimSpace(cursorLine) != "" && (strings.TrimSpace(restOfLine) != "" || !opensBlock(cursorLine)) {
+		// Mid-line: complete the current line only
+		cut(strings.IndexByte(completion, '\n'), models.FIMStopBoundary)
+		if rest := strings.TrimSpace(restOfLine); rest != "" {
+			trimmed := strings.TrimRight(completion, " \t")
+			if strings.HasSuffix(trimmed, rest) {
+				cut(len(trimmed)-len(rest), models.FIMStopSuffix)
+			}
+		}
+		return completion, reason
+	}
+
+	cut(p.blockEnd(completion, cursorLine), models.FIMStopBoundary)
+	cut(p.suffixOverlap(completion), models.FIMStopSuffix)
+	switch {
+	case strings.TrimSpace(restOfLine) != "" && strings.TrimSpace(completion) != "":
+		// The suffix continues on the cursor line, so the inserted lines
+		// must end with a newline
+		completion = strings.TrimRight(completion, " \t\n") + "\n"
+	case strings.TrimSpace(restOfLine) == "":
+		completion = strings.TrimRight(completion, " \t\n")
+	}
+	return completion, reason
+}
+
+// blockEnd returns the byte offset where a multi-line completion leaves the
+// block at the cursor, or -1
+func (p *FIMPlan) blockEnd(completion, cursorLine string) int {
+	base := indentWidth(cursorLine)
+	opened := opensBlock(cursorLine)
+	offset, blank := 0, 0
+	for i, line := range strings.SplitAfter(completion, "\n") {
+		start := offset
+		offset += len(line)
+		if i == 0 {
+			// The first line continues the cursor line
+			if strings.TrimSpace(cursorLine) == "" && opensBlock(line) {
+				opened = true
+			}
+			continue
+		}
+		if strings.TrimSpace(line) == "" {
+			blank++
+			if blank == 2 {
+				return start
+			}
+			continue
+		}
+		blank = 0
+		indent := indentWidth(line)
+		switch {
+		case opened && indent <= base:
+			if !indentBlockLanguages[p.language] && closesBlock(line) {
+				return offset
+			}
+			return start
+		case !opened && indent < base:
+			return start
+		}
+	}
+	return -1
+}
+
+// suffixOverlap returns the byte offset of the first completion line that
+// repeats the first non-blank line of the suffix, or -1
+func (p *FIMPlan) suffixOverlap(completion string) int {
+	var first string
+	for _, line := range strings.Split(p.suffix, "\n") {
+		if first = strings.TrimSpace(line); first != "" {
+			break
+		}
+	}
+	if first == "" {
+		return -1
+	}
+	offset := 0
+	for i, line := range strings.SplitAfter(completion, "\n") {
+		if i > 0 && strings.TrimSpace(line) == first {
+			return offset
+		}
+		offset += len(line)
+	}
+	return -1
+}
+
+// opensBlock reports whether a line ends with a block or bracket opener
+func opensBlock(line string) bool {
+	trimmed := strings.TrimRight(line, " \t\r\n")
+	return strings.HasSuffix(trimmed, "{") || strings.HasSuffix(trimmed, ":") ||
+		strings.HasSuffix(trimmed, "(") || strings.HasSuffix(trimmed, "[") ||
+		strings.HasSuffix(trimmed, " do") || strings.HasSuffix(trimmed, " then")
+}
+
+// closesBlock reports whether a line starts with a closing bracket or keyword
+func closesBlock(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	return strings.HasPrefix(trimmed, "}") || strings.HasPrefix(trimmed, ")") ||
+		strings.HasPrefix(trimmed, "]") || trimmed == "end" || strings.HasPrefix(trimmed, "end ") ||
+		trimmed == "fi" || trimmed == "done"
+}
+
+// indentWidth measures leading whitespace, counting a tab as fimTabWidth
+func indentWidth(line string) int {
+	width := 0
+	for _, r := range line {
+		switch r {
+		case ' ':
+			width++
+		case '\t':
+			width += fimTabWidth
+		default:
+			return width
+		}
+	}
+	return width
+}
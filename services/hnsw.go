@@ -0,0 +1,272 @@
+package services
+
+import (
+	"container/heap"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// HNSW parameters; see Malkov & Yashunin, "Efficient and robust approximate
+// nearest neighbor search using Hierarchical Navigable Small World graphs"
+const (
+	hnswM              = 16
+	hnswMaxLevel0      = 2 * hnswM
+	hnswEfConstruction = 200
+	hnswEfSearch       = 64
+)
+
+// hnswIndex is a Hierarchical Navigable Small World graph over vectors.
+// Removed nodes stay in the graph to keep it connected but are never
+// returned. It is not safe for concurrent writes; the owning collection
+// holds a lock.
+type hnswIndex struct {
+	distance  func(a, b []float64) float64
+	nodes     []*hnswNode
+	entry     int
+	maxLevel  int
+	removed   int
+	levelMult float64
+	rng       *rand.Rand
+}
+
+type hnswNode struct {
+	vector    []float64
+	neighbors [][]int
+	removed   bool
+}
+
+// hnswCandidate is a node with its distance to the query
+type hnswCandidate struct {
+	node     int
+	distance float64
+}
+
+func newHNSWIndex(distance func(a, b []float64) float64) *hnswIndex {
+	return &hnswIndex{
+		distance:  distance,
+		entry:     -1,
+		levelMult: 1 / math.Log(hnswM),
+		// Fixed seed keeps graphs reproducible across rebuilds of a snapshot
+		rng: rand.New(rand.NewSource(42)),
+	}
+}
+
+// live returns the number of nodes that have not been removed
+func (h *hnswIndex) live() int {
+	return len(h.nodes) - h.removed
+}
+
+// insert adds a vector and returns its node ID
+func (h *hnswIndex) insert(vector []float64) int {
+	level := int(math.Floor(-math.Log(1-h.rng.Float64()) * h.levelMult))
+	id := len(h.nodes)
+	node := &hnswNode{vector: vector, neighbors: make([][]int, level+1)}
+	h.nodes = append(h.nodes, node)
+
+	if h.entry < 0 {
+		h.entry = id
+		h.maxLevel = level
+		return id
+	}
+
+	entry := h.entry
+	for l := h.maxLevel; l > level; l-- {
+		entry = h.greedyClosest(vector, entry, l)
+	}
+
+	entries := []int{entry}
+	for l := minInt(level, h.maxLevel); l >= 0; l-- {
+		candidates := h.searchLayer(vector, entries, hnswEfConstruction, l)
+		maxNeighbors := hnswM
+		if l == 0 {
+			maxNeighbors = hnswMaxLevel0
+		}
+		node.neighbors[l] = h.selectNeighbors(candidates, hnswM)
+		for _, neighbor := range node.neighbors[l] {
+			links := append(h.nodes[neighbor].neighbors[l], id)
+			if len(links) > maxNeighbors {
+				pruned := make([]hnswCandidate, len(links))
+				for i, link := range links {
+					pruned[i] = hnswCandidate{link, h.distance(h.nodes[neighbor].vector, h.nodes[link].vector)}
+				}
+				sort.Slice(pruned, func(i, j int) bool { return pruned[i].distance < pruned[j].distance })
+				links = h.selectNeighbors(pruned, maxNeighbors)
+			}
+			h.nodes[neighbor].neighbors[l] = links
+		}
+		entries = entries[:0]
+		for _, candidate := range candidates {
+			entries = append(entries, candidate.node)
+		}
+	}
+
+	if level > h.maxLevel {
+		h.entry = id
+		h.maxLevel = level
+	}
+	return id
+}
+
+// remove marks a node as removed
+func (h *hnswIndex) remove(id int) {
+	if id >= 0 && id < len(h.nodes) && !h.nodes[id].removed {
+		h.nodes[id].removed = true
+		h.removed++
+	}
+}
+
+// search returns up to k nodes closest to the query, nearest first, among
+// those accepted by the filter. With a selective filter fewer than k may be
+// found; callers fall back to an exact scan.
+func (h *hnswIndex) search(query []float64, k, ef int, accept func(int) bool) []hnswCandidate {
+	if h.entry < 0 || k <= 0 {
+		return nil
+	}
+	if ef < k {
+		ef = k
+	}
+
+	entry := h.entry
+	for l := h.maxLevel; l > 0; l-- {
+		entry = h.greedyClosest(query, entry, l)
+	}
+
+	var results []hnswCandidate
+	for _, candidate := range h.searchLayer(query, []int{entry}, ef, 0) {
+		if h.nodes[candidate.node].removed || (accept != nil && !accept(candidate.node)) {
+			continue
+		}
+		results = append(results, candidate)
+		if len(results) == k {
+			break
+		}
+	}
+	return results
+}
+
+// greedyClosest walks one layer towards the query from an entry point
+func (h *hnswIndex) greedyClosest(query []float64, entry, level int) int {
+	best := entry
+	bestDistance := h.distance(query, h.nodes[entry].vector)
+	for changed := true; changed; {
+		changed = false
+		for _, neighbor := range h.nodes[best].neighbors[level] {
+			if d := h.distance(query, h.nodes[neighbor].vector); d < bestDistance {
+				best, bestDistance, changed = neighbor, d, true
+			}
+		}
+	}
+	return best
+}
+
+// searchLayer is a best-first search of one layer keeping the ef closest
+// nodes found, returned nearest first
+func (h *hnswIndex) searchLayer(query []float64, entries []int, ef, level int) []hnswCandidate {
+	visited := make(map[int]bool, ef*4)
+	candidates := &candidateHeap{}
+	results := &candidateHeap{max: true}
+	for _, entry := range entries {
+		if visited[entry] {
+			continue
+		}
+		visited[entry] = true
+		c := hnswCandidate{entry, h.distance(query, h.nodes[entry].vector)}
+		heap.Push(candidates, c)
+		heap.Push(results, c)
+	}
+	for results.Len() > ef {
+		heap.Pop(results)
+	}
+
+	for candidates.Len() > 0 {
+		current := heap.Pop(candidates).(hnswCandidate)
+		if results.Len() >= ef && current.distance > results.items[0].distance {
+			break
+		}
+		node := h.nodes[current.node]
+		if level >= len(node.neighbors) {
+			continue
+		}
+		for _, neighbor := range node.neighbors[level] {
+			if visited[neighbor] {
+				continue
+			}
+			visited[neighbor] = true
+			d := h.distance(query, h.nodes[neighbor].vector)
+			if results.Len() < ef || d < results.items[0].distance {
+				c := hnswCandidate{neighbor, d}
+				heap.Push(candidates, c)
+				heap.Push(results, c)
+				if results.Len() > ef {
+					heap.Pop(results)
+				}
+			}
+		}
+	}
+
+	found := results.items
+	sort.Slice(found, func(i, j int) bool { return found[i].distance < found[j].distance })
+	return found
+}
+
+// selectNeighbors applies the neighbor selection heuristic to candidates
+// sorted nearest first: a candidate is kept only if it is closer to the base
+// than to every neighbor already kept, which preserves links across clusters
+func (h *hnswIndex) selectNeighbors(candidates []hnswCandidate, max int) []int {
+	selected := make([]int, 0, max)
+	var skipped []int
+	for _, candidate := range candidates {
+		if len(selected) >= max {
+			break
+		}
+		keep := true
+		for _, chosen := range selected {
+			if h.distance(h.nodes[candidate.node].vector, h.nodes[chosen].vector) < candidate.distance {
+				keep = false
+				break
+			}
+		}
+		if keep {
+			selected = append(selected, candidate.node)
+		} else {
+			skipped = append(skipped, candidate.node)
+		}
+	}
+	// Fill remaining slots with the closest discarded candidates
+	for _, node := range skipped {
+		if len(selected) >= max {
+			break
+		}
+		selected = append(selected, node)
+	}
+	return selected
+}
+
+// candidateHeap is a min-heap by distance, or a max-heap when max is set
+type candidateHeap struct {
+	items []hnswCandidate
+	max   bool
+}
+
+func (c *candidateHeap) Len() int { return len(c.items) }
+func (c *candidateHeap) Less(i, j int) bool {
+	if c.max {
+		return c.items[i].distance > c.items[j].distance
+	}
+	return c.items[i].distance < c.items[j].distance
+}
+func (c *candidateHeap) Swap(i, j int)      { c.items[i], c.items[j] = c.items[j], c.items[i] }
+func (c *candidateHeap) Push(x interface{}) { c.items = append(c.items, x.(hnswCandidate)) }
+func (c *candidateHeap) Pop() interface{} {
+	last := c.items[len(c.items)-1]
+	c.items = c.items[:len(c.items)-1]
+	return last
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
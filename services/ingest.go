@@ -0,0 +1,588 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/Ammar0144/ai/models"
+	"golang.org/x/net/html"
+)
+
+// Upper bound on the text extracted from a single file, in bytes
+const maxExtractedText = 8 << 20
+
+// ErrUnsupportedFormat is returned for files whose format cannot be extracted
+var ErrUnsupportedFormat = errors.New("unsupported document format")
+
+// ExtractedDocument is the normalized text of a file
+type ExtractedDocument struct {
+	Format   string
+	Title    string
+	Text     string
+	Pages    int
+	Warnings []string
+}
+
+// formatAliases maps file extensions and format names to formats
+var formatAliases = map[string]string{
+	"pdf":      models.FormatPDF,
+	"html":     models.FormatHTML,
+	"htm":      models.FormatHTML,
+	"xhtml":    models.FormatHTML,
+	"md":       models.FormatMarkdown,
+	"markdown": models.FormatMarkdown,
+	"docx":     models.FormatDOCX,
+	"txt":      models.FormatText,
+	"text":     models.FormatText,
+}
+
+// ParseFormat resolves a format name or file extension, returning "" when
+// it is not supported
+func ParseFormat(name string) string {
+	return formatAliases[strings.TrimPrefix(strings.ToLower(strings.TrimSpace(name)), ".")]
+}
+
+// DetectFormat picks a file's format from its content, falling back to its
+// content type and extension, then to plain text for valid UTF-8
+func DetectFormat(filename, contentType string, data []byte) string {
+	head := bytes.TrimLeft(data[:minInt(len(data), 1024)], "\uFEFF\t\n\r ")
+	switch {
+	case bytes.HasPrefix(head, []byte("%PDF-")):
+		return models.FormatPDF
+	case bytes.HasPrefix(data, []byte("PK\x03\x04")):
+		if isDOCX(data) {
+			return models.FormatDOCX
+		}
+		return ""
+	}
+
+	mediaType := strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+	switch mediaType {
+	case "application/pdf":
+		return models.FormatPDF
+	case "text/html", "application/xhtml+xml":
+		return models.FormatHTML
+	case "text/markdown", "text/x-markdown":
+		return models.FormatMarkdown
+	}
+	if format := ParseFormat(path.Ext(filename)); format != "" {
+		return format
+	}
+
+	lower := strings.ToLower(string(head[:minInt(len(head), 64)]))
+	if strings.HasPrefix(lower, "<!doctype html") || strings.HasPrefix(lower, "<html") {
+		return models.FormatHTML
+	}
+	if utf8.Valid(data) || hasUTF16BOM(data) {
+		return models.FormatText
+	}
+	return ""
+}
+
+// ExtractDocument returns the normalized text of a file in the given format
+func ExtractDocument(data []byte, format string) (*ExtractedDocument, error) {
+	doc := &ExtractedDocument{Format: format}
+	var err error
+	switch format {
+	case models.FormatPDF:
+		var pages []string
+		doc.Title, pages, err = ExtractPDFText(data)
+		for i, page := range pages {
+			pages[i] = NormalizeText(page)
+			if pages[i] == "" {
+				doc.Warnings = append(doc.Warnings, fmt.Sprintf("page %d has no extractable text", i+1))
+			}
+		}
+		doc.Pages = len(pages)
+		doc.Text = strings.Join(pages, "\n\n")
+	case models.FormatHTML:
+		doc.Title, doc.Text, err = extractHTML(data)
+	case models.FormatDOCX:
+		doc.Title, doc.Text, err = extractDOCX(data)
+	case models.FormatMarkdown:
+		doc.Title, doc.Text = extractMarkdown(decodeText(data))
+	case models.FormatText:
+		doc.Text = decodeText(data)
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	doc.Title = NormalizeText(doc.Title)
+	doc.Text = NormalizeText(doc.Text)
+	if len(doc.Text) > maxExtractedText {
+		doc.Text = strings.ToValidUTF8(doc.Text[:maxExtractedText], "")
+		doc.Warnings = append(doc.Warnings, fmt.Sprintf("text truncated to %d bytes", maxExtractedText))
+	}
+	if doc.Text == "" {
+		doc.Warnings = append(doc.Warnings, "no text could be extracted")
+	}
+	return doc, nil
+}
+
+// ChunkDocument splits extracted text into token-bounded chunks that overlap
+// by up to overlap tokens
+func ChunkDocument(text string, tokens, overlap int) []models.IngestChunk {
+	pieces := ChunkText(text, tokens, overlap)
+	chunks := make([]models.IngestChunk, len(pieces))
+	for i, piece := range pieces {
+		chunks[i] = models.IngestChunk{
+			Index:  i,
+			Text:   piece.Text,
+			Start:  piece.Start,
+			End:    piece.End,
+			Tokens: piece.Tokens,
+		}
+	}
+	return chunks
+}
+
+// decodeText converts plain text to UTF-8: UTF-16 with a byte order mark is
+// decoded, and invalid UTF-8 is treated as Latin-1
+func decodeText(data []byte) string {
+	if hasUTF16BOM(data) {
+		bigEndian := data[0] == 0xFE
+		units := make([]uint16, (len(data)-2)/2)
+		for i := range units {
+			a, b := uint16(data[2+2*i]), uint16(data[3+2*i])
+			if bigEndian {
+				units[i] = a<<8 | b
+			} else {
+				units[i] = b<<8 | a
+			}
+		}
+		return string(utf16.Decode(units))
+	}
+	if utf8.Valid(data) {
+		return string(data)
+	}
+	runes := make([]rune, len(data))
+	for i, c := range data {
+		runes[i] = rune(c)
+	}
+	return string(runes)
+}
+
+func hasUTF16BOM(data []byte) bool {
+	return len(data) >= 2 && ((data[0] == 0xFE && data[1] == 0xFF) || (data[0] == 0xFF && data[1] == 0xFE))
+}
+
+// Unicode normalization tables. Without a full normalization library these
+// cover what extracted text commonly contains: compatibility ligatures and
+// spaces, invisible format characters and decomposed Latin accents.
+var (
+	compatibilityRunes = map[rune]string{
+		'\u00A0': " ", '\u2000': " ", '\u2001': " ", '\u2002': " ", '\u2003': " ",
+		'\u2004': " ", '\u2005': " ", '\u2006': " ", '\u2007': " ", '\u2008': " ",
+		'\u2009': " ", '\u200A': " ", '\u202F': " ", '\u205F': " ", '\u3000': " ",
+		'\u2028': "\n", '\u2029': "\n\n", '\u000B': "\n", '\u000C': "\n\n", '\u0085': "\n",
+		'\uFB00': "ff", '\uFB01': "fi", '\uFB02': "fl", '\uFB03': "ffi", '\uFB04': "ffl",
+		'\uFB05': "st", '\uFB06': "st", '\u2024': ".", '\u2025': "..", '\u2026': "...",
+		'\u2212': "-", '\u2010': "-", '\u2011': "-",
+	}
+	removedRunes = map[rune]bool{
+		'\u00AD': true, '\u200B': true, '\u200C': true, '\u200D': true, '\u2060': true,
+		'\uFEFF': true, '\uFFFD': true,
+	}
+	composedRunes = buildComposedRunes()
+)
+
+// buildComposedRunes maps a base letter and combining mark to the
+// precomposed character
+func buildComposedRunes() map[[2]rune]rune {
+	table := []struct {
+		mark     rune
+		bases    string
+		composed string
+	}{
+		{'\u0300', "AEIOUaeiou", "ÀÈÌÒÙàèìòù"},
+		{'\u0301', "AEIOUYaeiouyCcNnSsZz", "ÁÉÍÓÚÝáéíóúýĆćŃńŚśŹź"},
+		{'\u0302', "AEIOUaeiou", "ÂÊÎÔÛâêîôû"},
+		{'\u0303', "ANOano", "ÃÑÕãñõ"},
+		{'\u0308', "AEIOUaeiouy", "ÄËÏÖÜäëïöüÿ"},
+		{'\u030A', "AaUu", "ÅåŮů"},
+		{'\u030C', "CcSsZzEeRrNn", "ČčŠšŽžĚěŘřŇň"},
+		{'\u0327', "CcSs", "ÇçŞş"},
+	}
+	composed := make(map[[2]rune]rune)
+	for _, entry := range table {
+		results := []rune(entry.composed)
+		for i, base := range []rune(entry.bases) {
+			composed[[2]rune{base, entry.mark}] = results[i]
+		}
+	}
+	return composed
+}
+
+var (
+	hyphenatedBreak = regexp.MustCompile(`(\p{Ll})-\n(\p{Ll})`)
+	blankLines      = regexp.MustCompile(`\n{3,}`)
+)
+
+// NormalizeText cleans extracted text: line endings and Unicode spaces are
+// unified, ligatures expanded, invisible and control characters dropped,
+// common accents composed, words hyphenated across lines rejoined, runs of
+// spaces collapsed and blank lines limited to one between paragraphs
+func NormalizeText(text string) string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\r", "\n")
+
+	var b strings.Builder
+	b.Grow(len(text))
+	var previous rune = -1
+	for _, r := range text {
+		if replacement, ok := compatibilityRunes[r]; ok {
+			if previous >= 0 {
+				b.WriteRune(previous)
+			}
+			b.WriteString(replacement)
+			previous = -1
+			continue
+		}
+		if removedRunes[r] || (unicode.IsControl(r) && r != '\n' && r != '\t') {
+			continue
+		}
+		if previous >= 0 {
+			if composed, ok := composedRunes[[2]rune{previous, r}]; ok {
+				previous = composed
+				continue
+			}
+			b.WriteRune(previous)
+		}
+		previous = r
+	}
+	if previous >= 0 {
+		b.WriteRune(previous)
+	}
+
+	lines := strings.Split(b.String(), "\n")
+	for i, line := range lines {
+		lines[i] = strings.Join(strings.FieldsFunc(line, func(r rune) bool {
+			return r == ' ' || r == '\t'
+		}), " ")
+	}
+	text = strings.Join(lines, "\n")
+	text = hyphenatedBreak.ReplaceAllString(text, "$1$2")
+	text = blankLines.ReplaceAllString(text, "\n\n")
+	return strings.TrimSpace(text)
+}
+
+// HTML elements whose content is not text, and elements that start a new
+// block of text
+var (
+	htmlSkipped = map[string]bool{
+		"script": true, "style": true, "noscript": true, "template": true,
+		"svg": true, "iframe": true, "head": true, "nav": true,
+	}
+	htmlBlocks = map[string]bool{
+		"p": true, "div": true, "section": true, "article": true, "main": true,
+		"header": true, "footer": true, "aside": true, "blockquote": true, "pre": true,
+		"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+		"ul": true, "ol": true, "li": true, "table": true, "tr": true, "dl": true,
+		"dt": true, "dd": true, "figure": true, "figcaption": true, "form": true, "hr": true,
+	}
+)
+
+// extractHTML returns the page title and visible text of an HTML document
+func extractHTML(data []byte) (string, string, error) {
+	root, err := html.Parse(bytes.NewReader([]byte(decodeText(data))))
+	if err != nil {
+		return "", "", fmt.Errorf("failed to parse HTML: %w", err)
+	}
+
+	var title string
+	var b strings.Builder
+	var walk func(n *html.Node, pre bool)
+	walk = func(n *html.Node, pre bool) {
+		switch n.Type {
+		case html.TextNode:
+			if pre {
+				b.WriteString(n.Data)
+			} else if words := strings.Fields(n.Data); len(words) > 0 {
+				// Keep a single space where the source had whitespace
+				// around inline text
+				if unicode.IsSpace(rune(n.Data[0])) {
+					b.WriteByte(' ')
+				}
+				b.WriteString(strings.Join(words, " "))
+				if unicode.IsSpace(rune(n.Data[len(n.Data)-1])) {
+					b.WriteByte(' ')
+				}
+			} else if n.Data != "" {
+				b.WriteByte(' ')
+			}
+			return
+		case html.ElementNode:
+			if n.Data == "title" && title == "" {
+				title = htmlNodeText(n)
+			}
+			if htmlSkipped[n.Data] {
+				for child := n.FirstChild; child != nil && title == ""; child = child.NextSibling {
+					if child.Type == html.ElementNode && child.Data == "title" {
+						title = htmlNodeText(child)
+					}
+				}
+				return
+			}
+			switch {
+			case n.Data == "br":
+				b.WriteByte('\n')
+			case n.Data == "td" || n.Data == "th":
+				b.WriteByte('\t')
+			case htmlBlocks[n.Data]:
+				b.WriteString("\n\n")
+			}
+			if n.Data == "li" {
+				b.WriteString("- ")
+			}
+			pre = pre || n.Data == "pre"
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child, pre)
+		}
+		if n.Type == html.ElementNode && htmlBlocks[n.Data] {
+			b.WriteString("\n\n")
+		}
+	}
+	walk(root, false)
+
+	// Keep the title even though head is skipped, without repeating it
+	text := b.String()
+	if title != "" && !strings.Contains(text[:minInt(len(text), 2*len(title)+64)], strings.TrimSpace(title)) {
+		text = title + "\n\n" + text
+	}
+	return title, text, nil
+}
+
+// htmlNodeText concatenates the text nodes below n
+func htmlNodeText(n *html.Node) string {
+	var b strings.Builder
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type == html.TextNode {
+			b.WriteString(child.Data)
+		} else {
+			b.WriteString(htmlNodeText(child))
+		}
+	}
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// isDOCX reports whether a zip archive holds a Word document
+func isDOCX(data []byte) bool {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return false
+	}
+	for _, file := range archive.File {
+		if file.Name == "word/document.xml" {
+			return true
+		}
+	}
+	return false
+}
+
+// extractDOCX returns the title and paragraph text of a Word document
+func extractDOCX(data []byte) (string, string, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", "", fmt.Errorf("failed to open DOCX archive: %w", err)
+	}
+	files := make(map[string]*zip.File)
+	for _, file := range archive.File {
+		files[file.Name] = file
+	}
+	document, ok := files["word/document.xml"]
+	if !ok {
+		return "", "", fmt.Errorf("DOCX archive has no word/document.xml")
+	}
+
+	text, err := docxText(document)
+	if err != nil {
+		return "", "", err
+	}
+	var title string
+	if core, ok := files["docProps/core.xml"]; ok {
+		title = docxTitle(core)
+	}
+	return title, text, nil
+}
+
+// docxText reads runs of text from WordprocessingML, ending paragraphs and
+// table rows with line breaks and separating cells with tabs
+func docxText(file *zip.File) (string, error) {
+	reader, err := file.Open()
+	if err != nil {
+		return "", fmt.Errorf("failed to read DOCX document: %w", err)
+	}
+	defer reader.Close()
+
+	decoder := xml.NewDecoder(io.LimitReader(reader, 4*maxExtractedText))
+	var b strings.Builder
+	inText := false
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to parse DOCX document: %w", err)
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "t":
+				inText = true
+			case "tab":
+				b.WriteByte('\t')
+			case "br", "cr":
+				b.WriteByte('\n')
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				b.WriteString("\n\n")
+			case "tc":
+				b.WriteByte('\t')
+			case "tr":
+				b.WriteByte('\n')
+			}
+		case xml.CharData:
+			if inText {
+				b.Write(t)
+			}
+		}
+	}
+	return b.String(), nil
+}
+
+// docxTitle returns dc:title from the document properties, if set
+func docxTitle(file *zip.File) string {
+	reader, err := file.Open()
+	if err != nil {
+		return ""
+	}
+	defer reader.Close()
+
+	var props struct {
+		Title string `xml:"title"`
+	}
+	if err := xml.NewDecoder(io.LimitReader(reader, 1<<20)).Decode(&props); err != nil {
+		return ""
+	}
+	return props.Title
+}
+
+var (
+	markdownFence     = regexp.MustCompile("^\\s*(```|~~~)")
+	markdownHeading   = regexp.MustCompile(`^\s{0,3}#{1,6}\s+(.*?)\s*#*\s*$`)
+	markdownSetext    = regexp.MustCompile(`^\s{0,3}(=+|-+)\s*$`)
+	markdownRule      = regexp.MustCompile(`^\s{0,3}([-*_]\s*){3,}$`)
+	markdownQuote     = regexp.MustCompile(`^\s{0,3}>\s?`)
+	markdownListItem  = regexp.MustCompile(`^(\s*)([-*+]|\d+[.)])\s+(\[[ xX]\]\s+)?`)
+	markdownTableRule = regexp.MustCompile(`^\s*\|?\s*:?-+:?\s*(\|\s*:?-+:?\s*)*\|?\s*$`)
+	markdownImage     = regexp.MustCompile(`!\[([^\]]*)\]\([^)]*\)`)
+	markdownLink      = regexp.MustCompile(`\[([^\]]+)\](\([^)]*\)|\[[^\]]*\])`)
+	markdownRefDef    = regexp.MustCompile(`^\s{0,3}\[[^\]]+\]:\s+\S+.*$`)
+	markdownCode      = regexp.MustCompile("`+([^`]+)`+")
+	markdownEmphasis  = regexp.MustCompile(`(\*\*|\*|~~)(\S(?:.*?\S)?)(\*\*|\*|~~)`)
+	markdownUnderline = regexp.MustCompile(`(^|\W)(__|_)(\S(?:.*?\S)?)(__|_)(\W|$)`)
+	markdownAutolink  = regexp.MustCompile(`<((?:https?|mailto):[^>]+)>`)
+	markdownTag       = regexp.MustCompile(`</?[a-zA-Z][^>]*>`)
+)
+
+// extractMarkdown strips Markdown syntax, keeping the text of headings,
+// links, images (alt text), code and list items. The first heading is the
+// title.
+func extractMarkdown(text string) (string, string) {
+	var title string
+	var out []string
+	inFence := false
+	for _, line := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		if markdownFence.MatchString(line) {
+			inFence = !inFence
+			out = append(out, "")
+			continue
+		}
+		if inFence {
+			out = append(out, line)
+			continue
+		}
+		if markdownRefDef.MatchString(line) || markdownRule.MatchString(line) || markdownTableRule.MatchString(line) && strings.Contains(line, "-") && strings.Contains(line, "|") {
+			continue
+		}
+		if markdownSetext.MatchString(line) && len(out) > 0 && strings.TrimSpace(out[len(out)-1]) != "" {
+			if title == "" && strings.HasPrefix(strings.TrimSpace(line), "=") {
+				title = strings.TrimSpace(out[len(out)-1])
+			}
+			out = append(out, "")
+			continue
+		}
+		if match := markdownHeading.FindStringSubmatch(line); match != nil {
+			heading := markdownInline(match[1])
+			if title == "" {
+				title = heading
+			}
+			out = append(out, "", heading, "")
+			continue
+		}
+		for markdownQuote.MatchString(line) {
+			line = markdownQuote.ReplaceAllString(line, "")
+		}
+		line = markdownListItem.ReplaceAllString(line, "$1- ")
+		if strings.Count(line, "|") >= 2 {
+			cells := strings.Split(strings.Trim(strings.TrimSpace(line), "|"), "|")
+			for i, cell := range cells {
+				cells[i] = strings.TrimSpace(cell)
+			}
+			line = strings.Join(cells, "\t")
+		}
+		out = append(out, markdownInline(line))
+	}
+	return title, strings.Join(out, "\n")
+}
+
+// markdownInline strips inline Markdown syntax from a line
+func markdownInline(line string) string {
+	line = markdownImage.ReplaceAllString(line, "$1")
+	line = markdownLink.ReplaceAllString(line, "$1")
+	line = markdownAutolink.ReplaceAllString(line, "$1")
+	line = markdownCode.ReplaceAllString(line, "$1")
+	line = markdownTag.ReplaceAllString(line, "")
+	for i := 0; i < 3; i++ {
+		replaced := markdownEmphasis.ReplaceAllStringFunc(line, func(match string) string {
+			parts := markdownEmphasis.FindStringSubmatch(match)
+			if parts[1] != parts[3] {
+				return match
+			}
+			return parts[2]
+		})
+		// Underscores only mark emphasis at word boundaries, not in snake_case
+		replaced = markdownUnderline.ReplaceAllStringFunc(replaced, func(match string) string {
+			parts := markdownUnderline.FindStringSubmatch(match)
+			if parts[2] != parts[4] {
+				return match
+			}
+			return parts[1] + parts[3] + parts[5]
+		})
+		if replaced == line {
+			break
+		}
+		line = replaced
+	}
+	line = strings.NewReplacer(`\*`, "*", `\_`, "_", `\#`, "#", `\[`, "[", `\]`, "]", "\\`", "`").Replace(line)
+	return line
+}
@@ -0,0 +1,178 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/Ammar0144/ai/models"
+)
+
+// Memory settings
+const (
+	MemoryModeSummary            = "summary"
+	DefaultMemoryThresholdTokens = 600
+	DefaultMemoryKeepRecent      = 4
+	summaryChunkTokens           = 500
+	summaryMaxTokens             = 120
+	summaryMessagePrefix         = "Summary of the earlier conversation: "
+)
+
+// summaryInstructions tells the model how to compress older turns
+const summaryInstructions = "Summarize the conversation below in a few short sentences. Keep names, facts, decisions and open questions. Do not add anything that was not said."
+
+// MemoryResult is a chat history with older turns folded into a summary
+type MemoryResult struct {
+	Messages []models.ChatMessage
+	// Summary is set when older turns were replaced by a summary
+	Summary            *models.MemorySummary
+	SummarizedMessages int
+	Refreshed          bool
+}
+
+// ApplySummaryMemory replaces older turns with a compact system summary once
+// the history exceeds the token threshold. Leading system messages and the
+// most recent turns are kept verbatim. nodeIDs identifies the stored messages
+// at the start of messages; when cached covers one of them, only the turns
+// after it are summarized and folded into the cached summary.
+func (s *AIService) ApplySummaryMemory(messages []models.ChatMessage, nodeIDs []string, cached *models.MemorySummary, opts models.MemoryOptions) (*MemoryResult, error) {
+	threshold := opts.ThresholdTokens
+	if threshold <= 0 {
+		threshold = DefaultMemoryThresholdTokens
+	}
+	keepRecent := opts.KeepRecent
+	if keepRecent <= 0 {
+		keepRecent = DefaultMemoryKeepRecent
+	}
+
+	result := &MemoryResult{Messages: messages}
+	if EstimateMessagesTokens(messages) <= threshold {
+		return result, nil
+	}
+
+	pinned := 0
+	for pinned < len(messages) && messages[pinned].Role == "system" {
+		pinned++
+	}
+
+	cut := len(messages) - keepRecent
+	// Never separate tool results from the assistant message that requested them
+	for cut > pinned && messages[cut].Role == "tool" {
+		cut--
+	}
+	if cut <= pinned {
+		return result, nil
+	}
+
+	start := pinned
+	summary := ""
+	if cached != nil && cached.CoveredNodeID != "" {
+		for k, id := range nodeIDs {
+			if id != cached.CoveredNodeID || k < pinned {
+				continue
+			}
+			summary = cached.Content
+			if k+1 >= cut {
+				cut = k + 1
+			}
+			start = k + 1
+			break
+		}
+	}
+
+	if start < cut {
+		log.Printf("ApplySummaryMemory: summarizing %d older messages", cut-start)
+		folded, err := s.foldSummary(summary, messages[start:cut])
+		if err != nil {
+			return nil, err
+		}
+		summary = folded
+		result.Refreshed = true
+	}
+
+	covered := ""
+	if cut-1 < len(nodeIDs) {
+		covered = nodeIDs[cut-1]
+	}
+	result.Summary = &models.MemorySummary{
+		Content:         summary,
+		CoveredNodeID:   covered,
+		CoveredMessages: cut - pinned,
+		UpdatedAt:       time.Now(),
+	}
+	if !result.Refreshed && cached != nil {
+		result.Summary.UpdatedAt = cached.UpdatedAt
+	}
+	result.SummarizedMessages = cut - pinned
+
+	condensed := make([]models.ChatMessage, 0, pinned+1+len(messages)-cut)
+	condensed = append(condensed, messages[:pinned]...)
+	condensed = append(condensed, models.ChatMessage{Role: "system", Content: summaryMessagePrefix + summary})
+	condensed = append(condensed, messages[cut:]...)
+	result.Messages = condensed
+	return result, nil
+}
+
+// foldSummary folds messages into an existing summary, one transcript chunk
+// at a time so each summarization call fits in the model context
+func (s *AIService) foldSummary(summary string, messages []models.ChatMessage) (string, error) {
+	var chunk []string
+	chunkTokens := 0
+
+	flush := func() error {
+		if len(chunk) == 0 {
+			return nil
+		}
+		var prompt strings.Builder
+		if summary != "" {
+			prompt.WriteString("Existing summary: ")
+			prompt.WriteString(summary)
+			prompt.WriteString("\n\n")
+		}
+		prompt.WriteString("Conversation:\n")
+		prompt.WriteString(strings.Join(chunk, "\n"))
+
+		updated, err := s.GetChatCompletion([]models.ChatMessage{
+			{Role: "system", Content: summaryInstructions},
+			{Role: "user", Content: prompt.String()},
+		}, summaryMaxTokens, 0.3)
+		if err != nil {
+			return fmt.Errorf("summarization failed: %w", err)
+		}
+		summary = strings.TrimSpace(updated)
+		chunk = nil
+		chunkTokens = 0
+		return nil
+	}
+
+	for _, msg := range messages {
+		line := transcriptLine(msg)
+		tokens := EstimateTokens(line)
+		if chunkTokens+tokens > summaryChunkTokens {
+			if err := flush(); err != nil {
+				return "", err
+			}
+		}
+		// A single oversized message is truncated rather than overflowing the context
+		if tokens > summaryChunkTokens {
+			line = TruncateToTokens(line, summaryChunkTokens)
+			tokens = summaryChunkTokens
+		}
+		chunk = append(chunk, line)
+		chunkTokens += tokens
+	}
+	if err := flush(); err != nil {
+		return "", err
+	}
+	return summary, nil
+}
+
+// transcriptLine renders a message as "role: content"
+func transcriptLine(msg models.ChatMessage) string {
+	content := msg.Content
+	for _, call := range msg.ToolCalls {
+		content += fmt.Sprintf(" [called %s(%s)]", call.Function.Name, call.Function.Arguments)
+	}
+	return msg.Role + ": " + strings.TrimSpace(content)
+}
@@ -0,0 +1,1340 @@
+package services
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/ascii85"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// This file is a minimal PDF reader for text extraction. It parses indirect
+// objects and object streams, walks the page tree and interprets the text
+// operators of each page's content streams, decoding text through the fonts'
+// ToUnicode CMaps or simple encodings. Layout is approximated: moves to a new
+// baseline become line breaks and wide gaps become spaces.
+
+// Limits guarding against hostile input. maxPDFScanBytes bounds the bytes
+// lexed and decoded per file, which shared streams and nested forms could
+// otherwise multiply without end.
+const (
+	maxPDFDepth         = 32
+	maxPDFDecodedStream = 64 << 20
+	maxPDFFormRecursion = 5
+	maxPDFScanBytes     = 64 << 20
+	maxPDFFontCodes     = 1 << 16
+)
+
+// Layout heuristics, as fractions of the font size: a gap wider than
+// pdfWordGap between strings on a line is a space, and a baseline move
+// larger than pdfLineGap or pdfParagraphGap starts a line or paragraph.
+// Fonts without widths assume pdfDefaultGlyphWidth thousandths of an em.
+const (
+	pdfWordGap           = 0.15
+	pdfLineGap           = 0.5
+	pdfParagraphGap      = 2.0
+	pdfDefaultGlyphWidth = 500
+)
+
+// ErrPDFEncrypted is returned for encrypted PDFs, which are not supported
+var ErrPDFEncrypted = errors.New("encrypted PDFs are not supported")
+
+// ErrPDFTooComplex is returned when extraction exceeds maxPDFScanBytes
+var ErrPDFTooComplex = errors.New("PDF is too complex to extract")
+
+// pdfBudget counts down the bytes a file may still lex and decode; a nil
+// budget is unlimited
+type pdfBudget struct {
+	remaining int
+}
+
+// spend reports whether n more bytes fit in the budget
+func (b *pdfBudget) spend(n int) bool {
+	if b == nil {
+		return true
+	}
+	b.remaining -= n
+	return b.remaining >= 0
+}
+
+func (b *pdfBudget) exhausted() bool {
+	return b != nil && b.remaining < 0
+}
+
+type pdfName string
+
+type pdfRef struct {
+	num int
+	gen int
+}
+
+type pdfDict map[string]interface{}
+
+type pdfStream struct {
+	dict pdfDict
+	data []byte
+}
+
+// pdfKeyword is a bare word such as an operator, obj, stream or R
+type pdfKeyword string
+
+// Token delimiters
+type pdfDelimiter byte
+
+const (
+	pdfArrayStart pdfDelimiter = '['
+	pdfArrayEnd   pdfDelimiter = ']'
+	pdfDictStart  pdfDelimiter = '<'
+	pdfDictEnd    pdfDelimiter = '>'
+)
+
+var pdfObjectHeader = regexp.MustCompile(`(\d+)\s+(\d+)\s+obj\b`)
+var pdfTrailer = regexp.MustCompile(`trailer\s*<<`)
+var pdfEndObj = []byte("endobj")
+
+// pdfLexer splits PDF syntax into tokens: float64, string (literal or hex
+// string bytes), pdfName, pdfKeyword, bool, nil and delimiters
+type pdfLexer struct {
+	data   []byte
+	pos    int
+	budget *pdfBudget
+}
+
+func isPDFSpace(c byte) bool {
+	return c == 0 || c == '\t' || c == '\n' || c == '\f' || c == '\r' || c == ' '
+}
+
+func isPDFDelimiter(c byte) bool {
+	return strings.IndexByte("()<>[]{}/%", c) >= 0
+}
+
+func (l *pdfLexer) skipSpace() {
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		if c == '%' {
+			for l.pos < len(l.data) && l.data[l.pos] != '\n' && l.data[l.pos] != '\r' {
+				l.pos++
+			}
+			continue
+		}
+		if !isPDFSpace(c) {
+			return
+		}
+		l.pos++
+	}
+}
+
+// next returns the next token, or io.EOF; ErrPDFTooComplex once the budget
+// is spent
+func (l *pdfLexer) next() (interface{}, error) {
+	if l.budget.exhausted() {
+		return nil, ErrPDFTooComplex
+	}
+	start := l.pos
+	token, err := l.scan()
+	l.budget.spend(l.pos - start)
+	return token, err
+}
+
+func (l *pdfLexer) scan() (interface{}, error) {
+	l.skipSpace()
+	if l.pos >= len(l.data) {
+		return nil, io.EOF
+	}
+	c := l.data[l.pos]
+	switch {
+	case c == '[' || c == ']':
+		l.pos++
+		return pdfDelimiter(c), nil
+	case c == '<' && l.pos+1 < len(l.data) && l.data[l.pos+1] == '<':
+		l.pos += 2
+		return pdfDictStart, nil
+	case c == '>' && l.pos+1 < len(l.data) && l.data[l.pos+1] == '>':
+		l.pos += 2
+		return pdfDictEnd, nil
+	case c == '<':
+		return l.hexString(), nil
+	case c == '(':
+		return l.literalString(), nil
+	case c == '/':
+		return l.name(), nil
+	case c == '{' || c == '}' || c == ')' || c == '>':
+		l.pos++
+		return pdfKeyword(string(c)), nil
+	}
+
+	start := l.pos
+	for l.pos < len(l.data) && !isPDFSpace(l.data[l.pos]) && !isPDFDelimiter(l.data[l.pos]) {
+		l.pos++
+	}
+	word := string(l.data[start:l.pos])
+	switch word {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null":
+		return nil, nil
+	}
+	if n, err := strconv.ParseFloat(word, 64); err == nil && (word[0] == '-' || word[0] == '+' || word[0] == '.' || (word[0] >= '0' && word[0] <= '9')) {
+		return n, nil
+	}
+	return pdfKeyword(word), nil
+}
+
+func (l *pdfLexer) hexString() string {
+	l.pos++
+	var digits []byte
+	for l.pos < len(l.data) && l.data[l.pos] != '>' {
+		c := l.data[l.pos]
+		if (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F') {
+			digits = append(digits, c)
+		}
+		l.pos++
+	}
+	l.pos++
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	decoded, _ := hex.DecodeString(string(digits))
+	return string(decoded)
+}
+
+func (l *pdfLexer) literalString() string {
+	l.pos++
+	var out []byte
+	depth := 1
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		l.pos++
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return string(out)
+			}
+		case '\\':
+			if l.pos >= len(l.data) {
+				return string(out)
+			}
+			e := l.data[l.pos]
+			l.pos++
+			switch e {
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			case 'b':
+				c = '\b'
+			case 'f':
+				c = '\f'
+			case '\r':
+				if l.pos < len(l.data) && l.data[l.pos] == '\n' {
+					l.pos++
+				}
+				continue
+			case '\n':
+				continue
+			default:
+				if e >= '0' && e <= '7' {
+					value := int(e - '0')
+					for i := 0; i < 2 && l.pos < len(l.data) && l.data[l.pos] >= '0' && l.data[l.pos] <= '7'; i++ {
+						value = value*8 + int(l.data[l.pos]-'0')
+						l.pos++
+					}
+					c = byte(value)
+				} else {
+					c = e
+				}
+			}
+		}
+		out = append(out, c)
+	}
+	return string(out)
+}
+
+func (l *pdfLexer) name() pdfName {
+	l.pos++
+	var out []byte
+	for l.pos < len(l.data) && !isPDFSpace(l.data[l.pos]) && !isPDFDelimiter(l.data[l.pos]) {
+		c := l.data[l.pos]
+		if c == '#' && l.pos+2 < len(l.data) {
+			if decoded, err := hex.DecodeString(string(l.data[l.pos+1 : l.pos+3])); err == nil {
+				out = append(out, decoded[0])
+				l.pos += 3
+				continue
+			}
+		}
+		out = append(out, c)
+		l.pos++
+	}
+	return pdfName(out)
+}
+
+// pdfParser builds objects from tokens, with lookahead for "n g R"
+// references. ends holds the offset after each pending token, so that
+// consumed is where the last returned token ended.
+type pdfParser struct {
+	lex      *pdfLexer
+	pending  []interface{}
+	ends     []int
+	consumed int
+}
+
+func newPDFParser(data []byte, pos int, budget *pdfBudget) *pdfParser {
+	return &pdfParser{lex: &pdfLexer{data: data, pos: pos, budget: budget}, consumed: pos}
+}
+
+func (p *pdfParser) token() (interface{}, error) {
+	if len(p.pending) > 0 {
+		token := p.pending[0]
+		p.discard(1)
+		return token, nil
+	}
+	token, err := p.lex.next()
+	p.consumed = p.lex.pos
+	return token, err
+}
+
+func (p *pdfParser) peek(n int) interface{} {
+	for len(p.pending) <= n {
+		token, err := p.lex.next()
+		if err != nil {
+			return err
+		}
+		p.pending = append(p.pending, token)
+		p.ends = append(p.ends, p.lex.pos)
+	}
+	return p.pending[n]
+}
+
+// discard drops n peeked tokens
+func (p *pdfParser) discard(n int) {
+	p.consumed = p.ends[n-1]
+	p.pending, p.ends = p.pending[n:], p.ends[n:]
+}
+
+// object parses one object; operators and other keywords are returned as
+// pdfKeyword values
+func (p *pdfParser) object(depth int) (interface{}, error) {
+	if depth > maxPDFDepth {
+		return nil, fmt.Errorf("PDF objects nested too deeply")
+	}
+	token, err := p.token()
+	if err != nil {
+		return nil, err
+	}
+	switch t := token.(type) {
+	case float64:
+		// An integer followed by an integer and R is a reference
+		if gen, ok := p.peek(0).(float64); ok && t == math.Trunc(t) && gen == math.Trunc(gen) {
+			if keyword, ok := p.peek(1).(pdfKeyword); ok && keyword == "R" {
+				p.discard(2)
+				return pdfRef{int(t), int(gen)}, nil
+			}
+		}
+		return t, nil
+	case pdfDelimiter:
+		switch t {
+		case pdfArrayStart:
+			var array []interface{}
+			for {
+				if end, ok := p.peek(0).(pdfDelimiter); ok && end == pdfArrayEnd {
+					p.discard(1)
+					return array, nil
+				}
+				if _, ok := p.peek(0).(error); ok {
+					return array, nil
+				}
+				value, err := p.object(depth + 1)
+				if err != nil {
+					return array, err
+				}
+				array = append(array, value)
+			}
+		case pdfDictStart:
+			dict := make(pdfDict)
+			for {
+				if end, ok := p.peek(0).(pdfDelimiter); ok && end == pdfDictEnd {
+					p.discard(1)
+					return dict, nil
+				}
+				key, err := p.object(depth + 1)
+				if err != nil {
+					return dict, err
+				}
+				name, ok := key.(pdfName)
+				if !ok {
+					if _, isKeyword := key.(pdfKeyword); isKeyword {
+						return dict, nil
+					}
+					continue
+				}
+				value, err := p.object(depth + 1)
+				if err != nil {
+					return dict, err
+				}
+				dict[string(name)] = value
+			}
+		}
+		return pdfKeyword(string(rune(t))), nil
+	}
+	return token, nil
+}
+
+// pdfDocument holds the parsed objects of a PDF
+type pdfDocument struct {
+	objects map[int]interface{}
+	trailer pdfDict
+	budget  *pdfBudget
+}
+
+// parsePDF locates every indirect object by scanning for "n g obj" headers
+// rather than trusting the cross-reference table, which tolerates damaged
+// files and incremental updates (later definitions win). The scan is a
+// single forward pass: each object is lexed no further than the next
+// endobj, and headers inside an object already parsed, such as in stream
+// data or strings, are skipped.
+func parsePDF(data []byte) (*pdfDocument, error) {
+	if !bytes.HasPrefix(bytes.TrimLeft(data, "\x00\t\n\f\r "), []byte("%PDF-")) {
+		return nil, fmt.Errorf("not a PDF file")
+	}
+	doc := &pdfDocument{
+		objects: make(map[int]interface{}),
+		trailer: make(pdfDict),
+		budget:  &pdfBudget{remaining: maxPDFScanBytes},
+	}
+
+	end, endObj := 0, -1
+	for _, match := range pdfObjectHeader.FindAllSubmatchIndex(data, -1) {
+		if match[0] < end {
+			continue
+		}
+		if endObj < match[1] {
+			endObj = len(data)
+			if i := bytes.Index(data[match[1]:], pdfEndObj); i >= 0 {
+				endObj = match[1] + i
+			}
+		}
+		num, _ := strconv.Atoi(string(data[match[2]:match[3]]))
+		parser := newPDFParser(data[:endObj], match[1], doc.budget)
+		value, err := parser.object(0)
+		end = parser.consumed
+		if err != nil && value == nil {
+			continue
+		}
+		if dict, ok := value.(pdfDict); ok {
+			if keyword, ok := parser.peek(0).(pdfKeyword); ok && keyword == "stream" {
+				start := parser.lex.pos
+				if start < len(data) && data[start] == '\r' {
+					start++
+				}
+				if start < len(data) && data[start] == '\n' {
+					start++
+				}
+				stream := streamData(data, start, dict)
+				value = pdfStream{dict: dict, data: stream}
+				end = start + len(stream)
+			}
+			if dict["Root"] != nil && (dict["Type"] == pdfName("XRef") || len(doc.trailer) == 0) {
+				mergeTrailer(doc.trailer, dict)
+			}
+		}
+		doc.objects[num] = value
+	}
+
+	end = 0
+	for _, match := range pdfTrailer.FindAllIndex(data, -1) {
+		if match[0] < end {
+			continue
+		}
+		parser := newPDFParser(data, match[1]-2, doc.budget)
+		value, _ := parser.object(0)
+		end = parser.consumed
+		if dict, ok := value.(pdfDict); ok {
+			mergeTrailer(doc.trailer, dict)
+		}
+	}
+	if doc.budget.exhausted() {
+		return nil, ErrPDFTooComplex
+	}
+	if doc.trailer["Encrypt"] != nil {
+		return nil, ErrPDFEncrypted
+	}
+
+	doc.expandObjectStreams()
+	return doc, nil
+}
+
+// mergeTrailer keeps the latest value of each trailer key
+func mergeTrailer(trailer, dict pdfDict) {
+	for _, key := range []string{"Root", "Encrypt", "Info"} {
+		if value, ok := dict[key]; ok {
+			trailer[key] = value
+		}
+	}
+}
+
+// streamData returns the raw bytes of a stream, trusting /Length only when
+// it lands on endstream
+func streamData(data []byte, start int, dict pdfDict) []byte {
+	if length, ok := dict["Length"].(float64); ok {
+		end := start + int(length)
+		if length >= 0 && end <= len(data) && bytes.HasPrefix(bytes.TrimLeft(data[end:], "\x00\t\n\f\r "), []byte("endstream")) {
+			return data[start:end]
+		}
+	}
+	end := bytes.Index(data[start:], []byte("endstream"))
+	if end < 0 {
+		return data[start:]
+	}
+	return bytes.TrimRight(data[start:start+end], "\r\n")
+}
+
+// expandObjectStreams adds objects stored inside compressed object streams
+func (d *pdfDocument) expandObjectStreams() {
+	nums := make([]int, 0, len(d.objects))
+	for num := range d.objects {
+		nums = append(nums, num)
+	}
+	sort.Ints(nums)
+	for _, num := range nums {
+		stream, ok := d.objects[num].(pdfStream)
+		if !ok || stream.dict["Type"] != pdfName("ObjStm") {
+			continue
+		}
+		data, err := d.decodeStream(stream)
+		if err != nil {
+			continue
+		}
+		count, _ := d.resolve(stream.dict["N"]).(float64)
+		first, _ := d.resolve(stream.dict["First"]).(float64)
+		header := newPDFParser(data, 0, d.budget)
+		for i := 0; i < int(count); i++ {
+			objNum, ok1 := header.peek(0).(float64)
+			offset, ok2 := header.peek(1).(float64)
+			if !ok1 || !ok2 {
+				break
+			}
+			header.discard(2)
+			if _, exists := d.objects[int(objNum)]; exists {
+				continue
+			}
+			pos := int(first) + int(offset)
+			if pos < 0 || pos >= len(data) {
+				continue
+			}
+			if value, err := newPDFParser(data, pos, d.budget).object(0); err == nil || value != nil {
+				d.objects[int(objNum)] = value
+			}
+		}
+	}
+}
+
+// resolve follows indirect references
+func (d *pdfDocument) resolve(value interface{}) interface{} {
+	for i := 0; i < maxPDFDepth; i++ {
+		ref, ok := value.(pdfRef)
+		if !ok {
+			return value
+		}
+		value = d.objects[ref.num]
+	}
+	return nil
+}
+
+func (d *pdfDocument) dict(value interface{}) pdfDict {
+	switch v := d.resolve(value).(type) {
+	case pdfDict:
+		return v
+	case pdfStream:
+		return v.dict
+	}
+	return nil
+}
+
+// decodeStream applies a stream's filters; FlateDecode, ASCIIHexDecode and
+// ASCII85Decode are supported. The decoded bytes count against the budget.
+func (d *pdfDocument) decodeStream(stream pdfStream) ([]byte, error) {
+	if d.budget.exhausted() {
+		return nil, ErrPDFTooComplex
+	}
+	var filters []interface{}
+	switch f := d.resolve(stream.dict["Filter"]).(type) {
+	case pdfName:
+		filters = []interface{}{f}
+	case []interface{}:
+		filters = f
+	}
+
+	data := stream.data
+	for _, filter := range filters {
+		switch d.resolve(filter) {
+		case pdfName("FlateDecode"), pdfName("Fl"):
+			reader, err := zlib.NewReader(bytes.NewReader(data))
+			if err != nil {
+				return nil, fmt.Errorf("failed to inflate stream: %w", err)
+			}
+			// Truncated streams are common; keep what was decoded
+			decoded, err := io.ReadAll(io.LimitReader(reader, maxPDFDecodedStream))
+			if err != nil && len(decoded) == 0 {
+				return nil, fmt.Errorf("failed to inflate stream: %w", err)
+			}
+			data = decoded
+		case pdfName("ASCIIHexDecode"), pdfName("AHx"):
+			data = []byte((&pdfLexer{data: append(append([]byte("<"), data...), '>')}).hexString())
+		case pdfName("ASCII85Decode"), pdfName("A85"):
+			trimmed := bytes.TrimSuffix(bytes.TrimSpace(data), []byte("~>"))
+			decoded := make([]byte, len(trimmed)*4/5+4)
+			n, _, err := ascii85.Decode(decoded, bytes.TrimPrefix(trimmed, []byte("<~")), true)
+			if err != nil {
+				return nil, fmt.Errorf("failed to decode ASCII85 stream: %w", err)
+			}
+			data = decoded[:n]
+		default:
+			return nil, fmt.Errorf("unsupported stream filter %v", filter)
+		}
+	}
+	if !d.budget.spend(len(data)) {
+		return nil, ErrPDFTooComplex
+	}
+	return data, nil
+}
+
+// pdfPage is a page's content streams and resources
+type pdfPage struct {
+	contents  []interface{}
+	resources pdfDict
+}
+
+// pages walks the page tree in document order, inheriting resources
+func (d *pdfDocument) pages() []pdfPage {
+	var pages []pdfPage
+	visited := make(map[int]bool)
+	var walk func(node interface{}, resources pdfDict, depth int)
+	walk = func(node interface{}, resources pdfDict, depth int) {
+		if ref, ok := node.(pdfRef); ok {
+			if visited[ref.num] {
+				return
+			}
+			visited[ref.num] = true
+		}
+		dict := d.dict(node)
+		if dict == nil || depth > maxPDFDepth {
+			return
+		}
+		if own := d.dict(dict["Resources"]); own != nil {
+			resources = own
+		}
+		if kids, ok := d.resolve(dict["Kids"]).([]interface{}); ok {
+			for _, kid := range kids {
+				walk(kid, resources, depth+1)
+			}
+			return
+		}
+		page := pdfPage{resources: resources}
+		switch contents := d.resolve(dict["Contents"]).(type) {
+		case []interface{}:
+			page.contents = contents
+		case nil:
+		default:
+			page.contents = []interface{}{dict["Contents"]}
+		}
+		pages = append(pages, page)
+	}
+
+	if root := d.dict(d.trailer["Root"]); root != nil {
+		walk(root["Pages"], nil, 0)
+	}
+	if len(pages) > 0 {
+		return pages
+	}
+
+	// Without a usable page tree, take page objects in object order
+	nums := make([]int, 0, len(d.objects))
+	for num, value := range d.objects {
+		if dict, ok := value.(pdfDict); ok && dict["Type"] == pdfName("Page") {
+			nums = append(nums, num)
+		}
+	}
+	sort.Ints(nums)
+	for _, num := range nums {
+		walk(pdfRef{num: num}, nil, 0)
+	}
+	return pages
+}
+
+// ExtractPDFText returns the document title, if set, and the text of each
+// page of a PDF
+func ExtractPDFText(data []byte) (string, []string, error) {
+	doc, err := parsePDF(data)
+	if err != nil {
+		return "", nil, err
+	}
+	pages := doc.pages()
+	if len(pages) == 0 {
+		return "", nil, fmt.Errorf("no pages found in PDF")
+	}
+	var title string
+	if info := doc.dict(doc.trailer["Info"]); info != nil {
+		if raw, ok := doc.resolve(info["Title"]).(string); ok {
+			title = pdfTextString(raw)
+		}
+	}
+
+	fonts := make(map[interface{}]*pdfFont)
+	texts := make([]string, len(pages))
+	for i, page := range pages {
+		var content []byte
+		for _, ref := range page.contents {
+			stream, ok := doc.resolve(ref).(pdfStream)
+			if !ok {
+				continue
+			}
+			data, err := doc.decodeStream(stream)
+			if err != nil {
+				continue
+			}
+			content = append(append(content, data...), '\n')
+		}
+		extractor := newPDFTextExtractor(doc, fonts)
+		extractor.run(content, page.resources, 0)
+		texts[i] = extractor.text.String()
+	}
+	if doc.budget.exhausted() {
+		return "", nil, ErrPDFTooComplex
+	}
+	return title, texts, nil
+}
+
+// pdfTextString decodes a text string outside content streams: UTF-16BE
+// with a byte order mark, else PDFDocEncoding, approximated by WinAnsi
+func pdfTextString(raw string) string {
+	if strings.HasPrefix(raw, "\xfe\xff") {
+		return utf16BEString(raw[2:])
+	}
+	runes := make([]rune, len(raw))
+	for i := 0; i < len(raw); i++ {
+		runes[i] = winAnsiRune(raw[i])
+	}
+	return string(runes)
+}
+
+// pdfMatrix is an affine transform [a b c d e f]
+type pdfMatrix [6]float64
+
+var pdfIdentity = pdfMatrix{1, 0, 0, 1, 0, 0}
+
+// multiply returns m × n, applying m first
+func (m pdfMatrix) multiply(n pdfMatrix) pdfMatrix {
+	return pdfMatrix{
+		m[0]*n[0] + m[1]*n[2],
+		m[0]*n[1] + m[1]*n[3],
+		m[2]*n[0] + m[3]*n[2],
+		m[2]*n[1] + m[3]*n[3],
+		m[4]*n[0] + m[5]*n[2] + n[4],
+		m[4]*n[1] + m[5]*n[3] + n[5],
+	}
+}
+
+func pdfTranslate(tx, ty float64) pdfMatrix {
+	return pdfMatrix{1, 0, 0, 1, tx, ty}
+}
+
+// pdfTextState is the part of the graphics state saved by q and restored by Q
+type pdfTextState struct {
+	ctm         pdfMatrix
+	font        *pdfFont
+	size        float64
+	charSpacing float64
+	wordSpacing float64
+	scale       float64
+	leading     float64
+	rise        float64
+}
+
+// pdfTextExtractor interprets the text operators of a content stream,
+// tracking glyph positions so that gaps between shown strings become spaces
+// and moves to another baseline become line breaks
+type pdfTextExtractor struct {
+	doc     *pdfDocument
+	fonts   map[interface{}]*pdfFont
+	text    strings.Builder
+	state   pdfTextState
+	stack   []pdfTextState
+	tm      pdfMatrix
+	tlm     pdfMatrix
+	lastX   float64
+	lastY   float64
+	hasLast bool
+}
+
+func newPDFTextExtractor(doc *pdfDocument, fonts map[interface{}]*pdfFont) *pdfTextExtractor {
+	return &pdfTextExtractor{
+		doc:   doc,
+		fonts: fonts,
+		state: pdfTextState{ctm: pdfIdentity, font: &pdfFont{codeBytes: 1, defaultWidth: pdfDefaultGlyphWidth}, size: 1, scale: 1},
+		tm:    pdfIdentity,
+		tlm:   pdfIdentity,
+	}
+}
+
+func (e *pdfTextExtractor) run(content []byte, resources pdfDict, depth int) {
+	parser := newPDFParser(content, 0, e.doc.budget)
+	var operands []interface{}
+	number := func(i int) float64 {
+		if i < 0 || i >= len(operands) {
+			return 0
+		}
+		n, _ := operands[i].(float64)
+		return n
+	}
+	for {
+		value, err := parser.object(0)
+		if err != nil {
+			return
+		}
+		operator, ok := value.(pdfKeyword)
+		if !ok {
+			operands = append(operands, value)
+			continue
+		}
+
+		n := len(operands)
+		switch operator {
+		case "q":
+			e.stack = append(e.stack, e.state)
+		case "Q":
+			if len(e.stack) > 0 {
+				e.state = e.stack[len(e.stack)-1]
+				e.stack = e.stack[:len(e.stack)-1]
+			}
+		case "cm":
+			if n >= 6 {
+				e.state.ctm = pdfMatrix{number(n - 6), number(n - 5), number(n - 4), number(n - 3), number(n - 2), number(n - 1)}.multiply(e.state.ctm)
+			}
+		case "BI":
+			e.skipInlineImage(parser)
+		case "BT":
+			e.tm, e.tlm = pdfIdentity, pdfIdentity
+		case "Tf":
+			if n >= 2 {
+				if name, ok := operands[n-2].(pdfName); ok {
+					e.state.font = e.loadFont(resources, name)
+				}
+				e.state.size = number(n - 1)
+			}
+		case "Tc":
+			e.state.charSpacing = number(n - 1)
+		case "Tw":
+			e.state.wordSpacing = number(n - 1)
+		case "Tz":
+			e.state.scale = number(n-1) / 100
+		case "TL":
+			e.state.leading = number(n - 1)
+		case "Ts":
+			e.state.rise = number(n - 1)
+		case "Td", "TD":
+			if n >= 2 {
+				if operator == "TD" {
+					e.state.leading = -number(n - 1)
+				}
+				e.tlm = pdfTranslate(number(n-2), number(n-1)).multiply(e.tlm)
+				e.tm = e.tlm
+			}
+		case "Tm":
+			if n >= 6 {
+				e.tlm = pdfMatrix{number(n - 6), number(n - 5), number(n - 4), number(n - 3), number(n - 2), number(n - 1)}
+				e.tm = e.tlm
+			}
+		case "T*":
+			e.nextLine()
+		case "Tj":
+			if n >= 1 {
+				e.show(operands[n-1])
+			}
+		case "'":
+			e.nextLine()
+			if n >= 1 {
+				e.show(operands[n-1])
+			}
+		case "\"":
+			if n >= 3 {
+				e.state.wordSpacing, e.state.charSpacing = number(n-3), number(n-2)
+				e.nextLine()
+				e.show(operands[n-1])
+			}
+		case "TJ":
+			if n >= 1 {
+				if array, ok := operands[n-1].([]interface{}); ok {
+					for _, item := range array {
+						if adjust, ok := item.(float64); ok {
+							e.tm = pdfTranslate(-adjust/1000*e.state.size*e.state.scale, 0).multiply(e.tm)
+							continue
+						}
+						e.show(item)
+					}
+				}
+			}
+		case "Do":
+			if n >= 1 && depth < maxPDFFormRecursion {
+				if name, ok := operands[n-1].(pdfName); ok {
+					e.runForm(resources, name, depth)
+				}
+			}
+		}
+		operands = operands[:0]
+	}
+}
+
+func (e *pdfTextExtractor) nextLine() {
+	e.tlm = pdfTranslate(0, -e.state.leading).multiply(e.tlm)
+	e.tm = e.tlm
+}
+
+// runForm extracts text from a form XObject
+func (e *pdfTextExtractor) runForm(resources pdfDict, name pdfName, depth int) {
+	xobjects := e.doc.dict(resources["XObject"])
+	if xobjects == nil {
+		return
+	}
+	form, ok := e.doc.resolve(xobjects[string(name)]).(pdfStream)
+	if !ok || form.dict["Subtype"] != pdfName("Form") {
+		return
+	}
+	data, err := e.doc.decodeStream(form)
+	if err != nil {
+		return
+	}
+	formResources := e.doc.dict(form.dict["Resources"])
+	if formResources == nil {
+		formResources = resources
+	}
+
+	saved, tm, tlm := e.state, e.tm, e.tlm
+	if matrix, ok := e.doc.resolve(form.dict["Matrix"]).([]interface{}); ok && len(matrix) == 6 {
+		var m pdfMatrix
+		for i, v := range matrix {
+			m[i], _ = e.doc.resolve(v).(float64)
+		}
+		e.state.ctm = m.multiply(e.state.ctm)
+	}
+	e.run(data, formResources, depth+1)
+	e.state, e.tm, e.tlm = saved, tm, tlm
+}
+
+// skipInlineImage moves past BI ... ID <binary data> EI
+func (e *pdfTextExtractor) skipInlineImage(parser *pdfParser) {
+	for {
+		token, err := parser.token()
+		if err != nil {
+			return
+		}
+		if keyword, ok := token.(pdfKeyword); ok && keyword == "ID" {
+			break
+		}
+	}
+	lex := parser.lex
+	parser.pending, parser.ends = nil, nil
+	for i := lex.pos; i+2 < len(lex.data); i++ {
+		if isPDFSpace(lex.data[i]) && lex.data[i+1] == 'E' && lex.data[i+2] == 'I' &&
+			(i+3 == len(lex.data) || isPDFSpace(lex.data[i+3])) {
+			lex.pos = i + 3
+			return
+		}
+	}
+	lex.pos = len(lex.data)
+}
+
+// position returns the device position of the text origin and the
+// effective font size
+func (e *pdfTextExtractor) position() (float64, float64, float64) {
+	m := pdfTranslate(0, e.state.rise).multiply(e.tm).multiply(e.state.ctm)
+	size := math.Abs(e.state.size) * math.Hypot(m[2], m[3])
+	if size == 0 {
+		size = 1
+	}
+	return m[4], m[5], size
+}
+
+// show appends a shown string, first inserting a line break or space when
+// it does not continue where the previous string ended
+func (e *pdfTextExtractor) show(value interface{}) {
+	raw, ok := value.(string)
+	if !ok {
+		return
+	}
+	x, y, size := e.position()
+	if e.hasLast {
+		dx, dy := x-e.lastX, math.Abs(y-e.lastY)
+		switch {
+		case dy > pdfParagraphGap*size:
+			e.newline()
+			e.newline()
+		case dy > pdfLineGap*size:
+			e.newline()
+		case dx > pdfWordGap*size || dx < -size:
+			e.space()
+		}
+	}
+
+	for _, glyph := range e.state.font.glyphs(raw) {
+		e.text.WriteString(glyph.text)
+		advance := glyph.width/1000*e.state.size + e.state.charSpacing
+		if glyph.space {
+			advance += e.state.wordSpacing
+		}
+		e.tm = pdfTranslate(advance*e.state.scale, 0).multiply(e.tm)
+	}
+	e.lastX, e.lastY, _ = e.position()
+	e.hasLast = true
+}
+
+// newline ends the current line; called twice it leaves a blank line
+func (e *pdfTextExtractor) newline() {
+	text := e.text.String()
+	if len(text) > 0 && !strings.HasSuffix(text, "\n\n") {
+		e.text.WriteByte('\n')
+	}
+}
+
+func (e *pdfTextExtractor) space() {
+	text := e.text.String()
+	if len(text) > 0 && !strings.HasSuffix(text, " ") && !strings.HasSuffix(text, "\n") {
+		e.text.WriteByte(' ')
+	}
+}
+
+// loadFont returns the decoder for a font resource, cached per font object
+func (e *pdfTextExtractor) loadFont(resources pdfDict, name pdfName) *pdfFont {
+	fontsDict := e.doc.dict(resources["Font"])
+	if fontsDict == nil {
+		return &pdfFont{codeBytes: 1, defaultWidth: pdfDefaultGlyphWidth}
+	}
+	ref := fontsDict[string(name)]
+	key := interface{}(ref)
+	if _, isRef := ref.(pdfRef); !isRef {
+		key = fmt.Sprintf("%p/%s", resources, name)
+	}
+	if font, ok := e.fonts[key]; ok {
+		return font
+	}
+	font := e.doc.newFont(e.doc.dict(ref))
+	e.fonts[key] = font
+	return font
+}
+
+// pdfFont decodes character codes to text and glyph widths
+type pdfFont struct {
+	codeBytes    int
+	toUnicode    map[string]string
+	encoding     map[byte]rune
+	widths       map[int]float64
+	defaultWidth float64
+}
+
+// pdfGlyph is one decoded character code; width is in thousandths of the
+// font size
+type pdfGlyph struct {
+	text  string
+	width float64
+	space bool
+}
+
+func (d *pdfDocument) newFont(dict pdfDict) *pdfFont {
+	font := &pdfFont{codeBytes: 1, defaultWidth: pdfDefaultGlyphWidth}
+	if dict == nil {
+		return font
+	}
+	font.widths = make(map[int]float64)
+	if dict["Subtype"] == pdfName("Type0") {
+		font.codeBytes = 2
+		font.defaultWidth = 1000
+		if descendants, ok := d.resolve(dict["DescendantFonts"]).([]interface{}); ok && len(descendants) > 0 {
+			d.cidWidths(font, d.dict(descendants[0]))
+		}
+	} else {
+		first, _ := d.resolve(dict["FirstChar"]).(float64)
+		if widths, ok := d.resolve(dict["Widths"]).([]interface{}); ok {
+			for i, w := range widths {
+				if width, ok := d.resolve(w).(float64); ok {
+					font.widths[int(first)+i] = width
+				}
+			}
+		}
+		if descriptor := d.dict(dict["FontDescriptor"]); descriptor != nil {
+			if missing, ok := d.resolve(descriptor["MissingWidth"]).(float64); ok && missing > 0 {
+				font.defaultWidth = missing
+			}
+		}
+	}
+	if stream, ok := d.resolve(dict["ToUnicode"]).(pdfStream); ok {
+		if data, err := d.decodeStream(stream); err == nil {
+			font.parseCMap(data, d.budget)
+		}
+	}
+
+	switch encoding := d.resolve(dict["Encoding"]).(type) {
+	case pdfDict:
+		if differences, ok := d.resolve(encoding["Differences"]).([]interface{}); ok {
+			font.encoding = make(map[byte]rune)
+			code := 0
+			for _, item := range differences {
+				switch v := d.resolve(item).(type) {
+				case float64:
+					code = int(v)
+				case pdfName:
+					if r, ok := glyphNameRune(string(v)); ok && code >= 0 && code < 256 {
+						font.encoding[byte(code)] = r
+					}
+					code++
+				}
+			}
+		}
+	}
+	return font
+}
+
+// cidWidths reads /DW and /W from a CIDFont: W holds "c [w1 w2 ...]" and
+// "cfirst clast w" entries
+func (d *pdfDocument) cidWidths(font *pdfFont, cidFont pdfDict) {
+	if cidFont == nil {
+		return
+	}
+	if dw, ok := d.resolve(cidFont["DW"]).(float64); ok {
+		font.defaultWidth = dw
+	}
+	w, _ := d.resolve(cidFont["W"]).([]interface{})
+	for i := 0; i < len(w); {
+		first, ok := d.resolve(w[i]).(float64)
+		if !ok || i+1 >= len(w) {
+			return
+		}
+		if widths, ok := d.resolve(w[i+1]).([]interface{}); ok {
+			for j, value := range widths {
+				if width, ok := d.resolve(value).(float64); ok {
+					font.widths[int(first)+j] = width
+				}
+			}
+			i += 2
+			continue
+		}
+		last, ok1 := d.resolve(w[i+1]).(float64)
+		width, ok2 := d.resolve(w[minInt(i+2, len(w)-1)]).(float64)
+		if !ok1 || !ok2 || i+2 >= len(w) || last-first > 0xFFFF {
+			return
+		}
+		for code := int(first); code <= int(last) && len(font.widths) < maxPDFFontCodes; code++ {
+			font.widths[code] = width
+		}
+		i += 3
+	}
+}
+
+// parseCMap reads bfchar and bfrange mappings from a ToUnicode CMap
+func (f *pdfFont) parseCMap(data []byte, budget *pdfBudget) {
+	f.toUnicode = make(map[string]string)
+	parser := newPDFParser(data, 0, budget)
+	var operands []interface{}
+	mode := ""
+	for {
+		value, err := parser.object(0)
+		if err != nil {
+			return
+		}
+		keyword, ok := value.(pdfKeyword)
+		if !ok {
+			operands = append(operands, value)
+			if mode == "bfchar" && len(operands) == 2 {
+				src, _ := operands[0].(string)
+				dst, _ := operands[1].(string)
+				f.toUnicode[src] = utf16BEString(dst)
+				operands = operands[:0]
+			}
+			if mode == "bfrange" && len(operands) == 3 {
+				f.addRange(operands)
+				operands = operands[:0]
+			}
+			continue
+		}
+		switch keyword {
+		case "begincodespacerange":
+			mode = "codespace"
+		case "endcodespacerange":
+			if len(operands) >= 1 {
+				if low, ok := operands[0].(string); ok && len(low) > 0 {
+					f.codeBytes = len(low)
+				}
+			}
+			mode = ""
+		case "beginbfchar":
+			mode = "bfchar"
+		case "beginbfrange":
+			mode = "bfrange"
+		case "endbfchar", "endbfrange":
+			mode = ""
+		}
+		operands = operands[:0]
+	}
+}
+
+// addRange adds a bfrange entry: <lo> <hi> <dst> or <lo> <hi> [<dst>...]
+func (f *pdfFont) addRange(operands []interface{}) {
+	low, ok1 := operands[0].(string)
+	high, ok2 := operands[1].(string)
+	if !ok1 || !ok2 || len(low) != len(high) || len(low) == 0 || len(low) > 4 {
+		return
+	}
+	lo, hi := bytesToInt(low), bytesToInt(high)
+	if hi < lo || hi-lo > 0xFFFF {
+		return
+	}
+	for code := lo; code <= hi && len(f.toUnicode) < maxPDFFontCodes; code++ {
+		src := intToBytes(code, len(low))
+		switch dst := operands[2].(type) {
+		case string:
+			if len(dst) < 2 {
+				return
+			}
+			// Increment the last UTF-16 unit of the destination
+			units := []byte(dst)
+			last := int(units[len(units)-2])<<8 | int(units[len(units)-1])
+			last += code - lo
+			units[len(units)-2], units[len(units)-1] = byte(last>>8), byte(last)
+			f.toUnicode[src] = utf16BEString(string(units))
+		case []interface{}:
+			if index := code - lo; index < len(dst) {
+				if s, ok := dst[index].(string); ok {
+					f.toUnicode[src] = utf16BEString(s)
+				}
+			}
+		}
+	}
+}
+
+// glyphs splits a shown string into character codes with their text and
+// widths
+func (f *pdfFont) glyphs(raw string) []pdfGlyph {
+	var glyphs []pdfGlyph
+	for i := 0; i < len(raw); {
+		width := f.codeBytes
+		text, mapped := "", false
+		if f.toUnicode != nil {
+			// Try the code width from the CMap, then other widths
+			for _, w := range []int{f.codeBytes, 1, 2, 3, 4} {
+				if i+w > len(raw) {
+					continue
+				}
+				if t, ok := f.toUnicode[raw[i:i+w]]; ok {
+					text, width, mapped = t, w, true
+					break
+				}
+			}
+		}
+		if i+width > len(raw) {
+			width = len(raw) - i
+		}
+		code := bytesToInt(raw[i : i+width])
+		if !mapped && width == 1 {
+			// Composite fonts without a ToUnicode CMap use glyph IDs, which
+			// have no text; simple fonts use their encoding
+			if r, ok := f.encoding[byte(code)]; ok {
+				text = string(r)
+			} else {
+				text = string(winAnsiRune(byte(code)))
+			}
+		}
+		glyphWidth, ok := f.widths[code]
+		if !ok {
+			glyphWidth = f.defaultWidth
+		}
+		glyphs = append(glyphs, pdfGlyph{text: text, width: glyphWidth, space: width == 1 && code == ' '})
+		i += width
+	}
+	return glyphs
+}
+
+func utf16BEString(s string) string {
+	if len(s)%2 == 1 {
+		s += "\x00"
+	}
+	units := make([]uint16, len(s)/2)
+	for i := range units {
+		units[i] = uint16(s[2*i])<<8 | uint16(s[2*i+1])
+	}
+	return string(utf16.Decode(units))
+}
+
+func bytesToInt(s string) int {
+	n := 0
+	for i := 0; i < len(s); i++ {
+		n = n<<8 | int(s[i])
+	}
+	return n
+}
+
+func intToBytes(n, width int) string {
+	out := make([]byte, width)
+	for i := width - 1; i >= 0; i-- {
+		out[i] = byte(n)
+		n >>= 8
+	}
+	return string(out)
+}
+
+// winAnsiSpecials are the WinAnsiEncoding codes that differ from Latin-1
+var winAnsiSpecials = map[byte]rune{
+	0x80: '€', 0x82: '‚', 0x83: 'ƒ', 0x84: '„', 0x85: '…', 0x86: '†', 0x87: '‡',
+	0x88: 'ˆ', 0x89: '‰', 0x8A: 'Š', 0x8B: '‹', 0x8C: 'Œ', 0x8E: 'Ž', 0x91: '‘',
+	0x92: '’', 0x93: '“', 0x94: '”', 0x95: '•', 0x96: '–', 0x97: '—',
+	0x98: '˜', 0x99: '™', 0x9A: 'š', 0x9B: '›', 0x9C: 'œ', 0x9E: 'ž', 0x9F: 'Ÿ',
+}
+
+func winAnsiRune(c byte) rune {
+	if r, ok := winAnsiSpecials[c]; ok {
+		return r
+	}
+	return rune(c)
+}
+
+// glyphNames maps common Adobe glyph names that are not single characters
+var glyphNames = map[string]rune{
+	"space": ' ', "exclam": '!', "quotedbl": '"', "numbersign": '#', "dollar": '$',
+	"percent": '%', "ampersand": '&', "quotesingle": '\'', "parenleft": '(', "parenright": ')',
+	"asterisk": '*', "plus": '+', "comma": ',', "hyphen": '-', "period": '.', "slash": '/',
+	"zero": '0', "one": '1', "two": '2', "three": '3', "four": '4', "five": '5', "six": '6',
+	"seven": '7', "eight": '8', "nine": '9', "colon": ':', "semicolon": ';', "less": '<',
+	"equal": '=', "greater": '>', "question": '?', "at": '@', "bracketleft": '[',
+	"backslash": '\\', "bracketright": ']', "underscore": '_', "braceleft": '{', "bar": '|',
+	"braceright": '}', "quoteleft": '‘', "quoteright": '’', "quotedblleft": '“',
+	"quotedblright": '”', "bullet": '•', "endash": '–', "emdash": '—', "ellipsis": '…',
+	"fi": 'ﬁ', "fl": 'ﬂ', "ff": 'ﬀ', "ffi": 'ﬃ', "ffl": 'ﬄ', "copyright": '©',
+	"registered": '®', "trademark": '™', "degree": '°', "Euro": '€', "section": '§',
+}
+
+func glyphNameRune(name string) (rune, bool) {
+	if r, ok := glyphNames[name]; ok {
+		return r, true
+	}
+	if len(name) == 1 {
+		return rune(name[0]), true
+	}
+	if strings.HasPrefix(name, "uni") && len(name) == 7 {
+		if n, err := strconv.ParseUint(name[3:], 16, 32); err == nil {
+			return rune(n), true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,113 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// buildTestPDF writes a one-page PDF showing each line in Helvetica, with a
+// cross-reference table
+func buildTestPDF(title string, lines ...string) []byte {
+	var content bytes.Buffer
+	content.WriteString("BT /F1 12 Tf 72 720 Td 14 TL\n")
+	for _, line := range lines {
+		fmt.Fprintf(&content, "(%s) Tj T*\n", line)
+	}
+	content.WriteString("ET")
+
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Resources << /Font << /F1 5 0 R >> >> /Contents 4 0 R >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", content.Len(), content.String()),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+		fmt.Sprintf("<< /Title (%s) >>", title),
+	}
+	var pdf bytes.Buffer
+	pdf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = pdf.Len()
+		fmt.Fprintf(&pdf, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+	xref := pdf.Len()
+	fmt.Fprintf(&pdf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&pdf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&pdf, "trailer\n<< /Size %d /Root 1 0 R /Info 6 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return pdf.Bytes()
+}
+
+func TestExtractPDFText(t *testing.T) {
+	title, pages, err := ExtractPDFText(buildTestPDF("Report", "Hello World", "Second line (1 0 obj) here"))
+	if err != nil {
+		t.Fatalf("ExtractPDFText: %v", err)
+	}
+	if title != "Report" {
+		t.Errorf("title = %q, want Report", title)
+	}
+	if len(pages) != 1 {
+		t.Fatalf("got %d pages, want 1", len(pages))
+	}
+	for _, want := range []string{"Hello World", "Second line (1 0 obj) here"} {
+		if !strings.Contains(pages[0], want) {
+			t.Errorf("page text %q does not contain %q", pages[0], want)
+		}
+	}
+}
+
+func TestExtractPDFTextHostileInput(t *testing.T) {
+	tests := map[string][]byte{
+		"unterminated strings": append([]byte("%PDF-1.4\n"), bytes.Repeat([]byte("0 0 obj("), 1<<17)...),
+		"unterminated dicts":   append([]byte("%PDF-1.4\n"), bytes.Repeat([]byte("0 0 obj<</A["), 1<<17)...),
+		"trailers":             append([]byte("%PDF-1.4\n"), bytes.Repeat([]byte("trailer<<("), 1<<17)...),
+		"headers without endobj": append([]byte("%PDF-1.4\n"),
+			bytes.Repeat([]byte("1 0 obj 5\n"), 1<<17)...),
+	}
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			started := time.Now()
+			ExtractPDFText(data)
+			if elapsed := time.Since(started); elapsed > 10*time.Second {
+				t.Errorf("extraction took %v", elapsed)
+			}
+		})
+	}
+}
+
+func TestExtractPDFTextNestedForms(t *testing.T) {
+	// Each form draws the next one many times, multiplying the work by the
+	// fan-out at every level
+	var pdf bytes.Buffer
+	pdf.WriteString("%PDF-1.4\n1 0 obj << /Type /Catalog /Pages 2 0 R >> endobj\n")
+	pdf.WriteString("2 0 obj << /Type /Pages /Kids [3 0 R] >> endobj\n")
+	pdf.WriteString("3 0 obj << /Type /Page /Contents 4 0 R /Resources 5 0 R >> endobj\n")
+	draw := strings.Repeat("/X Do ", 200)
+	fmt.Fprintf(&pdf, "4 0 obj << /Length %d >> stream\n%s\nendstream endobj\n", len(draw), draw)
+	pdf.WriteString("5 0 obj << /XObject << /X 6 0 R >> >> endobj\n")
+	body := draw + strings.Repeat("(padding) Tj ", 1000)
+	fmt.Fprintf(&pdf, "6 0 obj << /Subtype /Form /Resources 5 0 R /Length %d >> stream\n%s\nendstream endobj\n", len(body), body)
+	pdf.WriteString("trailer << /Root 1 0 R >>\n")
+
+	if _, _, err := ExtractPDFText(pdf.Bytes()); !errors.Is(err, ErrPDFTooComplex) {
+		t.Errorf("err = %v, want ErrPDFTooComplex", err)
+	}
+}
+
+func FuzzExtractPDFText(f *testing.F) {
+	f.Add(buildTestPDF("Title", "Hello World"))
+	f.Add([]byte("%PDF-1.4\n0 0 obj(0 0 obj(0 0 obj("))
+	f.Add([]byte("%PDF-1.4\n1 0 obj << /Type /ObjStm /N 3 /First 6 /Length 12 >> stream\n2 0 3 1 (((\nendstream endobj"))
+	f.Add([]byte("%PDF-1.4\n1 0 obj << /W [0 65535 1 0 65535 1] /DW 1 >> endobj trailer << /Root 1 0 R >>"))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		_, pages, err := ExtractPDFText(data)
+		if err == nil && len(pages) == 0 {
+			t.Errorf("no error and no pages")
+		}
+	})
+}
@@ -0,0 +1,341 @@
+package services
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/Ammar0144/ai/models"
+)
+
+// Question answering limits and defaults
+const (
+	maxQAPassages         = 100
+	maxQAInputTokens      = 50000
+	maxQAQuestionTokens   = 128
+	maxQATopK             = 10
+	defaultQATopK         = 3
+	defaultQAMaxTokens    = 48
+	defaultQAConfidence   = 0.6
+	qaChunkTokens         = 192
+	qaChunkOverlap        = 32
+	qaAlignmentSlackWords = 3
+)
+
+const qaInstruction = "Answer the question using only the context below. Copy the answer word for word from the context as a short phrase. If the context does not contain the answer, reply \"no answer\"."
+
+// noAnswerPhrases are model replies that decline to answer
+var noAnswerPhrases = []string{"no answer", "unanswerable", "i don't know", "i do not know", "not in the context", "cannot be answered", "not mentioned"}
+
+// qaArticles are ignored when scoring token overlap, as in SQuAD
+var qaArticles = map[string]bool{"a": true, "an": true, "the": true}
+
+// qaContext is a passage chunk placed in the prompt
+type qaContext struct {
+	passage int
+	chunk   TextChunk
+	score   float64
+}
+
+// QAPlan is a validated question with the passage chunks chosen for its
+// prompt
+type QAPlan struct {
+	passages      []models.QAPassage
+	contexts      []qaContext
+	minConfidence float64
+
+	Prompt      string
+	MaxTokens   int
+	Temperature float64
+}
+
+// PlanQA validates a question answering request, fills in defaults, and
+// ranks passage chunks against the question with BM25. The best top_k
+// chunks that fit the context window go into the prompt; when nothing
+// matches the question lexically the passages are used in order.
+func PlanQA(req *models.QARequest) (*QAPlan, error) {
+	req.Question = strings.TrimSpace(req.Question)
+	if req.Question == "" {
+		return nil, fmt.Errorf("question cannot be empty")
+	}
+	if EstimateTokens(req.Question) > maxQAQuestionTokens {
+		return nil, fmt.Errorf("question cannot exceed %d tokens", maxQAQuestionTokens)
+	}
+	passages := req.Passages
+	if strings.TrimSpace(req.Context) != "" {
+		// Passage indexes in spans refer to passages, so the two cannot mix
+		if len(passages) > 0 {
+			return nil, fmt.Errorf("set either context or passages, not both")
+		}
+		passages = []models.QAPassage{{Text: req.Context}}
+	}
+	if len(passages) == 0 {
+		return nil, fmt.Errorf("at least one passage or a context is required")
+	}
+	if len(passages) > maxQAPassages {
+		return nil, fmt.Errorf("at most %d passages are allowed", maxQAPassages)
+	}
+	if req.TopK == 0 {
+		req.TopK = defaultQATopK
+	}
+	if req.TopK < 1 || req.TopK > maxQATopK {
+		return nil, fmt.Errorf("top_k must be between 1 and %d", maxQATopK)
+	}
+	if req.MinConfidence == 0 {
+		req.MinConfidence = defaultQAConfidence
+	}
+	if req.MinConfidence < 0 || req.MinConfidence > 1 {
+		return nil, fmt.Errorf("min_confidence must be between 0 and 1")
+	}
+	if req.MaxTokens == 0 {
+		req.MaxTokens = defaultQAMaxTokens
+	}
+	if req.MaxTokens < 1 || req.MaxTokens > ModelContextTokens/4 {
+		return nil, fmt.Errorf("max_tokens must be between 1 and %d", ModelContextTokens/4)
+	}
+	if req.Temperature == 0 {
+		req.Temperature = 0.7
+	}
+
+	var chunks []qaContext
+	index := newBM25Index()
+	total := 0
+	for i, passage := range passages {
+		if strings.TrimSpace(passage.Text) == "" {
+			return nil, fmt.Errorf("passage %d has no text", i)
+		}
+		total += EstimateTokens(passage.Text)
+		if total > maxQAInputTokens {
+			return nil, fmt.Errorf("passages cannot exceed %d tokens in total", maxQAInputTokens)
+		}
+		for _, chunk := range ChunkText(passage.Text, qaChunkTokens, qaChunkOverlap) {
+			index.add(strconv.Itoa(len(chunks)), chunk.Text)
+			chunks = append(chunks, qaContext{passage: i, chunk: chunk})
+		}
+	}
+
+	ranked := index.search(req.Question, len(chunks), nil)
+	order := make([]qaContext, 0, len(chunks))
+	for _, match := range ranked {
+		n, _ := strconv.Atoi(match.id)
+		chunks[n].score = match.score
+		order = append(order, chunks[n])
+	}
+	if len(order) == 0 {
+		order = chunks
+	}
+
+	plan := &QAPlan{
+		passages:      passages,
+		minConfidence: req.MinConfidence,
+		MaxTokens:     req.MaxTokens,
+		Temperature:   req.Temperature,
+	}
+	question := "\nQuestion: " + req.Question + "\nAnswer:"
+	budget := ModelContextTokens - req.MaxTokens - EstimateTokens(qaInstruction) - EstimateTokens(question) - messageOverheadTokens
+	var blocks []string
+	for _, context := range order {
+		if len(plan.contexts) == req.TopK {
+			break
+		}
+		block := qaContextBlock(len(plan.contexts)+1, passages[context.passage].Title, context.chunk.Text)
+		tokens := EstimateTokens(block)
+		if tokens > budget {
+			continue
+		}
+		budget -= tokens
+		blocks = append(blocks, block)
+		plan.contexts = append(plan.contexts, context)
+	}
+	if len(plan.contexts) == 0 {
+		return nil, fmt.Errorf("question leaves no room for context")
+	}
+	plan.Prompt = qaInstruction + "\n\nContext:\n" + strings.Join(blocks, "\n") + "\n" + question
+	return plan, nil
+}
+
+func qaContextBlock(marker int, title, text string) string {
+	if title != "" {
+		return fmt.Sprintf("[%d] %s: %s", marker, title, text)
+	}
+	return fmt.Sprintf("[%d] %s", marker, text)
+}
+
+// Sources describes the chunks placed in the prompt, in prompt order
+func (p *QAPlan) Sources() []models.QASource {
+	sources := make([]models.QASource, len(p.contexts))
+	for i, context := range p.contexts {
+		sources[i] = models.QASource{
+			Marker:       i + 1,
+			PassageIndex: context.passage,
+			PassageID:    p.passages[context.passage].ID,
+			Start:        context.chunk.Start,
+			End:          context.chunk.End,
+			Score:        context.score,
+		}
+	}
+	return sources
+}
+
+// Align maps the model's answer back to an exact span of a prompt chunk.
+// An answer found verbatim (ignoring case) has confidence 1; otherwise the
+// word window with the best token F1 against the answer is used and its F1
+// is the confidence. Below the plan's minimum confidence, or when the model
+// declined, there is no span.
+func (p *QAPlan) Align(output string) (*models.QASpan, float64) {
+	answer := CleanQAAnswer(output)
+	if answer == "" || isNoAnswer(answer) {
+		return nil, 0
+	}
+
+	var best *models.QASpan
+	bestScore := 0.0
+	for _, context := range p.contexts {
+		start, end, score := alignSpan(context.chunk.Text, answer)
+		if score <= bestScore {
+			continue
+		}
+		bestScore = score
+		runes := []rune(context.chunk.Text)
+		best = &models.QASpan{
+			PassageIndex: context.passage,
+			PassageID:    p.passages[context.passage].ID,
+			Start:        context.chunk.Start + start,
+			End:          context.chunk.Start + end,
+			Text:         string(runes[start:end]),
+		}
+		if score == 1 {
+			break
+		}
+	}
+	if best == nil || bestScore < p.minConfidence {
+		return nil, bestScore
+	}
+	return best, bestScore
+}
+
+// CleanQAAnswer reduces a completion to its answer: the first line, without
+// an "Answer:" label, surrounding quotes or a trailing full stop
+func CleanQAAnswer(output string) string {
+	answer := strings.TrimSpace(output)
+	if i := strings.IndexByte(answer, '\n'); i >= 0 {
+		answer = answer[:i]
+	}
+	answer = strings.TrimSpace(answer)
+	if strings.HasPrefix(strings.ToLower(answer), "answer:") {
+		answer = strings.TrimSpace(answer[len("answer:"):])
+	}
+	answer = strings.Trim(answer, "\"'“”‘’`")
+	return strings.TrimSpace(strings.TrimSuffix(answer, "."))
+}
+
+func isNoAnswer(answer string) bool {
+	lower := strings.ToLower(answer)
+	for _, phrase := range noAnswerPhrases {
+		if strings.HasPrefix(lower, phrase) {
+			return true
+		}
+	}
+	return false
+}
+
+// qaWord is a lowercased word with its rune offsets
+type qaWord struct {
+	text  string
+	start int
+	end   int
+}
+
+// qaWords splits text into letter/digit words with rune offsets
+func qaWords(text string) []qaWord {
+	var words []qaWord
+	start := -1
+	var current []rune
+	i := 0
+	for _, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if start < 0 {
+				start = i
+			}
+			current = append(current, unicode.ToLower(r))
+		} else if start >= 0 {
+			words = append(words, qaWord{string(current), start, i})
+			start, current = -1, current[:0]
+		}
+		i++
+	}
+	if start >= 0 {
+		words = append(words, qaWord{string(current), start, i})
+	}
+	return words
+}
+
+// alignSpan finds the answer in text, returning rune offsets and a score
+func alignSpan(text, answer string) (int, int, float64) {
+	if start := indexFoldRunes([]rune(text), []rune(answer)); start >= 0 {
+		return start, start + len([]rune(answer)), 1
+	}
+
+	counts := make(map[string]int)
+	answerLength := 0
+	for _, word := range qaWords(answer) {
+		if !qaArticles[word.text] {
+			counts[word.text]++
+			answerLength++
+		}
+	}
+	if answerLength == 0 {
+		return 0, 0, 0
+	}
+
+	words := qaWords(text)
+	bestStart, bestEnd, bestScore := 0, 0, 0.0
+	for i := range words {
+		if counts[words[i].text] == 0 {
+			continue
+		}
+		remaining := make(map[string]int, len(counts))
+		for word, n := range counts {
+			remaining[word] = n
+		}
+		overlap, length := 0, 0
+		for j := i; j < len(words) && j-i < answerLength+qaAlignmentSlackWords; j++ {
+			if qaArticles[words[j].text] {
+				continue
+			}
+			length++
+			if remaining[words[j].text] > 0 {
+				remaining[words[j].text]--
+				overlap++
+			} else {
+				continue
+			}
+			// Spans end on a matching word; ties keep the shorter span
+			if score := 2 * float64(overlap) / float64(length+answerLength); score > bestScore {
+				bestStart, bestEnd, bestScore = words[i].start, words[j].end, score
+			}
+		}
+	}
+	return bestStart, bestEnd, bestScore
+}
+
+// indexFoldRunes is a case-insensitive search returning a rune offset
+func indexFoldRunes(text, pattern []rune) int {
+	if len(pattern) == 0 {
+		return -1
+	}
+	for i := 0; i+len(pattern) <= len(text); i++ {
+		match := true
+		for j, r := range pattern {
+			if unicode.ToLower(text[i+j]) != unicode.ToLower(r) {
+				match = false
+				break
+			}
+		}
+		if match {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,708 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Ammar0144/ai/models"
+)
+
+// RAG limits
+const (
+	defaultRAGTopK        = 5
+	maxRAGTopK            = 20
+	maxIngestDocuments    = 100
+	maxDocumentIDLength   = 128
+	minRAGSourceTokens    = 32
+	reciprocalRankFusionK = 60
+)
+
+// RAG errors
+var (
+	ErrRAGCollectionNotFound = errors.New("collection not found")
+	ErrRAGCollectionExists   = errors.New("collection already exists")
+	ErrDocumentNotFound      = errors.New("document not found")
+	ErrInvalidRAGRequest     = errors.New("invalid request")
+)
+
+// ragInstruction tells the model how to use and cite the retrieved sources
+const ragInstruction = "Answer the user's question using only the numbered sources below. " +
+	"Cite the sources you use by number in square brackets, like [1]. " +
+	"If the sources do not contain the answer, say that you don't know."
+
+// citationPattern matches [1], [2, 3] and similar markers in an answer
+var citationPattern = regexp.MustCompile(`\[(\d+(?:\s*,\s*\d+)*)\]`)
+
+// RAGStore holds document collections chunked for retrieval. Each collection
+// keeps a BM25 index over its chunks and, when enabled, chunk embeddings for
+// hybrid retrieval. With AI_RAG_DIR set, each collection is saved to
+// <name>.json after every change and loaded at startup.
+type RAGStore struct {
+	ai          *AIService
+	collections map[string]*ragCollection
+	dir         string
+	mutex       sync.RWMutex
+}
+
+type ragCollection struct {
+	info      models.RAGCollection
+	documents map[string]*models.RAGDocument
+	chunks    map[string]*ragChunk
+	docChunks map[string][]string
+	bm25      *bm25Index
+	mutex     sync.RWMutex
+}
+
+// ragChunk is a chunk with its embedding, in the form it is saved
+type ragChunk struct {
+	models.RAGChunk
+	Vector []float64 `json:"vector,omitempty"`
+}
+
+type ragSnapshot struct {
+	Collection models.RAGCollection  `json:"collection"`
+	Documents  []*models.RAGDocument `json:"documents"`
+	Chunks     []*ragChunk           `json:"chunks"`
+}
+
+// NewRAGStore creates a RAG store, loading collections from AI_RAG_DIR
+func NewRAGStore(ai *AIService) *RAGStore {
+	store := &RAGStore{
+		ai:          ai,
+		collections: make(map[string]*ragCollection),
+		dir:         os.Getenv("AI_RAG_DIR"),
+	}
+	if store.dir == "" {
+		return store
+	}
+
+	files, err := filepath.Glob(filepath.Join(store.dir, "*.json"))
+	if err != nil {
+		log.Printf("RAG store: failed to list %s: %v", store.dir, err)
+	}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			log.Printf("RAG store: failed to read %s: %v", file, err)
+			continue
+		}
+		var snapshot ragSnapshot
+		if err := json.Unmarshal(data, &snapshot); err != nil {
+			log.Printf("RAG store: failed to parse %s: %v", file, err)
+			continue
+		}
+		collection := newRAGCollection(snapshot.Collection)
+		chunks := make(map[string][]*ragChunk)
+		for _, chunk := range snapshot.Chunks {
+			chunks[chunk.DocumentID] = append(chunks[chunk.DocumentID], chunk)
+		}
+		for _, document := range snapshot.Documents {
+			collection.addDocument(document, chunks[document.ID])
+		}
+		store.collections[collection.info.Name] = collection
+		log.Printf("RAG store: loaded collection %s with %d documents", collection.info.Name, len(collection.documents))
+	}
+	return store
+}
+
+func newRAGCollection(info models.RAGCollection) *ragCollection {
+	return &ragCollection{
+		info:      info,
+		documents: make(map[string]*models.RAGDocument),
+		chunks:    make(map[string]*ragChunk),
+		docChunks: make(map[string][]string),
+		bm25:      newBM25Index(),
+	}
+}
+
+// addDocument indexes a document and its chunks; callers hold the write lock
+func (c *ragCollection) addDocument(document *models.RAGDocument, chunks []*ragChunk) {
+	c.documents[document.ID] = document
+	ids := make([]string, len(chunks))
+	for i, chunk := range chunks {
+		c.chunks[chunk.ID] = chunk
+		c.bm25.add(chunk.ID, chunk.Text)
+		ids[i] = chunk.ID
+	}
+	c.docChunks[document.ID] = ids
+}
+
+// removeDocument drops a document and returns it with its chunks; callers
+// hold the write lock
+func (c *ragCollection) removeDocument(id string) (*models.RAGDocument, []*ragChunk) {
+	document, ok := c.documents[id]
+	if !ok {
+		return nil, nil
+	}
+	var chunks []*ragChunk
+	for _, chunkID := range c.docChunks[id] {
+		chunk := c.chunks[chunkID]
+		c.bm25.remove(chunkID, chunk.Text)
+		delete(c.chunks, chunkID)
+		chunks = append(chunks, chunk)
+	}
+	delete(c.documents, id)
+	delete(c.docChunks, id)
+	return document, chunks
+}
+
+// infoLocked returns the collection description; callers hold a lock
+func (c *ragCollection) infoLocked() models.RAGCollection {
+	info := c.info
+	info.Documents = len(c.documents)
+	info.Chunks = len(c.chunks)
+	return info
+}
+
+// save writes the collection when a directory is configured; callers hold a lock
+func (r *RAGStore) save(c *ragCollection) error {
+	if r.dir == "" {
+		return nil
+	}
+	snapshot := ragSnapshot{Collection: c.infoLocked()}
+	for _, document := range c.documents {
+		snapshot.Documents = append(snapshot.Documents, document)
+	}
+	sort.Slice(snapshot.Documents, func(i, j int) bool { return snapshot.Documents[i].ID < snapshot.Documents[j].ID })
+	for _, document := range snapshot.Documents {
+		for _, chunkID := range c.docChunks[document.ID] {
+			snapshot.Chunks = append(snapshot.Chunks, c.chunks[chunkID])
+		}
+	}
+	return writeJSONFileAtomic(r.collectionPath(c.info.Name), snapshot)
+}
+
+func (r *RAGStore) collectionPath(name string) string {
+	return filepath.Join(r.dir, name+".json")
+}
+
+// CreateCollection adds an empty document collection
+func (r *RAGStore) CreateCollection(req models.CreateRAGCollectionRequest) (models.RAGCollection, error) {
+	if !templateIDPattern.MatchString(req.Name) {
+		return models.RAGCollection{}, fmt.Errorf("%w: name must be a lowercase slug", ErrInvalidRAGRequest)
+	}
+	tokens, overlap, err := ResolveChunking(req.ChunkTokens, req.ChunkOverlap)
+	if err != nil {
+		return models.RAGCollection{}, fmt.Errorf("%w: %v", ErrInvalidRAGRequest, err)
+	}
+	req.ChunkTokens, req.ChunkOverlap = tokens, overlap
+	if req.EmbeddingModel != "" && !req.Vectors {
+		return models.RAGCollection{}, fmt.Errorf("%w: embedding_model requires vectors", ErrInvalidRAGRequest)
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, exists := r.collections[req.Name]; exists {
+		return models.RAGCollection{}, ErrRAGCollectionExists
+	}
+	now := time.Now()
+	collection := newRAGCollection(models.RAGCollection{
+		Name:           req.Name,
+		Description:    req.Description,
+		Vectors:        req.Vectors,
+		EmbeddingModel: req.EmbeddingModel,
+		ChunkTokens:    req.ChunkTokens,
+		ChunkOverlap:   req.ChunkOverlap,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	})
+	if err := r.save(collection); err != nil {
+		return models.RAGCollection{}, err
+	}
+	r.collections[req.Name] = collection
+	return collection.infoLocked(), nil
+}
+
+// collection looks up a collection by name
+func (r *RAGStore) collection(name string) (*ragCollection, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	collection, ok := r.collections[name]
+	if !ok {
+		return nil, ErrRAGCollectionNotFound
+	}
+	return collection, nil
+}
+
+// GetCollection describes a collection
+func (r *RAGStore) GetCollection(name string) (models.RAGCollection, error) {
+	collection, err := r.collection(name)
+	if err != nil {
+		return models.RAGCollection{}, err
+	}
+	collection.mutex.RLock()
+	defer collection.mutex.RUnlock()
+	return collection.infoLocked(), nil
+}
+
+// ListCollections describes every collection, ordered by name
+func (r *RAGStore) ListCollections() []models.RAGCollection {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	infos := make([]models.RAGCollection, 0, len(r.collections))
+	for _, collection := range r.collections {
+		collection.mutex.RLock()
+		infos = append(infos, collection.infoLocked())
+		collection.mutex.RUnlock()
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos
+}
+
+// DeleteCollection removes a collection and its saved file
+func (r *RAGStore) DeleteCollection(name string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, ok := r.collections[name]; !ok {
+		return ErrRAGCollectionNotFound
+	}
+	if r.dir != "" {
+		if err := os.Remove(r.collectionPath(name)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to remove collection file: %w", err)
+		}
+	}
+	delete(r.collections, name)
+	return nil
+}
+
+// Ingest chunks, embeds and indexes documents, replacing documents with the
+// same ID. Chunking and embedding happen before the collection is locked, so
+// queries are not blocked by a slow embedder.
+func (r *RAGStore) Ingest(ctx context.Context, name string, inputs []models.RAGDocumentInput) (*models.IngestDocumentsResponse, error) {
+	if len(inputs) == 0 {
+		return nil, fmt.Errorf("%w: at least one document is required", ErrInvalidRAGRequest)
+	}
+	if len(inputs) > maxIngestDocuments {
+		return nil, fmt.Errorf("%w: at most %d documents can be ingested at once", ErrInvalidRAGRequest, maxIngestDocuments)
+	}
+	info, err := r.GetCollection(name)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	documents := make([]*models.RAGDocument, len(inputs))
+	chunks := make([][]*ragChunk, len(inputs))
+	var texts []string
+	var targets []*ragChunk
+	now := time.Now()
+	for i, input := range inputs {
+		if input.ID == "" {
+			input.ID = NewID("doc")
+		}
+		if len(input.ID) > maxDocumentIDLength || strings.ContainsAny(input.ID, "/#?") {
+			return nil, fmt.Errorf("%w: document %d: id must be at most %d characters without '/', '#' or '?'", ErrInvalidRAGRequest, i, maxDocumentIDLength)
+		}
+		if seen[input.ID] {
+			return nil, fmt.Errorf("%w: document id %s appears more than once", ErrInvalidRAGRequest, input.ID)
+		}
+		seen[input.ID] = true
+		if strings.TrimSpace(input.Text) == "" {
+			return nil, fmt.Errorf("%w: document %s has no text", ErrInvalidRAGRequest, input.ID)
+		}
+
+		for j, piece := range ChunkText(input.Text, info.ChunkTokens, info.ChunkOverlap) {
+			chunk := &ragChunk{RAGChunk: models.RAGChunk{
+				ID:         fmt.Sprintf("%s#%d", input.ID, j),
+				DocumentID: input.ID,
+				Index:      j,
+				Text:       piece.Text,
+				Start:      piece.Start,
+				End:        piece.End,
+				Tokens:     piece.Tokens,
+			}}
+			chunks[i] = append(chunks[i], chunk)
+			texts = append(texts, chunk.Text)
+			targets = append(targets, chunk)
+		}
+		documents[i] = &models.RAGDocument{
+			ID:         input.ID,
+			Title:      input.Title,
+			Source:     input.Source,
+			Metadata:   input.Metadata,
+			Characters: len([]rune(input.Text)),
+			Chunks:     len(chunks[i]),
+			Text:       input.Text,
+			CreatedAt:  now,
+		}
+	}
+
+	if info.Vectors {
+		vectors, err := r.ai.EmbedAll(ctx, texts, info.EmbeddingModel)
+		if err != nil {
+			return nil, err
+		}
+		for i, vector := range vectors {
+			targets[i].Vector = vector
+		}
+	}
+
+	collection, err := r.collection(name)
+	if err != nil {
+		return nil, err
+	}
+	collection.mutex.Lock()
+	defer collection.mutex.Unlock()
+
+	type replaced struct {
+		document *models.RAGDocument
+		chunks   []*ragChunk
+	}
+	var previous []replaced
+	for i, document := range documents {
+		if old, oldChunks := collection.removeDocument(document.ID); old != nil {
+			previous = append(previous, replaced{old, oldChunks})
+		}
+		collection.addDocument(document, chunks[i])
+	}
+	updatedAt := collection.info.UpdatedAt
+	collection.info.UpdatedAt = now
+	if err := r.save(collection); err != nil {
+		for _, document := range documents {
+			collection.removeDocument(document.ID)
+		}
+		for _, old := range previous {
+			collection.addDocument(old.document, old.chunks)
+		}
+		collection.info.UpdatedAt = updatedAt
+		return nil, err
+	}
+
+	response := &models.IngestDocumentsResponse{Collection: name, Documents: make([]models.RAGDocument, len(documents))}
+	for i, document := range documents {
+		response.Documents[i] = *document
+		response.Documents[i].Text = ""
+		response.Chunks += document.Chunks
+	}
+	return response, nil
+}
+
+// ListDocuments returns a collection's documents without their text
+func (r *RAGStore) ListDocuments(name string) ([]models.RAGDocument, error) {
+	collection, err := r.collection(name)
+	if err != nil {
+		return nil, err
+	}
+	collection.mutex.RLock()
+	defer collection.mutex.RUnlock()
+
+	documents := make([]models.RAGDocument, 0, len(collection.documents))
+	for _, document := range collection.documents {
+		summary := *document
+		summary.Text = ""
+		documents = append(documents, summary)
+	}
+	sort.Slice(documents, func(i, j int) bool { return documents[i].ID < documents[j].ID })
+	return documents, nil
+}
+
+// GetDocument returns a document with its text and chunks
+func (r *RAGStore) GetDocument(name, id string) (models.RAGDocument, []models.RAGChunk, error) {
+	collection, err := r.collection(name)
+	if err != nil {
+		return models.RAGDocument{}, nil, err
+	}
+	collection.mutex.RLock()
+	defer collection.mutex.RUnlock()
+
+	document, ok := collection.documents[id]
+	if !ok {
+		return models.RAGDocument{}, nil, ErrDocumentNotFound
+	}
+	chunks := make([]models.RAGChunk, 0, len(collection.docChunks[id]))
+	for _, chunkID := range collection.docChunks[id] {
+		chunks = append(chunks, collection.chunks[chunkID].RAGChunk)
+	}
+	return *document, chunks, nil
+}
+
+// DeleteDocument removes a document and its chunks
+func (r *RAGStore) DeleteDocument(name, id string) error {
+	collection, err := r.collection(name)
+	if err != nil {
+		return err
+	}
+	collection.mutex.Lock()
+	defer collection.mutex.Unlock()
+
+	document, chunks := collection.removeDocument(id)
+	if document == nil {
+		return ErrDocumentNotFound
+	}
+	updatedAt := collection.info.UpdatedAt
+	collection.info.UpdatedAt = time.Now()
+	if err := r.save(collection); err != nil {
+		collection.addDocument(document, chunks)
+		collection.info.UpdatedAt = updatedAt
+		return err
+	}
+	return nil
+}
+
+// Search retrieves the chunks that best match a query. BM25 mode ranks by
+// keyword relevance, vector mode by embedding cosine similarity, and hybrid
+// mode fuses both rankings with reciprocal rank fusion. Filters apply to
+// document metadata.
+func (r *RAGStore) Search(ctx context.Context, name string, req models.RAGSearchRequest) (*models.RAGSearchResponse, error) {
+	if strings.TrimSpace(req.Query) == "" {
+		return nil, fmt.Errorf("%w: query cannot be empty", ErrInvalidRAGRequest)
+	}
+	topK := req.TopK
+	if topK == 0 {
+		topK = defaultRAGTopK
+	}
+	if topK < 0 || topK > maxRAGTopK {
+		return nil, fmt.Errorf("%w: top_k must be between 1 and %d", ErrInvalidRAGRequest, maxRAGTopK)
+	}
+	if err := ValidateFilter(req.Filter); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRAGRequest, err)
+	}
+	info, err := r.GetCollection(name)
+	if err != nil {
+		return nil, err
+	}
+
+	mode := req.Mode
+	switch mode {
+	case "":
+		mode = models.RetrievalBM25
+		if info.Vectors {
+			mode = models.RetrievalHybrid
+		}
+	case models.RetrievalBM25:
+	case models.RetrievalVector, models.RetrievalHybrid:
+		if !info.Vectors {
+			return nil, fmt.Errorf("%w: collection %s has no vectors; use mode bm25", ErrInvalidRAGRequest, name)
+		}
+	default:
+		return nil, fmt.Errorf("%w: unsupported mode %q; use hybrid, bm25 or vector", ErrInvalidRAGRequest, req.Mode)
+	}
+
+	var query []float64
+	if mode != models.RetrievalBM25 {
+		result, err := r.ai.Embed(ctx, []string{req.Query}, info.EmbeddingModel)
+		if err != nil {
+			return nil, err
+		}
+		query = result.Vectors[0]
+	}
+
+	collection, err := r.collection(name)
+	if err != nil {
+		return nil, err
+	}
+	collection.mutex.RLock()
+	defer collection.mutex.RUnlock()
+
+	var accept func(id string) bool
+	if len(req.Filter) > 0 {
+		accept = func(id string) bool {
+			return MatchFilter(req.Filter, collection.documents[collection.chunks[id].DocumentID].Metadata)
+		}
+	}
+
+	// Hybrid fusion looks deeper into each ranking than the final top_k
+	depth := topK
+	if mode == models.RetrievalHybrid {
+		depth = topK * 4
+		if depth < 20 {
+			depth = 20
+		}
+	}
+
+	matches := make(map[string]*models.RAGMatch)
+	match := func(id string) *models.RAGMatch {
+		if m, ok := matches[id]; ok {
+			return m
+		}
+		chunk := collection.chunks[id]
+		document := collection.documents[chunk.DocumentID]
+		m := &models.RAGMatch{RAGChunk: chunk.RAGChunk, Title: document.Title, Source: document.Source}
+		matches[id] = m
+		return m
+	}
+
+	if mode != models.RetrievalVector {
+		for rank, result := range collection.bm25.search(req.Query, depth, accept) {
+			m := match(result.id)
+			m.BM25Score = result.score
+			if mode == models.RetrievalHybrid {
+				m.Score += 1 / float64(reciprocalRankFusionK+rank+1)
+			} else {
+				m.Score = result.score
+			}
+		}
+	}
+	if mode != models.RetrievalBM25 {
+		for rank, result := range collection.vectorSearch(query, depth, accept) {
+			m := match(result.id)
+			m.VectorScore = result.score
+			if mode == models.RetrievalHybrid {
+				m.Score += 1 / float64(reciprocalRankFusionK+rank+1)
+			} else {
+				m.Score = result.score
+			}
+		}
+	}
+
+	response := &models.RAGSearchResponse{Collection: name, Mode: mode, Matches: make([]models.RAGMatch, 0, len(matches))}
+	for _, m := range matches {
+		response.Matches = append(response.Matches, *m)
+	}
+	sort.Slice(response.Matches, func(i, j int) bool {
+		if response.Matches[i].Score != response.Matches[j].Score {
+			return response.Matches[i].Score > response.Matches[j].Score
+		}
+		return response.Matches[i].ID < response.Matches[j].ID
+	})
+	if len(response.Matches) > topK {
+		response.Matches = response.Matches[:topK]
+	}
+	return response, nil
+}
+
+// vectorSearch ranks chunks by cosine similarity with an exact scan;
+// callers hold a lock
+func (c *ragCollection) vectorSearch(query []float64, k int, accept func(id string) bool) []rankedID {
+	queryNorm := math.Sqrt(dotProduct(query, query))
+	if queryNorm == 0 {
+		return nil
+	}
+	var results []rankedID
+	for id, chunk := range c.chunks {
+		if len(chunk.Vector) != len(query) || (accept != nil && !accept(id)) {
+			continue
+		}
+		norm := math.Sqrt(dotProduct(chunk.Vector, chunk.Vector))
+		if norm == 0 {
+			continue
+		}
+		results = append(results, rankedID{id, dotProduct(query, chunk.Vector) / (queryNorm * norm)})
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].score != results[j].score {
+			return results[i].score > results[j].score
+		}
+		return results[i].id < results[j].id
+	})
+	if len(results) > k {
+		results = results[:k]
+	}
+	return results
+}
+
+// LatestUserMessage returns the content of the last user message
+func LatestUserMessage(messages []models.ChatMessage) string {
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == "user" {
+			return messages[i].Content
+		}
+	}
+	return ""
+}
+
+// BuildRAGMessages adds the retrieved chunks to a conversation as numbered
+// sources in a system message after any leading system messages. Chunks are
+// added best first while they fit in the context left after the
+// conversation and the reply; a chunk that does not fit is skipped in favor
+// of smaller ones, and if none fit the best one is truncated. Returns the
+// messages and a citation for each source, numbered as in the prompt.
+func BuildRAGMessages(messages []models.ChatMessage, matches []models.RAGMatch, maxTokens int) ([]models.ChatMessage, []models.RAGCitation) {
+	budget := ModelContextTokens - maxTokens - EstimateMessagesTokens(messages) -
+		EstimateTokens(ragInstruction) - messageOverheadTokens
+
+	sources := []models.RAGCitation{}
+	var blocks []string
+	for _, match := range matches {
+		block := ragSourceBlock(len(sources)+1, match.Title, match.Text)
+		tokens := EstimateTokens(block)
+		if tokens > budget {
+			continue
+		}
+		budget -= tokens
+		blocks = append(blocks, block)
+		sources = append(sources, ragCitation(len(sources)+1, match))
+	}
+
+	if len(sources) == 0 && len(matches) > 0 {
+		best := matches[0]
+		header := ragSourceBlock(1, best.Title, "")
+		if room := budget - EstimateTokens(header); room >= minRAGSourceTokens {
+			text := strings.TrimSpace(TruncateToTokens(best.Text, room))
+			best.End = best.Start + len([]rune(text))
+			blocks = append(blocks, ragSourceBlock(1, best.Title, text))
+			sources = append(sources, ragCitation(1, best))
+		}
+	}
+
+	content := ragInstruction + "\n\nSources:\n\n" + strings.Join(blocks, "\n\n")
+	if len(blocks) == 0 {
+		content = ragInstruction + "\n\nSources: none were found."
+	}
+
+	leading := 0
+	for leading < len(messages) && messages[leading].Role == "system" {
+		leading++
+	}
+	result := make([]models.ChatMessage, 0, len(messages)+1)
+	result = append(result, messages[:leading]...)
+	result = append(result, models.ChatMessage{Role: "system", Content: content})
+	result = append(result, messages[leading:]...)
+	return result, sources
+}
+
+// ragSourceBlock formats one numbered source for the prompt
+func ragSourceBlock(marker int, title, text string) string {
+	header := "[" + strconv.Itoa(marker) + "]"
+	if title != "" {
+		header += " " + title
+	}
+	return header + "\n" + text
+}
+
+func ragCitation(marker int, match models.RAGMatch) models.RAGCitation {
+	return models.RAGCitation{
+		Marker:     marker,
+		ChunkID:    match.ID,
+		DocumentID: match.DocumentID,
+		Title:      match.Title,
+		Source:     match.Source,
+		Start:      match.Start,
+		End:        match.End,
+		Score:      match.Score,
+	}
+}
+
+// CitedSources returns the sources an answer refers to by [n] markers, in
+// order of first reference
+func CitedSources(answer string, sources []models.RAGCitation) []models.RAGCitation {
+	cited := []models.RAGCitation{}
+	seen := make(map[int]bool)
+	for _, group := range citationPattern.FindAllStringSubmatch(answer, -1) {
+		for _, part := range strings.Split(group[1], ",") {
+			marker, err := strconv.Atoi(strings.TrimSpace(part))
+			if err != nil || marker < 1 || marker > len(sources) || seen[marker] {
+				continue
+			}
+			seen[marker] = true
+			cited = append(cited, sources[marker-1])
+		}
+	}
+	return cited
+}
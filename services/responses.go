@@ -0,0 +1,343 @@
+package services
+
+import (
+	"container/list"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Ammar0144/ai/models"
+)
+
+// Response store defaults. In memory, the byte limit keeps large
+// summarization requests from exhausting memory; on disk, memory holds a
+// cache of recent responses.
+const (
+	defaultResponseRetention   = 7 * 24 * time.Hour
+	defaultResponseMaxEntries  = 10000
+	defaultResponseDiskEntries = 100000
+	responseCacheEntries       = 1000
+	responseMemoryBytes        = 64 << 20
+	responseSweepInterval      = 10 * time.Minute
+	responseDayLayout          = "2006-01-02"
+)
+
+// ErrResponseNotFound is returned for unknown or expired response IDs
+var ErrResponseNotFound = errors.New("response not found")
+
+// responseIDPattern matches identifiers of stored responses
+var responseIDPattern = regexp.MustCompile(`^resp_[0-9a-f]+$`)
+
+// ResponseStore keeps completed AI responses by ID for a retention period.
+// With a directory, each response is a JSON file under <dir>/<UTC day>/ and
+// recent responses are cached in memory; otherwise responses live in memory
+// only, bounded by count and size. Every response has its own file, so disk
+// access needs no lock.
+type ResponseStore struct {
+	dir        string
+	retention  time.Duration
+	maxEntries int
+	cache      *responseCache
+}
+
+// NewResponseStore creates a response store configured by AI_RESPONSE_DIR,
+// AI_RESPONSE_RETENTION (a duration such as "72h" or "30d", default 7 days;
+// 0 keeps responses until the entry limit) and AI_RESPONSE_MAX_ENTRIES
+// (default 10000 in memory and 100000 on disk)
+func NewResponseStore() *ResponseStore {
+	store := &ResponseStore{
+		dir:       os.Getenv("AI_RESPONSE_DIR"),
+		retention: defaultResponseRetention,
+	}
+	if value := os.Getenv("AI_RESPONSE_RETENTION"); value != "" {
+		retention, err := parseRetention(value)
+		if err != nil {
+			log.Printf("Response store: ignoring AI_RESPONSE_RETENTION: %v", err)
+		} else {
+			store.retention = retention
+		}
+	}
+	if value := os.Getenv("AI_RESPONSE_MAX_ENTRIES"); value != "" {
+		maxEntries, err := strconv.Atoi(value)
+		if err != nil || maxEntries < 0 {
+			log.Printf("Response store: ignoring AI_RESPONSE_MAX_ENTRIES %q; expected a non-negative integer", value)
+		} else {
+			store.maxEntries = maxEntries
+		}
+	}
+
+	if store.dir != "" {
+		if err := os.MkdirAll(store.dir, 0o755); err != nil {
+			log.Printf("Response store: failed to use %s, falling back to memory: %v", store.dir, err)
+			store.dir = ""
+		}
+	}
+	if store.dir == "" {
+		if store.maxEntries == 0 {
+			store.maxEntries = defaultResponseMaxEntries
+		}
+		store.cache = newResponseCache(store.maxEntries, responseMemoryBytes)
+		log.Printf("Response store: in-memory, %d responses for %s", store.maxEntries, retentionText(store.retention))
+	} else {
+		if store.maxEntries == 0 {
+			store.maxEntries = defaultResponseDiskEntries
+		}
+		store.cache = newResponseCache(responseCacheEntries, responseMemoryBytes)
+		log.Printf("Response store: on-disk at %s, %d responses for %s", store.dir, store.maxEntries, retentionText(store.retention))
+	}
+
+	store.sweep()
+	ticker := time.NewTicker(responseSweepInterval)
+	go func() {
+		for range ticker.C {
+			store.sweep()
+		}
+	}()
+	return store
+}
+
+// parseRetention parses a Go duration, also accepting whole days like "30d"
+func parseRetention(value string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		count, err := strconv.Atoi(days)
+		if err != nil || count < 0 {
+			return 0, fmt.Errorf("invalid retention %q", value)
+		}
+		return time.Duration(count) * 24 * time.Hour, nil
+	}
+	retention, err := time.ParseDuration(value)
+	if err != nil || retention < 0 {
+		return 0, fmt.Errorf("invalid retention %q", value)
+	}
+	return retention, nil
+}
+
+// retentionText describes a retention period for logs
+func retentionText(retention time.Duration) string {
+	if retention == 0 {
+		return "unlimited time"
+	}
+	return retention.String()
+}
+
+// expired reports whether a response created at t is past retention
+func (s *ResponseStore) expired(t time.Time) bool {
+	return s.retention > 0 && time.Since(t) > s.retention
+}
+
+// Add stores a completed response
+func (s *ResponseStore) Add(record models.ResponseRecord) error {
+	s.cache.add(record)
+	if s.dir == "" {
+		return nil
+	}
+
+	path := filepath.Join(s.dir, record.CreatedAt.UTC().Format(responseDayLayout), record.ID+".json")
+	if err := writeJSONFileAtomic(path, record); err != nil {
+		return fmt.Errorf("failed to save response: %w", err)
+	}
+	return nil
+}
+
+// Get returns a stored response
+func (s *ResponseStore) Get(id string) (models.ResponseRecord, error) {
+	if !responseIDPattern.MatchString(id) {
+		return models.ResponseRecord{}, ErrResponseNotFound
+	}
+	if record, ok := s.cache.get(id); ok {
+		if s.expired(record.CreatedAt) {
+			return models.ResponseRecord{}, ErrResponseNotFound
+		}
+		return record, nil
+	}
+	if s.dir == "" {
+		return models.ResponseRecord{}, ErrResponseNotFound
+	}
+
+	days, err := s.days()
+	if err != nil {
+		return models.ResponseRecord{}, err
+	}
+	// Newest first, since recent responses are looked up most
+	for i := len(days) - 1; i >= 0; i-- {
+		data, err := os.ReadFile(filepath.Join(s.dir, days[i], id+".json"))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return models.ResponseRecord{}, fmt.Errorf("failed to read response: %w", err)
+		}
+		var record models.ResponseRecord
+		if err := json.Unmarshal(data, &record); err != nil {
+			return models.ResponseRecord{}, fmt.Errorf("failed to parse response: %w", err)
+		}
+		if s.expired(record.CreatedAt) {
+			return models.ResponseRecord{}, ErrResponseNotFound
+		}
+		return record, nil
+	}
+	return models.ResponseRecord{}, ErrResponseNotFound
+}
+
+// days lists the day directories, oldest first
+func (s *ResponseStore) days() ([]string, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list responses: %w", err)
+	}
+	var days []string
+	for _, entry := range entries {
+		if _, err := time.Parse(responseDayLayout, entry.Name()); entry.IsDir() && err == nil {
+			days = append(days, entry.Name())
+		}
+	}
+	sort.Strings(days)
+	return days, nil
+}
+
+// sweep deletes responses past retention and the oldest beyond the entry limit.
+// It runs alongside Add, which only writes to the directories of today and,
+// around midnight, yesterday; those directories are never removed.
+func (s *ResponseStore) sweep() {
+	if s.retention > 0 {
+		s.cache.expire(time.Now().Add(-s.retention))
+	}
+	if s.dir == "" {
+		return
+	}
+
+	days, err := s.days()
+	if err != nil {
+		log.Printf("Response store: %v", err)
+		return
+	}
+
+	type storedFile struct {
+		path    string
+		modTime time.Time
+	}
+	var files []storedFile
+	removed := 0
+	oldestWritable := time.Now().UTC().AddDate(0, 0, -1).Format(responseDayLayout)
+	for _, day := range days {
+		dayDir := filepath.Join(s.dir, day)
+		entries, err := os.ReadDir(dayDir)
+		if err != nil {
+			log.Printf("Response store: failed to list %s: %v", dayDir, err)
+			continue
+		}
+		for _, entry := range entries {
+			info, err := entry.Info()
+			if err != nil || entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+				continue
+			}
+			path := filepath.Join(dayDir, entry.Name())
+			if s.expired(info.ModTime()) {
+				if os.Remove(path) == nil {
+					removed++
+				}
+				continue
+			}
+			files = append(files, storedFile{path: path, modTime: info.ModTime()})
+		}
+		// Day directories emptied by retention are removed; Remove fails on
+		// directories that still hold responses
+		if day < oldestWritable {
+			os.Remove(dayDir)
+		}
+	}
+
+	if s.maxEntries > 0 && len(files) > s.maxEntries {
+		sort.Slice(files, func(i, j int) bool { return files[i].modTime.Before(files[j].modTime) })
+		for _, file := range files[:len(files)-s.maxEntries] {
+			if os.Remove(file.path) == nil {
+				removed++
+			}
+		}
+	}
+	if removed > 0 {
+		log.Printf("Response store: removed %d expired or excess responses", removed)
+	}
+}
+
+// responseCache is a thread-safe LRU of responses bounded by count and size
+type responseCache struct {
+	capacity int
+	maxBytes int
+	bytes    int
+	records  map[string]*list.Element
+	order    *list.List
+	mutex    sync.Mutex
+}
+
+func newResponseCache(capacity, maxBytes int) *responseCache {
+	return &responseCache{
+		capacity: capacity,
+		maxBytes: maxBytes,
+		records:  make(map[string]*list.Element),
+		order:    list.New(),
+	}
+}
+
+// add stores a record, evicting the least recently used beyond the limits
+func (c *responseCache) add(record models.ResponseRecord) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if element, ok := c.records[record.ID]; ok {
+		c.remove(element)
+	}
+	c.records[record.ID] = c.order.PushFront(record)
+	c.bytes += recordSize(record)
+	for c.order.Len() > 1 && (c.order.Len() > c.capacity || c.bytes > c.maxBytes) {
+		c.remove(c.order.Back())
+	}
+}
+
+func (c *responseCache) get(id string) (models.ResponseRecord, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	element, ok := c.records[id]
+	if !ok {
+		return models.ResponseRecord{}, false
+	}
+	c.order.MoveToFront(element)
+	return element.Value.(models.ResponseRecord), true
+}
+
+// expire removes records created before cutoff
+func (c *responseCache) expire(cutoff time.Time) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for element := c.order.Front(); element != nil; {
+		next := element.Next()
+		if element.Value.(models.ResponseRecord).CreatedAt.Before(cutoff) {
+			c.remove(element)
+		}
+		element = next
+	}
+}
+
+// remove drops an element; callers hold the lock
+func (c *responseCache) remove(element *list.Element) {
+	record := element.Value.(models.ResponseRecord)
+	c.order.Remove(element)
+	delete(c.records, record.ID)
+	c.bytes -= recordSize(record)
+}
+
+// recordSize approximates the memory held by a record
+func recordSize(record models.ResponseRecord) int {
+	return len(record.Prompt) + len(record.Output) + len(record.Request) + len(record.Response)
+}
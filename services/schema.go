@@ -0,0 +1,211 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// ValidateSchema checks a decoded JSON value against a JSON Schema subset:
+// type, properties, required, additionalProperties (boolean), items, enum,
+// minLength, maxLength, minimum, maximum, minItems and maxItems
+func ValidateSchema(schema map[string]interface{}, value interface{}) error {
+	return validateSchemaAt(schema, value, "input")
+}
+
+// CheckSchema verifies that a schema only uses supported keywords and types
+func CheckSchema(schema map[string]interface{}) error {
+	return checkSchemaAt(schema, "schema")
+}
+
+func checkSchemaAt(schema map[string]interface{}, path string) error {
+	if t, ok := schema["type"]; ok {
+		name, isString := t.(string)
+		if !isString {
+			return fmt.Errorf("%s: type must be a string", path)
+		}
+		switch name {
+		case "object", "array", "string", "number", "integer", "boolean", "null":
+		default:
+			return fmt.Errorf("%s: unsupported type %q", path, name)
+		}
+	}
+	if properties, ok := schema["properties"]; ok {
+		props, isMap := properties.(map[string]interface{})
+		if !isMap {
+			return fmt.Errorf("%s: properties must be an object", path)
+		}
+		for name, sub := range props {
+			subSchema, isMap := sub.(map[string]interface{})
+			if !isMap {
+				return fmt.Errorf("%s.%s: schema must be an object", path, name)
+			}
+			if err := checkSchemaAt(subSchema, path+"."+name); err != nil {
+				return err
+			}
+		}
+	}
+	if items, ok := schema["items"]; ok {
+		itemSchema, isMap := items.(map[string]interface{})
+		if !isMap {
+			return fmt.Errorf("%s: items must be an object", path)
+		}
+		if err := checkSchemaAt(itemSchema, path+"[]"); err != nil {
+			return err
+		}
+	}
+	if required, ok := schema["required"]; ok {
+		if _, err := stringList(required); err != nil {
+			return fmt.Errorf("%s: required must be a list of strings", path)
+		}
+	}
+	return nil
+}
+
+func validateSchemaAt(schema map[string]interface{}, value interface{}, path string) error {
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		matched := false
+		for _, option := range enum {
+			if fmt.Sprint(option) == fmt.Sprint(value) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return fmt.Errorf("%s: must be one of %v", path, enum)
+		}
+	}
+
+	schemaType, _ := schema["type"].(string)
+	switch schemaType {
+	case "":
+		return nil
+
+	case "object":
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s: must be an object", path)
+		}
+		required, _ := stringList(schema["required"])
+		for _, name := range required {
+			if _, present := object[name]; !present {
+				return fmt.Errorf("%s.%s: is required", path, name)
+			}
+		}
+		properties, _ := schema["properties"].(map[string]interface{})
+		if additional, ok := schema["additionalProperties"].(bool); ok && !additional {
+			for _, name := range sortedKeys(object) {
+				if _, declared := properties[name]; !declared {
+					return fmt.Errorf("%s.%s: is not allowed", path, name)
+				}
+			}
+		}
+		for _, name := range sortedKeys(object) {
+			propertySchema, ok := properties[name].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if err := validateSchemaAt(propertySchema, object[name], path+"."+name); err != nil {
+				return err
+			}
+		}
+
+	case "array":
+		items, ok := value.([]interface{})
+		if !ok {
+			return fmt.Errorf("%s: must be an array", path)
+		}
+		if min, ok := schemaNumber(schema, "minItems"); ok && float64(len(items)) < min {
+			return fmt.Errorf("%s: must have at least %v items", path, min)
+		}
+		if max, ok := schemaNumber(schema, "maxItems"); ok && float64(len(items)) > max {
+			return fmt.Errorf("%s: must have at most %v items", path, max)
+		}
+		if itemSchema, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range items {
+				if err := validateSchemaAt(itemSchema, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+
+	case "string":
+		text, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("%s: must be a string", path)
+		}
+		length := float64(len([]rune(text)))
+		if min, ok := schemaNumber(schema, "minLength"); ok && length < min {
+			return fmt.Errorf("%s: must be at least %v characters", path, min)
+		}
+		if max, ok := schemaNumber(schema, "maxLength"); ok && length > max {
+			return fmt.Errorf("%s: must be at most %v characters", path, max)
+		}
+
+	case "number", "integer":
+		number, ok := value.(float64)
+		if !ok {
+			return fmt.Errorf("%s: must be a %s", path, schemaType)
+		}
+		if schemaType == "integer" && number != math.Trunc(number) {
+			return fmt.Errorf("%s: must be an integer", path)
+		}
+		if min, ok := schemaNumber(schema, "minimum"); ok && number < min {
+			return fmt.Errorf("%s: must be >= %v", path, min)
+		}
+		if max, ok := schemaNumber(schema, "maximum"); ok && number > max {
+			return fmt.Errorf("%s: must be <= %v", path, max)
+		}
+
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("%s: must be a boolean", path)
+		}
+
+	case "null":
+		if value != nil {
+			return fmt.Errorf("%s: must be null", path)
+		}
+	}
+	return nil
+}
+
+// SchemaProperties returns the property names declared by an object schema
+func SchemaProperties(schema map[string]interface{}) []string {
+	properties, _ := schema["properties"].(map[string]interface{})
+	return sortedKeys(properties)
+}
+
+func schemaNumber(schema map[string]interface{}, key string) (float64, bool) {
+	value, ok := schema[key].(float64)
+	return value, ok
+}
+
+func stringList(value interface{}) ([]string, error) {
+	switch list := value.(type) {
+	case nil:
+		return nil, nil
+	case []string:
+		return list, nil
+	case []interface{}:
+		names := make([]string, 0, len(list))
+		for _, item := range list {
+			name, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("expected string, got %T", item)
+			}
+			names = append(names, name)
+		}
+		return names, nil
+	}
+	return nil, fmt.Errorf("expected list, got %T", value)
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
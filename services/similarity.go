@@ -0,0 +1,78 @@
+package services
+
+import (
+	"math"
+	"strings"
+	"unicode"
+)
+
+// Tokenize lowercases text and splits it into letter/digit terms
+func Tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// TFIDFSimilarities scores each document against the query by the cosine
+// similarity of their TF-IDF vectors. Document frequencies are computed over
+// the given documents, so scores are only comparable within one call.
+func TFIDFSimilarities(query string, documents []string) []float64 {
+	docTerms := make([][]string, len(documents))
+	df := make(map[string]int)
+	for i, doc := range documents {
+		docTerms[i] = Tokenize(doc)
+		seen := make(map[string]bool)
+		for _, term := range docTerms[i] {
+			if !seen[term] {
+				seen[term] = true
+				df[term]++
+			}
+		}
+	}
+
+	// Smoothed IDF so terms present in every document still carry weight
+	n := float64(len(documents))
+	idf := func(term string) float64 {
+		return math.Log((n+1)/(float64(df[term])+1)) + 1
+	}
+
+	queryVector := tfidfVector(Tokenize(query), idf)
+	scores := make([]float64, len(documents))
+	for i, terms := range docTerms {
+		scores[i] = CosineSimilarity(queryVector, tfidfVector(terms, idf))
+	}
+	return scores
+}
+
+// tfidfVector builds a sparse vector with sublinear term frequency
+func tfidfVector(terms []string, idf func(string) float64) map[string]float64 {
+	counts := make(map[string]int)
+	for _, term := range terms {
+		counts[term]++
+	}
+	vector := make(map[string]float64, len(counts))
+	for term, count := range counts {
+		vector[term] = (1 + math.Log(float64(count))) * idf(term)
+	}
+	return vector
+}
+
+// CosineSimilarity returns the cosine of the angle between two sparse vectors,
+// or 0 when either is empty
+func CosineSimilarity(a, b map[string]float64) float64 {
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+	var dot, normA, normB float64
+	for term, weight := range a {
+		dot += weight * b[term]
+		normA += weight * weight
+	}
+	for _, weight := range b {
+		normB += weight * weight
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
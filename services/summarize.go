@@ -0,0 +1,238 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+
+	"github.com/Ammar0144/ai/models"
+)
+
+// Summarization limits and token budgets
+const (
+	maxSummarizeInputTokens = 100000
+	maxSummarizeFocusTokens = 64
+	maxSummarizeParallel    = 4
+	maxSummarizeLevels      = 6
+	partialSummaryTokens    = 120
+	summaryPromptOverhead   = 64
+	summaryChunkOverlap     = 16
+)
+
+// summaryLength is the output budget and instruction for a summary length
+type summaryLength struct {
+	tokens  int
+	prose   string
+	bullets int
+}
+
+var summaryLengths = map[string]summaryLength{
+	models.SummaryShort:  {tokens: 60, prose: "in two or three sentences", bullets: 3},
+	models.SummaryMedium: {tokens: 150, prose: "in one paragraph", bullets: 5},
+	models.SummaryLong:   {tokens: 300, prose: "in several paragraphs", bullets: 8},
+}
+
+// bulletMarker matches list markers the model may put before a bullet
+var bulletMarker = regexp.MustCompile(`^\s*([-*•]|\d+[.)])\s*`)
+
+// SummaryPlan is a validated summarization request
+type SummaryPlan struct {
+	text        string
+	length      summaryLength
+	style       string
+	focus       string
+	temperature float64
+	chunkTokens int
+	finalBudget int
+
+	InputTokens int
+}
+
+// SummaryResult is a summary with the work it took
+type SummaryResult struct {
+	Summary string
+	Chunks  int
+	Levels  int
+	Calls   int
+}
+
+// PlanSummary validates a summarization request, filling in default length,
+// style and temperature. Chunks are sized so a map prompt and its partial
+// summary fit in the model context; the final prompt must leave room for the
+// summary itself.
+func PlanSummary(req *models.SummarizeRequest) (*SummaryPlan, error) {
+	if strings.TrimSpace(req.Text) == "" {
+		return nil, fmt.Errorf("text cannot be empty")
+	}
+	if req.Length == "" {
+		req.Length = models.SummaryMedium
+	}
+	length, ok := summaryLengths[req.Length]
+	if !ok {
+		return nil, fmt.Errorf("length must be short, medium or long")
+	}
+	if req.Style == "" {
+		req.Style = models.SummaryProse
+	}
+	if req.Style != models.SummaryProse && req.Style != models.SummaryBullets {
+		return nil, fmt.Errorf("style must be prose or bullets")
+	}
+	req.Focus = strings.TrimSpace(req.Focus)
+	focusTokens := EstimateTokens(req.Focus)
+	if focusTokens > maxSummarizeFocusTokens {
+		return nil, fmt.Errorf("focus cannot exceed %d tokens", maxSummarizeFocusTokens)
+	}
+	if req.Temperature == 0 {
+		req.Temperature = 0.7
+	}
+
+	plan := &SummaryPlan{
+		text:        strings.TrimSpace(req.Text),
+		length:      length,
+		style:       req.Style,
+		focus:       req.Focus,
+		temperature: req.Temperature,
+		chunkTokens: ModelContextTokens - partialSummaryTokens - summaryPromptOverhead - focusTokens,
+		finalBudget: ModelContextTokens - length.tokens - summaryPromptOverhead - focusTokens,
+	}
+	plan.InputTokens = EstimateTokens(plan.text)
+	if plan.InputTokens > maxSummarizeInputTokens {
+		return nil, fmt.Errorf("text cannot exceed %d tokens (got about %d)", maxSummarizeInputTokens, plan.InputTokens)
+	}
+	return plan, nil
+}
+
+// FirstCall returns the prompt and max tokens of the first model call and
+// how many chunks the first pass has; a text that fits the context is
+// summarized in one call
+func (p *SummaryPlan) FirstCall() (string, int, int) {
+	if p.InputTokens <= p.finalBudget {
+		return p.finalPrompt(p.text), p.length.tokens, 1
+	}
+	chunks := ChunkText(p.text, p.chunkTokens, summaryChunkOverlap)
+	return p.mapPrompt(chunks[0].Text, 1), partialSummaryTokens, len(chunks)
+}
+
+// Summarize runs map-reduce summarization: while the text is too long for
+// one prompt it is split into chunks that are summarized in parallel, and the
+// joined partial summaries become the text of the next level. The first
+// failed call cancels the rest.
+func (s *AIService) Summarize(ctx context.Context, plan *SummaryPlan) (*SummaryResult, error) {
+	result := &SummaryResult{}
+	text := plan.text
+	for level := 1; ; level++ {
+		result.Levels = level
+		tokens := EstimateTokens(text)
+		if tokens <= plan.finalBudget || level > maxSummarizeLevels {
+			if result.Chunks == 0 {
+				result.Chunks = 1
+			}
+			if tokens > plan.finalBudget {
+				log.Printf("Summarize: giving up reducing after %d levels, truncating %d tokens", maxSummarizeLevels, tokens)
+				text = TruncateToTokens(text, plan.finalBudget)
+			}
+			output, err := s.GetCompleteContext(ctx, plan.finalPrompt(text), plan.length.tokens, plan.temperature)
+			result.Calls++
+			if err != nil {
+				return result, err
+			}
+			result.Summary = plan.format(output)
+			return result, nil
+		}
+
+		chunks := ChunkText(text, plan.chunkTokens, summaryChunkOverlap)
+		if level == 1 {
+			result.Chunks = len(chunks)
+		}
+		log.Printf("Summarize: level %d, %d tokens in %d chunks", level, tokens, len(chunks))
+		partials, calls, err := s.summarizeChunks(ctx, plan, chunks, level)
+		result.Calls += calls
+		if err != nil {
+			return result, err
+		}
+
+		next := strings.Join(partials, "\n\n")
+		if next == "" {
+			return result, fmt.Errorf("model returned empty summaries")
+		}
+		if EstimateTokens(next) >= tokens {
+			// Summaries that do not shrink the text would never converge
+			level = maxSummarizeLevels
+		}
+		text = next
+	}
+}
+
+// summarizeChunks summarizes chunks concurrently, returning the non-empty
+// partial summaries in chunk order and the number of calls made
+func (s *AIService) summarizeChunks(ctx context.Context, plan *SummaryPlan, chunks []TextChunk, level int) ([]string, int, error) {
+	outputs := make([]string, len(chunks))
+	calls, err := runParallel(ctx, len(chunks), maxSummarizeParallel, func(ctx context.Context, i int) error {
+		output, err := s.GetCompleteContext(ctx, plan.mapPrompt(chunks[i].Text, level), partialSummaryTokens, plan.temperature)
+		if err != nil {
+			return err
+		}
+		outputs[i] = strings.TrimSpace(output)
+		return nil
+	})
+	if err != nil {
+		return nil, calls, fmt.Errorf("failed to summarize level %d: %w", level, err)
+	}
+
+	partials := make([]string, 0, len(outputs))
+	for _, output := range outputs {
+		if output != "" {
+			partials = append(partials, output)
+		}
+	}
+	return partials, calls, nil
+}
+
+func (p *SummaryPlan) focusClause() string {
+	if p.focus == "" {
+		return ""
+	}
+	return ", focusing only on what it says about: " + p.focus
+}
+
+// mapPrompt asks for a partial summary of a chunk; above the first level
+// the chunks are themselves summaries
+func (p *SummaryPlan) mapPrompt(chunk string, level int) string {
+	subject := "this part of a longer document"
+	if level > 1 {
+		subject = "these summaries of consecutive parts of a longer document as one shorter summary"
+	}
+	return fmt.Sprintf("Summarize %s%s. Keep names, numbers and conclusions.\n\nText:\n%s\n\nSummary:", subject, p.focusClause(), chunk)
+}
+
+// finalPrompt asks for the summary in the requested length and style. The
+// bullet prompt starts the first bullet so the model continues the list.
+func (p *SummaryPlan) finalPrompt(text string) string {
+	if p.style == models.SummaryBullets {
+		return fmt.Sprintf("Summarize the following text as %d bullet points%s.\n\nText:\n%s\n\nSummary:\n- ", p.length.bullets, p.focusClause(), text)
+	}
+	return fmt.Sprintf("Summarize the following text %s%s.\n\nText:\n%s\n\nSummary:", p.length.prose, p.focusClause(), text)
+}
+
+// format tidies the final output: bullet summaries get one "- " item per
+// non-empty line, up to the requested count
+func (p *SummaryPlan) format(output string) string {
+	output = strings.TrimSpace(output)
+	if p.style != models.SummaryBullets {
+		return output
+	}
+	var bullets []string
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(bulletMarker.ReplaceAllString(line, ""))
+		if line == "" {
+			continue
+		}
+		bullets = append(bullets, "- "+line)
+		if len(bullets) == p.length.bullets {
+			break
+		}
+	}
+	return strings.Join(bullets, "\n")
+}
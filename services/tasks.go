@@ -0,0 +1,239 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/Ammar0144/ai/models"
+)
+
+// defaultTaskRateLimit matches the per-minute limit of the built-in AI endpoints
+const defaultTaskRateLimit = 30
+
+// listMarkerPattern strips bullets and numbering from list-style output lines
+var listMarkerPattern = regexp.MustCompile(`^\s*(?:[-*•]|\d+[.)])\s+`)
+
+// taskFuncs are the helpers available to inline task prompts
+var taskFuncs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+}
+
+// LoadTaskDefinitions reads task definitions from the JSON file named by
+// AI_TASKS_CONFIG. Invalid definitions are logged and skipped so that one bad
+// entry does not take the other endpoints down.
+func LoadTaskDefinitions() []models.TaskDefinition {
+	path := os.Getenv("AI_TASKS_CONFIG")
+	if path == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		log.Printf("Tasks: failed to read %s: %v", path, err)
+		return nil
+	}
+
+	// Accept either a bare array or {"tasks": [...]}
+	var definitions []models.TaskDefinition
+	if err := json.Unmarshal(data, &definitions); err != nil {
+		var wrapped struct {
+			Tasks []models.TaskDefinition `json:"tasks"`
+		}
+		if err := json.Unmarshal(data, &wrapped); err != nil {
+			log.Printf("Tasks: failed to parse %s: %v", path, err)
+			return nil
+		}
+		definitions = wrapped.Tasks
+	}
+
+	seen := make(map[string]bool)
+	valid := make([]models.TaskDefinition, 0, len(definitions))
+	for i := range definitions {
+		def := definitions[i]
+		if err := ValidateTaskDefinition(&def); err != nil {
+			log.Printf("Tasks: skipping task %d (%q): %v", i, def.Name, err)
+			continue
+		}
+		if seen[def.Name] {
+			log.Printf("Tasks: skipping duplicate task %q", def.Name)
+			continue
+		}
+		seen[def.Name] = true
+		valid = append(valid, def)
+	}
+	log.Printf("Tasks: loaded %d task endpoints from %s", len(valid), path)
+	return valid
+}
+
+// ValidateTaskDefinition checks a task definition and fills in defaults
+func ValidateTaskDefinition(def *models.TaskDefinition) error {
+	if !templateIDPattern.MatchString(def.Name) {
+		return fmt.Errorf("name must be a lowercase slug")
+	}
+
+	switch def.Upstream {
+	case "":
+		def.Upstream = models.TaskUpstreamComplete
+	case models.TaskUpstreamComplete, models.TaskUpstreamGenerate, models.TaskUpstreamChat:
+	default:
+		return fmt.Errorf("unsupported upstream %q", def.Upstream)
+	}
+
+	switch {
+	case def.TemplateID != "" && def.Prompt != "":
+		return fmt.Errorf("set either prompt or template_id, not both")
+	case def.TemplateID == "" && strings.TrimSpace(def.Prompt) == "":
+		return fmt.Errorf("prompt or template_id is required")
+	case def.Prompt != "":
+		if _, err := template.New(def.Name).Funcs(taskFuncs).Parse(def.Prompt); err != nil {
+			return fmt.Errorf("invalid prompt template: %w", err)
+		}
+	}
+
+	if def.InputSchema != nil {
+		if err := CheckSchema(def.InputSchema); err != nil {
+			return fmt.Errorf("invalid input_schema: %w", err)
+		}
+	}
+
+	switch def.Output.Parser {
+	case "":
+		def.Output.Parser = models.TaskOutputText
+	case models.TaskOutputText, models.TaskOutputJSON, models.TaskOutputLines:
+	case models.TaskOutputRegex:
+		if def.Output.Pattern == "" {
+			return fmt.Errorf("regex output parser requires a pattern")
+		}
+		if _, err := regexp.Compile(def.Output.Pattern); err != nil {
+			return fmt.Errorf("invalid output pattern: %w", err)
+		}
+	default:
+		return fmt.Errorf("unsupported output parser %q", def.Output.Parser)
+	}
+
+	if def.RateLimit < 0 {
+		return fmt.Errorf("rate_limit cannot be negative")
+	}
+	if def.RateLimit == 0 {
+		def.RateLimit = defaultTaskRateLimit
+	}
+	if def.MaxTokens < 0 {
+		return fmt.Errorf("max_tokens cannot be negative")
+	}
+	return nil
+}
+
+// RenderTaskPrompt renders the prompt for a task from validated input, using
+// the stored template when the task references one
+func RenderTaskPrompt(def models.TaskDefinition, templates *TemplateStore, input map[string]interface{}) (string, error) {
+	if def.TemplateID != "" {
+		prompt, _, err := templates.Render(def.TemplateID, 0, input)
+		return prompt, err
+	}
+
+	// Declared but omitted optional fields render as empty strings
+	data := make(map[string]interface{}, len(input))
+	for _, name := range SchemaProperties(def.InputSchema) {
+		data[name] = ""
+	}
+	for name, value := range input {
+		data[name] = value
+	}
+
+	parsed, err := template.New(def.Name).Funcs(taskFuncs).Option("missingkey=error").Parse(def.Prompt)
+	if err != nil {
+		return "", fmt.Errorf("invalid prompt template: %w", err)
+	}
+	var out strings.Builder
+	if err := parsed.Execute(&out, data); err != nil {
+		return "", fmt.Errorf("failed to render prompt: %w", err)
+	}
+	return out.String(), nil
+}
+
+// CallUpstream sends a prompt to one of the LLM endpoints by name. The system
+// message is only used by the chat upstream.
+func (s *AIService) CallUpstream(upstream, system, prompt string, maxTokens int, temperature float64) (string, error) {
+	return s.CallUpstreamContext(context.Background(), upstream, system, prompt, maxTokens, temperature)
+}
+
+// CallUpstreamContext is CallUpstream with a caller-controlled context
+func (s *AIService) CallUpstreamContext(ctx context.Context, upstream, system, prompt string, maxTokens int, temperature float64) (string, error) {
+	switch upstream {
+	case models.TaskUpstreamGenerate:
+		return s.GetGenerateContext(ctx, prompt, maxTokens, temperature)
+	case models.TaskUpstreamChat:
+		return s.GetChatCompletionContext(ctx, upstreamChatMessages(system, prompt), maxTokens, temperature)
+	default:
+		return s.GetCompleteContext(ctx, prompt, maxTokens, temperature)
+	}
+}
+
+// ParseTaskOutput converts raw model output according to the task's parser
+func ParseTaskOutput(output models.TaskOutput, raw string) (interface{}, error) {
+	text := strings.TrimSpace(raw)
+	switch output.Parser {
+	case models.TaskOutputJSON:
+		return extractJSONValue(text)
+
+	case models.TaskOutputLines:
+		lines := []string{}
+		for _, line := range strings.Split(text, "\n") {
+			line = strings.TrimSpace(listMarkerPattern.ReplaceAllString(line, ""))
+			if line != "" {
+				lines = append(lines, line)
+			}
+		}
+		return lines, nil
+
+	case models.TaskOutputRegex:
+		pattern, err := regexp.Compile(output.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid output pattern: %w", err)
+		}
+		match := pattern.FindStringSubmatch(text)
+		if match == nil {
+			return nil, fmt.Errorf("output did not match pattern")
+		}
+		fields := make(map[string]string)
+		for i, name := range pattern.SubexpNames() {
+			if i > 0 && name != "" {
+				fields[name] = match[i]
+			}
+		}
+		if len(fields) > 0 {
+			return fields, nil
+		}
+		if len(match) > 1 {
+			return match[1], nil
+		}
+		return match[0], nil
+
+	default:
+		return text, nil
+	}
+}
+
+// extractJSONValue returns the first JSON object or array found in text
+func extractJSONValue(text string) (interface{}, error) {
+	for i, r := range text {
+		if r != '{' && r != '[' {
+			continue
+		}
+		var value interface{}
+		if err := json.NewDecoder(strings.NewReader(text[i:])).Decode(&value); err == nil {
+			return value, nil
+		}
+	}
+	return nil, errors.New("output contains no JSON value")
+}
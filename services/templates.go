@@ -0,0 +1,437 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/Ammar0144/ai/models"
+)
+
+// Template errors
+var (
+	ErrTemplateNotFound = errors.New("template not found")
+	ErrTemplateExists   = errors.New("template already exists")
+	ErrVersionNotFound  = errors.New("template version not found")
+	// ErrInvalidTemplateRequest marks errors caused by the request rather
+	// than the store
+	ErrInvalidTemplateRequest = errors.New("invalid template request")
+)
+
+// templateIDPattern restricts template IDs to URL-safe slugs
+var templateIDPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+// TemplateStore is a thread-safe registry of versioned prompt templates,
+// optionally persisted to a JSON file
+type TemplateStore struct {
+	templates map[string]*models.PromptTemplate
+	path      string
+	mutex     sync.RWMutex
+}
+
+// NewTemplateStore creates a template store, loading AI_TEMPLATE_FILE when set
+func NewTemplateStore() *TemplateStore {
+	store := &TemplateStore{
+		templates: make(map[string]*models.PromptTemplate),
+		path:      os.Getenv("AI_TEMPLATE_FILE"),
+	}
+
+	if store.path != "" {
+		data, err := os.ReadFile(store.path)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+		case err != nil:
+			log.Printf("Template store: failed to read %s: %v", store.path, err)
+		default:
+			var templates []*models.PromptTemplate
+			if err := json.Unmarshal(data, &templates); err != nil {
+				log.Printf("Template store: failed to parse %s: %v", store.path, err)
+			}
+			for _, tmpl := range templates {
+				store.templates[tmpl.ID] = tmpl
+			}
+			log.Printf("Template store: loaded %d templates from %s", len(templates), store.path)
+		}
+	}
+	return store
+}
+
+// save persists the registry when a file is configured; callers hold the lock
+func (t *TemplateStore) save() error {
+	if t.path == "" {
+		return nil
+	}
+	templates := make([]*models.PromptTemplate, 0, len(t.templates))
+	for _, tmpl := range t.templates {
+		templates = append(templates, tmpl)
+	}
+	sort.Slice(templates, func(i, j int) bool { return templates[i].ID < templates[j].ID })
+	return writeJSONFileAtomic(t.path, templates)
+}
+
+// Create registers a new template with its first version
+func (t *TemplateStore) Create(req models.PromptTemplateRequest) (*models.PromptTemplate, error) {
+	id := strings.ToLower(strings.TrimSpace(req.ID))
+	if id == "" {
+		id = slugify(req.Name)
+	}
+	if !templateIDPattern.MatchString(id) {
+		return nil, fmt.Errorf("%w: invalid template id %q: use lowercase letters, digits, '-' and '_'", ErrInvalidTemplateRequest, id)
+	}
+	version, err := newTemplateVersion(1, req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTemplateRequest, err)
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if _, exists := t.templates[id]; exists {
+		return nil, ErrTemplateExists
+	}
+
+	now := time.Now()
+	tmpl := &models.PromptTemplate{
+		ID:            id,
+		Name:          req.Name,
+		Description:   req.Description,
+		ActiveVersion: 1,
+		Versions:      []models.TemplateVersion{version},
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	t.templates[id] = tmpl
+	if err := t.save(); err != nil {
+		delete(t.templates, id)
+		return nil, err
+	}
+	return cloneTemplate(tmpl), nil
+}
+
+// AddVersion stores a new version of an existing template and activates it
+func (t *TemplateStore) AddVersion(id string, req models.PromptTemplateRequest) (*models.PromptTemplate, error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	tmpl, ok := t.templates[id]
+	if !ok {
+		return nil, ErrTemplateNotFound
+	}
+
+	version, err := newTemplateVersion(latestVersion(tmpl)+1, req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTemplateRequest, err)
+	}
+	original := *tmpl
+	tmpl.Versions = append(tmpl.Versions, version)
+	tmpl.ActiveVersion = version.Version
+	if req.Name != "" {
+		tmpl.Name = req.Name
+	}
+	if req.Description != "" {
+		tmpl.Description = req.Description
+	}
+	tmpl.UpdatedAt = time.Now()
+
+	if err := t.save(); err != nil {
+		*tmpl = original
+		return nil, err
+	}
+	return cloneTemplate(tmpl), nil
+}
+
+// Rollback restores a prior version by copying it forward as a new version,
+// so the history stays linear and auditable
+func (t *TemplateStore) Rollback(id string, version int) (*models.PromptTemplate, error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	tmpl, ok := t.templates[id]
+	if !ok {
+		return nil, ErrTemplateNotFound
+	}
+	previous, err := findVersion(tmpl, version)
+	if err != nil {
+		return nil, err
+	}
+
+	original := *tmpl
+	restored := *previous
+	restored.Version = latestVersion(tmpl) + 1
+	restored.Note = fmt.Sprintf("rollback to version %d", version)
+	restored.CreatedAt = time.Now()
+	tmpl.Versions = append(tmpl.Versions, restored)
+	tmpl.ActiveVersion = restored.Version
+	tmpl.UpdatedAt = restored.CreatedAt
+
+	if err := t.save(); err != nil {
+		*tmpl = original
+		return nil, err
+	}
+	return cloneTemplate(tmpl), nil
+}
+
+// Get returns a template by ID
+func (t *TemplateStore) Get(id string) (*models.PromptTemplate, error) {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+
+	tmpl, ok := t.templates[id]
+	if !ok {
+		return nil, ErrTemplateNotFound
+	}
+	return cloneTemplate(tmpl), nil
+}
+
+// List returns all templates sorted by ID
+func (t *TemplateStore) List() []*models.PromptTemplate {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+
+	templates := make([]*models.PromptTemplate, 0, len(t.templates))
+	for _, tmpl := range t.templates {
+		templates = append(templates, cloneTemplate(tmpl))
+	}
+	sort.Slice(templates, func(i, j int) bool { return templates[i].ID < templates[j].ID })
+	return templates
+}
+
+// Delete removes a template and its history
+func (t *TemplateStore) Delete(id string) error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	tmpl, ok := t.templates[id]
+	if !ok {
+		return ErrTemplateNotFound
+	}
+	delete(t.templates, id)
+	if err := t.save(); err != nil {
+		t.templates[id] = tmpl
+		return err
+	}
+	return nil
+}
+
+// Render renders a template version (the active one when version is 0)
+func (t *TemplateStore) Render(id string, version int, variables map[string]interface{}) (string, int, error) {
+	tmpl, err := t.Get(id)
+	if err != nil {
+		return "", 0, err
+	}
+	if version == 0 {
+		version = tmpl.ActiveVersion
+	}
+	selected, err := findVersion(tmpl, version)
+	if err != nil {
+		return "", 0, err
+	}
+
+	prompt, err := RenderTemplateBody(selected.Body, selected.Variables, variables)
+	if err != nil {
+		return "", 0, fmt.Errorf("%w: %v", ErrInvalidTemplateRequest, err)
+	}
+	return prompt, version, nil
+}
+
+// RenderTemplateBody validates and coerces variables against their
+// declarations and executes the body as a Go text/template
+func RenderTemplateBody(body string, declared []models.TemplateVariable, values map[string]interface{}) (string, error) {
+	parsed, err := template.New("prompt").Option("missingkey=error").Parse(body)
+	if err != nil {
+		return "", fmt.Errorf("invalid template: %w", err)
+	}
+
+	data := make(map[string]interface{}, len(declared))
+	known := make(map[string]bool, len(declared))
+	for _, variable := range declared {
+		known[variable.Name] = true
+		value, ok := values[variable.Name]
+		if !ok || value == nil {
+			if variable.Default != nil {
+				value = variable.Default
+			} else if variable.Required {
+				return "", fmt.Errorf("missing required variable %q", variable.Name)
+			} else {
+				value = zeroValue(variable.Type)
+			}
+		}
+		coerced, err := coerceVariable(variable, value)
+		if err != nil {
+			return "", err
+		}
+		data[variable.Name] = coerced
+	}
+	for name := range values {
+		if !known[name] {
+			return "", fmt.Errorf("unknown variable %q", name)
+		}
+	}
+
+	var out strings.Builder
+	if err := parsed.Execute(&out, data); err != nil {
+		return "", fmt.Errorf("failed to render template: %w", err)
+	}
+	return out.String(), nil
+}
+
+// newTemplateVersion validates a request and builds a version from it
+func newTemplateVersion(number int, req models.PromptTemplateRequest) (models.TemplateVersion, error) {
+	if strings.TrimSpace(req.Body) == "" {
+		return models.TemplateVersion{}, fmt.Errorf("template body cannot be empty")
+	}
+	if _, err := template.New("prompt").Parse(req.Body); err != nil {
+		return models.TemplateVersion{}, fmt.Errorf("invalid template: %w", err)
+	}
+
+	seen := make(map[string]bool)
+	for i, variable := range req.Variables {
+		if variable.Name == "" {
+			return models.TemplateVersion{}, fmt.Errorf("variables[%d]: name is required", i)
+		}
+		if seen[variable.Name] {
+			return models.TemplateVersion{}, fmt.Errorf("variables[%d]: duplicate name %q", i, variable.Name)
+		}
+		seen[variable.Name] = true
+		switch variable.Type {
+		case "", "string", "number", "integer", "boolean":
+		default:
+			return models.TemplateVersion{}, fmt.Errorf("variables[%d]: unsupported type %q", i, variable.Type)
+		}
+		if variable.Default != nil {
+			if _, err := coerceVariable(variable, variable.Default); err != nil {
+				return models.TemplateVersion{}, fmt.Errorf("variables[%d]: invalid default: %w", i, err)
+			}
+		}
+	}
+
+	return models.TemplateVersion{
+		Version:   number,
+		Body:      req.Body,
+		Variables: req.Variables,
+		Note:      req.Note,
+		CreatedAt: time.Now(),
+	}, nil
+}
+
+// coerceVariable converts a JSON value to the declared variable type
+func coerceVariable(variable models.TemplateVariable, value interface{}) (interface{}, error) {
+	switch variable.Type {
+	case "", "string":
+		switch v := value.(type) {
+		case string:
+			return v, nil
+		case float64:
+			return strconv.FormatFloat(v, 'f', -1, 64), nil
+		case bool:
+			return strconv.FormatBool(v), nil
+		}
+	case "number":
+		switch v := value.(type) {
+		case float64:
+			return v, nil
+		case string:
+			if f, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+				return f, nil
+			}
+		}
+	case "integer":
+		switch v := value.(type) {
+		case float64:
+			if v == math.Trunc(v) {
+				return int64(v), nil
+			}
+		case string:
+			if n, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64); err == nil {
+				return n, nil
+			}
+		}
+	case "boolean":
+		switch v := value.(type) {
+		case bool:
+			return v, nil
+		case string:
+			if b, err := strconv.ParseBool(strings.TrimSpace(v)); err == nil {
+				return b, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("variable %q must be of type %s", variable.Name, typeName(variable.Type))
+}
+
+// zeroValue returns the empty value for an optional variable without a default
+func zeroValue(variableType string) interface{} {
+	switch variableType {
+	case "number":
+		return float64(0)
+	case "integer":
+		return float64(0)
+	case "boolean":
+		return false
+	}
+	return ""
+}
+
+func typeName(variableType string) string {
+	if variableType == "" {
+		return "string"
+	}
+	return variableType
+}
+
+func findVersion(tmpl *models.PromptTemplate, version int) (*models.TemplateVersion, error) {
+	for i := range tmpl.Versions {
+		if tmpl.Versions[i].Version == version {
+			return &tmpl.Versions[i], nil
+		}
+	}
+	return nil, ErrVersionNotFound
+}
+
+func latestVersion(tmpl *models.PromptTemplate) int {
+	latest := 0
+	for _, version := range tmpl.Versions {
+		if version.Version > latest {
+			latest = version.Version
+		}
+	}
+	return latest
+}
+
+// cloneTemplate deep-copies a template so callers cannot mutate stored state
+func cloneTemplate(tmpl *models.PromptTemplate) *models.PromptTemplate {
+	copied := *tmpl
+	copied.Versions = make([]models.TemplateVersion, len(tmpl.Versions))
+	for i, version := range tmpl.Versions {
+		version.Variables = append([]models.TemplateVariable(nil), version.Variables...)
+		copied.Versions[i] = version
+	}
+	return &copied
+}
+
+// slugify turns a display name into a template ID
+func slugify(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(strings.TrimSpace(name)) {
+		switch {
+		case (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9'):
+			b.WriteRune(r)
+			dash = false
+		case !dash && b.Len() > 0:
+			b.WriteRune('-')
+			dash = true
+		}
+	}
+	return strings.TrimSuffix(b.String(), "-")
+}
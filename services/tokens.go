@@ -0,0 +1,109 @@
+package services
+
+import (
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/Ammar0144/ai/models"
+)
+
+// ModelContextTokens is the context window of the default model (distilgpt2)
+const ModelContextTokens = 1024
+
+// messageOverheadTokens approximates the per-message cost of role markers
+const messageOverheadTokens = 4
+
+// EstimateTokens approximates the number of BPE tokens in text. Words count as
+// one token plus one per six further characters, and every punctuation or
+// symbol character counts as its own token, which tracks GPT-2's tokenizer
+// closely enough for budgeting.
+func EstimateTokens(text string) int {
+	tokens := 0
+	wordLength := 0
+	flush := func() {
+		if wordLength > 0 {
+			tokens += 1 + (wordLength-1)/6
+			wordLength = 0
+		}
+	}
+
+	for _, r := range text {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			wordLength++
+		case unicode.IsSpace(r):
+			flush()
+		default:
+			flush()
+			tokens++
+		}
+	}
+	flush()
+	return tokens
+}
+
+// EstimateMessagesTokens approximates the tokens used by a chat history
+func EstimateMessagesTokens(messages []models.ChatMessage) int {
+	total := 0
+	for _, msg := range messages {
+		total += messageOverheadTokens + EstimateTokens(msg.Content)
+		for _, call := range msg.ToolCalls {
+			total += EstimateTokens(call.Function.Name) + EstimateTokens(call.Function.Arguments)
+		}
+	}
+	return total
+}
+
+// TruncateToTokens returns the longest prefix of text that fits in maxTokens
+// according to EstimateTokens
+func TruncateToTokens(text string, maxTokens int) string {
+	tokens := 0
+	wordLength := 0
+	for i, r := range text {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			// A word costs one token, plus one per six further characters
+			if wordLength == 0 || wordLength%6 == 0 {
+				tokens++
+			}
+			wordLength++
+		case unicode.IsSpace(r):
+			wordLength = 0
+		default:
+			wordLength = 0
+			tokens++
+		}
+		if tokens > maxTokens {
+			return text[:i]
+		}
+	}
+	return text
+}
+
+// TruncateTailToTokens returns the longest suffix of text that fits in
+// maxTokens according to EstimateTokens. Words are counted from their end,
+// which gives a cut word the same cost EstimateTokens gives it.
+func TruncateTailToTokens(text string, maxTokens int) string {
+	tokens := 0
+	wordLength := 0
+	for end := len(text); end > 0; {
+		r, size := utf8.DecodeLastRuneInString(text[:end])
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if wordLength == 0 || wordLength%6 == 0 {
+				tokens++
+			}
+			wordLength++
+		case unicode.IsSpace(r):
+			wordLength = 0
+		default:
+			wordLength = 0
+			tokens++
+		}
+		if tokens > maxTokens {
+			return text[end:]
+		}
+		end -= size
+	}
+	return text
+}
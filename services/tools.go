@@ -0,0 +1,424 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/Ammar0144/ai/models"
+)
+
+// Tool choice modes
+const (
+	ToolChoiceAuto     = "auto"
+	ToolChoiceNone     = "none"
+	ToolChoiceRequired = "required"
+	ToolChoiceFunction = "function"
+)
+
+// toolChoiceAttempts is how many completions are tried before a required tool
+// choice is reported as unmet
+const toolChoiceAttempts = 2
+
+// ErrToolChoiceNotMet is returned when the model does not make the tool call
+// that tool_choice requires
+var ErrToolChoiceNotMet = errors.New("model did not call the tool required by tool_choice")
+
+// ToolChoice is the normalized form of a request's tool_choice
+type ToolChoice struct {
+	Mode string
+	Name string
+}
+
+// ChatResult is the outcome of a chat completion that may include tool calls
+type ChatResult struct {
+	Content      string
+	ToolCalls    []models.ToolCall
+	FinishReason string
+}
+
+// enforce checks a result against the choice. Calls to other functions than a
+// named one are dropped; it reports false when no required call remains.
+func (c ToolChoice) enforce(result *ChatResult) bool {
+	switch c.Mode {
+	case ToolChoiceRequired:
+		return len(result.ToolCalls) > 0
+	case ToolChoiceFunction:
+		var calls []models.ToolCall
+		for _, call := range result.ToolCalls {
+			if call.Function.Name == c.Name {
+				calls = append(calls, call)
+			}
+		}
+		result.ToolCalls = calls
+		return len(calls) > 0
+	}
+	return true
+}
+
+// ParseToolChoice validates a raw tool_choice value against the declared tools
+func ParseToolChoice(raw interface{}, tools []models.Tool) (ToolChoice, error) {
+	switch value := raw.(type) {
+	case nil:
+		return ToolChoice{Mode: ToolChoiceAuto}, nil
+	case string:
+		switch value {
+		case ToolChoiceRequired:
+			if len(tools) == 0 {
+				return ToolChoice{}, fmt.Errorf("tool_choice %q requires tools", value)
+			}
+			return ToolChoice{Mode: value}, nil
+		case ToolChoiceAuto, ToolChoiceNone:
+			return ToolChoice{Mode: value}, nil
+		}
+		return ToolChoice{}, fmt.Errorf("invalid tool_choice %q", value)
+	case map[string]interface{}:
+		function, _ := value["function"].(map[string]interface{})
+		name, _ := function["name"].(string)
+		if name == "" {
+			return ToolChoice{}, fmt.Errorf("tool_choice function name is required")
+		}
+		if findTool(tools, name) == nil {
+			return ToolChoice{}, fmt.Errorf("tool_choice references unknown tool %q", name)
+		}
+		return ToolChoice{Mode: ToolChoiceFunction, Name: name}, nil
+	}
+	return ToolChoice{}, fmt.Errorf("invalid tool_choice")
+}
+
+// ValidateTools checks tool definitions for required fields and duplicates
+func ValidateTools(tools []models.Tool) error {
+	seen := make(map[string]bool)
+	for i, tool := range tools {
+		if tool.Type != "" && tool.Type != "function" {
+			return fmt.Errorf("tools[%d]: unsupported type %q", i, tool.Type)
+		}
+		if tool.Function.Name == "" {
+			return fmt.Errorf("tools[%d]: function name is required", i)
+		}
+		if seen[tool.Function.Name] {
+			return fmt.Errorf("tools[%d]: duplicate function name %q", i, tool.Function.Name)
+		}
+		seen[tool.Function.Name] = true
+	}
+	return nil
+}
+
+// GetChatCompletionWithTools generates a chat completion that may call tools.
+// Upstreams with the "tools" capability receive the tools natively; for all
+// others the tools are described in a system prompt and calls are parsed
+// back out of the generated text.
+func (s *AIService) GetChatCompletionWithTools(messages []models.ChatMessage, tools []models.Tool, choice ToolChoice, maxTokens int, temperature float64) (*ChatResult, error) {
+	return s.GetChatCompletionWithToolsContext(context.Background(), messages, tools, choice, maxTokens, temperature)
+}
+
+// GetChatCompletionWithToolsContext is GetChatCompletionWithTools with a caller-controlled context.
+// When tool_choice requires a call and the model answers without one, the
+// completion is retried once before ErrToolChoiceNotMet is returned.
+func (s *AIService) GetChatCompletionWithToolsContext(ctx context.Context, messages []models.ChatMessage, tools []models.Tool, choice ToolChoice, maxTokens int, temperature float64) (*ChatResult, error) {
+	if len(messages) == 0 {
+		return nil, fmt.Errorf("messages cannot be empty")
+	}
+
+	if len(tools) == 0 || choice.Mode == ToolChoiceNone {
+		return s.toolCompletion(ctx, messages, nil, choice, maxTokens, temperature)
+	}
+	for attempt := 1; ; attempt++ {
+		result, err := s.toolCompletion(ctx, messages, tools, choice, maxTokens, temperature)
+		if err != nil {
+			return nil, err
+		}
+		if choice.enforce(result) {
+			return result, nil
+		}
+		if attempt == toolChoiceAttempts {
+			return nil, ErrToolChoiceNotMet
+		}
+		log.Printf("GetChatCompletionWithTools: no call matching tool_choice %s %s, retrying", choice.Mode, choice.Name)
+	}
+}
+
+// toolCompletion runs one completion, natively or with emulated tools
+func (s *AIService) toolCompletion(ctx context.Context, messages []models.ChatMessage, tools []models.Tool, choice ToolChoice, maxTokens int, temperature float64) (*ChatResult, error) {
+	if s.HasCapability("tools") {
+		return s.nativeToolCompletion(ctx, messages, tools, choice, maxTokens, temperature)
+	}
+
+	prompted := emulatedToolMessages(messages, tools, choice)
+	content, err := s.GetChatCompletionContext(ctx, prompted, maxTokens, temperature)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(tools) == 0 {
+		return &ChatResult{Content: content, FinishReason: "stop"}, nil
+	}
+
+	remaining, calls := ParseToolCalls(content, tools)
+	if len(calls) == 0 {
+		return &ChatResult{Content: content, FinishReason: "stop"}, nil
+	}
+
+	log.Printf("GetChatCompletionWithTools: parsed %d emulated tool call(s)", len(calls))
+	return &ChatResult{Content: remaining, ToolCalls: calls, FinishReason: "tool_calls"}, nil
+}
+
+// nativeToolCompletion forwards tools to an upstream that supports them
+func (s *AIService) nativeToolCompletion(ctx context.Context, messages []models.ChatMessage, tools []models.Tool, choice ToolChoice, maxTokens int, temperature float64) (*ChatResult, error) {
+	request := chatRequestBody(messages, tools, choice, maxTokens, temperature)
+
+	response, err := s.callLLMEndpointWithJSONContext(ctx, "/chat/completions", request)
+	if err != nil {
+		log.Printf("nativeToolCompletion: LLM call failed: %v", err)
+		return nil, fmt.Errorf("chat completion failed: %w", err)
+	}
+
+	result := &ChatResult{FinishReason: "stop"}
+	if content, ok := response["content"].(string); ok {
+		result.Content = strings.TrimSpace(content)
+	} else if generatedText, ok := response["generated_text"].(string); ok {
+		result.Content = strings.TrimSpace(generatedText)
+	}
+
+	if rawCalls, ok := response["tool_calls"]; ok && rawCalls != nil {
+		data, err := json.Marshal(rawCalls)
+		if err == nil {
+			var calls []models.ToolCall
+			if err := json.Unmarshal(data, &calls); err == nil && len(calls) > 0 {
+				for i := range calls {
+					if calls[i].ID == "" {
+						calls[i].ID = NewID("call")
+					}
+					if calls[i].Type == "" {
+						calls[i].Type = "function"
+					}
+				}
+				result.ToolCalls = calls
+				result.FinishReason = "tool_calls"
+			}
+		}
+	}
+
+	if result.Content == "" && len(result.ToolCalls) == 0 {
+		return nil, fmt.Errorf("unexpected response format from LLM server")
+	}
+	return result, nil
+}
+
+// BuildToolPrompt describes the available tools and the expected call format
+func BuildToolPrompt(tools []models.Tool, choice ToolChoice) string {
+	var b strings.Builder
+	b.WriteString("You can call the following tools:\n")
+	for _, tool := range tools {
+		b.WriteString("- ")
+		b.WriteString(tool.Function.Name)
+		if tool.Function.Description != "" {
+			b.WriteString(": ")
+			b.WriteString(tool.Function.Description)
+		}
+		if len(tool.Function.Parameters) > 0 {
+			if params, err := json.Marshal(tool.Function.Parameters); err == nil {
+				b.WriteString(" Parameters (JSON Schema): ")
+				b.Write(params)
+			}
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("To call a tool, reply with only a JSON object of the form:\n")
+	b.WriteString(`{"tool_call": {"name": "<tool name>", "arguments": {<arguments>}}}`)
+	b.WriteString("\n")
+
+	switch choice.Mode {
+	case ToolChoiceRequired:
+		b.WriteString("You must call one of the tools.")
+	case ToolChoiceFunction:
+		b.WriteString(fmt.Sprintf("You must call the tool %q.", choice.Name))
+	default:
+		b.WriteString("If no tool is needed, answer the user directly.")
+	}
+	return b.String()
+}
+
+// flattenToolMessages rewrites tool-related messages into plain chat turns
+// for upstreams that only understand system, user and assistant roles
+func flattenToolMessages(messages []models.ChatMessage) []models.ChatMessage {
+	flattened := make([]models.ChatMessage, 0, len(messages))
+	for _, msg := range messages {
+		switch {
+		case msg.Role == "tool":
+			label := msg.Name
+			if label == "" {
+				label = msg.ToolCallID
+			}
+			flattened = append(flattened, models.ChatMessage{
+				Role:    "user",
+				Content: fmt.Sprintf("Tool result (%s): %s", label, msg.Content),
+			})
+		case len(msg.ToolCalls) > 0:
+			parts := []string{}
+			if strings.TrimSpace(msg.Content) != "" {
+				parts = append(parts, msg.Content)
+			}
+			for _, call := range msg.ToolCalls {
+				parts = append(parts, formatEmulatedCall(call))
+			}
+			flattened = append(flattened, models.ChatMessage{
+				Role:    msg.Role,
+				Content: strings.Join(parts, "\n"),
+			})
+		default:
+			flattened = append(flattened, models.ChatMessage{Role: msg.Role, Content: msg.Content})
+		}
+	}
+	return flattened
+}
+
+// formatEmulatedCall renders a tool call in the format the model is asked to emit
+func formatEmulatedCall(call models.ToolCall) string {
+	var args interface{} = map[string]interface{}{}
+	if call.Function.Arguments != "" {
+		if err := json.Unmarshal([]byte(call.Function.Arguments), &args); err != nil {
+			args = call.Function.Arguments
+		}
+	}
+	data, _ := json.Marshal(map[string]interface{}{
+		"tool_call": map[string]interface{}{
+			"name":      call.Function.Name,
+			"arguments": args,
+		},
+	})
+	return string(data)
+}
+
+// ParseToolCalls extracts emulated tool calls from generated text. It returns
+// the text with the tool call JSON removed and the calls that reference one
+// of the declared tools.
+func ParseToolCalls(text string, tools []models.Tool) (string, []models.ToolCall) {
+	var calls []models.ToolCall
+	var remaining strings.Builder
+	last := 0
+
+	for _, span := range findJSONObjects(text) {
+		var payload map[string]interface{}
+		if err := json.Unmarshal([]byte(text[span[0]:span[1]]), &payload); err != nil {
+			continue
+		}
+		found := extractCalls(payload, tools)
+		if len(found) == 0 {
+			continue
+		}
+		calls = append(calls, found...)
+		remaining.WriteString(text[last:span[0]])
+		last = span[1]
+	}
+	remaining.WriteString(text[last:])
+
+	return strings.TrimSpace(remaining.String()), calls
+}
+
+// extractCalls reads the call shapes a model is likely to produce
+func extractCalls(payload map[string]interface{}, tools []models.Tool) []models.ToolCall {
+	var candidates []interface{}
+	if call, ok := payload["tool_call"]; ok {
+		candidates = append(candidates, call)
+	} else if list, ok := payload["tool_calls"].([]interface{}); ok {
+		candidates = append(candidates, list...)
+	} else if _, ok := payload["name"]; ok {
+		candidates = append(candidates, payload)
+	}
+
+	var calls []models.ToolCall
+	for _, candidate := range candidates {
+		entry, ok := candidate.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		// Accept the OpenAI nesting {"function": {"name": ..., "arguments": ...}}
+		if function, ok := entry["function"].(map[string]interface{}); ok {
+			entry = function
+		}
+		name, _ := entry["name"].(string)
+		if findTool(tools, name) == nil {
+			continue
+		}
+
+		arguments := "{}"
+		switch args := entry["arguments"].(type) {
+		case string:
+			if json.Valid([]byte(args)) {
+				arguments = args
+			}
+		case nil:
+		default:
+			if data, err := json.Marshal(args); err == nil {
+				arguments = string(data)
+			}
+		}
+
+		calls = append(calls, models.ToolCall{
+			ID:   NewID("call"),
+			Type: "function",
+			Function: models.ToolCallFunction{
+				Name:      name,
+				Arguments: arguments,
+			},
+		})
+	}
+	return calls
+}
+
+// findJSONObjects returns the byte ranges of top-level balanced JSON objects
+func findJSONObjects(text string) [][2]int {
+	var spans [][2]int
+	depth := 0
+	start := -1
+	inString := false
+	escaped := false
+
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			}
+			continue
+		}
+		switch c {
+		case '"':
+			if depth > 0 {
+				inString = true
+			}
+		case '{':
+			if depth == 0 {
+				start = i
+			}
+			depth++
+		case '}':
+			if depth > 0 {
+				depth--
+				if depth == 0 {
+					spans = append(spans, [2]int{start, i + 1})
+				}
+			}
+		}
+	}
+	return spans
+}
+
+// findTool returns the declared tool with the given name
+func findTool(tools []models.Tool, name string) *models.Tool {
+	for i := range tools {
+		if tools[i].Function.Name == name {
+			return &tools[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package services
+
+import (
+	"github.com/Ammar0144/ai/models"
+)
+
+// UpstreamRequest is an HTTP request the service sends to the LLM server
+type UpstreamRequest struct {
+	Method   string
+	URL      string
+	Endpoint string
+	Body     interface{}
+	// Routing records the decisions that shaped the request
+	Routing []string
+	// Messages is the final chat history for chat requests
+	Messages []models.ChatMessage
+}
+
+// newLLMRequest builds a /complete or /generate body with default parameters
+func newLLMRequest(prompt string, maxTokens int, temperature float64) LLMRequest {
+	if maxTokens == 0 {
+		maxTokens = 150
+	}
+	if temperature == 0 {
+		temperature = 0.7
+	}
+	return LLMRequest{
+		Prompt:      prompt,
+		MaxLength:   maxTokens,
+		Temperature: temperature,
+		DoSample:    true,
+	}
+}
+
+// chatRequestBody builds a /chat/completions body, including tools and
+// tool_choice when the upstream handles them natively
+func chatRequestBody(messages []models.ChatMessage, tools []models.Tool, choice ToolChoice, maxTokens int, temperature float64) map[string]interface{} {
+	request := map[string]interface{}{
+		"messages":    messages,
+		"max_tokens":  maxTokens,
+		"temperature": temperature,
+	}
+	if len(tools) > 0 {
+		request["tools"] = tools
+		switch choice.Mode {
+		case ToolChoiceFunction:
+			request["tool_choice"] = map[string]interface{}{
+				"type":     "function",
+				"function": map[string]string{"name": choice.Name},
+			}
+		case "":
+		default:
+			request["tool_choice"] = choice.Mode
+		}
+	}
+	return request
+}
+
+// emulatedToolMessages flattens tool turns into plain text and prepends the
+// tool instructions for upstreams without native tool support
+func emulatedToolMessages(messages []models.ChatMessage, tools []models.Tool, choice ToolChoice) []models.ChatMessage {
+	prompted := flattenToolMessages(messages)
+	if len(tools) > 0 {
+		prompted = append([]models.ChatMessage{{
+			Role:    "system",
+			Content: BuildToolPrompt(tools, choice),
+		}}, prompted...)
+	}
+	return prompted
+}
+
+// newUpstreamRequest describes a POST to an LLM server endpoint
+func (s *AIService) newUpstreamRequest(endpoint string, body interface{}, routing ...string) UpstreamRequest {
+	return UpstreamRequest{
+		Method:   "POST",
+		URL:      s.llmBaseURL + endpoint,
+		Endpoint: endpoint,
+		Body:     body,
+		Routing:  routing,
+	}
+}
+
+// BuildCompleteRequest returns the request GetComplete would send
+func (s *AIService) BuildCompleteRequest(prompt string, maxTokens int, temperature float64) UpstreamRequest {
+	return s.newUpstreamRequest("/complete", newLLMRequest(prompt, maxTokens, temperature), "upstream: /complete")
+}
+
+// BuildGenerateRequest returns the request GetGenerate would send
+func (s *AIService) BuildGenerateRequest(prompt string, maxTokens int, temperature float64) UpstreamRequest {
+	return s.newUpstreamRequest("/generate", newLLMRequest(prompt, maxTokens, temperature), "upstream: /generate")
+}
+
+// BuildChatRequest returns the request GetChatCompletionWithTools would send,
+// recording whether tools are passed natively or emulated in the prompt
+func (s *AIService) BuildChatRequest(messages []models.ChatMessage, tools []models.Tool, choice ToolChoice, maxTokens int, temperature float64) UpstreamRequest {
+	if len(tools) == 0 || choice.Mode == ToolChoiceNone {
+		tools = nil
+	}
+
+	var request UpstreamRequest
+	switch {
+	case s.HasCapability("tools"):
+		request = s.newUpstreamRequest("/chat/completions", chatRequestBody(messages, tools, choice, maxTokens, temperature), "upstream: /chat/completions")
+		request.Messages = messages
+		if len(tools) > 0 {
+			request.Routing = append(request.Routing, "tools: passed natively (upstream has the tools capability)")
+		}
+	default:
+		prompted := emulatedToolMessages(messages, tools, choice)
+		request = s.newUpstreamRequest("/chat/completions", chatRequestBody(prompted, nil, ToolChoice{}, maxTokens, temperature), "upstream: /chat/completions")
+		request.Messages = prompted
+		if len(tools) > 0 {
+			request.Routing = append(request.Routing, "tools: emulated with a system prompt (upstream lacks the tools capability)")
+		}
+	}
+	return request
+}
+
+// BuildUpstreamRequest returns the request CallUpstream would send
+func (s *AIService) BuildUpstreamRequest(upstream, system, prompt string, maxTokens int, temperature float64) UpstreamRequest {
+	switch upstream {
+	case models.TaskUpstreamGenerate:
+		return s.BuildGenerateRequest(prompt, maxTokens, temperature)
+	case models.TaskUpstreamChat:
+		return s.BuildChatRequest(upstreamChatMessages(system, prompt), nil, ToolChoice{}, maxTokens, temperature)
+	default:
+		return s.BuildCompleteRequest(prompt, maxTokens, temperature)
+	}
+}
+
+// upstreamChatMessages wraps a prompt and optional system message as a chat
+func upstreamChatMessages(system, prompt string) []models.ChatMessage {
+	var messages []models.ChatMessage
+	if system != "" {
+		messages = append(messages, models.ChatMessage{Role: "system", Content: system})
+	}
+	return append(messages, models.ChatMessage{Role: "user", Content: prompt})
+}
@@ -0,0 +1,216 @@
+package services
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// ValidateFilter checks a metadata filter. Filters map field names to a value
+// (equality) or to an operator object with $eq, $ne, $in, $nin, $gt, $gte,
+// $lt, $lte or $exists; $and and $or take lists of filters. Nested metadata
+// fields are addressed with dots, e.g. "source.type".
+func ValidateFilter(filter map[string]interface{}) error {
+	for key, value := range filter {
+		switch key {
+		case "$and", "$or":
+			clauses, ok := value.([]interface{})
+			if !ok || len(clauses) == 0 {
+				return fmt.Errorf("%s must be a non-empty list of filters", key)
+			}
+			for _, clause := range clauses {
+				sub, ok := clause.(map[string]interface{})
+				if !ok {
+					return fmt.Errorf("%s must contain filter objects", key)
+				}
+				if err := ValidateFilter(sub); err != nil {
+					return err
+				}
+			}
+		default:
+			if strings.HasPrefix(key, "$") {
+				return fmt.Errorf("unsupported filter operator %s", key)
+			}
+			ops, ok := value.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			for op, operand := range ops {
+				switch op {
+				case "$eq", "$ne", "$gt", "$gte", "$lt", "$lte":
+				case "$in", "$nin":
+					if _, ok := operand.([]interface{}); !ok {
+						return fmt.Errorf("%s.%s must be a list", key, op)
+					}
+				case "$exists":
+					if _, ok := operand.(bool); !ok {
+						return fmt.Errorf("%s.$exists must be a boolean", key)
+					}
+				default:
+					return fmt.Errorf("unsupported filter operator %s", op)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// MatchFilter reports whether metadata satisfies a validated filter
+func MatchFilter(filter map[string]interface{}, metadata map[string]interface{}) bool {
+	for key, value := range filter {
+		switch key {
+		case "$and":
+			for _, clause := range value.([]interface{}) {
+				if !MatchFilter(clause.(map[string]interface{}), metadata) {
+					return false
+				}
+			}
+		case "$or":
+			matched := false
+			for _, clause := range value.([]interface{}) {
+				if MatchFilter(clause.(map[string]interface{}), metadata) {
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return false
+			}
+		default:
+			field, present := lookupField(metadata, key)
+			ops, isOps := value.(map[string]interface{})
+			if !isOps {
+				if !present || !filterEqual(field, value) {
+					return false
+				}
+				continue
+			}
+			for op, operand := range ops {
+				if !matchOperator(op, operand, field, present) {
+					return false
+				}
+			}
+		}
+	}
+	return true
+}
+
+// matchOperator applies one comparison operator to a field
+func matchOperator(op string, operand, field interface{}, present bool) bool {
+	switch op {
+	case "$exists":
+		return present == operand.(bool)
+	case "$eq":
+		return present && filterEqual(field, operand)
+	case "$ne":
+		return !present || !filterEqual(field, operand)
+	case "$in", "$nin":
+		found := false
+		if present {
+			for _, option := range operand.([]interface{}) {
+				if filterEqual(field, option) {
+					found = true
+					break
+				}
+			}
+		}
+		return found == (op == "$in")
+	case "$gt", "$gte", "$lt", "$lte":
+		if !present {
+			return false
+		}
+		cmp, ok := filterCompare(field, operand)
+		if !ok {
+			return false
+		}
+		switch op {
+		case "$gt":
+			return cmp > 0
+		case "$gte":
+			return cmp >= 0
+		case "$lt":
+			return cmp < 0
+		default:
+			return cmp <= 0
+		}
+	}
+	return false
+}
+
+// lookupField resolves a dotted path in nested metadata
+func lookupField(metadata map[string]interface{}, path string) (interface{}, bool) {
+	if value, ok := metadata[path]; ok {
+		return value, true
+	}
+	var current interface{} = metadata
+	for _, part := range strings.Split(path, ".") {
+		object, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if current, ok = object[part]; !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// filterEqual compares values; a list field matches when any element does
+func filterEqual(field, value interface{}) bool {
+	if list, ok := field.([]interface{}); ok {
+		if _, valueIsList := value.([]interface{}); !valueIsList {
+			for _, item := range list {
+				if filterEqual(item, value) {
+					return true
+				}
+			}
+			return false
+		}
+	}
+	if a, ok := toFloat(field); ok {
+		if b, ok := toFloat(value); ok {
+			return a == b
+		}
+	}
+	return reflect.DeepEqual(field, value)
+}
+
+// filterCompare orders two numbers or two strings
+func filterCompare(field, value interface{}) (int, bool) {
+	if a, ok := toFloat(field); ok {
+		b, ok := toFloat(value)
+		if !ok {
+			return 0, false
+		}
+		switch {
+		case a < b:
+			return -1, true
+		case a > b:
+			return 1, true
+		}
+		return 0, true
+	}
+	a, ok := field.(string)
+	if !ok {
+		return 0, false
+	}
+	b, ok := value.(string)
+	if !ok {
+		return 0, false
+	}
+	return strings.Compare(a, b), true
+}
+
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	}
+	return 0, false
+}
@@ -0,0 +1,659 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Ammar0144/ai/models"
+)
+
+// Vector store limits and tuning
+const (
+	defaultVectorTopK = 10
+	maxVectorTopK     = 100
+	maxVectorDims     = 4096
+	maxUpsertRecords  = 1000
+
+	// Collections this small are always scanned exactly
+	exactScanThreshold = 128
+	// Filters matching at most this many records are scanned exactly
+	filteredScanThreshold = 1000
+
+	vectorSnapshotInterval = 30 * time.Second
+)
+
+// Vector store errors
+var (
+	ErrCollectionNotFound = errors.New("collection not found")
+	ErrCollectionExists   = errors.New("collection already exists")
+	ErrRecordNotFound     = errors.New("record not found")
+	// ErrInvalidVectorRequest wraps validation failures of client input
+	ErrInvalidVectorRequest = errors.New("invalid vector request")
+)
+
+// VectorStore holds named collections of vectors, each with an HNSW index.
+// When AI_VECTOR_DIR is set, collections are loaded from snapshots at startup
+// and changed collections are snapshotted to disk every 30 seconds.
+type VectorStore struct {
+	collections map[string]*vectorCollection
+	dir         string
+	mutex       sync.RWMutex
+}
+
+type vectorCollection struct {
+	info    models.VectorCollection
+	records map[string]*vectorEntry
+	// nodeRecords maps index node IDs to record IDs
+	nodeRecords []string
+	index       *hnswIndex
+	dirty       bool
+	mutex       sync.RWMutex
+	// snapshotMutex orders snapshots so an older copy never replaces a newer
+	// one on disk
+	snapshotMutex sync.Mutex
+}
+
+type vectorEntry struct {
+	record models.VectorRecord
+	node   int
+}
+
+// vectorSnapshot is the on-disk form of a collection. The index is rebuilt on
+// load rather than stored.
+type vectorSnapshot struct {
+	Collection models.VectorCollection `json:"collection"`
+	Records    []models.VectorRecord   `json:"records"`
+}
+
+// NewVectorStore creates a vector store, loading snapshots from AI_VECTOR_DIR
+func NewVectorStore() *VectorStore {
+	store := &VectorStore{
+		collections: make(map[string]*vectorCollection),
+		dir:         os.Getenv("AI_VECTOR_DIR"),
+	}
+	if store.dir == "" {
+		return store
+	}
+
+	files, err := filepath.Glob(filepath.Join(store.dir, "*.json"))
+	if err != nil {
+		log.Printf("Vector store: failed to list %s: %v", store.dir, err)
+	}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			log.Printf("Vector store: failed to read %s: %v", file, err)
+			continue
+		}
+		var snapshot vectorSnapshot
+		if err := json.Unmarshal(data, &snapshot); err != nil {
+			log.Printf("Vector store: failed to parse %s: %v", file, err)
+			continue
+		}
+		collection := newVectorCollection(snapshot.Collection)
+		for _, record := range snapshot.Records {
+			if err := collection.upsert(record); err != nil {
+				log.Printf("Vector store: skipping record %s in %s: %v", record.ID, file, err)
+			}
+		}
+		collection.dirty = false
+		store.collections[collection.info.Name] = collection
+		log.Printf("Vector store: loaded collection %s with %d records", collection.info.Name, len(collection.records))
+	}
+
+	go store.snapshotLoop()
+	return store
+}
+
+// snapshotLoop periodically writes changed collections to disk
+func (v *VectorStore) snapshotLoop() {
+	ticker := time.NewTicker(vectorSnapshotInterval)
+	for range ticker.C {
+		v.mutex.RLock()
+		names := make([]string, 0, len(v.collections))
+		for name, collection := range v.collections {
+			collection.mutex.RLock()
+			if collection.dirty {
+				names = append(names, name)
+			}
+			collection.mutex.RUnlock()
+		}
+		v.mutex.RUnlock()
+
+		for _, name := range names {
+			if _, err := v.Snapshot(name); err != nil {
+				log.Printf("Vector store: failed to snapshot %s: %v", name, err)
+			}
+		}
+	}
+}
+
+func newVectorCollection(info models.VectorCollection) *vectorCollection {
+	collection := &vectorCollection{
+		info:    info,
+		records: make(map[string]*vectorEntry),
+	}
+	collection.index = newHNSWIndex(metricDistance(info.Metric))
+	return collection
+}
+
+// metricDistance returns the index distance for a metric, lower is closer.
+// Cosine vectors are normalized on insert, so cosine distance is 1 - dot.
+func metricDistance(metric string) func(a, b []float64) float64 {
+	switch metric {
+	case models.MetricDot:
+		return func(a, b []float64) float64 { return -dotProduct(a, b) }
+	case models.MetricL2:
+		return func(a, b []float64) float64 {
+			var sum float64
+			for i := range a {
+				d := a[i] - b[i]
+				sum += d * d
+			}
+			return sum
+		}
+	default:
+		return func(a, b []float64) float64 { return 1 - dotProduct(a, b) }
+	}
+}
+
+// metricScore converts an index distance to the score reported to clients
+func metricScore(metric string, distance float64) float64 {
+	switch metric {
+	case models.MetricDot:
+		return -distance
+	case models.MetricL2:
+		return math.Sqrt(distance)
+	default:
+		return 1 - distance
+	}
+}
+
+func dotProduct(a, b []float64) float64 {
+	var sum float64
+	for i := range a {
+		sum += a[i] * b[i]
+	}
+	return sum
+}
+
+// prepareVector validates a vector and returns the form stored in the index
+func (c *vectorCollection) prepareVector(vector []float64) ([]float64, error) {
+	if c.info.Dimensions != 0 && len(vector) != c.info.Dimensions {
+		return nil, fmt.Errorf("vector has %d dimensions, collection expects %d", len(vector), c.info.Dimensions)
+	}
+	var norm float64
+	for _, value := range vector {
+		if math.IsNaN(value) || math.IsInf(value, 0) {
+			return nil, fmt.Errorf("vector contains a non-finite value")
+		}
+		norm += value * value
+	}
+	prepared := append([]float64(nil), vector...)
+	if c.info.Metric == models.MetricCosine {
+		if norm == 0 {
+			return nil, fmt.Errorf("zero vector cannot be used with the cosine metric")
+		}
+		norm = math.Sqrt(norm)
+		for i := range prepared {
+			prepared[i] /= norm
+		}
+	}
+	return prepared, nil
+}
+
+// upsert inserts or replaces one record; callers hold the write lock
+func (c *vectorCollection) upsert(record models.VectorRecord) error {
+	prepared, err := c.prepareRecord(record)
+	if err != nil {
+		return err
+	}
+	c.insert(record, prepared)
+	return nil
+}
+
+// prepareRecord validates a record and returns its vector in index form
+func (c *vectorCollection) prepareRecord(record models.VectorRecord) ([]float64, error) {
+	if strings.TrimSpace(record.ID) == "" {
+		return nil, fmt.Errorf("record id is required")
+	}
+	if len(record.Vector) == 0 {
+		return nil, fmt.Errorf("record %s has no vector", record.ID)
+	}
+	if len(record.Vector) > maxVectorDims {
+		return nil, fmt.Errorf("vectors cannot have more than %d dimensions", maxVectorDims)
+	}
+	prepared, err := c.prepareVector(record.Vector)
+	if err != nil {
+		return nil, fmt.Errorf("record %s: %w", record.ID, err)
+	}
+	return prepared, nil
+}
+
+// insert stores a record prepared by prepareRecord; callers hold the write
+// lock
+func (c *vectorCollection) insert(record models.VectorRecord, prepared []float64) {
+	if c.info.Dimensions == 0 {
+		c.info.Dimensions = len(record.Vector)
+	}
+
+	if existing, ok := c.records[record.ID]; ok {
+		c.index.remove(existing.node)
+	}
+	node := c.index.insert(prepared)
+	c.nodeRecords = append(c.nodeRecords, record.ID)
+	record.Vector = append([]float64(nil), record.Vector...)
+	c.records[record.ID] = &vectorEntry{record: record, node: node}
+	c.dirty = true
+}
+
+// remove deletes one record; callers hold the write lock
+func (c *vectorCollection) remove(id string) bool {
+	entry, ok := c.records[id]
+	if !ok {
+		return false
+	}
+	c.index.remove(entry.node)
+	delete(c.records, id)
+	c.dirty = true
+	return true
+}
+
+// compact rebuilds the index once removed nodes outnumber live ones, since
+// removed nodes still cost traversal time; callers hold the write lock
+func (c *vectorCollection) compact() {
+	if c.index.removed < 1000 || c.index.removed < c.index.live() {
+		return
+	}
+	ids := make([]string, 0, len(c.records))
+	for id := range c.records {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	c.index = newHNSWIndex(metricDistance(c.info.Metric))
+	c.nodeRecords = c.nodeRecords[:0]
+	for _, id := range ids {
+		entry := c.records[id]
+		prepared, _ := c.prepareVector(entry.record.Vector)
+		entry.node = c.index.insert(prepared)
+		c.nodeRecords = append(c.nodeRecords, id)
+	}
+}
+
+// infoLocked returns the collection description; callers hold a lock
+func (c *vectorCollection) infoLocked() models.VectorCollection {
+	info := c.info
+	info.Count = len(c.records)
+	return info
+}
+
+// CreateCollection adds an empty collection
+func (v *VectorStore) CreateCollection(req models.CreateCollectionRequest) (models.VectorCollection, error) {
+	if !templateIDPattern.MatchString(req.Name) {
+		return models.VectorCollection{}, fmt.Errorf("%w: name must be a lowercase slug", ErrInvalidVectorRequest)
+	}
+	switch req.Metric {
+	case "":
+		req.Metric = models.MetricCosine
+	case models.MetricCosine, models.MetricDot, models.MetricL2:
+	default:
+		return models.VectorCollection{}, fmt.Errorf("%w: unsupported metric %q; use cosine, dot or l2", ErrInvalidVectorRequest, req.Metric)
+	}
+	if req.Dimensions < 0 || req.Dimensions > maxVectorDims {
+		return models.VectorCollection{}, fmt.Errorf("%w: dimensions must be between 1 and %d", ErrInvalidVectorRequest, maxVectorDims)
+	}
+
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	if _, exists := v.collections[req.Name]; exists {
+		return models.VectorCollection{}, ErrCollectionExists
+	}
+	now := time.Now()
+	collection := newVectorCollection(models.VectorCollection{
+		Name:           req.Name,
+		Dimensions:     req.Dimensions,
+		Metric:         req.Metric,
+		EmbeddingModel: req.EmbeddingModel,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	})
+	collection.dirty = true
+	v.collections[req.Name] = collection
+	return collection.infoLocked(), nil
+}
+
+// collection looks up a collection by name
+func (v *VectorStore) collection(name string) (*vectorCollection, error) {
+	v.mutex.RLock()
+	defer v.mutex.RUnlock()
+
+	collection, ok := v.collections[name]
+	if !ok {
+		return nil, ErrCollectionNotFound
+	}
+	return collection, nil
+}
+
+// GetCollection describes a collection
+func (v *VectorStore) GetCollection(name string) (models.VectorCollection, error) {
+	collection, err := v.collection(name)
+	if err != nil {
+		return models.VectorCollection{}, err
+	}
+	collection.mutex.RLock()
+	defer collection.mutex.RUnlock()
+	return collection.infoLocked(), nil
+}
+
+// ListCollections describes every collection, ordered by name
+func (v *VectorStore) ListCollections() []models.VectorCollection {
+	v.mutex.RLock()
+	defer v.mutex.RUnlock()
+
+	infos := make([]models.VectorCollection, 0, len(v.collections))
+	for _, collection := range v.collections {
+		collection.mutex.RLock()
+		infos = append(infos, collection.infoLocked())
+		collection.mutex.RUnlock()
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos
+}
+
+// DeleteCollection removes a collection and its snapshot
+func (v *VectorStore) DeleteCollection(name string) error {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	if _, ok := v.collections[name]; !ok {
+		return ErrCollectionNotFound
+	}
+	delete(v.collections, name)
+	if v.dir != "" {
+		if err := os.Remove(v.snapshotPath(name)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to remove snapshot: %w", err)
+		}
+	}
+	return nil
+}
+
+// CheckUpsertSize rejects a batch size Upsert refuses, so callers can check
+// it before embedding the records' text
+func CheckUpsertSize(count int) error {
+	if count == 0 {
+		return fmt.Errorf("%w: at least one record is required", ErrInvalidVectorRequest)
+	}
+	if count > maxUpsertRecords {
+		return fmt.Errorf("%w: at most %d records can be upserted at once", ErrInvalidVectorRequest, maxUpsertRecords)
+	}
+	return nil
+}
+
+// Upsert inserts or replaces records. Every record is validated before any is
+// applied, so a bad record leaves the collection unchanged.
+func (v *VectorStore) Upsert(name string, records []models.VectorRecord) (int, error) {
+	if err := CheckUpsertSize(len(records)); err != nil {
+		return 0, err
+	}
+	collection, err := v.collection(name)
+	if err != nil {
+		return 0, err
+	}
+
+	collection.mutex.Lock()
+	defer collection.mutex.Unlock()
+
+	dimensions := collection.info.Dimensions
+	prepared := make([][]float64, len(records))
+	for i, record := range records {
+		if strings.TrimSpace(record.ID) == "" {
+			return 0, fmt.Errorf("%w: record %d: id is required", ErrInvalidVectorRequest, i)
+		}
+		if dimensions == 0 {
+			dimensions = len(record.Vector)
+		}
+		if len(record.Vector) != dimensions {
+			return 0, fmt.Errorf("%w: record %s: vector has %d dimensions, collection expects %d", ErrInvalidVectorRequest, record.ID, len(record.Vector), dimensions)
+		}
+		if prepared[i], err = collection.prepareRecord(record); err != nil {
+			return 0, fmt.Errorf("%w: %v", ErrInvalidVectorRequest, err)
+		}
+	}
+	for i, record := range records {
+		collection.insert(record, prepared[i])
+	}
+	collection.info.UpdatedAt = time.Now()
+	collection.compact()
+	return len(collection.records), nil
+}
+
+// Delete removes records by ID and by metadata filter, returning the number
+// deleted and the number left
+func (v *VectorStore) Delete(name string, ids []string, filter map[string]interface{}) (int, int, error) {
+	if len(ids) == 0 && len(filter) == 0 {
+		return 0, 0, fmt.Errorf("%w: ids or filter is required", ErrInvalidVectorRequest)
+	}
+	if err := ValidateFilter(filter); err != nil {
+		return 0, 0, fmt.Errorf("%w: %v", ErrInvalidVectorRequest, err)
+	}
+	collection, err := v.collection(name)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	collection.mutex.Lock()
+	defer collection.mutex.Unlock()
+
+	deleted := 0
+	for _, id := range ids {
+		if collection.remove(id) {
+			deleted++
+		}
+	}
+	if len(filter) > 0 {
+		for id, entry := range collection.records {
+			if MatchFilter(filter, entry.record.Metadata) && collection.remove(id) {
+				deleted++
+			}
+		}
+	}
+	if deleted > 0 {
+		collection.info.UpdatedAt = time.Now()
+		collection.compact()
+	}
+	return deleted, len(collection.records), nil
+}
+
+// GetRecord returns one record
+func (v *VectorStore) GetRecord(name, id string) (models.VectorRecord, error) {
+	collection, err := v.collection(name)
+	if err != nil {
+		return models.VectorRecord{}, err
+	}
+	collection.mutex.RLock()
+	defer collection.mutex.RUnlock()
+
+	entry, ok := collection.records[id]
+	if !ok {
+		return models.VectorRecord{}, ErrRecordNotFound
+	}
+	return entry.record, nil
+}
+
+// Query returns the records closest to a vector, closest first. The HNSW
+// index is used unless the collection is small, the filter is selective or
+// an exact scan is requested; an index search that finds fewer than top_k
+// filtered matches falls back to a scan.
+func (v *VectorStore) Query(name string, req models.VectorQueryRequest) (*models.VectorQueryResponse, error) {
+	started := time.Now()
+	if err := ValidateFilter(req.Filter); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidVectorRequest, err)
+	}
+	topK := req.TopK
+	if topK == 0 {
+		topK = defaultVectorTopK
+	}
+	if topK < 0 || topK > maxVectorTopK {
+		return nil, fmt.Errorf("%w: top_k must be between 1 and %d", ErrInvalidVectorRequest, maxVectorTopK)
+	}
+	collection, err := v.collection(name)
+	if err != nil {
+		return nil, err
+	}
+
+	collection.mutex.RLock()
+	defer collection.mutex.RUnlock()
+
+	response := &models.VectorQueryResponse{
+		Collection: name,
+		Metric:     collection.info.Metric,
+		Matches:    []models.VectorMatch{},
+	}
+	if len(collection.records) == 0 {
+		return response, nil
+	}
+	if len(req.Vector) == 0 {
+		return nil, fmt.Errorf("%w: vector or text is required", ErrInvalidVectorRequest)
+	}
+	query, err := collection.prepareVector(req.Vector)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidVectorRequest, err)
+	}
+
+	var accept func(node int) bool
+	var filtered []*vectorEntry
+	exact := req.Exact || len(collection.records) <= exactScanThreshold
+	if len(req.Filter) > 0 {
+		for _, entry := range collection.records {
+			if MatchFilter(req.Filter, entry.record.Metadata) {
+				filtered = append(filtered, entry)
+			}
+		}
+		if len(filtered) <= filteredScanThreshold {
+			exact = true
+		}
+		accept = func(node int) bool {
+			entry, ok := collection.records[collection.nodeRecords[node]]
+			return ok && entry.node == node && MatchFilter(req.Filter, entry.record.Metadata)
+		}
+	}
+
+	var candidates []hnswCandidate
+	if !exact {
+		ef := hnswEfSearch
+		if topK*4 > ef {
+			ef = topK * 4
+		}
+		candidates = collection.index.search(query, topK, ef, accept)
+		if len(candidates) < topK && len(candidates) < collection.index.live() {
+			exact = true
+		}
+	}
+	if exact {
+		if filtered == nil && len(req.Filter) == 0 {
+			for _, entry := range collection.records {
+				filtered = append(filtered, entry)
+			}
+		}
+		candidates = collection.scan(query, filtered, topK)
+	}
+
+	for _, candidate := range candidates {
+		entry := collection.records[collection.nodeRecords[candidate.node]]
+		match := models.VectorMatch{
+			ID:       entry.record.ID,
+			Score:    metricScore(collection.info.Metric, candidate.distance),
+			Text:     entry.record.Text,
+			Metadata: entry.record.Metadata,
+		}
+		if req.IncludeVectors {
+			match.Vector = entry.record.Vector
+		}
+		response.Matches = append(response.Matches, match)
+	}
+	response.Exact = exact
+	response.TookMs = time.Since(started).Milliseconds()
+	return response, nil
+}
+
+// scan computes exact distances to the given entries; callers hold a lock
+func (c *vectorCollection) scan(query []float64, entries []*vectorEntry, topK int) []hnswCandidate {
+	candidates := make([]hnswCandidate, 0, len(entries))
+	for _, entry := range entries {
+		candidates = append(candidates, hnswCandidate{entry.node, c.index.distance(query, c.index.nodes[entry.node].vector)})
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].distance != candidates[j].distance {
+			return candidates[i].distance < candidates[j].distance
+		}
+		return c.nodeRecords[candidates[i].node] < c.nodeRecords[candidates[j].node]
+	})
+	if len(candidates) > topK {
+		candidates = candidates[:topK]
+	}
+	return candidates
+}
+
+// Snapshot writes a collection to disk and returns its description
+func (v *VectorStore) Snapshot(name string) (models.VectorCollection, error) {
+	if v.dir == "" {
+		return models.VectorCollection{}, fmt.Errorf("%w: snapshots are disabled; set AI_VECTOR_DIR to enable them", ErrInvalidVectorRequest)
+	}
+	collection, err := v.collection(name)
+	if err != nil {
+		return models.VectorCollection{}, err
+	}
+
+	collection.snapshotMutex.Lock()
+	defer collection.snapshotMutex.Unlock()
+
+	// Copy under the lock, write without it
+	collection.mutex.Lock()
+	snapshot := vectorSnapshot{Collection: collection.infoLocked()}
+	snapshotAt := time.Now()
+	snapshot.Collection.SnapshotAt = &snapshotAt
+	snapshot.Records = make([]models.VectorRecord, 0, len(collection.records))
+	for _, entry := range collection.records {
+		snapshot.Records = append(snapshot.Records, entry.record)
+	}
+	collection.dirty = false
+	collection.mutex.Unlock()
+
+	sort.Slice(snapshot.Records, func(i, j int) bool { return snapshot.Records[i].ID < snapshot.Records[j].ID })
+
+	// Write under the store lock, so a collection deleted since the copy is
+	// not brought back on disk
+	v.mutex.RLock()
+	if v.collections[name] != collection {
+		v.mutex.RUnlock()
+		return models.VectorCollection{}, ErrCollectionNotFound
+	}
+	err = writeJSONFileAtomic(v.snapshotPath(name), snapshot)
+	v.mutex.RUnlock()
+	if err != nil {
+		collection.mutex.Lock()
+		collection.dirty = true
+		collection.mutex.Unlock()
+		return models.VectorCollection{}, err
+	}
+
+	collection.mutex.Lock()
+	collection.info.SnapshotAt = snapshot.Collection.SnapshotAt
+	collection.mutex.Unlock()
+	return snapshot.Collection, nil
+}
+
+func (v *VectorStore) snapshotPath(name string) string {
+	return filepath.Join(v.dir, name+".json")
+}